@@ -0,0 +1,84 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"strings"
+)
+
+const (
+	// defaultCommentAccountEmailMismatch is used when the comment_account_email_mismatch is not configured
+	defaultCommentAccountEmailMismatch = "  \n\nThe commits of %s use the email %s, which differs from the email %s " +
+		"of the account. Please make sure that the commits use the email used to sign the CLA."
+	// defaultCommentAccountEmailSigned is used when the comment_account_email_signed is not configured
+	defaultCommentAccountEmailSigned = "  \n\nThe email %[3]s of the account of %[1]s has signed the CLA, but the " +
+		"commits use the email %[2]s. Please amend the commits by `git commit --amend --reset-author` after setting " +
+		"the email of the account by `git config user.email`, and push them again."
+)
+
+// accountEmailMismatch is the author of the pull request whose commits use an email other than the one of the account
+type accountEmailMismatch struct {
+	user         string
+	commitEmail  string
+	accountEmail string
+	// signed is whether the email of the account has signed the CLA
+	signed bool
+}
+
+// authorEmailMismatch checks the email of the account of the author of the pull request under the
+// check_author_account_email, when the author has not signed by the email of the commits. It returns nil
+// if the emails are the same or the email of the account is unknown
+func (bot *robot) authorEmailMismatch(pr *prSnapshot, repoCnf *repoConfig) *accountEmailMismatch {
+	if !repoCnf.CheckAuthorAccountEmail || pr.author == "" {
+		return nil
+	}
+	commitEmail, ok := pr.unsignedUserEmails[pr.author]
+	if !ok {
+		return nil
+	}
+
+	pr.watchdog.countAPICall()
+	accountEmail, success := bot.cli.GetUserEmail(pr.author)
+	if !success || accountEmail == "" || strings.EqualFold(accountEmail, commitEmail) {
+		return nil
+	}
+
+	signState, _ := bot.lookupEmailSignState(pr, accountEmail, repoCnf, nil)
+	return &accountEmailMismatch{
+		user:         pr.author,
+		commitEmail:  commitEmail,
+		accountEmail: accountEmail,
+		signed:       signState == client.CLASignStateYes,
+	}
+}
+
+// accountEmailMismatch renders the note warning that the emails of the commits differ from the one of the account
+func (r *commentRenderer) accountEmailMismatch(m *accountEmailMismatch) string {
+	if m == nil {
+		return ""
+	}
+
+	format := r.format(r.cnf.CommentAccountEmailMismatch, defaultCommentAccountEmailMismatch)
+	if m.signed {
+		format = r.format(r.cnf.CommentAccountEmailSigned, defaultCommentAccountEmailSigned)
+	}
+	data := r.data(nil)
+	data.Users, data.CommitEmail, data.AccountEmail = r.userMarks([]string{m.user}), maskEmail(m.commitEmail),
+		maskEmail(m.accountEmail)
+	return r.render(format, data, func(s string) string {
+		return fmt.Sprintf(s, data.Users, data.CommitEmail, data.AccountEmail)
+	})
+}
@@ -0,0 +1,70 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAuthorEmailMismatch(t *testing.T) {
+	mc := &multiServerClient{
+		mockClient: &mockClient{
+			successfulAddPRLabels: true, successfulCreatePRComment: true, successfulGetUserEmail: true,
+			userEmails: map[string]string{"u1": "u1@corp.com"},
+		},
+		states: map[string]string{
+			"icla?email=u1%40example.com": client.CLASignStateNo,
+			"icla?email=u1%40corp.com":    client.CLASignStateYes,
+		},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{
+		UserMarkFormat: "@ddd", PlaceholderCommitter: "ddd", CommentSomeNeedSign: "%s need to sign %s %s",
+	}}
+	repoCnf := &repoConfig{CheckURL: "icla", CLALabelYes: labelYes, CLALabelNo: labelNo, CheckAuthorAccountEmail: true}
+	commits := []client.PRCommit{{AuthorName: "u1", AuthorEmail: "u1@example.com"}}
+
+	check := func() *prSnapshot {
+		pr := newPRSnapshot(mc, org, repo, number)
+		pr.author = "u1"
+		_, signResult := bot.checkCLASignResult(pr, commits, repoCnf)
+		bot.waitCLASignature(pr, signResult, nil, repoCnf)
+		return pr
+	}
+
+	// the email of the account has signed the CLA
+	check()
+	assert.Contains(t, mc.comment, "The email u***@corp.com of the account of @u1 has signed the CLA, "+
+		"but the commits use the email u***@example.com.")
+
+	// neither has signed the CLA
+	mc.states["icla?email=u1%40corp.com"] = client.CLASignStateNo
+	check()
+	assert.Contains(t, mc.comment, "The commits of @u1 use the email u***@example.com, which differs from "+
+		"the email u***@corp.com of the account.")
+
+	// the same email, or the email of the account is hidden
+	mc.userEmails["u1"] = "U1@example.com"
+	check()
+	assert.NotContains(t, mc.comment, "of the account")
+	mc.userEmails["u1"] = ""
+	check()
+	assert.NotContains(t, mc.comment, "of the account")
+
+	// the option is disabled
+	mc.userEmails["u1"] = "u1@corp.com"
+	repoCnf.CheckAuthorAccountEmail = false
+	assert.Equal(t, (*accountEmailMismatch)(nil), bot.authorEmailMismatch(check(), repoCnf))
+}
@@ -0,0 +1,296 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	adminPathStateExport = "/admin/state/export"
+	adminPathStateImport = "/admin/state/import"
+	adminPathRecheckOrg  = "/admin/recheck-org"
+	adminPathStats       = "/admin/stats"
+	// adminPathUnsigned is the prefix of the unsigned contributors of a repository, /v1/unsigned/{org}/{repo}
+	adminPathUnsigned = "/v1/unsigned/"
+)
+
+// adminTenant is a consumer of the administration api identified by its api key
+type adminTenant struct {
+	Name   string `json:"name" required:"true"`
+	APIKey string `json:"api_key" required:"true"`
+	// RequestsPerMinute is the quota of the tenant, there is no limit if it is 0
+	RequestsPerMinute int `json:"requests_per_minute"`
+}
+
+// adminTenants is the content of the file listing the tenants of the administration api
+type adminTenants struct {
+	Tenants []adminTenant `json:"tenants"`
+}
+
+func (t *adminTenants) Validate() error {
+	names := map[string]bool{}
+	keys := map[string]bool{}
+	for i := range t.Tenants {
+		item := &t.Tenants[i]
+		if item.Name == "" || item.APIKey == "" {
+			return errors.New("the name and api_key of an admin tenant can not be empty")
+		}
+		if item.RequestsPerMinute < 0 {
+			return errors.New("the requests_per_minute of the admin tenant " + item.Name + " can not be negative")
+		}
+		if names[item.Name] || keys[item.APIKey] {
+			return errors.New("the admin tenant " + item.Name + " is duplicated")
+		}
+		names[item.Name], keys[item.APIKey] = true, true
+	}
+
+	return nil
+}
+
+// tenantQuota limits the requests of each tenant in a fixed window of one minute
+type tenantQuota struct {
+	mu      sync.Mutex
+	windows map[string]time.Time
+	counts  map[string]int
+	now     func() time.Time
+}
+
+func newTenantQuota() *tenantQuota {
+	return &tenantQuota{windows: map[string]time.Time{}, counts: map[string]int{}, now: time.Now}
+}
+
+func (q *tenantQuota) allow(t *adminTenant) bool {
+	if t.RequestsPerMinute == 0 {
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	window := q.now().Truncate(time.Minute)
+	if !q.windows[t.Name].Equal(window) {
+		q.windows[t.Name] = window
+		q.counts[t.Name] = 0
+	}
+	if q.counts[t.Name] >= t.RequestsPerMinute {
+		return false
+	}
+	q.counts[t.Name]++
+	return true
+}
+
+// adminServer serves the administration api of the bot
+type adminServer struct {
+	bot     *robot
+	store   stateStore
+	tenants []adminTenant
+	quota   *tenantQuota
+	log     *logrus.Entry
+}
+
+// registerAdminHandlers mounts the administration api on the mux.
+// The api is disabled when there is no tenant
+func registerAdminHandlers(mux *http.ServeMux, bot *robot, tenants []adminTenant, logger *logrus.Entry) {
+	if len(tenants) == 0 {
+		logger.Info("the admin api is disabled because no admin tenant is provided")
+		return
+	}
+
+	s := &adminServer{bot: bot, store: bot.store, tenants: tenants, quota: newTenantQuota(), log: logger}
+	mux.HandleFunc(adminPathStateExport, s.authorized(http.MethodGet, s.handleStateExport))
+	mux.HandleFunc(adminPathStateImport, s.authorized(http.MethodPost, s.handleStateImport))
+	mux.HandleFunc(adminPathRecheckOrg, s.authorized(http.MethodPost, s.handleRecheckOrg))
+	mux.HandleFunc(adminPathStats, s.authorized(http.MethodGet, s.handleStats))
+	mux.HandleFunc(adminPathUnsigned, s.authorized(http.MethodGet, s.handleUnsigned))
+	mux.HandleFunc(adminPathComplianceStats, s.authorized(http.MethodGet, s.handleComplianceStats))
+	mux.HandleFunc(adminPathBacklog, s.authorized(http.MethodGet, s.handleBacklog))
+	mux.HandleFunc(adminPathPRCLAStatus, s.authorized(http.MethodGet, s.handlePRCLAStatus))
+}
+
+func (s *adminServer) findTenant(r *http.Request) *adminTenant {
+	key := []byte(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	for i := range s.tenants {
+		if subtle.ConstantTimeCompare(key, []byte(s.tenants[i].APIKey)) == 1 {
+			return &s.tenants[i]
+		}
+	}
+
+	return nil
+}
+
+// statusRecorder records the status code written by the handler for the audit
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (s *adminServer) authorized(method string, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant := s.findTenant(r)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			name := ""
+			if tenant != nil {
+				name = tenant.Name
+			}
+			s.log.WithFields(logrus.Fields{
+				"audit":       "admin",
+				"tenant":      name,
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"remote-addr": r.RemoteAddr,
+				"status":      rec.status,
+			}).Info("admin request")
+		}()
+
+		if tenant == nil {
+			rec.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method != method {
+			rec.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !s.quota.allow(tenant) {
+			rec.Header().Set("Retry-After", "60")
+			rec.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		fn(rec, r)
+	}
+}
+
+func (s *adminServer) handleStateExport(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.store.exportSnapshot()
+	s.log.Infof("export the state of %d pull requests", len(snapshot.PRs))
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+func (s *adminServer) handleStateImport(w http.ResponseWriter, r *http.Request) {
+	var snapshot stateSnapshot
+	if err := json.NewDecoder(r.Body).Decode(&snapshot); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid snapshot: " + err.Error()})
+		return
+	}
+
+	if err := s.store.importSnapshot(snapshot); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.log.Infof("import the state of %d pull requests", len(snapshot.PRs))
+	writeJSON(w, http.StatusOK, map[string]int{"imported": len(snapshot.PRs)})
+}
+
+func (s *adminServer) handleRecheckOrg(w http.ResponseWriter, r *http.Request) {
+	org := r.URL.Query().Get("org")
+	if org == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing the org"})
+		return
+	}
+
+	n := s.bot.recheckOrg(org, s.log.WithField("recheck-org", org), nil)
+	if n < 0 {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "the org is being rechecked"})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]int{"queued": n})
+}
+
+// adminStats is the runtime statistics of the bot
+type adminStats struct {
+	// APIErrors is the error rates of the api calls in the sliding window of the error budget
+	APIErrors []apiErrorRate `json:"api_errors"`
+	// DegradedPlatforms lists the platforms whose non-essential operations are paused
+	DegradedPlatforms []string `json:"degraded_platforms"`
+}
+
+func (s *adminServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, adminStats{
+		APIErrors:         s.bot.budget.snapshot(),
+		DegradedPlatforms: s.bot.budget.degradedPlatforms(),
+	})
+}
+
+// unsignedContributor is an unsigned email of a repository, which is only shown hashed and masked
+type unsignedContributor struct {
+	// EmailHash is the hex of the sha256 of the email in lower case, to be matched against the known emails
+	EmailHash   string `json:"email_hash"`
+	MaskedEmail string `json:"masked_email"`
+	// PullRequests are the numbers of the pull requests blocked by the email
+	PullRequests []string `json:"pull_requests"`
+	Count        int      `json:"count"`
+}
+
+// unsignedContributors lists the unsigned emails of the pull requests of a repository whose latest evaluation
+// is unsigned, the email blocking the most pull requests first
+type unsignedContributors struct {
+	Org          string                `json:"org"`
+	Repo         string                `json:"repo"`
+	Contributors []unsignedContributor `json:"contributors"`
+}
+
+func (s *adminServer) handleUnsigned(w http.ResponseWriter, r *http.Request) {
+	org, repo, found := strings.Cut(strings.TrimPrefix(r.URL.Path, adminPathUnsigned), "/")
+	if !found || org == "" || repo == "" || strings.Contains(repo, "/") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "the path must be " + adminPathUnsigned + "{org}/{repo}"})
+		return
+	}
+
+	result := unsignedContributors{Org: org, Repo: repo, Contributors: []unsignedContributor{}}
+	for email, numbers := range s.store.listUnsignedEmails(org, repo) {
+		hash := sha256.Sum256([]byte(email))
+		result.Contributors = append(result.Contributors, unsignedContributor{
+			EmailHash:    hex.EncodeToString(hash[:]),
+			MaskedEmail:  maskEmailText(email),
+			PullRequests: numbers,
+			Count:        len(numbers),
+		})
+	}
+	sort.Slice(result.Contributors, func(i, j int) bool {
+		a, b := &result.Contributors[i], &result.Contributors[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.EmailHash < b.EmailHash
+	})
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,227 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/opensourceways/robot-universal-cla/adminclient"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdminStateHandlers(t *testing.T) {
+	logger := framework.NewLogger()
+	tenants := []adminTenant{{Name: "admin", APIKey: "secret"}}
+	mux := http.NewServeMux()
+	store := newMemoryStateStore()
+	store.put(prState{Org: org, Repo: repo, Number: number, Status: prStatusSigned})
+	registerAdminHandlers(mux, &robot{store: store}, tenants, logger)
+
+	req := httptest.NewRequest(http.MethodGet, adminPathStateExport, nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var snapshot stateSnapshot
+	assert.Equal(t, nil, json.Unmarshal(w.Body.Bytes(), &snapshot))
+	assert.Equal(t, 1, len(snapshot.PRs))
+
+	req = httptest.NewRequest(http.MethodPost, adminPathStateImport, bytes.NewReader(w.Body.Bytes()))
+	req.Header.Set("Authorization", "Bearer secret")
+	target := newMemoryStateStore()
+	mux = http.NewServeMux()
+	registerAdminHandlers(mux, &robot{store: target}, tenants, logger)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, ok := target.get(org, repo, number)
+	assert.Equal(t, true, ok)
+
+	req = httptest.NewRequest(http.MethodPost, adminPathStateImport, bytes.NewReader([]byte("{")))
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAdminStateCommentIDs(t *testing.T) {
+	logger := framework.NewLogger()
+	tenants := []adminTenant{{Name: "admin", APIKey: "secret"}}
+	mc := &mockClient{successfulCreatePRComment: true, createdCommentID: "21"}
+	bot := &robot{cli: mc, cnf: &configuration{}, store: newMemoryStateStore()}
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.recordPRState(pr, false, [3][]string{{}, {"u1"}})
+	bot.replaceCLAResultComment(pr, &repoConfig{}, withResultMarker(resultKindNeedSign, "sign the CLA"))
+	// the ids are kept when the PR is evaluated again
+	bot.recordPRState(pr, false, [3][]string{{}, {"u1"}})
+
+	mux := http.NewServeMux()
+	registerAdminHandlers(mux, bot, tenants, logger)
+	req := httptest.NewRequest(http.MethodGet, adminPathStateExport, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, adminPathStateImport, bytes.NewReader(w.Body.Bytes()))
+	req.Header.Set("Authorization", "Bearer secret")
+	target := newMemoryStateStore()
+	mux = http.NewServeMux()
+	registerAdminHandlers(mux, &robot{store: target}, tenants, logger)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	s, _ := target.get(org, repo, number)
+	assert.Equal(t, []string{"21"}, s.CommentIDs)
+
+	// the comment is not recorded if the platform does not return its id
+	mc.createdCommentID = ""
+	bot.replaceCLAResultComment(newPRSnapshot(mc, org, repo, number), &repoConfig{}, "sign the CLA")
+	s, _ = bot.store.get(org, repo, number)
+	assert.Empty(t, s.CommentIDs)
+}
+
+func TestAdminTenantQuota(t *testing.T) {
+	tenants := &adminTenants{Tenants: []adminTenant{
+		{Name: "ops", APIKey: "k1", RequestsPerMinute: 2},
+		{Name: "admin", APIKey: "k2"},
+	}}
+	assert.Equal(t, nil, tenants.Validate())
+
+	mux := http.NewServeMux()
+	registerAdminHandlers(mux, &robot{store: newMemoryStateStore()}, tenants.Tenants, framework.NewLogger())
+	request := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, adminPathStateExport, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("k1"))
+	assert.Equal(t, http.StatusOK, request("k1"))
+	assert.Equal(t, http.StatusTooManyRequests, request("k1"))
+	// the quota of a tenant does not affect the others
+	assert.Equal(t, http.StatusOK, request("k2"))
+	assert.Equal(t, http.StatusUnauthorized, request("k3"))
+
+	q := newTenantQuota()
+	now := time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC)
+	q.now = func() time.Time { return now }
+	tenant := &tenants.Tenants[0]
+	assert.Equal(t, true, q.allow(tenant))
+	assert.Equal(t, true, q.allow(tenant))
+	assert.Equal(t, false, q.allow(tenant))
+	now = now.Add(time.Minute)
+	assert.Equal(t, true, q.allow(tenant))
+
+	tenants.Tenants = append(tenants.Tenants, adminTenant{Name: "ops", APIKey: "k4"})
+	assert.NotEqual(t, nil, tenants.Validate())
+}
+
+func TestAdminUnsignedHandler(t *testing.T) {
+	store := newMemoryStateStore()
+	store.put(prState{Org: org, Repo: repo, Number: "1", Status: prStatusUnsigned,
+		UnsignedUsers: []string{"u1", "u2"}, UnsignedEmails: []string{"u1@example.com", "u2@example.com"}})
+	store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusUnsigned,
+		UnsignedUsers: []string{"u1"}, UnsignedEmails: []string{"u1@example.com"}})
+	store.put(prState{Org: org, Repo: "repo2", Number: "1", Status: prStatusUnsigned,
+		UnsignedUsers: []string{"u3"}, UnsignedEmails: []string{"u3@example.com"}})
+	mux := http.NewServeMux()
+	registerAdminHandlers(mux, &robot{store: store}, []adminTenant{{Name: "admin", APIKey: "secret"}}, framework.NewLogger())
+
+	request := func(path string) (int, unsignedContributors) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		result := unsignedContributors{}
+		_ = json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result
+	}
+
+	code, result := request(adminPathUnsigned + org + "/" + repo)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, len(result.Contributors))
+	assert.Equal(t, "u***@example.com", result.Contributors[0].MaskedEmail)
+	assert.Equal(t, []string{"1", "2"}, result.Contributors[0].PullRequests)
+	assert.Equal(t, 2, result.Contributors[0].Count)
+	assert.Equal(t, 64, len(result.Contributors[0].EmailHash))
+	assert.NotEqual(t, result.Contributors[0].EmailHash, result.Contributors[1].EmailHash)
+	assert.NotContains(t, fmt.Sprint(result), "u1@example.com")
+
+	// the pull request signed later is no longer blocked
+	store.put(prState{Org: org, Repo: repo, Number: "1", Status: prStatusSigned, SignedUsers: []string{"u1", "u2"}})
+	_, result = request(adminPathUnsigned + org + "/" + repo)
+	assert.Equal(t, 1, len(result.Contributors))
+	assert.Equal(t, []string{"2"}, result.Contributors[0].PullRequests)
+
+	code, result = request(adminPathUnsigned + org + "/repo3")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []unsignedContributor{}, result.Contributors)
+
+	code, _ = request(adminPathUnsigned + org)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+// TestAdminClientCompatibility guards that the snapshot of the admin client keeps all the fields of the state
+func TestAdminClientCompatibility(t *testing.T) {
+	bot := &robot{cli: new(mockClient), cnf: &configuration{}, store: newMemoryStateStore()}
+	pr := newPRSnapshot(bot.cli, org, repo, number)
+	pr.head, pr.eventTime = "org1/repo1/sha1", time.Now().UTC()
+	pr.addUnsignedUser("u2", "U2@example.com")
+	bot.recordPRState(pr, false, [3][]string{{"u1"}, {"u2"}})
+	bot.recordOverride(pr, &claOverride{By: "m1", Reason: "imported", At: pr.eventTime, ExpiresAt: pr.eventTime})
+
+	want, _ := json.Marshal(bot.store.exportSnapshot())
+	snapshot := adminclient.StateSnapshot{}
+	assert.Equal(t, nil, json.Unmarshal(want, &snapshot))
+	assert.Equal(t, stateSnapshotVersion, adminclient.SnapshotVersion)
+	got, _ := json.Marshal(snapshot)
+	assert.JSONEq(t, string(want), string(got))
+
+	budget := newAPIErrorBudget(&errorBudgetConfig{DegradeErrorRate: 0.5, MinRequests: 1})
+	budget.record(platformCLAServer, "CheckCLASignature", false)
+	want, _ = json.Marshal(adminStats{APIErrors: budget.snapshot(), DegradedPlatforms: budget.degradedPlatforms()})
+	stats := adminclient.Stats{}
+	assert.Equal(t, nil, json.Unmarshal(want, &stats))
+	got, _ = json.Marshal(stats)
+	assert.JSONEq(t, string(want), string(got))
+
+	want, _ = json.Marshal(unsignedContributors{Org: org, Repo: repo, Contributors: []unsignedContributor{
+		{EmailHash: "h", MaskedEmail: "u***@example.com", PullRequests: []string{number}, Count: 1},
+	}})
+	unsigned := adminclient.UnsignedContributors{}
+	assert.Equal(t, nil, json.Unmarshal(want, &unsigned))
+	got, _ = json.Marshal(unsigned)
+	assert.JSONEq(t, string(want), string(got))
+
+	want, _ = json.Marshal(prCLAStatus{Org: org, Repo: repo, Number: number, Status: prStatusSigned,
+		Mergeable: true, HeadSHA: "sha1", LastEvaluation: pr.eventTime})
+	status := adminclient.PRCLAStatus{}
+	assert.Equal(t, nil, json.Unmarshal(want, &status))
+	got, _ = json.Marshal(status)
+	assert.JSONEq(t, string(want), string(got))
+}
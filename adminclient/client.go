@@ -0,0 +1,348 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package adminclient is the Go client of the admin api of robot-universal-cla, which is described in openapi.yaml.
+//
+// The client follows the compatibility of the admin api: the existing paths and fields are never removed or
+// changed in meaning within the same SnapshotVersion, and the new fields are only added as optional ones.
+package adminclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SnapshotVersion is the version of the state snapshot supported by this client
+const SnapshotVersion = 1
+
+// PRState is the context the bot keeps for a pull request
+type PRState struct {
+	Org                string            `json:"org"`
+	Repo               string            `json:"repo"`
+	Number             string            `json:"number"`
+	Status             string            `json:"status"`
+	SignedUsers        []string          `json:"signed_users,omitempty"`
+	UnsignedUsers      []string          `json:"unsigned_users,omitempty"`
+	UnknownUsers       []string          `json:"unknown_users,omitempty"`
+	CommentIDs         []string          `json:"comment_ids,omitempty"`
+	LastEvaluation     time.Time         `json:"last_evaluation"`
+	LastEventTime      time.Time         `json:"last_event_time,omitempty"`
+	Head               string            `json:"head,omitempty"`
+	HeadSHA            string            `json:"head_sha,omitempty"`
+	SignedCommits      string            `json:"signed_commits,omitempty"`
+	Base               string            `json:"base,omitempty"`
+	Scope              string            `json:"scope,omitempty"`
+	Since              string            `json:"since,omitempty"`
+	UnsignedEmails     []string          `json:"unsigned_emails,omitempty"`
+	UnsignedUserEmails map[string]string `json:"unsigned_user_emails,omitempty"`
+	CommentCount       int               `json:"comment_count,omitempty"`
+	EmailsSent         int               `json:"emails_sent,omitempty"`
+	History            []PREvaluation    `json:"history,omitempty"`
+	Override           *CLAOverride      `json:"override,omitempty"`
+	Closed             bool              `json:"closed,omitempty"`
+}
+
+// CLAOverride is the override of the CLA check of a pull request which expires
+type CLAOverride struct {
+	By        string    `json:"by"`
+	Reason    string    `json:"reason"`
+	At        time.Time `json:"at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	HeadSHA   string    `json:"head_sha"`
+}
+
+// PREvaluation is an entry of the history of a pull request
+type PREvaluation struct {
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+	Head   string    `json:"head,omitempty"`
+}
+
+// ContributorState is the milestones of a contributor of an organization
+type ContributorState struct {
+	Org           string    `json:"org"`
+	User          string    `json:"user"`
+	FirstSeen     time.Time `json:"first_seen"`
+	FirstSigned   time.Time `json:"first_signed,omitempty"`
+	FirstSignedPR string    `json:"first_signed_pr,omitempty"`
+}
+
+// StateSnapshot is a versioned copy of the state of the bot
+type StateSnapshot struct {
+	Version      int                  `json:"version"`
+	ExportedAt   time.Time            `json:"exported_at"`
+	PRs          []PRState            `json:"prs"`
+	Indexes      map[string][]string  `json:"indexes,omitempty"`
+	Contributors []ContributorState   `json:"contributors,omitempty"`
+	Emailed      map[string]time.Time `json:"emailed,omitempty"`
+}
+
+// APIErrorRate is the error rate of an endpoint of a platform in the sliding window of the error budget
+type APIErrorRate struct {
+	Platform  string  `json:"platform"`
+	Endpoint  string  `json:"endpoint"`
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// Stats is the runtime statistics of the bot
+type Stats struct {
+	APIErrors         []APIErrorRate `json:"api_errors"`
+	DegradedPlatforms []string       `json:"degraded_platforms"`
+}
+
+// UnsignedContributor is an unsigned email of a repository, which is only shown hashed and masked
+type UnsignedContributor struct {
+	// EmailHash is the hex of the sha256 of the email in lower case
+	EmailHash    string   `json:"email_hash"`
+	MaskedEmail  string   `json:"masked_email"`
+	PullRequests []string `json:"pull_requests"`
+	Count        int      `json:"count"`
+}
+
+// UnsignedContributors lists the unsigned emails of the pull requests of a repository
+type UnsignedContributors struct {
+	Org          string                `json:"org"`
+	Repo         string                `json:"repo"`
+	Contributors []UnsignedContributor `json:"contributors"`
+}
+
+// ComplianceCounts is the CLA compliance of the pull requests of a repository or an organization
+type ComplianceCounts struct {
+	// BlockedPRs is the number of the pull requests whose latest evaluation is unsigned
+	BlockedPRs int `json:"blocked_prs"`
+	// UnsignedEmails is the number of the distinct unsigned emails of the blocked pull requests
+	UnsignedEmails int `json:"unsigned_emails"`
+	// Resolved is the number of the times a pull request turned signed after being unsigned
+	Resolved int `json:"resolved"`
+	// AvgSecondsToSign is the average time from the unsigned evaluation to the signed one of the resolved ones
+	AvgSecondsToSign float64 `json:"avg_seconds_to_sign"`
+}
+
+// RepoCompliance is the CLA compliance of a repository
+type RepoCompliance struct {
+	Repo string `json:"repo"`
+	ComplianceCounts
+}
+
+// OrgCompliance is the CLA compliance of an organization and its repositories
+type OrgCompliance struct {
+	Org string `json:"org"`
+	ComplianceCounts
+	Repos []RepoCompliance `json:"repos"`
+}
+
+// ComplianceStats is the CLA compliance of the organizations
+type ComplianceStats struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Orgs        []OrgCompliance `json:"orgs"`
+}
+
+// BacklogContributor is an unsigned contributor with the pull requests blocked as org/repo/number
+type BacklogContributor struct {
+	Name         string   `json:"name"`
+	MaskedEmail  string   `json:"masked_email"`
+	PullRequests []string `json:"pull_requests"`
+}
+
+// BacklogReport lists the unsigned contributors blocking the pull requests, the contributor blocking the most first
+type BacklogReport struct {
+	GeneratedAt  time.Time            `json:"generated_at"`
+	Contributors []BacklogContributor `json:"contributors"`
+	// Text is the report in markdown
+	Text string `json:"text"`
+}
+
+// PRCLAStatus is the CLA status of a pull request in its latest evaluation
+type PRCLAStatus struct {
+	Org        string `json:"org"`
+	Repo       string `json:"repo"`
+	Number     string `json:"number"`
+	Status     string `json:"status"`
+	Overridden bool   `json:"overridden"`
+	// SignedCommits is the result of the require_signed_commits, which is empty if it is not required
+	SignedCommits string `json:"signed_commits,omitempty"`
+	// Mergeable is true if all the contributors have signed or the check is overridden, the commits are signed
+	// if required, and the head to merge is the one evaluated
+	Mergeable bool `json:"mergeable"`
+	// HeadSHA is the head commit evaluated, which should be checked to still be the head before merging
+	HeadSHA        string    `json:"head_sha,omitempty"`
+	LastEvaluation time.Time `json:"last_evaluation"`
+}
+
+// Error is returned when the admin api responds with a status other than the expected one
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("admin api responded %d", e.StatusCode)
+	}
+	return fmt.Sprintf("admin api responded %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the admin api of the bot with the api key of a tenant
+type Client struct {
+	baseURL string
+	apiKey  string
+	hc      *http.Client
+}
+
+// New creates a client of the bot served at baseURL, such as http://robot-universal-cla:8888
+func New(baseURL, apiKey string) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), apiKey: apiKey, hc: &http.Client{Timeout: time.Minute}}
+}
+
+// WithHTTPClient replaces the http client used to send the requests
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	c.hc = hc
+	return c
+}
+
+// ExportState exports the state of the bot
+func (c *Client) ExportState(ctx context.Context) (*StateSnapshot, error) {
+	snapshot := &StateSnapshot{}
+	if err := c.do(ctx, http.MethodGet, "/admin/state/export", nil, http.StatusOK, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// ImportState replaces the state of the bot with the snapshot and returns the number of the pull requests imported
+func (c *Client) ImportState(ctx context.Context, snapshot *StateSnapshot) (int, error) {
+	result := struct {
+		Imported int `json:"imported"`
+	}{}
+	if err := c.do(ctx, http.MethodPost, "/admin/state/import", snapshot, http.StatusOK, &result); err != nil {
+		return 0, err
+	}
+	return result.Imported, nil
+}
+
+// RecheckOrg queues the rechecks of the blocked pull requests of the organization and returns their number.
+// An Error with http.StatusConflict is returned if the organization is being rechecked
+func (c *Client) RecheckOrg(ctx context.Context, org string) (int, error) {
+	result := struct {
+		Queued int `json:"queued"`
+	}{}
+	path := "/admin/recheck-org?org=" + url.QueryEscape(org)
+	if err := c.do(ctx, http.MethodPost, path, nil, http.StatusAccepted, &result); err != nil {
+		return 0, err
+	}
+	return result.Queued, nil
+}
+
+// Stats returns the runtime statistics of the bot, including the error rates of its api calls
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	stats := &Stats{}
+	if err := c.do(ctx, http.MethodGet, "/admin/stats", nil, http.StatusOK, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ComplianceStats returns the CLA compliance of the repositories of the organization, or of all the organizations
+// if org is empty
+func (c *Client) ComplianceStats(ctx context.Context, org string) (*ComplianceStats, error) {
+	stats := &ComplianceStats{}
+	path := "/api/v1/stats"
+	if org != "" {
+		path += "?org=" + url.QueryEscape(org)
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, http.StatusOK, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Backlog returns the report of the unsigned contributors blocking the pull requests of the configured repositories
+func (c *Client) Backlog(ctx context.Context) (*BacklogReport, error) {
+	report := &BacklogReport{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/backlog", nil, http.StatusOK, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Unsigned lists the unsigned emails of the pull requests of the repository, the email blocking the most first
+func (c *Client) Unsigned(ctx context.Context, org, repo string) (*UnsignedContributors, error) {
+	result := &UnsignedContributors{}
+	path := "/v1/unsigned/" + url.PathEscape(org) + "/" + url.PathEscape(repo)
+	if err := c.do(ctx, http.MethodGet, path, nil, http.StatusOK, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PRCLAStatus returns the CLA status of the pull request for admitting the merge of its head sha, which is not
+// mergeable unless the sha is the head evaluated. The head is not checked if the sha is empty. An Error with
+// http.StatusNotFound is returned if the pull request has not been evaluated
+func (c *Client) PRCLAStatus(ctx context.Context, org, repo, number, sha string) (*PRCLAStatus, error) {
+	result := &PRCLAStatus{}
+	query := url.Values{"org": {org}, "repo": {repo}, "number": {number}}
+	if sha != "" {
+		query.Set("sha", sha)
+	}
+	path := "/api/v1/pr-cla-status?" + query.Encode()
+	if err := c.do(ctx, http.MethodGet, path, nil, http.StatusOK, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body any, expected int, receiver any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expected {
+		failure := struct {
+			Error string `json:"error"`
+		}{}
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		return &Error{StatusCode: resp.StatusCode, Message: failure.Error}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(receiver)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package adminclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/admin/state/export":
+			_ = json.NewEncoder(w).Encode(StateSnapshot{Version: SnapshotVersion, PRs: []PRState{{Org: "org1"}}})
+		case "/admin/state/import":
+			snapshot := StateSnapshot{}
+			_ = json.NewDecoder(r.Body).Decode(&snapshot)
+			_ = json.NewEncoder(w).Encode(map[string]int{"imported": len(snapshot.PRs)})
+		case "/api/v1/stats":
+			_ = json.NewEncoder(w).Encode(ComplianceStats{Orgs: []OrgCompliance{{
+				Org: r.URL.Query().Get("org"), ComplianceCounts: ComplianceCounts{BlockedPRs: 3},
+			}}})
+		case "/api/v1/backlog":
+			_ = json.NewEncoder(w).Encode(BacklogReport{Contributors: []BacklogContributor{{Name: "u1"}}})
+		case "/api/v1/pr-cla-status":
+			_ = json.NewEncoder(w).Encode(PRCLAStatus{Number: r.URL.Query().Get("number"),
+				HeadSHA: r.URL.Query().Get("sha"), Mergeable: true})
+		case "/admin/stats":
+			_ = json.NewEncoder(w).Encode(Stats{APIErrors: []APIErrorRate{{Platform: "cla-server", Requests: 2}}})
+		case "/v1/unsigned/org1/repo 1":
+			_ = json.NewEncoder(w).Encode(UnsignedContributors{Org: "org1", Contributors: []UnsignedContributor{{Count: 2}}})
+		case "/admin/recheck-org":
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "the org is being rechecked"})
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/", "secret")
+	snapshot, err := c.ExportState(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "org1", snapshot.PRs[0].Org)
+
+	n, err := c.ImportState(context.Background(), snapshot)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, n)
+
+	stats, err := c.Stats(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, stats.APIErrors[0].Requests)
+
+	compliance, err := c.ComplianceStats(context.Background(), "org1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "org1", compliance.Orgs[0].Org)
+	assert.Equal(t, 3, compliance.Orgs[0].BlockedPRs)
+
+	backlog, err := c.Backlog(context.Background())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "u1", backlog.Contributors[0].Name)
+
+	status, err := c.PRCLAStatus(context.Background(), "org1", "repo1", "2", "sha1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "2", status.Number)
+	assert.Equal(t, "sha1", status.HeadSHA)
+	assert.Equal(t, true, status.Mergeable)
+
+	unsigned, err := c.Unsigned(context.Background(), "org1", "repo 1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, unsigned.Contributors[0].Count)
+
+	_, err = c.RecheckOrg(context.Background(), "org1")
+	var apiErr *Error
+	assert.Equal(t, true, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	assert.Equal(t, "the org is being rechecked", apiErr.Message)
+
+	_, err = New(server.URL, "wrong").ExportState(context.Background())
+	assert.Equal(t, "admin api responded 401", err.Error())
+}
@@ -0,0 +1,281 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	auditSinkStdout  = "stdout"
+	auditSinkFile    = "file"
+	auditSinkWebhook = "webhook"
+
+	auditActionLabelAdd      = "label_add"
+	auditActionLabelRemove   = "label_remove"
+	auditActionLabelCreate   = "label_create"
+	auditActionCommentCreate = "comment_create"
+	auditActionCommentUpdate = "comment_update"
+	auditActionCommentDelete = "comment_delete"
+	auditActionCommentQueue  = "comment_queue"
+	auditActionReactionAdd   = "reaction_add"
+	auditActionVerdict       = "verdict"
+	auditActionOverride      = "override"
+
+	auditOutcomeSuccess = "success"
+	auditOutcomeFailure = "failure"
+
+	// auditActorRobot is the actor of the mutations not triggered by a user or a named trigger
+	auditActorRobot = "robot"
+	// the actors of the evaluations triggered by the bot itself
+	auditActorRecheck        = "recheck-org"
+	auditActorRescan         = "rescan"
+	auditActorCallback       = "cla-signed-callback"
+	auditActorSameHeadPRs    = "same-head-sync"
+	auditActorOverrideExpiry = "override-expiry"
+	auditActorLabelRetry     = "label-retry"
+
+	// auditWebhookQueueSize bounds the records waiting to be posted to the webhook, the newer ones are dropped
+	// when it is full
+	auditWebhookQueueSize = 1000
+	auditWebhookTimeout   = 10 * time.Second
+	auditFilePerm         = 0o600
+)
+
+// auditConfig records the mutations of the pull requests and the CLA verdicts as json lines
+type auditConfig struct {
+	// Sink is one of stdout, file and webhook. The audit is disabled if it is empty. It is read at the startup
+	Sink string `json:"sink"`
+	// Path is the file the records are appended to, for the file sink
+	Path string `json:"path"`
+	// URL is where each record is posted to, for the webhook sink
+	URL string `json:"url"`
+}
+
+func (c *auditConfig) validate() error {
+	switch c.Sink {
+	case "", auditSinkStdout:
+	case auditSinkFile:
+		if c.Path == "" {
+			return errors.New("the path of the audit is required by the file sink")
+		}
+	case auditSinkWebhook:
+		if c.URL == "" {
+			return errors.New("the url of the audit is required by the webhook sink")
+		}
+	default:
+		return fmt.Errorf("unsupported audit sink %q, it must be one of %s, %s and %s",
+			c.Sink, auditSinkStdout, auditSinkFile, auditSinkWebhook)
+	}
+	return nil
+}
+
+// auditRecord is a mutation of a pull request, or the CLA verdict of an evaluation
+type auditRecord struct {
+	Time   time.Time `json:"time"`
+	Org    string    `json:"org"`
+	Repo   string    `json:"repo"`
+	Number string    `json:"number"`
+	// Actor is the user or the trigger of the bot causing the mutation
+	Actor  string `json:"actor"`
+	Action string `json:"action"`
+	// Target is the labels or the id of the comment mutated
+	Target  string `json:"target,omitempty"`
+	Outcome string `json:"outcome"`
+	// Detail is the decision and the counts of the contributors of the verdict, or the reason of the override
+	Detail string `json:"detail,omitempty"`
+}
+
+type auditSink interface {
+	write(record []byte) error
+}
+
+// writerSink writes the records to a writer, such as the stdout
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *writerSink) write(record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.w.Write(record)
+	return err
+}
+
+// fileSink appends the records to a file, which is opened for each record so that it can be rotated
+type fileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *fileSink) write(record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, auditFilePerm)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(record); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// webhookSink posts the records to the url in the background, so that a slow receiver does not block the handling
+type webhookSink struct {
+	url   string
+	cli   *http.Client
+	queue chan []byte
+	log   *logrus.Entry
+}
+
+func newWebhookSink(url string, logger *logrus.Entry) *webhookSink {
+	s := &webhookSink{
+		url:   url,
+		cli:   &http.Client{Timeout: auditWebhookTimeout},
+		queue: make(chan []byte, auditWebhookQueueSize),
+		log:   logger,
+	}
+	go s.run()
+	return s
+}
+
+func (s *webhookSink) write(record []byte) error {
+	select {
+	case s.queue <- record:
+		return nil
+	default:
+		return errors.New("the queue of the audit webhook is full")
+	}
+}
+
+func (s *webhookSink) run() {
+	for record := range s.queue {
+		if err := s.post(record); err != nil {
+			s.log.WithError(err).Errorf("failed to post the audit record: %s", bytes.TrimSpace(record))
+		}
+	}
+}
+
+func (s *webhookSink) post(record []byte) error {
+	resp, err := s.cli.Post(s.url, "application/json", bytes.NewReader(record))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d of the audit webhook", resp.StatusCode)
+	}
+	return nil
+}
+
+// auditLog writes the audit records to the sink. All the methods are safe on a nil auditLog, which is disabled
+type auditLog struct {
+	sink auditSink
+	now  func() time.Time
+	log  *logrus.Entry
+	// maskEmails masks the emails in the target and the detail of the records, in the privacy_mode
+	maskEmails bool
+}
+
+func newAuditLog(cfg *auditConfig, logger *logrus.Entry) *auditLog {
+	var sink auditSink
+	switch cfg.Sink {
+	case auditSinkStdout:
+		sink = &writerSink{w: os.Stdout}
+	case auditSinkFile:
+		sink = &fileSink{path: cfg.Path}
+	case auditSinkWebhook:
+		sink = newWebhookSink(cfg.URL, logger)
+	default:
+		return nil
+	}
+	return &auditLog{sink: sink, now: time.Now, log: logger}
+}
+
+func (a *auditLog) record(r auditRecord) {
+	if a == nil {
+		return
+	}
+
+	r.Time = a.now().UTC()
+	if a.maskEmails {
+		r.Target, r.Detail = maskEmails(r.Target), maskEmails(r.Detail)
+	}
+	data, err := json.Marshal(r)
+	if err == nil {
+		err = a.sink.write(append(data, '\n'))
+	}
+	if err != nil {
+		a.log.WithError(err).Errorf("failed to write the audit record of %s on %s", r.Action, prKey(r.Org, r.Repo, r.Number))
+	}
+}
+
+func auditOutcome(success bool) string {
+	if success {
+		return auditOutcomeSuccess
+	}
+	return auditOutcomeFailure
+}
+
+// auditMutation records a mutation of the pull request, and logs it if it failed
+func (bot *robot) auditMutation(pr *prSnapshot, action, target string, success bool) {
+	if !success {
+		pr.logger().WithFields(logrus.Fields{"action": action, "target": target}).Warning("failed to mutate the pull request")
+	}
+	bot.audit.record(auditRecord{
+		Org: pr.org, Repo: pr.repo, Number: pr.number, Actor: pr.auditActor(),
+		Action: action, Target: target, Outcome: auditOutcome(success),
+	})
+}
+
+// auditVerdict records the decision of the evaluation of the pull request
+func (bot *robot) auditVerdict(pr *prSnapshot) {
+	if pr.stats.decision == "" {
+		return
+	}
+
+	bot.audit.record(auditRecord{
+		Org: pr.org, Repo: pr.repo, Number: pr.number, Actor: pr.auditActor(),
+		Action: auditActionVerdict, Outcome: pr.stats.decision,
+		Detail: fmt.Sprintf("signed=%d unsigned=%d unknown=%d", len(pr.stats.signResult[0]),
+			len(pr.stats.signResult[1]), len(pr.stats.signResult[2])),
+	})
+}
+
+func (pr *prSnapshot) auditActor() string {
+	if pr.actor == "" {
+		return auditActorRobot
+	}
+	return pr.actor
+}
+
+func auditLabels(labels []string) string {
+	return strings.Join(labels, ",")
+}
@@ -0,0 +1,95 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeAuditRecords(t *testing.T, data string) []auditRecord {
+	var records []auditRecord
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		var r auditRecord
+		assert.Equal(t, nil, json.Unmarshal([]byte(line), &r))
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestAuditMutations(t *testing.T) {
+	buf := &bytes.Buffer{}
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	mc := &mockClient{successfulAddPRLabels: true, successfulCreatePRComment: true}
+	bot := &robot{
+		cli:   mc,
+		cnf:   &configuration{},
+		audit: &auditLog{sink: &writerSink{w: buf}, now: func() time.Time { return now }, log: framework.NewLogger()},
+	}
+
+	pr := newPRSnapshot(mc, org, repo, number).withActor(commenter)
+	assert.Equal(t, true, bot.addPRLabels(pr, []string{labelYes, "lgtm"}))
+	assert.Equal(t, false, bot.removePRLabels(pr, []string{labelNo}))
+	assert.Equal(t, true, bot.postPRComment(pr, "comment"))
+	bot.deletePRComment(newPRSnapshot(mc, org, repo, number), "11")
+	pr.stats.decision = prStatusSigned
+	pr.stats.signResult[0] = []string{"a@example.com"}
+	bot.auditVerdict(pr)
+
+	records := decodeAuditRecords(t, buf.String())
+	assert.Equal(t, 5, len(records))
+	assert.Equal(t, auditRecord{
+		Time: now, Org: org, Repo: repo, Number: number, Actor: commenter,
+		Action: auditActionLabelAdd, Target: labelYes + ",lgtm", Outcome: auditOutcomeSuccess,
+	}, records[0])
+	assert.Equal(t, auditOutcomeFailure, records[1].Outcome)
+	assert.Equal(t, auditActionCommentCreate, records[2].Action)
+	assert.Equal(t, auditActorRobot, records[3].Actor)
+	assert.Equal(t, "11", records[3].Target)
+	assert.Equal(t, auditActionVerdict, records[4].Action)
+	assert.Equal(t, prStatusSigned, records[4].Outcome)
+	assert.Equal(t, "signed=1 unsigned=0 unknown=0", records[4].Detail)
+
+	// the audit is disabled if the robot has none
+	bot.audit = nil
+	buf.Reset()
+	bot.addPRLabels(pr, []string{labelYes})
+	assert.Equal(t, "", buf.String())
+}
+
+func TestAuditFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	a := newAuditLog(&auditConfig{Sink: auditSinkFile, Path: path}, framework.NewLogger())
+	a.record(auditRecord{Org: org, Repo: repo, Number: number, Action: auditActionLabelAdd})
+	a.record(auditRecord{Org: org, Repo: repo, Number: number, Action: auditActionLabelRemove})
+
+	data, err := os.ReadFile(path)
+	assert.Equal(t, nil, err)
+	records := decodeAuditRecords(t, string(data))
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, auditActionLabelRemove, records[1].Action)
+
+	assert.Nil(t, newAuditLog(&auditConfig{}, framework.NewLogger()))
+	assert.NotEqual(t, nil, (&auditConfig{Sink: auditSinkFile}).validate())
+	assert.NotEqual(t, nil, (&auditConfig{Sink: auditSinkWebhook}).validate())
+	assert.NotEqual(t, nil, (&auditConfig{Sink: "syslog"}).validate())
+	assert.Equal(t, nil, (&auditConfig{Sink: auditSinkStdout}).validate())
+}
@@ -0,0 +1,201 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// adminPathBacklog is the report of the unsigned contributors blocking the pull requests
+	adminPathBacklog = "/api/v1/backlog"
+
+	// defaultBacklogReportIntervalHours is used when the interval_hours of the backlog_report is not configured
+	defaultBacklogReportIntervalHours = 24
+	// backlogReportMaxLines bounds the contributors listed in the text of the report, the others are counted only
+	backlogReportMaxLines = 50
+	backlogReportTimeout  = 10 * time.Second
+)
+
+// backlogReportConfig posts the report of the unsigned contributors blocking the pull requests periodically
+type backlogReportConfig struct {
+	// WebhookURL is where the report is posted as json with a markdown `text`, such as the incoming webhook
+	// of a chat channel. The report is not posted if it is empty
+	WebhookURL string `json:"webhook_url"`
+	// IntervalHours is the interval to post the report. It is read at the startup. Default is 24
+	IntervalHours int `json:"interval_hours"`
+}
+
+func (c *backlogReportConfig) validate() error {
+	if c.IntervalHours < 0 {
+		return errors.New("the interval_hours of the backlog_report can not be negative")
+	}
+	if c.WebhookURL != "" {
+		return validateURL("webhook_url of the backlog_report", c.WebhookURL, false)
+	}
+	return nil
+}
+
+func (c *backlogReportConfig) interval() time.Duration {
+	if c.IntervalHours == 0 {
+		return defaultBacklogReportIntervalHours * time.Hour
+	}
+	return time.Duration(c.IntervalHours) * time.Hour
+}
+
+// backlogContributor is an unsigned contributor with the pull requests blocked, whose email is only shown masked
+type backlogContributor struct {
+	Name        string `json:"name"`
+	MaskedEmail string `json:"masked_email"`
+	// PullRequests are the pull requests blocked, as org/repo/number
+	PullRequests []string `json:"pull_requests"`
+}
+
+// backlogReport lists the unsigned contributors blocking the pull requests of the configured repositories,
+// the contributor blocking the most first
+type backlogReport struct {
+	GeneratedAt  time.Time            `json:"generated_at"`
+	Contributors []backlogContributor `json:"contributors"`
+	// Text is the report in markdown, for the chat channels
+	Text string `json:"text"`
+}
+
+// backlogReport compiles the report from the open pull requests whose latest evaluation is unsigned
+func (bot *robot) backlogReport() backlogReport {
+	report := backlogReport{GeneratedAt: time.Now().UTC(), Contributors: []backlogContributor{}}
+	if bot.store != nil {
+		index := map[string]int{}
+		for _, s := range bot.store.exportSnapshot().PRs {
+			if s.Closed || s.Status != prStatusUnsigned || bot.config().getMatchedRepoConfig(s.Org, s.Repo, nil) == nil {
+				continue
+			}
+
+			for _, user := range s.UnsignedUsers {
+				email := s.UnsignedUserEmails[user]
+				k := user + "\x00" + email
+				i, ok := index[k]
+				if !ok {
+					i, index[k] = len(report.Contributors), len(report.Contributors)
+					report.Contributors = append(report.Contributors, backlogContributor{
+						Name: user, MaskedEmail: maskEmailText(email), PullRequests: []string{},
+					})
+				}
+				report.Contributors[i].PullRequests = append(report.Contributors[i].PullRequests, s.key())
+			}
+		}
+	}
+
+	sort.Slice(report.Contributors, func(i, j int) bool {
+		a, b := &report.Contributors[i], &report.Contributors[j]
+		if len(a.PullRequests) != len(b.PullRequests) {
+			return len(a.PullRequests) > len(b.PullRequests)
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.MaskedEmail < b.MaskedEmail
+	})
+	for i := range report.Contributors {
+		sort.Strings(report.Contributors[i].PullRequests)
+	}
+	report.Text = report.markdown()
+	return report
+}
+
+func (r *backlogReport) markdown() string {
+	if len(r.Contributors) == 0 {
+		return "### CLA backlog\n\nNo pull request is blocked by an unsigned contributor."
+	}
+
+	prs := map[string]bool{}
+	for i := range r.Contributors {
+		for _, pr := range r.Contributors[i].PullRequests {
+			prs[pr] = true
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### CLA backlog\n\n%d unsigned contributors are blocking %d pull requests.\n",
+		len(r.Contributors), len(prs))
+	for i := range r.Contributors {
+		if i == backlogReportMaxLines {
+			fmt.Fprintf(&b, "\n... and %d more contributors", len(r.Contributors)-i)
+			break
+		}
+		c := &r.Contributors[i]
+		email := ""
+		if c.MaskedEmail != "" {
+			email = " (" + c.MaskedEmail + ")"
+		}
+		fmt.Fprintf(&b, "\n- %s%s: %s", c.Name, email, strings.Join(c.PullRequests, ", "))
+	}
+	return b.String()
+}
+
+// startBacklogReportScheduler posts the backlog report to the webhook_url of the backlog_report every
+// interval_hours in the background. It is disabled if the webhook_url is not set at the startup
+func startBacklogReportScheduler(bot *robot, logger *logrus.Entry) {
+	cfg := bot.config().BacklogReport
+	if cfg.WebhookURL == "" {
+		logger.Info("the backlog report is disabled")
+		return
+	}
+
+	cli := &http.Client{Timeout: backlogReportTimeout}
+	ticker := time.NewTicker(cfg.interval())
+	go func() {
+		for range ticker.C {
+			if err := bot.postBacklogReport(cli); err != nil {
+				logger.WithError(err).Error("failed to post the backlog report")
+			}
+		}
+	}()
+}
+
+// postBacklogReport posts the backlog report to the webhook_url of the backlog_report, which may be reloaded
+func (bot *robot) postBacklogReport(cli *http.Client) error {
+	webhookURL := bot.config().BacklogReport.WebhookURL
+	if webhookURL == "" {
+		return nil
+	}
+
+	report := bot.backlogReport()
+	data, err := json.Marshal(&report)
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.Post(webhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d of the backlog report webhook", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *adminServer) handleBacklog(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.bot.backlogReport())
+}
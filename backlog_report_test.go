@@ -0,0 +1,69 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBacklogReport(t *testing.T) {
+	store := newMemoryStateStore()
+	store.put(prState{Org: org, Repo: repo, Number: "1", Status: prStatusUnsigned, UnsignedUsers: []string{"u1", "u2"},
+		UnsignedUserEmails: map[string]string{"u1": "u1@example.com", "u2": "u2@example.com"}})
+	store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusUnsigned, UnsignedUsers: []string{"u2"},
+		UnsignedUserEmails: map[string]string{"u2": "u2@example.com"}})
+	// the state recorded before the emails of the users are recorded
+	store.put(prState{Org: org, Repo: "repo2", Number: "1", Status: prStatusUnsigned, UnsignedUsers: []string{"u3"}})
+	store.put(prState{Org: org, Repo: repo, Number: "3", Status: prStatusSigned, SignedUsers: []string{"u4"}})
+	// the closed pull request is not blocked
+	store.put(prState{Org: org, Repo: repo, Number: "4", Status: prStatusUnsigned, UnsignedUsers: []string{"u2"},
+		UnsignedUserEmails: map[string]string{"u2": "u2@example.com"}, Closed: true})
+	// the repository no longer configured
+	store.put(prState{Org: "org2", Repo: repo, Number: "1", Status: prStatusUnsigned, UnsignedUsers: []string{"u5"}})
+	bot := &robot{store: store, cnf: &configuration{ConfigItems: []repoConfig{{RepoFilter: config.RepoFilter{Repos: []string{org}}}}}}
+
+	report := bot.backlogReport()
+	assert.Equal(t, []backlogContributor{
+		{Name: "u2", MaskedEmail: "u***@example.com", PullRequests: []string{"org1/repo1/1", "org1/repo1/2"}},
+		{Name: "u1", MaskedEmail: "u***@example.com", PullRequests: []string{"org1/repo1/1"}},
+		{Name: "u3", MaskedEmail: "", PullRequests: []string{"org1/repo2/1"}},
+	}, report.Contributors)
+	assert.Equal(t, "### CLA backlog\n\n3 unsigned contributors are blocking 3 pull requests.\n"+
+		"\n- u2 (u***@example.com): org1/repo1/1, org1/repo1/2"+
+		"\n- u1 (u***@example.com): org1/repo1/1"+
+		"\n- u3: org1/repo2/1", report.Text)
+
+	var posted backlogReport
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&posted)
+	}))
+	defer webhook.Close()
+
+	bot.cnf.BacklogReport.WebhookURL = webhook.URL
+	assert.NoError(t, bot.postBacklogReport(webhook.Client()))
+	assert.Equal(t, report.Text, posted.Text)
+	assert.Equal(t, 3, len(posted.Contributors))
+}
+
+func TestEmptyBacklogReport(t *testing.T) {
+	bot := &robot{store: newMemoryStateStore(), cnf: &configuration{}}
+	report := bot.backlogReport()
+	assert.Empty(t, report.Contributors)
+	assert.Equal(t, "### CLA backlog\n\nNo pull request is blocked by an unsigned contributor.", report.Text)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultCommentRecheckTitle is the title of the breakdown posted for the `/cla recheck`
+const defaultCommentRecheckTitle = "### CLA Recheck Result  \n\n"
+
+// defaultCommentContributorNotFound is used when the comment_contributor_not_found is not configured
+const defaultCommentContributorNotFound = "### CLA Signature Manual  \n\nThe contributor %s of `/check-cla` is not " +
+	"found in the commits of the pull request, please check it. "
+
+// claSignDetail is the result of checking the CLA of an email, shown in the breakdown of the `/cla recheck`
+type claSignDetail struct {
+	user      string
+	email     string
+	signState string
+	claType   string
+}
+
+func (pr *prSnapshot) recordSignDetail(user, email, signState, claType string) {
+	if pr.recheck {
+		pr.signDetails = append(pr.signDetails, claSignDetail{user: user, email: email, signState: signState, claType: claType})
+	}
+}
+
+// maskEmail hides the local part of the email except its first letter, since the comments are public.
+// The visible parts are escaped for the markdown
+func maskEmail(email string) string {
+	return maskEmailWith(email, escapeMarkdownName)
+}
+
+// maskEmailText is the maskEmail without escaping, for the responses of the api
+func maskEmailText(email string) string {
+	return maskEmailWith(email, func(s string) string { return s })
+}
+
+func maskEmailWith(email string, escape func(string) string) string {
+	local, domain, found := strings.Cut(email, "@")
+	if !found || local == "" {
+		return escape(email)
+	}
+	first, _ := utf8.DecodeRuneInString(local)
+	return escape(string(first)) + "***@" + escape(domain)
+}
+
+func signStateText(signState string) string {
+	switch signState {
+	case client.CLASignStateYes:
+		return "signed"
+	case client.CLASignStateNo:
+		return "unsigned"
+	default:
+		return "unknown"
+	}
+}
+
+// postRecheckBreakdown posts the result of each email checked by the `/cla recheck`
+func (bot *robot) postRecheckBreakdown(pr *prSnapshot, repoCnf *repoConfig) {
+	if len(pr.signDetails) == 0 {
+		return
+	}
+
+	bot.createPRComment(pr, repoCnf, bot.renderer(pr).recheckBreakdown(pr.signDetails))
+}
+
+// checkContributor checks the CLA of only the contributor of the `/check-cla @username` or `/check-cla <email>`
+// bypassing the caches, and replies with the result of each email of the contributor. The labels are not changed,
+// since the other contributors are not checked
+func (bot *robot) checkContributor(pr *prSnapshot, repoCnf *repoConfig, target string, logger *logrus.Entry) {
+	if pr.watchdog == nil {
+		pr.watchdog = newEvaluationWatchdog(&bot.prConfig(pr).Watchdog)
+	}
+
+	commits, success := pr.getCommits()
+	if !success {
+		bot.createPRComment(pr, repoCnf, bot.commandTriggerComment(pr, repoCnf))
+		return
+	}
+	// the contributor is checked as the whole pull request is, such as by the mailmap and the plugins
+	commits = bot.prepareCommits(pr, commits, repoCnf)
+
+	if commits = contributorCommits(commits, target, repoCnf); len(commits) == 0 {
+		name := maskEmail(target)
+		if strings.HasPrefix(target, "@") {
+			name = escapeMarkdownName(target)
+		}
+		bot.createPRComment(pr, repoCnf, bot.renderer(pr).contributorNotFound(name))
+		return
+	}
+
+	pr.recheck = true
+	if _, _, stopped := bot.classifySignStates(pr, commits, repoCnf); stopped {
+		logger.Warningf("the check of the contributor %s of %s is stopped by the watchdog", target, pr.key())
+	}
+	bot.postRecheckBreakdown(pr, repoCnf)
+}
+
+// contributorNotFound renders the reply to the `/check-cla @username` whose contributor is not in the commits
+func (r *commentRenderer) contributorNotFound(name string) string {
+	data := r.data(nil)
+	data.Users = name
+	return r.render(r.format(r.cnf.CommentContributorNotFound, defaultCommentContributorNotFound), data,
+		func(s string) string { return fmt.Sprintf(s, data.Users) })
+}
+
+// contributorCommits picks the commits of the contributor of the login as @username or of the email,
+// who is the author or the committer as the CLA is checked by
+func contributorCommits(commits []client.PRCommit, target string, repoCnf *repoConfig) []client.PRCommit {
+	login, byLogin := strings.CutPrefix(target, "@")
+	return slices.DeleteFunc(slices.Clone(commits), func(c client.PRCommit) bool {
+		name, email := c.AuthorName, c.AuthorEmail
+		if repoCnf.CheckByCommitter {
+			name, email = c.CommitterName, c.CommitterEmail
+		}
+		if byLogin {
+			return !strings.EqualFold(name, login)
+		}
+		return !strings.EqualFold(email, target)
+	})
+}
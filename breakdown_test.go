@@ -0,0 +1,90 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRecheckBreakdown(t *testing.T) {
+	mc := &mockClient{
+		successfulCheckCLASignature: true,
+		successfulCreatePRComment:   true,
+		CLAState:                    client.CLASignStateYes,
+		claType:                     "corporate",
+	}
+	bot := &robot{cli: mc, cnf: &configuration{}, claCache: newCLAResultCache(0)}
+	repoCnf := &repoConfig{CLACacheTTLSeconds: 60, LitePRCommitter: litePRCommiter{Email: "noreply@gitcode.com"}}
+	// the cached result is bypassed
+	bot.claCache.put(claCheckURL(repoCnf.CheckURL, org, repo, "jane@example.com"), client.CLASignStateNo, time.Minute)
+
+	pr := newPRSnapshot(mc, org, repo, number)
+	pr.recheck = true
+	commits := []client.PRCommit{
+		{AuthorName: "jane", AuthorEmail: "jane@example.com"},
+		{AuthorName: "GitCode", AuthorEmail: "noreply@gitcode.com"},
+	}
+	_, _ = bot.checkCLASignResult(pr, commits, repoCnf)
+	bot.postRecheckBreakdown(pr, repoCnf)
+
+	assert.Equal(t, defaultCommentRecheckTitle+"| Contributor | Email | State | CLA |\n| --- | --- | --- | --- |\n"+
+		"| jane | j***@example.com | signed | corporate |\n"+
+		"| GitCode | n***@gitcode.com | unknown | - |\n", mc.comment)
+}
+
+func TestCheckContributor(t *testing.T) {
+	m := regexpCheckCLAContributorComment.FindStringSubmatch("/check-cla @jane")
+	assert.Equal(t, "@jane", m[1])
+	m = regexpCheckCLAContributorComment.FindStringSubmatch("/check-cla jane@example.com")
+	assert.Equal(t, "jane@example.com", m[1])
+	assert.Equal(t, true, regexpCheckCLAContributorComment.FindStringSubmatch("/check-cla authors") == nil)
+	assert.Equal(t, true, regexpCheckCLAContributorComment.FindStringSubmatch("/check-cla @jane @bob") == nil)
+
+	mc := &mockClient{
+		successfulCheckCLASignature:     true,
+		successfulCreatePRComment:       true,
+		successfulGetPullRequestCommits: true,
+		CLAState:                        client.CLASignStateNo,
+		commits: []client.PRCommit{
+			{AuthorName: "jane", AuthorEmail: "jane@example.com"},
+			{AuthorName: "bob", AuthorEmail: "bob@example.com"},
+			{AuthorName: "Jane", AuthorEmail: "jane@corp.com"},
+		},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	repoCnf := &repoConfig{}
+	logger := logrus.NewEntry(logrus.New())
+
+	// only the emails of the contributor are checked
+	bot.checkContributor(newPRSnapshot(mc, org, repo, number), repoCnf, "@jane", logger)
+	assert.Equal(t, defaultCommentRecheckTitle+"| Contributor | Email | State | CLA |\n| --- | --- | --- | --- |\n"+
+		"| jane | j***@example.com | unsigned | - |\n"+
+		"| Jane | j***@corp.com | unsigned | - |\n", mc.comment)
+
+	bot.checkContributor(newPRSnapshot(mc, org, repo, number), repoCnf, "BOB@example.com", logger)
+	assert.Contains(t, mc.comment, "| bob | b***@example.com | unsigned | - |\n")
+	assert.NotContains(t, mc.comment, "jane")
+
+	bot.checkContributor(newPRSnapshot(mc, org, repo, number), repoCnf, "carol@example.com", logger)
+	assert.Equal(t, fmt.Sprintf(defaultCommentContributorNotFound, "c***@example.com"), mc.comment)
+
+	bot.cnf.CommentContributorNotFound = "{{.Users}} is not found"
+	bot.checkContributor(newPRSnapshot(mc, org, repo, number), repoCnf, "@carol", logger)
+	assert.Equal(t, "@carol is not found", mc.comment)
+}
@@ -0,0 +1,295 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build chaos
+
+package main
+
+import (
+	"errors"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// envChaos is the environment variable configuring the chaos injection, such as
+// `failure_rate=0.3;latency=500ms;methods=CheckCLASignature,AddPRLabels`.
+// It only takes effect in the binary built with the `chaos` tag
+const envChaos = "CLA_ROBOT_CHAOS"
+
+// chaosConfig describes the failures and latency injected into the client calls
+type chaosConfig struct {
+	// failureRate is the probability in [0, 1] of a call failing
+	failureRate float64
+	// latency is the delay added before each call
+	latency time.Duration
+	// methods restricts the injection to the named methods, all methods are affected if it is empty
+	methods []string
+}
+
+func parseChaosConfig(s string) (cfg chaosConfig, err error) {
+	for _, item := range strings.Split(s, ";") {
+		k, v, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found {
+			continue
+		}
+		switch strings.TrimSpace(k) {
+		case "failure_rate":
+			if cfg.failureRate, err = strconv.ParseFloat(v, 64); err == nil && (cfg.failureRate < 0 || cfg.failureRate > 1) {
+				err = errors.New("the failure_rate must be in [0, 1]")
+			}
+		case "latency":
+			cfg.latency, err = time.ParseDuration(v)
+		case "methods":
+			cfg.methods = strings.Split(v, ",")
+		default:
+			err = errors.New("unknown chaos option: " + k)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// chaosClient injects failures and latency into the calls of the wrapped client
+type chaosClient struct {
+	iClient
+	cfg  chaosConfig
+	mu   sync.Mutex
+	rand *rand.Rand
+	log  *logrus.Entry
+}
+
+// wrapChaosClient wraps the client with the chaos injection configured by the environment
+func wrapChaosClient(cli iClient, logger *logrus.Entry) iClient {
+	s := os.Getenv(envChaos)
+	if s == "" {
+		return cli
+	}
+
+	cfg, err := parseChaosConfig(s)
+	if err != nil {
+		logger.WithError(err).Error("invalid chaos config, the chaos injection is disabled")
+		return cli
+	}
+
+	logger.Warningf("the chaos injection is enabled: %s", s)
+	return &chaosClient{iClient: cli, cfg: cfg, rand: rand.New(rand.NewSource(time.Now().UnixNano())), log: logger}
+}
+
+// inject applies the latency and returns true if the call should fail
+func (c *chaosClient) inject(method string) bool {
+	if len(c.cfg.methods) != 0 && !slices.Contains(c.cfg.methods, method) {
+		return false
+	}
+
+	time.Sleep(c.cfg.latency)
+	c.mu.Lock()
+	fail := c.rand.Float64() < c.cfg.failureRate
+	c.mu.Unlock()
+	if fail {
+		c.log.Warningf("chaos: inject a failure into %s", method)
+		return true
+	}
+	return false
+}
+
+func (c *chaosClient) CreatePRComment(org, repo, number, comment string) (string, bool) {
+	if c.inject("CreatePRComment") {
+		return "", false
+	}
+	return c.iClient.CreatePRComment(org, repo, number, comment)
+}
+
+func (c *chaosClient) GetPullRequestLabels(org, repo, number string) ([]string, bool) {
+	if c.inject("GetPullRequestLabels") {
+		return nil, false
+	}
+	return c.iClient.GetPullRequestLabels(org, repo, number)
+}
+
+func (c *chaosClient) AddPRLabels(org, repo, number string, labels []string) bool {
+	return !c.inject("AddPRLabels") && c.iClient.AddPRLabels(org, repo, number, labels)
+}
+
+func (c *chaosClient) RemovePRLabels(org, repo, number string, labels []string) bool {
+	return !c.inject("RemovePRLabels") && c.iClient.RemovePRLabels(org, repo, number, labels)
+}
+
+func (c *chaosClient) GetPullRequestCommits(org, repo, number string) ([]client.PRCommit, bool) {
+	if c.inject("GetPullRequestCommits") {
+		return nil, false
+	}
+	return c.iClient.GetPullRequestCommits(org, repo, number)
+}
+
+func (c *chaosClient) ListPullRequestComments(org, repo, number string) ([]prComment, bool) {
+	if c.inject("ListPullRequestComments") {
+		return nil, false
+	}
+	return c.iClient.ListPullRequestComments(org, repo, number)
+}
+
+func (c *chaosClient) DeletePRComment(org, repo, commentID string) bool {
+	return !c.inject("DeletePRComment") && c.iClient.DeletePRComment(org, repo, commentID)
+}
+
+func (c *chaosClient) UpdatePRComment(org, repo, commentID, comment string) bool {
+	return !c.inject("UpdatePRComment") && c.iClient.UpdatePRComment(org, repo, commentID, comment)
+}
+
+func (c *chaosClient) CheckCLASignature(urlStr string) (string, bool) {
+	if c.inject("CheckCLASignature") {
+		return client.CLASignStateUnknown, false
+	}
+	return c.iClient.CheckCLASignature(urlStr)
+}
+
+func (c *chaosClient) CheckCLASignatures(urlStr string, emails []string) (map[string]string, bool) {
+	if c.inject("CheckCLASignatures") {
+		return nil, false
+	}
+	return c.iClient.CheckCLASignatures(urlStr, emails)
+}
+
+func (c *chaosClient) CheckCLASignatureDetail(urlStr string) (string, string, bool) {
+	if c.inject("CheckCLASignatureDetail") {
+		return client.CLASignStateUnknown, "", false
+	}
+	return c.iClient.CheckCLASignatureDetail(urlStr)
+}
+
+func (c *chaosClient) GetPullRequestGraph(urlStr, org, repo, number string) (prGraph, bool) {
+	if c.inject("GetPullRequestGraph") {
+		return prGraph{}, false
+	}
+	return c.iClient.GetPullRequestGraph(urlStr, org, repo, number)
+}
+
+func (c *chaosClient) ListOrgRepos(org string) ([]string, bool) {
+	if c.inject("ListOrgRepos") {
+		return nil, false
+	}
+	return c.iClient.ListOrgRepos(org)
+}
+
+func (c *chaosClient) ListTeamMembers(org, team string) ([]string, bool) {
+	if c.inject("ListTeamMembers") {
+		return nil, false
+	}
+	return c.iClient.ListTeamMembers(org, team)
+}
+
+func (c *chaosClient) CheckPermission(org, repo, username string) (bool, bool) {
+	if c.inject("CheckPermission") {
+		return false, false
+	}
+	return c.iClient.CheckPermission(org, repo, username)
+}
+
+func (c *chaosClient) GetPullRequestCommitMessages(org, repo, number string) ([]prCommitMessage, bool) {
+	if c.inject("GetPullRequestCommitMessages") {
+		return nil, false
+	}
+	return c.iClient.GetPullRequestCommitMessages(org, repo, number)
+}
+
+func (c *chaosClient) GetPullRequestChangedFileCount(org, repo, number string) (int, bool) {
+	if c.inject("GetPullRequestChangedFileCount") {
+		return 0, false
+	}
+	return c.iClient.GetPullRequestChangedFileCount(org, repo, number)
+}
+
+func (c *chaosClient) ListOpenPullRequestsWithLabel(org, repo, label string) ([]string, bool) {
+	if c.inject("ListOpenPullRequestsWithLabel") {
+		return nil, false
+	}
+	return c.iClient.ListOpenPullRequestsWithLabel(org, repo, label)
+}
+
+func (c *chaosClient) VerifyCLASignatureID(urlStr, email string) (string, bool) {
+	if c.inject("VerifyCLASignatureID") {
+		return client.CLASignStateUnknown, false
+	}
+	return c.iClient.VerifyCLASignatureID(urlStr, email)
+}
+
+func (c *chaosClient) GetRepoMetadata(org, repo string) (repoMetadata, bool) {
+	if c.inject("GetRepoMetadata") {
+		return repoMetadata{}, false
+	}
+	return c.iClient.GetRepoMetadata(org, repo)
+}
+
+func (c *chaosClient) GetRepoPushPermission(org, repo string) (bool, bool) {
+	if c.inject("GetRepoPushPermission") {
+		return false, false
+	}
+	return c.iClient.GetRepoPushPermission(org, repo)
+}
+
+func (c *chaosClient) CreateIssue(org, repo, title, body string) bool {
+	return !c.inject("CreateIssue") && c.iClient.CreateIssue(org, repo, title, body)
+}
+
+func (c *chaosClient) CreateRepoLabel(org, repo string, label repoLabel) bool {
+	return !c.inject("CreateRepoLabel") && c.iClient.CreateRepoLabel(org, repo, label)
+}
+
+func (c *chaosClient) GetCommitVerificationStatus(org, repo, sha string) (bool, bool) {
+	if c.inject("GetCommitVerificationStatus") {
+		return false, false
+	}
+	return c.iClient.GetCommitVerificationStatus(org, repo, sha)
+}
+
+func (c *chaosClient) GetUserEmail(login string) (string, bool) {
+	if c.inject("GetUserEmail") {
+		return "", false
+	}
+	return c.iClient.GetUserEmail(login)
+}
+
+func (c *chaosClient) GetBotLogin() (string, bool) {
+	if c.inject("GetBotLogin") {
+		return "", false
+	}
+	return c.iClient.GetBotLogin()
+}
+
+func (c *chaosClient) AddCommentReaction(org, repo, commentID, reaction string) bool {
+	return !c.inject("AddCommentReaction") && c.iClient.AddCommentReaction(org, repo, commentID, reaction)
+}
+
+func (c *chaosClient) GetRepoFileContent(org, repo, path, ref string) ([]byte, bool) {
+	if c.inject("GetRepoFileContent") {
+		return nil, false
+	}
+	return c.iClient.GetRepoFileContent(org, repo, path, ref)
+}
+
+func (c *chaosClient) CreateCommitStatus(org, repo, sha string, status commitStatus) bool {
+	if c.inject("CreateCommitStatus") {
+		return false
+	}
+	return c.iClient.CreateCommitStatus(org, repo, sha, status)
+}
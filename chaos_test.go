@@ -0,0 +1,52 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build chaos
+
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseChaosConfig(t *testing.T) {
+	cfg, err := parseChaosConfig("failure_rate=0.5; latency=10ms; methods=AddPRLabels,CheckCLASignature")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0.5, cfg.failureRate)
+	assert.Equal(t, 10*time.Millisecond, cfg.latency)
+	assert.Equal(t, []string{"AddPRLabels", "CheckCLASignature"}, cfg.methods)
+
+	_, err = parseChaosConfig("failure_rate=2")
+	assert.NotEqual(t, nil, err)
+	_, err = parseChaosConfig("unknown=1")
+	assert.NotEqual(t, nil, err)
+}
+
+func TestChaosClient(t *testing.T) {
+	mc := &mockClient{successfulAddPRLabels: true, successfulCheckCLASignature: true, CLAState: client.CLASignStateYes}
+
+	t.Setenv(envChaos, "failure_rate=1;methods=CheckCLASignature")
+	cli := wrapChaosClient(mc, framework.NewLogger())
+	assert.Equal(t, true, cli.AddPRLabels(org, repo, number, []string{labelYes}))
+	state, success := cli.CheckCLASignature("http://localhost")
+	assert.Equal(t, false, success)
+	assert.Equal(t, client.CLASignStateUnknown, state)
+
+	t.Setenv(envChaos, "")
+	assert.Equal(t, iClient(mc), wrapChaosClient(mc, framework.NewLogger()))
+}
@@ -0,0 +1,118 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// teamMembersCacheTTL is how long the members of a team are cached
+const teamMembersCacheTTL = 10 * time.Minute
+
+// claAdmins are the users authorized to administrate the CLA check of the repositories, such as `/cla cancel`,
+// besides those with the permission on the repository
+type claAdmins struct {
+	// Users are the logins of the admins
+	Users []string `json:"users"`
+	// Teams are the teams whose members are the admins, as org/team, or team of the org of the repository
+	Teams []string `json:"teams"`
+}
+
+func (c *claAdmins) validate() error {
+	if slices.Contains(c.Users, "") {
+		return errors.New("the users of the cla_admins can not contain an empty item")
+	}
+	for _, team := range c.Teams {
+		org, name, found := strings.Cut(team, "/")
+		if team == "" || (found && (org == "" || name == "")) {
+			return errors.New("the teams of the cla_admins must be in the format of org/team or team")
+		}
+	}
+	return nil
+}
+
+type teamMembersEntry struct {
+	members   []string
+	expiredAt time.Time
+}
+
+// teamMembersCache caches the members of the teams looked up from the code hosting platform
+type teamMembersCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]teamMembersEntry
+	lookup  func(org, team string) ([]string, bool)
+	now     func() time.Time
+}
+
+func newTeamMembersCache(ttl time.Duration, lookup func(org, team string) ([]string, bool)) *teamMembersCache {
+	return &teamMembersCache{ttl: ttl, entries: map[string]teamMembersEntry{}, lookup: lookup, now: time.Now}
+}
+
+// get returns the members of the team, the failed lookup is not cached
+func (c *teamMembersCache) get(org, team string) ([]string, bool) {
+	k := org + "/" + team
+	now := c.now()
+
+	c.mu.Lock()
+	e, ok := c.entries[k]
+	c.mu.Unlock()
+	if ok && now.Before(e.expiredAt) {
+		return e.members, true
+	}
+
+	members, success := c.lookup(org, team)
+	if !success {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	c.entries[k] = teamMembersEntry{members: members, expiredAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return members, true
+}
+
+// listTeamMembers lists the members of the team by the cache, or by the client if there is no cache
+func (bot *robot) listTeamMembers(org, team string) ([]string, bool) {
+	if bot.teams == nil {
+		return bot.cli.ListTeamMembers(org, team)
+	}
+	return bot.teams.get(org, team)
+}
+
+// isCLAAdmin checks whether the user can administrate the CLA check of the repository, who has the permission
+// on the repository, or is one of the cla_admins
+func (bot *robot) isCLAAdmin(org, repo, user string, repoCnf *repoConfig) bool {
+	if slices.ContainsFunc(repoCnf.CLAAdmins.Users, func(v string) bool { return strings.EqualFold(v, user) }) {
+		return true
+	}
+
+	for _, team := range repoCnf.CLAAdmins.Teams {
+		teamOrg, name, found := strings.Cut(team, "/")
+		if !found {
+			teamOrg, name = org, team
+		}
+		members, _ := bot.listTeamMembers(teamOrg, name)
+		if slices.ContainsFunc(members, func(v string) bool { return strings.EqualFold(v, user) }) {
+			return true
+		}
+	}
+
+	pass, _ := bot.cli.CheckPermission(org, repo, user)
+	return pass
+}
@@ -0,0 +1,66 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCLAAdmins(t *testing.T) {
+	assert.Equal(t, nil, (&claAdmins{Users: []string{"alice"}, Teams: []string{"cla", "org2/cla"}}).validate())
+	assert.NotEqual(t, nil, (&claAdmins{Users: []string{""}}).validate())
+	assert.NotEqual(t, nil, (&claAdmins{Teams: []string{"org2/"}}).validate())
+
+	mc := &mockClient{
+		successfulListTeamMembers: true,
+		teamMembers:               map[string][]string{org + "/cla": {"Bob"}, "org2/legal": {"carol"}},
+	}
+	bot := &robot{cli: mc}
+	repoCnf := &repoConfig{CLAAdmins: claAdmins{Users: []string{"Alice"}, Teams: []string{"cla", "org2/legal"}}}
+
+	assert.Equal(t, true, bot.isCLAAdmin(org, repo, "alice", repoCnf))
+	assert.Equal(t, true, bot.isCLAAdmin(org, repo, "bob", repoCnf))
+	assert.Equal(t, true, bot.isCLAAdmin(org, repo, "carol", repoCnf))
+	assert.Equal(t, false, bot.isCLAAdmin(org, repo, "dave", repoCnf))
+
+	// the permission on the repository is still accepted
+	mc.permission, mc.successfulCheckPermission = true, true
+	assert.Equal(t, true, bot.isCLAAdmin(org, repo, "dave", &repoConfig{}))
+}
+
+func TestTeamMembersCache(t *testing.T) {
+	calls, success := 0, false
+	c := newTeamMembersCache(time.Minute, func(org, team string) ([]string, bool) {
+		calls++
+		return []string{"bob"}, success
+	})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	// the failed lookup is not cached
+	_, ok := c.get(org, "cla")
+	assert.Equal(t, false, ok)
+	success = true
+	members, ok := c.get(org, "cla")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, []string{"bob"}, members)
+	_, _ = c.get(org, "cla")
+	assert.Equal(t, 2, calls)
+
+	now = now.Add(2 * time.Minute)
+	_, _ = c.get(org, "cla")
+	assert.Equal(t, 3, calls)
+}
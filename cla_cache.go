@@ -0,0 +1,267 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"container/list"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"go.opentelemetry.io/otel/attribute"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCLACacheSize is used when the cla_cache_size is not configured
+const defaultCLACacheSize = 10000
+
+type claCacheEntry struct {
+	key       string
+	signState string
+	expiredAt time.Time
+}
+
+// claResultCache caches the results of the CLA server by the check url, evicting the least recently used
+// entry when it is full. Only the signed results are cached, so that a contributor who has just signed is
+// not blocked by a stale unsigned result
+type claResultCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+func newCLAResultCache(size int) *claResultCache {
+	if size <= 0 {
+		size = defaultCLACacheSize
+	}
+	return &claResultCache{size: size, order: list.New(), entries: map[string]*list.Element{}, now: time.Now}
+}
+
+func (c *claResultCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+
+	entry := e.Value.(*claCacheEntry)
+	if c.now().After(entry.expiredAt) {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return "", false
+	}
+
+	c.order.MoveToFront(e)
+	return entry.signState, true
+}
+
+func (c *claResultCache) put(key, signState string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+
+	for c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*claCacheEntry).key)
+	}
+
+	c.entries[key] = c.order.PushFront(&claCacheEntry{key: key, signState: signState, expiredAt: c.now().Add(ttl)})
+}
+
+func (c *claResultCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+const (
+	// the variables of the check url replaced by the values of the pull request and the contributor,
+	// which can also be written as {{org}}, {{repo}} and {{email}}
+	checkURLVarOrg   = "{org}"
+	checkURLVarRepo  = "{repo}"
+	checkURLVarEmail = "{email}"
+)
+
+// claCheckURL returns the url to check the sign state of the email in the repository, which is also the key of
+// the cache. The variables in the check url are replaced by the escaped values, and the email is added to the
+// query if the check url has no {email}, such as https://cla.example.com/{org}/{repo}/check
+func claCheckURL(checkURL, org, repo, email string) string {
+	urlStr := expandCLAURL(checkURL, org, repo, email)
+	if strings.Contains(checkURL, checkURLVarEmail) {
+		return urlStr
+	}
+	return addURLQuery(urlStr, "email", email)
+}
+
+// expandCLAURL replaces the variables in the url of the CLA server by the values escaped for the path or the
+// query, so that one config item can serve the repositories of a multi-tenant CLA server
+func expandCLAURL(urlStr, org, repo, email string) string {
+	path, query, hasQuery := strings.Cut(urlStr, "?")
+	urlStr = expandCLAURLPart(path, url.PathEscape, org, repo, email)
+	if hasQuery {
+		urlStr += "?" + expandCLAURLPart(query, url.QueryEscape, org, repo, email)
+	}
+	return urlStr
+}
+
+func expandCLAURLPart(part string, escape func(string) string, org, repo, email string) string {
+	org, repo, email = escape(org), escape(repo), escape(email)
+	return strings.NewReplacer(
+		"{"+checkURLVarOrg+"}", org, "{"+checkURLVarRepo+"}", repo, "{"+checkURLVarEmail+"}", email,
+		checkURLVarOrg, org, checkURLVarRepo, repo, checkURLVarEmail, email,
+	).Replace(part)
+}
+
+// addURLQuery adds the escaped key and value to the query of the url, after the existing ones
+func addURLQuery(urlStr, key, value string) string {
+	sep := "?"
+	if strings.Contains(urlStr, "?") {
+		sep = "&"
+	}
+	return urlStr + sep + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
+
+// cachedSignState returns the signed result cached within the TTL of the repository
+func (bot *robot) cachedSignState(urlStr string, repoCnf *repoConfig) (string, bool) {
+	if bot.claCache == nil || repoCnf.CLACacheTTLSeconds == 0 {
+		return "", false
+	}
+	return bot.claCache.get(urlStr)
+}
+
+func (bot *robot) cacheSignState(urlStr, signState string, repoCnf *repoConfig) {
+	if bot.claCache != nil && repoCnf.CLACacheTTLSeconds != 0 && signState == client.CLASignStateYes {
+		bot.claCache.put(urlStr, signState, time.Duration(repoCnf.CLACacheTTLSeconds)*time.Second)
+	}
+}
+
+// checkCLASignature checks the sign state by the CLA server, reusing the signed result cached within the TTL
+func (bot *robot) checkCLASignature(pr *prSnapshot, urlStr string, repoCnf *repoConfig) string {
+	if signState, ok := bot.cachedSignState(urlStr, repoCnf); ok {
+		return signState
+	}
+
+	span := pr.startClientSpan("CheckCLASignature")
+	signState, success := bot.cli.CheckCLASignature(urlStr)
+	span.SetAttributes(attribute.String(spanAttrSignState, signState))
+	endClientSpan(span, success)
+	bot.cacheSignState(urlStr, signState, repoCnf)
+	return signState
+}
+
+// checkCLASignatureDetail checks the sign state and the type of the CLA by the CLA server, bypassing the cache
+func (bot *robot) checkCLASignatureDetail(pr *prSnapshot, urlStr string) (string, string) {
+	span := pr.startClientSpan("CheckCLASignatureDetail")
+	signState, claType, success := bot.cli.CheckCLASignatureDetail(urlStr)
+	span.SetAttributes(attribute.String(spanAttrSignState, signState))
+	endClientSpan(span, success)
+	return signState, claType
+}
+
+// checkEmailSignState checks the sign state of the email by the check urls of the repository in order.
+// The email is signed if any of them says so, and it is unknown if none says so and any of them fails.
+// The result of the check_url is taken from the batchStates if they are checked by the batch_check_url
+func (bot *robot) checkEmailSignState(pr *prSnapshot, email string, repoCnf *repoConfig,
+	batchStates map[string]string) (signState, claType string) {
+	signState = client.CLASignStateNo
+	for i, checkURL := range repoCnf.checkURLs() {
+		var state, t string
+		switch {
+		case i == 0 && batchStates != nil:
+			state = batchStates[email]
+		case pr.recheck:
+			pr.watchdog.countCLALookup()
+			state, t = bot.checkCLASignatureDetail(pr, repoCnf.versioned(claCheckURL(checkURL, pr.org, pr.repo, email)))
+		default:
+			pr.watchdog.countCLALookup()
+			state = bot.checkCLASignature(pr, repoCnf.versioned(claCheckURL(checkURL, pr.org, pr.repo, email)), repoCnf)
+		}
+
+		if state == client.CLASignStateYes {
+			return state, t
+		}
+		if state != client.CLASignStateNo {
+			signState = client.CLASignStateUnknown
+		}
+	}
+	return
+}
+
+// claLoginCheckURL returns the url to check the sign state of the login on the code hosting platform
+func claLoginCheckURL(checkURL, org, repo, login string) string {
+	return addURLQuery(expandCLAURL(checkURL, org, repo, ""), "login", login)
+}
+
+// checkLoginSignState checks the sign state of the login by the login_check_url. It is unknown if the
+// login_check_url is not configured
+func (bot *robot) checkLoginSignState(pr *prSnapshot, login string, repoCnf *repoConfig) (signState, claType string) {
+	if repoCnf.LoginCheckURL == "" || login == "" {
+		return client.CLASignStateUnknown, ""
+	}
+
+	pr.watchdog.countCLALookup()
+	urlStr := repoCnf.versioned(claLoginCheckURL(repoCnf.LoginCheckURL, pr.org, pr.repo, login))
+	if pr.recheck {
+		signState, claType = bot.checkCLASignatureDetail(pr, urlStr)
+		return
+	}
+	return bot.checkCLASignature(pr, urlStr, repoCnf), ""
+}
+
+// checkCLASignatures checks the sign states of the emails by one request to the batch_check_url,
+// except those cached. The email missing in the response is unknown
+func (bot *robot) checkCLASignatures(pr *prSnapshot, emails []string, repoCnf *repoConfig) map[string]string {
+	states := make(map[string]string, len(emails))
+	var missed []string
+	for _, email := range emails {
+		if email == "" || email == repoCnf.LitePRCommitter.Email {
+			continue
+		}
+		if _, ok := bot.corporateCLA.lookup(repoCnf.CorporateCLAFile, email); ok {
+			continue
+		}
+		if signState, ok := bot.cachedSignState(repoCnf.versioned(claCheckURL(repoCnf.CheckURL, pr.org, pr.repo, email)), repoCnf); ok {
+			states[email] = signState
+		} else {
+			missed = append(missed, email)
+		}
+	}
+	if len(missed) == 0 {
+		return states
+	}
+
+	pr.watchdog.countCLALookup()
+	span := pr.startClientSpan("CheckCLASignatures")
+	result, success := bot.cli.CheckCLASignatures(repoCnf.versioned(expandCLAURL(repoCnf.BatchCheckURL, pr.org, pr.repo, "")), missed)
+	endClientSpan(span, success)
+	for _, email := range missed {
+		signState := client.CLASignStateUnknown
+		if v, ok := result[email]; success && ok {
+			signState = v
+		}
+		states[email] = signState
+		bot.cacheSignState(repoCnf.versioned(claCheckURL(repoCnf.CheckURL, pr.org, pr.repo, email)), signState, repoCnf)
+	}
+	return states
+}
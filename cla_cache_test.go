@@ -0,0 +1,175 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCLAResultCache(t *testing.T) {
+	c := newCLAResultCache(2)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.put("e1", client.CLASignStateYes, time.Minute)
+	c.put("e2", client.CLASignStateYes, time.Minute)
+	_, _ = c.get("e1")
+	// e2 is the least recently used one
+	c.put("e3", client.CLASignStateYes, time.Minute)
+	assert.Equal(t, 2, c.len())
+	_, ok := c.get("e2")
+	assert.Equal(t, false, ok)
+
+	c.now = func() time.Time { return now.Add(2 * time.Minute) }
+	_, ok = c.get("e1")
+	assert.Equal(t, false, ok)
+	assert.Equal(t, 1, c.len())
+}
+
+func TestCLACheckURL(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		checkURL string
+		email    string
+		expected string
+	}{
+		{"plus and unicode", "https://cla/check", "a+b@例子.com", "https://cla/check?email=a%2Bb%40%E4%BE%8B%E5%AD%90.com"},
+		{"own query", "https://cla/check?type=icla", "a@b.com", "https://cla/check?type=icla&email=a%40b.com"},
+		{"templated", "https://cla/{org}/{repo}?email={email}", "a+b@c.com", "https://cla/o%2F1/r?email=a%2Bb%40c.com"},
+		{"email in path", "https://cla/{repo}/{email}", "a b@c.com", "https://cla/r/a%20b@c.com"},
+		{"org without email", "https://cla/{org}", "a@b.com", "https://cla/o%2F1?email=a%40b.com"},
+		{"double braces", "https://cla/{{org}}:{{repo}}?email={{email}}", "a@b.com", "https://cla/o%2F1:r?email=a%40b.com"},
+	}
+	for i := range testCases {
+		t.Run(testCases[i].desc, func(t *testing.T) {
+			assert.Equal(t, testCases[i].expected, claCheckURL(testCases[i].checkURL, "o/1", "r", testCases[i].email))
+		})
+	}
+}
+
+func TestCheckCLASignatureWithCache(t *testing.T) {
+	mc := &mockClient{successfulCheckCLASignature: true, CLAState: client.CLASignStateYes}
+	bot := &robot{cli: mc, cnf: &configuration{}, claCache: newCLAResultCache(0)}
+	repoCnf := &repoConfig{CLACacheTTLSeconds: 60}
+	pr := newPRSnapshot(mc, org, repo, number)
+
+	assert.Equal(t, client.CLASignStateYes, bot.checkCLASignature(pr, "url?email=e1", repoCnf))
+	mc.method = ""
+	assert.Equal(t, client.CLASignStateYes, bot.checkCLASignature(pr, "url?email=e1", repoCnf))
+	assert.Equal(t, "", mc.method)
+
+	// the unsigned result is not cached
+	mc.CLAState = client.CLASignStateNo
+	assert.Equal(t, client.CLASignStateNo, bot.checkCLASignature(pr, "url?email=e2", repoCnf))
+	mc.CLAState = client.CLASignStateYes
+	assert.Equal(t, client.CLASignStateYes, bot.checkCLASignature(pr, "url?email=e2", repoCnf))
+}
+
+// multiServerClient answers the CLA checks by the sign states of the check urls
+type multiServerClient struct {
+	*mockClient
+	states map[string]string
+}
+
+func (m *multiServerClient) CheckCLASignature(urlStr string) (string, bool) {
+	signState, ok := m.states[urlStr]
+	return signState, ok
+}
+
+func TestCheckEmailSignState(t *testing.T) {
+	mc := &multiServerClient{mockClient: new(mockClient)}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	repoCnf := &repoConfig{CheckURL: "icla", CheckURLs: []string{"ccla"}}
+	pr := newPRSnapshot(mc, org, repo, number)
+
+	testCases := []struct {
+		desc   string
+		states map[string]string
+		batch  map[string]string
+		out    string
+	}{
+		{"signed the individual cla", map[string]string{"icla?email=e1": client.CLASignStateYes}, nil, client.CLASignStateYes},
+		{"signed the corporation cla", map[string]string{
+			"icla?email=e1": client.CLASignStateNo, "ccla?email=e1": client.CLASignStateYes}, nil, client.CLASignStateYes},
+		{"signed none", map[string]string{
+			"icla?email=e1": client.CLASignStateNo, "ccla?email=e1": client.CLASignStateNo}, nil, client.CLASignStateNo},
+		{"failed to check one", map[string]string{"icla?email=e1": client.CLASignStateNo}, nil, client.CLASignStateUnknown},
+		{"checked by the batch", map[string]string{"ccla?email=e1": client.CLASignStateNo},
+			map[string]string{"e1": client.CLASignStateNo}, client.CLASignStateNo},
+	}
+	for i := range testCases {
+		t.Run(testCases[i].desc, func(t *testing.T) {
+			mc.states = testCases[i].states
+			signState, _ := bot.checkEmailSignState(pr, "e1", repoCnf, testCases[i].batch)
+			assert.Equal(t, testCases[i].out, signState)
+		})
+	}
+}
+
+func TestCheckURLOfRepos(t *testing.T) {
+	mc := &multiServerClient{mockClient: new(mockClient), states: map[string]string{
+		"https://cla/org1/repo1?email=e1":       client.CLASignStateYes,
+		"https://cla/org1/repo2?email=e1":       client.CLASignStateNo,
+		"https://cla/org1/repo2/login?login=u1": client.CLASignStateYes,
+	}}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	// one config item serves all the repositories of the org
+	repoCnf := &repoConfig{
+		RepoFilter:    config.RepoFilter{Repos: []string{org}},
+		CheckURL:      "https://cla/{{org}}/{{repo}}?email={{email}}",
+		LoginCheckURL: "https://cla/{{org}}/{{repo}}/login",
+	}
+
+	signState, _ := bot.checkEmailSignState(newPRSnapshot(mc, org, repo, number), "e1", repoCnf, nil)
+	assert.Equal(t, client.CLASignStateYes, signState)
+	pr := newPRSnapshot(mc, org, "repo2", number)
+	signState, _ = bot.checkEmailSignState(pr, "e1", repoCnf, nil)
+	assert.Equal(t, client.CLASignStateNo, signState)
+	signState, _ = bot.checkLoginSignState(pr, "u1", repoCnf)
+	assert.Equal(t, client.CLASignStateYes, signState)
+}
+
+func TestCheckCLASignResultByLogin(t *testing.T) {
+	mc := &multiServerClient{mockClient: &mockClient{successfulCreatePRComment: true}, states: map[string]string{
+		"login?login=u1": client.CLASignStateYes,
+		"login?login=u2": client.CLASignStateNo,
+		"login?login=u3": client.CLASignStateUnknown,
+	}}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	repoCnf := &repoConfig{CheckURL: "icla", EmailDomainDenylist: []string{"noreply.example.com"}}
+	commits := []client.PRCommit{
+		{AuthorName: "u1", AuthorEmail: ""},
+		{AuthorName: "u2", AuthorEmail: "u2@noreply.example.com"},
+		{AuthorName: "u3", AuthorEmail: "u3@noreply.example.com"},
+	}
+
+	// the contributors without a usable email are unknown without the login_check_url
+	_, result := bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	assert.Equal(t, []string{"u1", "u2", "u3"}, result[2])
+
+	// the login is checked, and the contributor failed to be checked by the login is still unknown
+	repoCnf.LoginCheckURL = "login"
+	pr := newPRSnapshot(mc, org, repo, number)
+	_, result = bot.checkCLASignResult(pr, commits, repoCnf)
+	assert.Equal(t, []string{"u3"}, result[2])
+	// the unusable email is not recorded for the list of the unsigned contributors
+	assert.Empty(t, pr.unsignedEmails)
+
+	_, result = bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits[:2], repoCnf)
+	assert.Equal(t, []string{"u2"}, result[1])
+}
@@ -0,0 +1,97 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// the colors of the cla_label_yes and cla_label_no created without the color configured
+	defaultCLALabelYesColor = "#0e8a16"
+	defaultCLALabelNoColor  = "#d73a4a"
+)
+
+// regexpLabelColor matches a hex color of the label, such as #0e8a16
+var regexpLabelColor = regexp.MustCompile(`^#?[0-9a-fA-F]{6}$`)
+
+// labelStyle is the color and the description of a label created in the repository
+type labelStyle struct {
+	// Color is the hex color as #0e8a16
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+func (s *labelStyle) validate(field string) error {
+	if s.Color != "" && !regexpLabelColor.MatchString(s.Color) {
+		return fmt.Errorf("the color of the %s must be a hex color as #0e8a16, but it is %q", field, s.Color)
+	}
+	return nil
+}
+
+// repoLabel is a label of a repository to be created on the code hosting platform
+type repoLabel struct {
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description,omitempty"`
+}
+
+// claLabel returns the label of the repository of the cla_label_yes or the cla_label_no with its style
+func (c *repoConfig) claLabel(name, label string) repoLabel {
+	style, color := c.CLALabelNoStyle, defaultCLALabelNoColor
+	if label == c.CLALabelYes {
+		style, color = c.CLALabelYesStyle, defaultCLALabelYesColor
+	}
+	if style.Color != "" {
+		color = style.Color
+	}
+	if !strings.HasPrefix(color, "#") {
+		color = "#" + color
+	}
+	return repoLabel{Name: name, Color: color, Description: style.Description}
+}
+
+// addCLALabel adds the cla_label_yes or the cla_label_no to the pull request. If it failed and the
+// create_cla_labels is set, the label is created in the repository and added again, for the platforms
+// which only add the labels existing in the repository. Nothing is written under the report enforcement level
+func (bot *robot) addCLALabel(pr *prSnapshot, repoCnf *repoConfig, label string) bool {
+	if !repoCnf.enforces(enforcementLabel) {
+		return true
+	}
+
+	name := bot.labelName(pr, label)
+	if bot.addPRLabels(pr, []string{name}) {
+		return true
+	}
+	if !repoCnf.CreateCLALabels || !bot.labels.allow() {
+		return false
+	}
+
+	pr.stats.writes++
+	ok := bot.cli.CreateRepoLabel(pr.org, pr.repo, repoCnf.claLabel(name, label))
+	bot.auditMutation(pr, auditActionLabelCreate, name, ok)
+	return ok && bot.addPRLabels(pr, []string{name})
+}
+
+// removeCLALabel removes the CLA label from the pull request if it is among the prLabels. Nothing is written
+// under the report enforcement level. It returns false if removing the label failed
+func (bot *robot) removeCLALabel(pr *prSnapshot, prLabels []string, repoCnf *repoConfig, label string) bool {
+	if name := bot.labelName(pr, label); repoCnf.enforces(enforcementLabel) && slices.Contains(prLabels, name) {
+		return bot.removePRLabels(pr, []string{name})
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"testing"
+)
+
+// repoLabelsClient only adds the labels existing in the repository, as some platforms do
+type repoLabelsClient struct {
+	*mockClient
+	existing []string
+}
+
+func (c *repoLabelsClient) AddPRLabels(org, repo, number string, labels []string) bool {
+	for _, label := range labels {
+		if !slices.Contains(c.existing, label) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *repoLabelsClient) CreateRepoLabel(org, repo string, label repoLabel) bool {
+	c.existing = append(c.existing, label.Name)
+	return c.mockClient.CreateRepoLabel(org, repo, label)
+}
+
+func TestAddCLALabel(t *testing.T) {
+	mc := &repoLabelsClient{mockClient: &mockClient{successfulCreateRepoLabel: true}}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	repoCnf := &repoConfig{
+		CLALabelYes: "cla-yes", CLALabelNo: "cla-no",
+		CLALabelNoStyle: labelStyle{Color: "b60205", Description: "the CLA is not signed"},
+	}
+	pr := newPRSnapshot(mc, org, repo, number)
+
+	// the label is not created without the create_cla_labels
+	assert.Equal(t, false, bot.addCLALabel(pr, repoCnf, repoCnf.CLALabelYes))
+	assert.Empty(t, mc.repoLabels)
+
+	repoCnf.CreateCLALabels = true
+	assert.Equal(t, true, bot.addCLALabel(pr, repoCnf, repoCnf.CLALabelYes))
+	assert.Equal(t, true, bot.addCLALabel(pr, repoCnf, repoCnf.CLALabelNo))
+	// the existing label is not created again
+	assert.Equal(t, true, bot.addCLALabel(pr, repoCnf, repoCnf.CLALabelYes))
+	assert.Equal(t, []repoLabel{
+		{Name: "cla-yes", Color: defaultCLALabelYesColor},
+		{Name: "cla-no", Color: "#b60205", Description: "the CLA is not signed"},
+	}, mc.repoLabels)
+
+	// the label is not added if it can not be created
+	mc.successfulCreateRepoLabel = false
+	mc.existing = nil
+	assert.Equal(t, false, bot.addCLALabel(pr, repoCnf, repoCnf.CLALabelYes))
+}
+
+func TestValidateLabelStyle(t *testing.T) {
+	assert.NoError(t, (&labelStyle{}).validate("cla_label_yes_style"))
+	assert.NoError(t, (&labelStyle{Color: "#0E8A16"}).validate("cla_label_yes_style"))
+	assert.ErrorContains(t, (&labelStyle{Color: "green"}).validate("cla_label_no_style"),
+		"the color of the cla_label_no_style must be a hex color")
+}
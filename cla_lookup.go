@@ -0,0 +1,113 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// defaultCLALookupConcurrency is used when the cla_lookup_concurrency is not configured
+const defaultCLALookupConcurrency = 4
+
+func (c *configuration) claLookupConcurrency() int {
+	if c.CLALookupConcurrency == 0 {
+		return defaultCLALookupConcurrency
+	}
+	return c.CLALookupConcurrency
+}
+
+// emailSignResult is the sign state of an email looked up by an evaluation
+type emailSignResult struct {
+	signState string
+	claType   string
+}
+
+func (pr *prSnapshot) memoizedSignState(email string) (emailSignResult, bool) {
+	pr.signStatesMu.Lock()
+	defer pr.signStatesMu.Unlock()
+
+	r, ok := pr.signStates[strings.ToLower(email)]
+	return r, ok
+}
+
+func (pr *prSnapshot) memoizeSignState(email string, r emailSignResult) {
+	pr.signStatesMu.Lock()
+	defer pr.signStatesMu.Unlock()
+
+	if pr.signStates == nil {
+		pr.signStates = map[string]emailSignResult{}
+	}
+	pr.signStates[strings.ToLower(email)] = r
+}
+
+// lookupEmailSignState checks the sign state of the email once per evaluation, the emails differing only in case
+// are the same one
+func (bot *robot) lookupEmailSignState(pr *prSnapshot, email string, repoCnf *repoConfig,
+	batchStates map[string]string) (signState, claType string) {
+	if r, ok := pr.memoizedSignState(email); ok {
+		return r.signState, r.claType
+	}
+
+	signState, claType = bot.checkEmailSignState(pr, email, repoCnf, batchStates)
+	pr.memoizeSignState(email, emailSignResult{signState: signState, claType: claType})
+	return
+}
+
+// prefetchEmailSignStates looks up the distinct emails which need the CLA server by a bounded pool of workers,
+// so that a pull request of many contributors is not checked one email after another. The lookups stop once
+// the watchdog trips
+func (bot *robot) prefetchEmailSignStates(pr *prSnapshot, emails []string, repoCnf *repoConfig,
+	batchStates map[string]string) {
+	var pending []string
+	seen := map[string]bool{}
+	for _, email := range emails {
+		if email == "" || email == repoCnf.LitePRCommitter.Email || repoCnf.emailDomainDenied(email) ||
+			repoCnf.emailDomainAllowed(email) || seen[strings.ToLower(email)] {
+			continue
+		}
+		if _, ok := bot.corporateCLA.lookup(repoCnf.CorporateCLAFile, email); ok {
+			continue
+		}
+		seen[strings.ToLower(email)] = true
+		pending = append(pending, email)
+	}
+
+	workers := min(bot.prConfig(pr).claLookupConcurrency(), len(pending))
+	// a single lookup gains nothing from the workers, it is left to the evaluation
+	if workers < 2 {
+		return
+	}
+
+	queue := make(chan string, len(pending))
+	for _, email := range pending {
+		queue <- email
+	}
+	close(queue)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for email := range queue {
+				if pr.watchdog.tripped() {
+					return
+				}
+				bot.lookupEmailSignState(pr, email, repoCnf, batchStates)
+			}
+		}()
+	}
+	wg.Wait()
+}
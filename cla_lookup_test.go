@@ -0,0 +1,85 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lookupCountingClient answers the CLA lookups by the states of the emails, and counts the lookups of each email
+type lookupCountingClient struct {
+	*mockClient
+	mu     sync.Mutex
+	states map[string]string
+	calls  map[string]int
+}
+
+func (c *lookupCountingClient) CheckCLASignature(urlStr string) (string, bool) {
+	_, email, _ := strings.Cut(urlStr, "?email=")
+	email, _ = url.QueryUnescape(email)
+	email = strings.ToLower(email)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[email]++
+	return c.states[email], true
+}
+
+func TestLookupEmailSignStates(t *testing.T) {
+	cli := &lookupCountingClient{
+		mockClient: &mockClient{},
+		states: map[string]string{
+			"a@example.com": client.CLASignStateYes,
+			"b@example.com": client.CLASignStateNo,
+			"c@example.com": client.CLASignStateYes,
+		},
+		calls: map[string]int{},
+	}
+	bot := &robot{cli: cli, cnf: &configuration{}}
+	repoCnf := &repoConfig{CheckURL: "http://cla/check"}
+	commits := []client.PRCommit{
+		{AuthorName: "alice", AuthorEmail: "a@example.com"},
+		{AuthorName: "Alice", AuthorEmail: "A@example.com"},
+		{AuthorName: "bob", AuthorEmail: "b@example.com"},
+		{AuthorName: "carol", AuthorEmail: "c@example.com"},
+		{AuthorName: "carol", AuthorEmail: "c@example.com"},
+	}
+
+	pr := newPRSnapshot(cli, org, repo, number)
+	states, _, stopped := bot.classifySignStates(pr, commits, repoCnf)
+	assert.Equal(t, false, stopped)
+	assert.Equal(t, [3][]string{{"alice", "Alice", "carol"}, {"bob"}, nil}, states)
+	assert.Equal(t, map[string]int{"a@example.com": 1, "b@example.com": 1, "c@example.com": 1}, cli.calls)
+
+	// the lookups are sequential if the concurrency is 1
+	bot.cnf.CLALookupConcurrency = 1
+	cli.calls = map[string]int{}
+	states, _, _ = bot.classifySignStates(newPRSnapshot(cli, org, repo, number), commits, repoCnf)
+	assert.Equal(t, []string{"bob"}, states[1])
+	assert.Equal(t, 3, len(cli.calls))
+
+	// the prefetch stops once the watchdog trips
+	bot.cnf.CLALookupConcurrency = 0
+	cli.calls = map[string]int{}
+	pr = newPRSnapshot(cli, org, repo, number)
+	pr.watchdog = newEvaluationWatchdog(&watchdogConfig{MaxCLALookups: 1})
+	_, _, stopped = bot.classifySignStates(pr, commits, repoCnf)
+	assert.Equal(t, true, stopped)
+	assert.LessOrEqual(t, len(cli.calls), defaultCLALookupConcurrency)
+}
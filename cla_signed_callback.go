@@ -0,0 +1,112 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// claSignedCallbackPath is called by the CLA service when a contributor signs the CLA, with the JWT of the
+// cla_webhook whose email claim is the contributor
+const claSignedCallbackPath = "/cla-signed-callback"
+
+// claSignedCallback rechecks the pull requests blocked by the contributor who has just signed the CLA,
+// so that the contributor does not have to comment the command
+type claSignedCallback struct {
+	bot      *robot
+	verifier *claWebhookVerifier
+	log      *logrus.Entry
+}
+
+// registerCLASignedCallback mounts the callback on the mux. The cla_webhook is read on each request, so that
+// it follows the reloads. The callback is disabled while the jwks_url of the cla_webhook is not set, since the
+// webhooks can not be authenticated
+func registerCLASignedCallback(mux *http.ServeMux, bot *robot, logger *logrus.Entry) {
+	if bot.config().CLAWebhook.JWKSURL == "" {
+		logger.Info("the cla signed callback is disabled until the jwks_url of the cla_webhook is set")
+	}
+
+	verifier := newCLAWebhookVerifier(func() *claWebhookConfig { return &bot.config().CLAWebhook })
+	h := &claSignedCallback{bot: bot, verifier: verifier, log: logger}
+	mux.HandleFunc(claSignedCallbackPath, h.handle)
+}
+
+func (h *claSignedCallback) handle(w http.ResponseWriter, r *http.Request) {
+	if h.bot.config().CLAWebhook.JWKSURL == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	claims, err := h.verifier.verifyRequest(r)
+	if err != nil {
+		h.log.WithError(err).Warning("rejected the cla signed callback")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	n := h.bot.recheckBlockedByEmail(claims.Email, h.log.WithField("signed-email", maskEmailText(claims.Email)))
+	writeJSON(w, http.StatusAccepted, map[string]int{"queued": n})
+}
+
+// listPRsBlockedByEmail lists the open pull requests whose latest evaluation is unsigned because of the email
+func (bot *robot) listPRsBlockedByEmail(email string) []prState {
+	email = strings.ToLower(email)
+	var blocked []prState
+	for _, s := range bot.store.exportSnapshot().PRs {
+		if !s.Closed && s.Status == prStatusUnsigned && slices.Contains(s.UnsignedEmails, email) {
+			blocked = append(blocked, s)
+		}
+	}
+
+	return blocked
+}
+
+// recheckBlockedByEmail queues the rechecks of the pull requests blocked by the email, throttled by the rate
+// limiter of the recheck as the recheck of an org is. It returns the number of queued pull requests
+func (bot *robot) recheckBlockedByEmail(email string, logger *logrus.Entry) int {
+	if bot.store == nil {
+		return 0
+	}
+
+	prs := bot.listPRsBlockedByEmail(email)
+	go func() {
+		for i := range prs {
+			if err := bot.rechecker.limiter.Wait(context.Background()); err != nil {
+				logger.WithError(err).Error("the recheck of the signed contributor is interrupted")
+				return
+			}
+			if bot.budget.degraded(platformCodeHosting) {
+				logger.Warningf("the recheck of the signed contributor is paused by the error budget, "+
+					"%d pull requests are left", len(prs)-i)
+				return
+			}
+
+			s := prs[i]
+			if repoCnf := bot.getRepoConfig(s.Org, s.Repo); repoCnf != nil {
+				bot.recheckSerialized(newPRSnapshot(bot.cli, s.Org, s.Repo, s.Number).withActor(auditActorCallback), repoCnf,
+					logger.WithField("recheck-pr", s.key()))
+			}
+		}
+	}()
+
+	return len(prs)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCLASignedCallback(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	enc := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string][]jsonWebKey{"keys": {
+			{Kty: "EC", Kid: "ec1", Crv: "P-256", X: enc(key.X.Bytes()), Y: enc(key.Y.Bytes())},
+		}})
+	}))
+	defer jwks.Close()
+
+	mc := &mockClient{
+		successfulGetPullRequestCommits: true,
+		successfulCheckCLASignature:     true,
+		successfulAddPRLabels:           true,
+		commits:                         []client.PRCommit{{AuthorName: "u1", AuthorEmail: "u1@example.com"}},
+		CLAState:                        client.CLASignStateYes,
+	}
+	repoCnf := repoConfig{RepoFilter: config.RepoFilter{Repos: []string{org}}, CLALabelYes: labelYes, CLALabelNo: labelNo}
+	bot := &robot{
+		cli: mc,
+		cnf: &configuration{ConfigItems: []repoConfig{repoCnf}, CLAWebhook: claWebhookConfig{
+			JWKSURL: jwks.URL, Issuer: "cla-service", Audience: "cla-robot",
+		}},
+		store:     newMemoryStateStore(),
+		rechecker: newOrgRechecker(60000),
+	}
+	bot.store.put(prState{Org: org, Repo: repo, Number: "1", Status: prStatusUnsigned,
+		UnsignedUsers: []string{"u1"}, UnsignedEmails: []string{"u1@example.com"}})
+	bot.store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusUnsigned,
+		UnsignedUsers: []string{"u2"}, UnsignedEmails: []string{"u2@example.com"}})
+	bot.store.put(prState{Org: org, Repo: repo, Number: "3", Status: prStatusSigned})
+	// the closed one is not rechecked
+	bot.store.put(prState{Org: org, Repo: repo, Number: "4", Status: prStatusUnsigned,
+		UnsignedUsers: []string{"u1"}, UnsignedEmails: []string{"u1@example.com"}, Closed: true})
+
+	mux := http.NewServeMux()
+	registerCLASignedCallback(mux, bot, framework.NewLogger())
+	callback := func(method, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, claSignedCallbackPath, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	token := signTestJWT(t, "ES256", "ec1", key, map[string]any{
+		"iss": "cla-service", "aud": "cla-robot", "exp": time.Now().Unix() + 60, "email": "U1@example.com",
+	})
+	assert.Equal(t, http.StatusMethodNotAllowed, callback(http.MethodGet, token).Code)
+	assert.Equal(t, http.StatusUnauthorized, callback(http.MethodPost, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, callback(http.MethodPost, token+"x").Code)
+
+	w := callback(http.MethodPost, token)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"queued":1}`, w.Body.String())
+	assert.Eventually(t, func() bool {
+		s, _ := bot.store.get(org, repo, "1")
+		return s.Status == prStatusSigned
+	}, time.Second, 10*time.Millisecond)
+	s, _ := bot.store.get(org, repo, "2")
+	assert.Equal(t, prStatusUnsigned, s.Status)
+
+	// the reloaded cla_webhook is used by the following requests
+	reloaded := *bot.cnf
+	reloaded.CLAWebhook.Issuer = "another-cla-service"
+	bot.cnf = &reloaded
+	assert.Equal(t, http.StatusUnauthorized, callback(http.MethodPost, token).Code)
+
+	// the callback is disabled without the jwks_url
+	bot.cnf.CLAWebhook = claWebhookConfig{}
+	assert.Equal(t, http.StatusNotFound, callback(http.MethodPost, token).Code)
+	mux = http.NewServeMux()
+	registerCLASignedCallback(mux, bot, framework.NewLogger())
+	assert.Equal(t, http.StatusNotFound, callback(http.MethodPost, token).Code)
+}
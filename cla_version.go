@@ -0,0 +1,110 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"slices"
+)
+
+const (
+	// defaultCommentCLAReconfirm is used when the comment_cla_reconfirm is not configured
+	defaultCommentCLAReconfirm = "  \n\nThe CLA has been updated to the version %s. %s signed an earlier version, " +
+		"and need to sign the new one to confirm it."
+
+	// auditActorCLAVersion is the actor of the rechecks caused by the change of the cla_version
+	auditActorCLAVersion = "cla-version"
+)
+
+// versioned adds the cla_version to the query of the url of the CLA server, so that only the contributors who
+// have signed the version are signed. The url is returned as it is if the cla_version is not set
+func (c *repoConfig) versioned(urlStr string) string {
+	if c.CLAVersion == "" {
+		return urlStr
+	}
+	return addURLQuery(urlStr, "version", c.CLAVersion)
+}
+
+// reconfirmingUsers returns the unsigned users who were signed in the previous evaluation of the pull request,
+// who need to sign the new version of the CLA
+func (bot *robot) reconfirmingUsers(pr *prSnapshot, unsignedUsers []string, repoCnf *repoConfig) []string {
+	if repoCnf.CLAVersion == "" || bot.store == nil {
+		return nil
+	}
+
+	s, _ := bot.store.get(pr.org, pr.repo, pr.number)
+	var users []string
+	for _, user := range unsignedUsers {
+		if slices.Contains(s.SignedUsers, user) {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// claVersionChanged checks whether the cla_version of the repository is changed by the new configuration
+func claVersionChanged(old, c *configuration, org, repo string) bool {
+	oldCnf, newCnf := old.getRepoConfig(org, repo), c.getRepoConfig(org, repo)
+	return oldCnf != nil && newCnf != nil && oldCnf.CLAVersion != newCnf.CLAVersion
+}
+
+// recheckCLAVersionChanges checks again the open signed pull requests of the repositories whose cla_version is changed
+// by the reloaded configuration, throttled by the rate limiter of the recheck, so that their contributors are asked
+// to sign the new version. It returns the number of the pull requests queued
+func (bot *robot) recheckCLAVersionChanges(old, c *configuration, logger *logrus.Entry) int {
+	if bot.store == nil {
+		return 0
+	}
+
+	var prs []prState
+	for _, s := range bot.store.exportSnapshot().PRs {
+		if !s.Closed && s.Status == prStatusSigned && claVersionChanged(old, c, s.Org, s.Repo) {
+			prs = append(prs, s)
+		}
+	}
+	if len(prs) == 0 {
+		return 0
+	}
+
+	logger.Infof("recheck %d signed pull requests since the cla_version is changed", len(prs))
+	go func() {
+		for i := range prs {
+			if err := bot.rechecker.limiter.Wait(context.Background()); err != nil {
+				logger.WithError(err).Error("the recheck of the cla_version is interrupted")
+				return
+			}
+
+			s := &prs[i]
+			if repoCnf := bot.getRepoConfig(s.Org, s.Repo); repoCnf != nil {
+				bot.recheckSerialized(newPRSnapshot(bot.cli, s.Org, s.Repo, s.Number).withActor(auditActorCLAVersion),
+					repoCnf, logger.WithField("cla-version-pr", s.key()))
+			}
+		}
+	}()
+	return len(prs)
+}
+
+// claReconfirm renders the note asking the users who signed an earlier version of the CLA to sign the new one
+func (r *commentRenderer) claReconfirm(users []string, repoCnf *repoConfig) string {
+	if len(users) == 0 {
+		return ""
+	}
+
+	data := r.data(repoCnf)
+	data.Users = r.userMarks(users)
+	return r.render(r.format(r.cnf.CommentCLAReconfirm, defaultCommentCLAReconfirm), data,
+		func(s string) string { return fmt.Sprintf(s, repoCnf.CLAVersion, data.Users) })
+}
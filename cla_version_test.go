@@ -0,0 +1,50 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCLAVersion(t *testing.T) {
+	repoCnf := &repoConfig{CLAVersion: "2.0 rc"}
+	assert.Equal(t, "http://cla/check?email=a%40example.com&version=2.0+rc",
+		repoCnf.versioned(claCheckURL("http://cla/check", org, repo, "a@example.com")))
+	assert.Equal(t, "http://cla/batch?version=2.0+rc", repoCnf.versioned("http://cla/batch"))
+	assert.Equal(t, "http://cla/batch", (&repoConfig{}).versioned("http://cla/batch"))
+
+	// the users signed in the previous evaluation are asked to sign the new version
+	bot := &robot{cli: new(mockClient), cnf: &configuration{}, store: newMemoryStateStore()}
+	pr := newPRSnapshot(bot.cli, org, repo, number)
+	bot.recordPRState(pr, true, [3][]string{{"alice", "bob"}})
+	assert.Equal(t, []string{"bob"}, bot.reconfirmingUsers(pr, []string{"bob", "carol"}, repoCnf))
+	assert.Equal(t, 0, len(bot.reconfirmingUsers(pr, []string{"bob"}, &repoConfig{})))
+
+	old := &configuration{ConfigItems: []repoConfig{{RepoFilter: config.RepoFilter{Repos: []string{org}}, CLAVersion: "1.0"}}}
+	c := &configuration{ConfigItems: []repoConfig{{RepoFilter: config.RepoFilter{Repos: []string{org}}, CLAVersion: "2.0"}}}
+	assert.Equal(t, true, claVersionChanged(old, c, org, repo))
+	assert.Equal(t, false, claVersionChanged(old, old, org, repo))
+	assert.Equal(t, false, claVersionChanged(old, c, "org2", repo))
+
+	// the unsigned pull requests are not rechecked since they are asked to sign anyway
+	bot.recordPRState(pr, false, [3][]string{nil, {"bob"}})
+	assert.Equal(t, 0, bot.recheckCLAVersionChanges(old, c, logrus.NewEntry(logrus.New())))
+
+	// nor the closed pull requests
+	bot.store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusSigned, Closed: true})
+	assert.Equal(t, 0, bot.recheckCLAVersionChanges(old, c, logrus.NewEntry(logrus.New())))
+}
@@ -0,0 +1,302 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksRefreshInterval is how long the keys fetched from the jwks_url are used before being fetched again
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefreshInterval bounds the fetches triggered by the tokens signed with an unknown key
+	jwksMinRefreshInterval = time.Minute
+)
+
+// claWebhookConfig describes how the webhooks pushed by the CLA service are authenticated.
+// Each webhook carries a JWT signed by the CLA service with RS256 or ES256, whose keys are published at JWKSURL
+type claWebhookConfig struct {
+	// JWKSURL is the url of the JSON Web Key Set of the CLA service. The webhooks are rejected if it is empty
+	JWKSURL string `json:"jwks_url"`
+	// Issuer is the required iss claim of the JWT
+	Issuer string `json:"issuer"`
+	// Audience is the required aud claim of the JWT, which identifies this bot
+	Audience string `json:"audience"`
+	// LeewaySeconds tolerates the clock skew when checking the exp and nbf claims
+	LeewaySeconds int `json:"leeway_seconds"`
+}
+
+func (c *claWebhookConfig) validate() error {
+	if c.JWKSURL != "" && (c.Issuer == "" || c.Audience == "") {
+		return errors.New("the issuer and audience of the cla_webhook must be set when the jwks_url is set")
+	}
+	if c.LeewaySeconds < 0 {
+		return errors.New("the leeway_seconds of the cla_webhook can not be negative")
+	}
+	return nil
+}
+
+// jwtAudience is the aud claim, which is either a string or an array of strings
+type jwtAudience []string
+
+func (a *jwtAudience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = jwtAudience{s}
+		return nil
+	}
+
+	var v []string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return errors.New("invalid aud claim")
+	}
+	*a = v
+	return nil
+}
+
+// claWebhookClaims is the claims of the JWT of a webhook of the CLA service
+type claWebhookClaims struct {
+	Issuer    string      `json:"iss"`
+	Audience  jwtAudience `json:"aud"`
+	ExpiresAt int64       `json:"exp"`
+	NotBefore int64       `json:"nbf"`
+	// Email is the email of the contributor whose CLA state changed
+	Email string `json:"email"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey converts the key to a rsa or P-256 ecdsa public key, it returns nil for the other keys
+func (k *jsonWebKey) publicKey() crypto.PublicKey {
+	decode := func(s string) *big.Int {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil
+		}
+		return new(big.Int).SetBytes(b)
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, e := decode(k.N), decode(k.E)
+		if n == nil || e == nil || !e.IsInt64() {
+			return nil
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		x, y := decode(k.X), decode(k.Y)
+		if k.Crv != "P-256" || x == nil || y == nil || !elliptic.P256().IsOnCurve(x, y) {
+			return nil
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	}
+	return nil
+}
+
+// claWebhookVerifier verifies the JWT of the webhooks against the keys published by the CLA service.
+// The config is read on each verification, so that the changes of a reload are followed
+type claWebhookVerifier struct {
+	cfg func() *claWebhookConfig
+	hc  *http.Client
+	now func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	keysURL   string
+	fetchedAt time.Time
+}
+
+func newCLAWebhookVerifier(cfg func() *claWebhookConfig) *claWebhookVerifier {
+	return &claWebhookVerifier{cfg: cfg, hc: &http.Client{Timeout: 10 * time.Second}, now: time.Now}
+}
+
+// fetchKeys replaces the keys by those published at the jwks_url
+func (v *claWebhookVerifier) fetchKeys(jwksURL string) error {
+	resp, err := v.hc.Get(jwksURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("failed to fetch the jwks: " + resp.Status)
+	}
+
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for i := range set.Keys {
+		if key := set.Keys[i].publicKey(); key != nil {
+			keys[set.Keys[i].Kid] = key
+		}
+	}
+	v.keys, v.keysURL = keys, jwksURL
+	return nil
+}
+
+// key returns the key of the kid. The keys are fetched again when they are outdated, or when the kid is unknown
+// and the keys were not fetched recently, so that the rotated keys are picked up. A failed fetch keeps the old keys.
+// The keys of another jwks_url are never used
+func (v *claWebhookVerifier) key(jwksURL, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.keysURL != jwksURL {
+		v.keys, v.keysURL, v.fetchedAt = nil, "", time.Time{}
+	}
+
+	key, ok := v.keys[kid]
+	age := v.now().Sub(v.fetchedAt)
+	if v.fetchedAt.IsZero() || age >= jwksRefreshInterval || (!ok && age >= jwksMinRefreshInterval) {
+		v.fetchedAt = v.now()
+		if err := v.fetchKeys(jwksURL); err != nil {
+			return nil, err
+		}
+		key, ok = v.keys[kid]
+	}
+
+	if !ok {
+		return nil, errors.New("unknown key: " + kid)
+	}
+	return key, nil
+}
+
+// verify checks the signature of the token and its claims, and returns the claims
+func (v *claWebhookVerifier) verify(token string) (*claWebhookClaims, error) {
+	cfg := v.cfg()
+	if cfg.JWKSURL == "" {
+		return nil, errors.New("the jwks_url is not set")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	header := jwtHeader{}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+
+	key, err := v.key(cfg.JWKSURL, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	claims := &claWebhookClaims{}
+	if err = decodeJWTPart(parts[1], claims); err != nil {
+		return nil, err
+	}
+	if err = v.checkClaims(cfg, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *claWebhookVerifier) checkClaims(cfg *claWebhookConfig, claims *claWebhookClaims) error {
+	if claims.Issuer != cfg.Issuer {
+		return errors.New("unexpected issuer: " + claims.Issuer)
+	}
+	if !slices.Contains(claims.Audience, cfg.Audience) {
+		return errors.New("the token is not issued for this audience")
+	}
+
+	now, leeway := v.now().Unix(), int64(cfg.LeewaySeconds)
+	if claims.ExpiresAt == 0 || now > claims.ExpiresAt+leeway {
+		return errors.New("the token is expired")
+	}
+	if claims.NotBefore != 0 && now < claims.NotBefore-leeway {
+		return errors.New("the token is not valid yet")
+	}
+
+	if claims.Email == "" {
+		return errors.New("missing the email claim")
+	}
+	return nil
+}
+
+// verifyRequest verifies the JWT in the Authorization header of the webhook
+func (v *claWebhookVerifier) verifyRequest(r *http.Request) (*claWebhookClaims, error) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return nil, errors.New("missing the bearer token")
+	}
+	return v.verify(token)
+}
+
+func decodeJWTPart(s string, receiver any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	if err = json.Unmarshal(b, receiver); err != nil {
+		return errors.New("malformed token")
+	}
+	return nil
+}
+
+// verifyJWTSignature verifies the signature by the key, only RS256 and ES256 are accepted
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if alg == "RS256" && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if alg == "ES256" && len(sig) == 64 &&
+			ecdsa.Verify(k, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			return nil
+		}
+	}
+	return errors.New("invalid signature")
+}
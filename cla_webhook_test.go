@@ -0,0 +1,135 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func signTestJWT(t *testing.T, alg, kid string, key crypto.Signer, claims any) string {
+	header, _ := json.Marshal(jwtHeader{Alg: alg, Kid: kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, _ = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		assert.Equal(t, nil, err)
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestCLAWebhookVerifier(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	enc := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		_ = json.NewEncoder(w).Encode(map[string][]jsonWebKey{"keys": {
+			{Kty: "RSA", Kid: "rsa1", N: enc(rsaKey.N.Bytes()), E: enc(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{Kty: "EC", Kid: "ec1", Crv: "P-256", X: enc(ecKey.X.Bytes()), Y: enc(ecKey.Y.Bytes())},
+		}})
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	cfg := &claWebhookConfig{JWKSURL: server.URL, Issuer: "cla-service", Audience: "cla-robot", LeewaySeconds: 30}
+	v := newCLAWebhookVerifier(func() *claWebhookConfig { return cfg })
+	v.now = func() time.Time { return now }
+
+	valid := map[string]any{"iss": "cla-service", "aud": "cla-robot", "exp": now.Unix() + 60, "email": "a@example.com"}
+	claims, err := v.verify(signTestJWT(t, "RS256", "rsa1", rsaKey, valid))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "a@example.com", claims.Email)
+
+	valid["aud"] = []string{"other", "cla-robot"}
+	claims, err = v.verify(signTestJWT(t, "ES256", "ec1", ecKey, valid))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "a@example.com", claims.Email)
+	assert.Equal(t, 1, fetches)
+
+	testCases := []struct {
+		desc  string
+		token string
+		err   string
+	}{
+		{"malformed", "a.b", "malformed token"},
+		{"alg mismatch", signTestJWT(t, "ES256", "rsa1", rsaKey, valid), "invalid signature"},
+		{"wrong key", signTestJWT(t, "RS256", "ec1", rsaKey, valid), "invalid signature"},
+		{"issuer", signTestJWT(t, "RS256", "rsa1", rsaKey, map[string]any{
+			"iss": "other", "aud": "cla-robot", "exp": now.Unix() + 60, "email": "a@example.com"}), "unexpected issuer: other"},
+		{"audience", signTestJWT(t, "RS256", "rsa1", rsaKey, map[string]any{
+			"iss": "cla-service", "aud": "other", "exp": now.Unix() + 60, "email": "a@example.com"}),
+			"the token is not issued for this audience"},
+		{"expired", signTestJWT(t, "RS256", "rsa1", rsaKey, map[string]any{
+			"iss": "cla-service", "aud": "cla-robot", "exp": now.Unix() - 31, "email": "a@example.com"}),
+			"the token is expired"},
+		{"not before", signTestJWT(t, "RS256", "rsa1", rsaKey, map[string]any{
+			"iss": "cla-service", "aud": "cla-robot", "exp": now.Unix() + 60, "nbf": now.Unix() + 31, "email": "a@example.com"}),
+			"the token is not valid yet"},
+		{"email", signTestJWT(t, "RS256", "rsa1", rsaKey, map[string]any{
+			"iss": "cla-service", "aud": "cla-robot", "exp": now.Unix() + 60}), "missing the email claim"},
+	}
+	for i := range testCases {
+		t.Run(testCases[i].desc, func(t *testing.T) {
+			_, err := v.verify(testCases[i].token)
+			assert.EqualError(t, err, testCases[i].err)
+		})
+	}
+
+	// The unknown key is fetched again at most once per minute
+	_, err = v.verify(signTestJWT(t, "RS256", "rsa2", rsaKey, valid))
+	assert.EqualError(t, err, "unknown key: rsa2")
+	assert.Equal(t, 1, fetches)
+	now = now.Add(jwksMinRefreshInterval)
+	_, err = v.verify(signTestJWT(t, "RS256", "rsa2", rsaKey, valid))
+	assert.EqualError(t, err, "unknown key: rsa2")
+	assert.Equal(t, 2, fetches)
+
+	// the keys are fetched from the reloaded jwks_url at once
+	another := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string][]jsonWebKey{"keys": {}})
+	}))
+	defer another.Close()
+	cfg = &claWebhookConfig{JWKSURL: another.URL, Issuer: "cla-service", Audience: "cla-robot", LeewaySeconds: 30}
+	_, err = v.verify(signTestJWT(t, "RS256", "rsa1", rsaKey, valid))
+	assert.EqualError(t, err, "unknown key: rsa1")
+	assert.Equal(t, 2, fetches)
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	_, err = v.verifyRequest(req)
+	assert.EqualError(t, err, "missing the bearer token")
+
+	assert.NotEqual(t, nil, (&claWebhookConfig{JWKSURL: server.URL}).validate())
+}
@@ -0,0 +1,561 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/go-resty/resty/v2"
+	"github.com/opensourceways/go-gitcode/openapi"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gitCodeAPIBaseURL is the base url of the GitCode OpenAPI, used for the calls the OpenAPI sdk does not provide
+const gitCodeAPIBaseURL = "https://api.gitcode.com/api/v5/"
+
+// defaultCommentRateLimitBackoff is how long the comments are held when the platform limits their rate
+// without a Retry-After header
+const defaultCommentRateLimitBackoff = time.Minute
+
+// listPageSize is the number of the items of each page when listing by the GitCode OpenAPI
+const listPageSize = 100
+
+// prCommitMessage is a commit of a pull request with its sha and message
+type prCommitMessage struct {
+	client.PRCommit
+	SHA     string
+	Message string
+}
+
+// prComment is a comment of a pull request with the login of its author
+type prComment struct {
+	client.PRComment
+	Author string
+}
+
+// robotClient extends the client of the robot framework with the calls which the framework does not provide.
+// The read operations are routed to the reader, which uses the read-only token if it is provided
+type robotClient struct {
+	client.Client
+	api       *openapi.APIClient
+	reader    client.Client
+	readAPI   *openapi.APIClient
+	claServer *resty.Client
+	// graphQL queries the GraphQL endpoint by the read-only token if it is provided
+	graphQL *resty.Client
+	log     *logrus.Entry
+
+	// commentsLimitedUntil is when the rate limit of the comments of the token lifts
+	commentsLimitedUntil time.Time
+	mu                   sync.Mutex
+}
+
+func newRobotClient(token, readToken []byte, logger *logrus.Entry) *robotClient {
+	c := &robotClient{
+		Client:    client.NewClient(token, logger),
+		api:       openapi.NewAPIClientWithAuthorization(token),
+		claServer: resty.New().RemoveProxy().SetRetryCount(3),
+		log:       logger,
+	}
+
+	c.reader, c.readAPI = c.Client, c.api
+	c.graphQL = resty.New().RemoveProxy().SetAuthToken(string(token))
+	if len(readToken) != 0 {
+		c.reader = client.NewClient(readToken, logger)
+		c.readAPI = openapi.NewAPIClientWithAuthorization(readToken)
+		c.graphQL.SetAuthToken(string(readToken))
+	}
+	return c
+}
+
+// GetPullRequestLabels lists the labels of a pull request by the read-only token
+func (c *robotClient) GetPullRequestLabels(org, repo, number string) (result []string, success bool) {
+	return c.reader.GetPullRequestLabels(org, repo, number)
+}
+
+// GetPullRequestCommits lists the commits of a pull request by the read-only token
+func (c *robotClient) GetPullRequestCommits(org, repo, number string) (result []client.PRCommit, success bool) {
+	return c.reader.GetPullRequestCommits(org, repo, number)
+}
+
+// ListPullRequestComments lists the comments of a pull request together with their authors by the read-only token
+func (c *robotClient) ListPullRequestComments(org, repo, number string) (result []prComment, success bool) {
+	for page := 1; ; page++ {
+		comments, ok, err := c.readAPI.PullRequests.ListPullRequestComments(context.Background(), org, repo, number,
+			strconv.Itoa(page), "pr_comment")
+		if err != nil || !ok {
+			c.log.WithError(err).Errorf("list comments of %s/%s/%s failed", org, repo, number)
+			return nil, false
+		}
+		if len(comments) == 0 {
+			return result, true
+		}
+
+		for _, v := range comments {
+			result = append(result, prComment{
+				PRComment: client.PRComment{ID: v.ID.String(), Body: utils.GetString(v.Body)},
+				Author:    utils.GetString(utils.GetValue(v.User).Login),
+			})
+		}
+	}
+}
+
+// CheckPermission checks the permission of the user on the repository by the read-only token
+func (c *robotClient) CheckPermission(org, repo, username string) (pass, success bool) {
+	return c.reader.CheckPermission(org, repo, username)
+}
+
+// GetPullRequestCommitMessages lists the commits of a pull request together with their messages
+func (c *robotClient) GetPullRequestCommitMessages(org, repo, number string) (result []prCommitMessage, success bool) {
+	commits, success, err := c.readAPI.PullRequests.ListPullRequestCommits(context.Background(), org, repo, number)
+	if err != nil {
+		c.log.WithError(err).Errorf("list commits of %s/%s/%s failed", org, repo, number)
+		return nil, false
+	}
+
+	result = make([]prCommitMessage, len(commits))
+	for i := range commits {
+		commit := utils.GetValue(commits[i].Commit)
+		result[i] = prCommitMessage{
+			PRCommit: client.PRCommit{
+				AuthorName:     utils.GetString(utils.GetValue(commit.Author).Login),
+				AuthorEmail:    utils.GetString(utils.GetValue(commit.Author).Email),
+				CommitterName:  utils.GetString(utils.GetValue(commit.Committer).Login),
+				CommitterEmail: utils.GetString(utils.GetValue(commit.Committer).Email),
+			},
+			SHA:     utils.GetString(commits[i].SHA),
+			Message: utils.GetString(commit.Message),
+		}
+	}
+	return
+}
+
+// GetPullRequestChangedFileCount counts the files changed by a pull request, which tells whether it is empty
+func (c *robotClient) GetPullRequestChangedFileCount(org, repo, number string) (count int, success bool) {
+	files, success, err := c.readAPI.PullRequests.GetPullRequestChangeFiles(context.Background(), org, repo, number)
+	if err != nil {
+		c.log.WithError(err).Errorf("list changed files of %s/%s/%s failed", org, repo, number)
+		return 0, false
+	}
+	return len(files), success
+}
+
+// ListOpenPullRequestsWithLabel lists the numbers of the open pull requests of a repository with the label
+func (c *robotClient) ListOpenPullRequestsWithLabel(org, repo, label string) (numbers []string, success bool) {
+	for page := 1; ; page++ {
+		var prs []struct {
+			Number int `json:"number"`
+		}
+		path := "repos/" + org + "/" + repo + "/pulls?state=open&labels=" + url.QueryEscape(label) +
+			"&per_page=" + strconv.Itoa(listPageSize) + "&page=" + strconv.Itoa(page)
+		if !c.callAPI(http.MethodGet, path, nil, &prs) {
+			return nil, false
+		}
+
+		for i := range prs {
+			numbers = append(numbers, strconv.Itoa(prs[i].Number))
+		}
+		if len(prs) < listPageSize {
+			return numbers, true
+		}
+	}
+}
+
+// ListOrgRepos lists the paths of the repositories of an organization
+func (c *robotClient) ListOrgRepos(org string) (repos []string, success bool) {
+	for page := 1; ; page++ {
+		var items []struct {
+			Path string `json:"path"`
+		}
+		path := "orgs/" + org + "/repos?per_page=" + strconv.Itoa(listPageSize) + "&page=" + strconv.Itoa(page)
+		if !c.callAPI(http.MethodGet, path, nil, &items) {
+			return nil, false
+		}
+
+		for i := range items {
+			repos = append(repos, items[i].Path)
+		}
+		if len(items) < listPageSize {
+			return repos, true
+		}
+	}
+}
+
+// ListTeamMembers lists the logins of the members of a team of an organization
+func (c *robotClient) ListTeamMembers(org, team string) (logins []string, success bool) {
+	for page := 1; ; page++ {
+		var members []struct {
+			Login string `json:"login"`
+		}
+		path := "orgs/" + org + "/teams/" + url.PathEscape(team) + "/members?per_page=" + strconv.Itoa(listPageSize) +
+			"&page=" + strconv.Itoa(page)
+		if !c.callAPI(http.MethodGet, path, nil, &members) {
+			return nil, false
+		}
+
+		for i := range members {
+			logins = append(logins, members[i].Login)
+		}
+		if len(members) < listPageSize {
+			return logins, true
+		}
+	}
+}
+
+// GetRepoMetadata gets the visibility, archived state and default branch of a repository
+func (c *robotClient) GetRepoMetadata(org, repo string) (result repoMetadata, success bool) {
+	success = c.callAPI(http.MethodGet, "repos/"+org+"/"+repo, nil, &result)
+	return
+}
+
+// userProfile is the response of the GitCode OpenAPI with the profile of a user
+type userProfile struct {
+	Login string `json:"login"`
+	Email string `json:"email"`
+}
+
+// GetUserEmail gets the primary email of the account of the user, which is empty if the user hides it
+func (c *robotClient) GetUserEmail(login string) (email string, success bool) {
+	result := userProfile{}
+	success = c.callAPI(http.MethodGet, "users/"+url.PathEscape(login), nil, &result)
+	return result.Email, success
+}
+
+// GetBotLogin gets the login of the account of the token of the write operations, which posts the comments
+func (c *robotClient) GetBotLogin() (login string, success bool) {
+	result := userProfile{}
+	success = c.doAPI(c.api, http.MethodGet, "user", nil, &result)
+	return result.Login, success && result.Login != ""
+}
+
+// repoPermission is the response of the GitCode OpenAPI with the permission of the token on a repository
+type repoPermission struct {
+	Permission struct {
+		Admin bool `json:"admin"`
+		Push  bool `json:"push"`
+	} `json:"permission"`
+}
+
+// GetRepoPushPermission checks whether the token of the write operations can push to the repository,
+// which is required to create the labels in it
+func (c *robotClient) GetRepoPushPermission(org, repo string) (pass, success bool) {
+	result := repoPermission{}
+	if success = c.doAPI(c.api, http.MethodGet, "repos/"+org+"/"+repo, nil, &result); success {
+		pass = result.Permission.Admin || result.Permission.Push
+	}
+	return
+}
+
+// repoFileContent is the response of the GitCode OpenAPI when getting the content of a file
+type repoFileContent struct {
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+// GetRepoFileContent gets the content of the file at the ref of a repository, the default branch is used if ref is empty
+func (c *robotClient) GetRepoFileContent(org, repo, path, ref string) (content []byte, success bool) {
+	apiPath := "repos/" + org + "/" + repo + "/contents/" + url.PathEscape(path)
+	if ref != "" {
+		apiPath += "?ref=" + url.QueryEscape(ref)
+	}
+
+	result := repoFileContent{}
+	if !c.callAPI(http.MethodGet, apiPath, nil, &result) {
+		return nil, false
+	}
+
+	if result.Encoding != "base64" {
+		return []byte(result.Content), true
+	}
+	content, err := base64.StdEncoding.DecodeString(result.Content)
+	if err != nil {
+		c.log.WithError(err).Errorf("decode the content of %s in %s/%s failed", path, org, repo)
+		return nil, false
+	}
+	return content, true
+}
+
+// RemovePRLabels removes the labels from a pull request. The labels are escaped since the api of GitCode takes
+// them in the path, unlike those added
+func (c *robotClient) RemovePRLabels(org, repo, number string, labels []string) (success bool) {
+	return c.Client.RemovePRLabels(org, repo, number, escapePathLabels(labels))
+}
+
+func escapePathLabels(labels []string) []string {
+	escaped := make([]string, len(labels))
+	for i := range labels {
+		escaped[i] = url.QueryEscape(labels[i])
+	}
+	return escaped
+}
+
+// CreatePRComment creates a comment on a pull request, and remembers when the rate limit lifts if the platform
+// rejects it for the secondary rate limit
+func (c *robotClient) CreatePRComment(org, repo, number, comment string) (commentID string, success bool) {
+	path := "repos/" + org + "/" + repo + "/pulls/" + number + "/comments"
+	created := openapi.PullRequestComment{}
+	resp, err := c.send(c.api, http.MethodPost, path, map[string]string{"body": comment}, &created)
+	if retryAfter, limited := commentRateLimit(resp, err); limited {
+		c.mu.Lock()
+		c.commentsLimitedUntil = time.Now().Add(retryAfter)
+		c.mu.Unlock()
+	}
+	if err != nil {
+		c.log.WithError(err).Errorf("create the comment of %s/%s/%s failed", org, repo, number)
+		return "", false
+	}
+	if created.ID != nil {
+		commentID = created.ID.String()
+	}
+	return commentID, resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices
+}
+
+// CommentsRateLimitedUntil returns when the rate limit of the comments lifts, which is zero if it is not limited
+func (c *robotClient) CommentsRateLimitedUntil() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.commentsLimitedUntil
+}
+
+// commentRateLimit checks whether the response is the secondary rate limit, and how long to wait for it to lift
+func commentRateLimit(resp *http.Response, err error) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && err != nil && strings.Contains(strings.ToLower(err.Error()), "rate limit"))
+	if !limited {
+		return 0, false
+	}
+
+	if seconds, e := strconv.Atoi(resp.Header.Get("Retry-After")); e == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	return defaultCommentRateLimitBackoff, true
+}
+
+// UpdatePRComment edits the body of a comment of a pull request
+func (c *robotClient) UpdatePRComment(org, repo, commentID, comment string) (success bool) {
+	success, err := c.api.PullRequests.UpdatePullRequestComment(context.Background(), org, repo, commentID, comment)
+	if err != nil {
+		c.log.WithError(err).Errorf("update the comment %s of %s/%s failed", commentID, org, repo)
+		return false
+	}
+	return success
+}
+
+// CreateCommitStatus reports the status of the commit, which is shown on the pull requests containing it
+func (c *robotClient) CreateCommitStatus(org, repo, sha string, status commitStatus) (success bool) {
+	return c.callAPI(http.MethodPost, "repos/"+org+"/"+repo+"/statuses/"+sha, status, nil)
+}
+
+// CreateIssue creates an issue in the repository
+func (c *robotClient) CreateIssue(org, repo, title, body string) (success bool) {
+	return c.callAPI(http.MethodPost, "repos/"+org+"/issues", &openapi.IssueRequest{Repository: repo, Title: title, Body: body}, nil)
+}
+
+// CreateRepoLabel creates the label in the repository
+func (c *robotClient) CreateRepoLabel(org, repo string, label repoLabel) (success bool) {
+	return c.callAPI(http.MethodPost, "repos/"+org+"/"+repo+"/labels", &label, nil)
+}
+
+// commentReaction is the request of the GitCode OpenAPI to react to a comment
+type commentReaction struct {
+	Content string `json:"content"`
+}
+
+// AddCommentReaction reacts to the comment of the pull request with the emoji, such as +1 and rocket
+func (c *robotClient) AddCommentReaction(org, repo, commentID, reaction string) (success bool) {
+	return c.callAPI(http.MethodPost, "repos/"+org+"/"+repo+"/pulls/comments/"+commentID+"/reactions",
+		&commentReaction{Content: reaction}, nil)
+}
+
+// repoCommit is the response of the GitCode OpenAPI when getting a commit, with the verification of its signature
+type repoCommit struct {
+	Commit struct {
+		Verification struct {
+			Verified bool `json:"verified"`
+		} `json:"verification"`
+	} `json:"commit"`
+}
+
+// GetCommitVerificationStatus checks whether the commit is signed by a GPG or S/MIME signature verified by the platform
+func (c *robotClient) GetCommitVerificationStatus(org, repo, sha string) (verified, success bool) {
+	result := repoCommit{}
+	if success = c.callAPI(http.MethodGet, "repos/"+org+"/"+repo+"/commits/"+sha, nil, &result); success {
+		verified = result.Commit.Verification.Verified
+	}
+	return
+}
+
+// callAPI sends a request with a json body to the GitCode OpenAPI and decodes the response into the receiver.
+// The GET requests are sent by the read-only token
+func (c *robotClient) callAPI(method, path string, body, receiver any) bool {
+	api := c.api
+	if method == http.MethodGet {
+		api = c.readAPI
+	}
+	return c.doAPI(api, method, path, body, receiver)
+}
+
+// doAPI calls the GitCode OpenAPI by the client of the token
+func (c *robotClient) doAPI(api *openapi.APIClient, method, path string, body, receiver any) bool {
+	resp, err := c.send(api, method, path, body, receiver)
+	if err != nil {
+		c.log.WithError(err).Errorf("the request %s %s failed", method, path)
+		return false
+	}
+
+	return resp != nil && resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices
+}
+
+// send sends the request to the GitCode OpenAPI. The response is returned with the error of an unexpected status,
+// so that the caller can check its status and headers
+func (c *robotClient) send(api *openapi.APIClient, method, path string, body, receiver any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, gitCodeAPIBaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return api.Do(context.Background(), req, receiver)
+}
+
+// claSignatureID is the response of the CLA server when verifying a signature id
+type claSignatureID struct {
+	Data struct {
+		Valid bool `json:"valid"`
+		// Email is the email of the contributor the signature belongs to
+		Email string `json:"email"`
+	} `json:"data"`
+}
+
+// VerifyCLASignatureID asks the CLA server whether the signature id belongs to a valid signature of the email.
+// The signature of another email is not signed
+func (c *robotClient) VerifyCLASignatureID(urlStr, email string) (signState string, success bool) {
+	signState = client.CLASignStateUnknown
+	data := claSignatureID{}
+	if !c.getFromCLAServer(urlStr, &data) {
+		return
+	}
+
+	signState = client.CLASignStateNo
+	if data.Data.Valid && strings.EqualFold(data.Data.Email, email) {
+		signState = client.CLASignStateYes
+	}
+	return signState, true
+}
+
+// claSignatureDetail is the response of the CLA server with the type of the matched CLA, if it is provided
+type claSignatureDetail struct {
+	Data struct {
+		Signed bool   `json:"signed"`
+		Type   string `json:"type"`
+	} `json:"data"`
+}
+
+// CheckCLASignatureDetail checks the sign state of the email in the url, together with the type of the
+// matched CLA such as individual or corporate. The type is empty if the CLA server does not return it
+func (c *robotClient) CheckCLASignatureDetail(urlStr string) (signState, claType string, success bool) {
+	signState = client.CLASignStateUnknown
+	data := claSignatureDetail{}
+	if !c.getFromCLAServer(urlStr, &data) {
+		return
+	}
+
+	signState = client.CLASignStateNo
+	if data.Data.Signed {
+		signState = client.CLASignStateYes
+	}
+	return signState, data.Data.Type, true
+}
+
+// claSignatures is the response of the CLA server when checking the sign states of the emails in batch
+type claSignatures struct {
+	Data []struct {
+		Email  string `json:"email"`
+		Signed bool   `json:"signed"`
+	} `json:"data"`
+}
+
+// CheckCLASignatures checks the sign states of the emails by one request to the CLA server,
+// which receives {"emails": [...]} and responds {"data": [{"email": "", "signed": true}]}
+func (c *robotClient) CheckCLASignatures(urlStr string, emails []string) (signStates map[string]string, success bool) {
+	resp, err := c.claServer.R().SetBody(map[string][]string{"emails": emails}).Post(urlStr)
+	if err != nil {
+		c.log.WithError(err).Errorf("CLA batch request: %s failed", urlStr)
+		return nil, false
+	}
+
+	c.log.Infof("CLA batch request: %s of %d emails has sent out, response status: %s", urlStr, len(emails), resp.Status())
+	if resp.StatusCode() != http.StatusOK {
+		return nil, false
+	}
+
+	data := claSignatures{}
+	if err = json.Unmarshal(resp.Body(), &data); err != nil {
+		c.log.WithError(err).Errorf("CLA batch response of %s is invalid", urlStr)
+		return nil, false
+	}
+
+	signStates = make(map[string]string, len(data.Data))
+	for _, item := range data.Data {
+		signStates[item.Email] = client.CLASignStateNo
+		if item.Signed {
+			signStates[item.Email] = client.CLASignStateYes
+		}
+	}
+	return signStates, true
+}
+
+func (c *robotClient) getFromCLAServer(urlStr string, receiver any) bool {
+	resp, err := c.claServer.R().Get(urlStr)
+	if err != nil {
+		c.log.WithError(err).Errorf("CLA request: %s failed", urlStr)
+		return false
+	}
+
+	c.log.Infof("CLA request: %s has sent out, response status: %s", urlStr, resp.Status())
+	if resp.StatusCode() != http.StatusOK {
+		return false
+	}
+
+	if err = json.Unmarshal(resp.Body(), receiver); err != nil {
+		c.log.WithError(err).Errorf("CLA response of %s is invalid", urlStr)
+		return false
+	}
+	return true
+}
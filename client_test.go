@@ -0,0 +1,41 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/go-resty/resty/v2"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifyCLASignatureID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"valid": true, "email": "Alice@example.com"}}`))
+	}))
+	defer srv.Close()
+
+	c := &robotClient{claServer: resty.New(), log: logrus.NewEntry(logrus.New())}
+	signState, success := c.VerifyCLASignatureID(srv.URL, "alice@example.com")
+	assert.Equal(t, true, success)
+	assert.Equal(t, client.CLASignStateYes, signState)
+
+	// the valid signature of another contributor is not signed
+	signState, success = c.VerifyCLASignatureID(srv.URL, "bob@example.com")
+	assert.Equal(t, true, success)
+	assert.Equal(t, client.CLASignStateNo, signState)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// fullWidthSlash is the slash typed by the Chinese and Japanese IMEs in place of `/`
+	fullWidthSlash = '／'
+	// the full-width forms of the printable ASCII, from `！` to `～`
+	fullWidthFirst = '！'
+	fullWidthLast  = '～'
+	fullWidthShift = fullWidthFirst - '!'
+)
+
+// normalizeCommand cleans up the comment typed on the mobile clients or with the IMEs before matching the commands:
+// the BOM and the zero-width characters are dropped, the unicode spaces become ascii spaces, the line breaks become
+// `\n` and the full-width slash becomes `/`. The relaxed matching also folds the full-width forms to ascii,
+// collapses the spaces and lowers the case, so that `／ＣＬＡ　ｒｅｃｈｅｃｋ` is `/cla recheck`
+func normalizeCommand(comment string, relaxed bool) string {
+	comment = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(comment)
+	comment = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\uFEFF' || r == '\u200B' || r == '\u200C' || r == '\u200D' || r == '\u2060':
+			return -1
+		case r == '\n':
+			return r
+		case unicode.IsSpace(r):
+			return ' '
+		case r == fullWidthSlash:
+			return '/'
+		case relaxed && r >= fullWidthFirst && r <= fullWidthLast:
+			return r - fullWidthShift
+		}
+		return r
+	}, comment)
+
+	if relaxed {
+		lines := strings.Split(comment, "\n")
+		for i := range lines {
+			lines[i] = strings.Join(strings.Fields(lines[i]), " ")
+		}
+		comment = strings.ToLower(strings.Join(lines, "\n"))
+	}
+	return strings.TrimSpace(comment)
+}
@@ -0,0 +1,170 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// commandRateWindow is the window the commands are counted in
+	commandRateWindow = time.Minute
+	// defaultCommentCommandThrottled is used when the comment of the command_rate_limit is not configured
+	defaultCommentCommandThrottled = "The CLA is being checked too often on this pull request, " +
+		"please wait %d seconds before commenting the command again."
+)
+
+// commandRateLimitConfig throttles the commands checking the CLA, such as `/check-cla` and `/cla recheck`,
+// so that the rapid commands do not hammer the CLA server. Each limit is disabled if it is 0
+type commandRateLimitConfig struct {
+	// PerPRPerMinute is the max number of the commands on a pull request in a minute
+	PerPRPerMinute int `json:"per_pr_per_minute"`
+	// PerUserPerMinute is the max number of the commands of a user in a minute, on all the pull requests
+	PerUserPerMinute int `json:"per_user_per_minute"`
+	// Comment is the reply to the command throttled, once a window for the pull request. It has one %d for the
+	// seconds to wait. A default comment is used if it is empty
+	Comment string `json:"comment"`
+}
+
+func (c *commandRateLimitConfig) validate() error {
+	if c.PerPRPerMinute < 0 || c.PerUserPerMinute < 0 {
+		return errors.New("the limits of the command_rate_limit can not be negative")
+	}
+	return nil
+}
+
+// isCheckCommand checks whether the comment is a command checking the CLA
+func isCheckCommand(comment string) bool {
+	return regexpCheckCLAComment.MatchString(comment) || regexpCheckCLASinceComment.MatchString(comment) ||
+		regexpCheckCLAContributorComment.MatchString(comment) || regexpRecheckCLAComment.MatchString(comment)
+}
+
+// commandLimiter counts the commands of each pull request and each user in the sliding window.
+// All the methods are safe on a nil limiter, which throttles nothing
+type commandLimiter struct {
+	mu    sync.Mutex
+	calls map[string][]time.Time
+	// replied remembers when the throttled command of the pull request was replied to
+	replied map[string]time.Time
+	now     func() time.Time
+}
+
+func newCommandLimiter() *commandLimiter {
+	return &commandLimiter{calls: map[string][]time.Time{}, replied: map[string]time.Time{}, now: time.Now}
+}
+
+// allow records the command if none of the keys exceeds its limit, or returns how long to wait otherwise.
+// The limits are keyed by the keys, and a limit of 0 is disabled
+func (l *commandLimiter) allow(limits map[string]int) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.forget(now)
+
+	var wait time.Duration
+	for key, limit := range limits {
+		if calls := l.calls[key]; limit != 0 && len(calls) >= limit {
+			wait = max(wait, calls[len(calls)-limit].Add(commandRateWindow).Sub(now))
+		}
+	}
+	if wait > 0 {
+		return wait, false
+	}
+
+	for key, limit := range limits {
+		if limit != 0 {
+			l.calls[key] = append(l.calls[key], now)
+		}
+	}
+	return 0, true
+}
+
+// forget drops the commands out of the window
+func (l *commandLimiter) forget(now time.Time) {
+	for key, calls := range l.calls {
+		i := 0
+		for i < len(calls) && now.Sub(calls[i]) >= commandRateWindow {
+			i++
+		}
+		if i == len(calls) {
+			delete(l.calls, key)
+		} else {
+			l.calls[key] = calls[i:]
+		}
+	}
+	for key, t := range l.replied {
+		if now.Sub(t) >= commandRateWindow {
+			delete(l.replied, key)
+		}
+	}
+}
+
+// reply checks whether the throttled command of the pull request should be replied to, which is once a window
+func (l *commandLimiter) reply(key string) bool {
+	if l == nil {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if t, ok := l.replied[key]; ok && now.Sub(t) < commandRateWindow {
+		return false
+	}
+	l.replied[key] = now
+	return true
+}
+
+// commandThrottled checks whether the command of the user on the pull request exceeds the command_rate_limit,
+// and asks to wait if so
+func (bot *robot) commandThrottled(pr *prSnapshot, repoCnf *repoConfig, commenter string, logger *logrus.Entry) bool {
+	cfg := &bot.prConfig(pr).CommandRateLimit
+	if cfg.PerPRPerMinute == 0 && cfg.PerUserPerMinute == 0 {
+		return false
+	}
+
+	limits := map[string]int{"pr:" + pr.key(): cfg.PerPRPerMinute}
+	if commenter != "" {
+		limits["user:"+commenter] = cfg.PerUserPerMinute
+	}
+	wait, ok := bot.commands.allow(limits)
+	if ok {
+		return false
+	}
+
+	logger.Warningf("throttle the command of %s on %s for %s", commenter, pr.key(), wait)
+	if bot.commands.reply(pr.key()) {
+		bot.createPRComment(pr, repoCnf, bot.renderer(pr).commandThrottled(wait))
+	}
+	return true
+}
+
+// commandThrottled renders the reply asking to wait before commenting the command again
+func (r *commentRenderer) commandThrottled(wait time.Duration) string {
+	data := r.data(nil)
+	data.WaitSeconds = int(math.Ceil(wait.Seconds()))
+	return r.render(r.format(r.cnf.CommandRateLimit.Comment, defaultCommentCommandThrottled), data,
+		func(s string) string { return fmt.Sprintf(s, data.WaitSeconds) })
+}
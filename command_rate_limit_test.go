@@ -0,0 +1,92 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCommandLimiter(t *testing.T) {
+	now := time.Now()
+	l := newCommandLimiter()
+	l.now = func() time.Time { return now }
+
+	limits := map[string]int{"pr:a": 2, "user:u": 0}
+	_, ok := l.allow(limits)
+	assert.Equal(t, true, ok)
+	now = now.Add(20 * time.Second)
+	_, ok = l.allow(limits)
+	assert.Equal(t, true, ok)
+
+	// the third command waits for the first one to leave the window
+	now = now.Add(10 * time.Second)
+	wait, ok := l.allow(limits)
+	assert.Equal(t, false, ok)
+	assert.Equal(t, 30*time.Second, wait)
+	assert.Equal(t, 0, len(l.calls["user:u"]))
+
+	now = now.Add(30 * time.Second)
+	_, ok = l.allow(limits)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 2, len(l.calls["pr:a"]))
+
+	// the reply is once a window
+	assert.Equal(t, true, l.reply("a"))
+	assert.Equal(t, false, l.reply("a"))
+	now = now.Add(time.Minute)
+	assert.Equal(t, true, l.reply("a"))
+
+	var nilLimiter *commandLimiter
+	_, ok = nilLimiter.allow(limits)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, false, nilLimiter.reply("a"))
+}
+
+func TestCommandThrottled(t *testing.T) {
+	assert.Equal(t, true, isCheckCommand("/check-cla"))
+	assert.Equal(t, true, isCheckCommand("/check-cla @jane"))
+	assert.Equal(t, false, isCheckCommand("/cla help"))
+
+	now := time.Now()
+	cli := &commentRecordingClient{mockClient: &mockClient{}}
+	bot := &robot{cli: cli, cnf: &configuration{}, commands: newCommandLimiter()}
+	bot.commands.now = func() time.Time { return now }
+	repoCnf := &repoConfig{}
+	logger := logrus.NewEntry(logrus.New())
+	pr := newPRSnapshot(cli, org, repo, number)
+
+	// nothing is throttled if the limits are not configured
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, false, bot.commandThrottled(pr, repoCnf, "jane", logger))
+	}
+
+	bot.cnf.CommandRateLimit = commandRateLimitConfig{PerPRPerMinute: 2, PerUserPerMinute: 3}
+	assert.Equal(t, false, bot.commandThrottled(pr, repoCnf, "jane", logger))
+	assert.Equal(t, false, bot.commandThrottled(pr, repoCnf, "bob", logger))
+	now = now.Add(15 * time.Second)
+	assert.Equal(t, true, bot.commandThrottled(pr, repoCnf, "jane", logger))
+	assert.Equal(t, true, bot.commandThrottled(pr, repoCnf, "bob", logger))
+	assert.Equal(t, []string{prKey(org, repo, number) + ": " + fmt.Sprintf(defaultCommentCommandThrottled, 45)}, cli.posted)
+
+	// the user is limited on all the pull requests
+	other := newPRSnapshot(cli, org, repo, "2")
+	assert.Equal(t, false, bot.commandThrottled(other, repoCnf, "jane", logger))
+	assert.Equal(t, false, bot.commandThrottled(other, repoCnf, "jane", logger))
+	assert.Equal(t, true, bot.commandThrottled(newPRSnapshot(cli, org, repo, "3"), repoCnf, "jane", logger))
+	assert.Equal(t, 2, len(cli.posted))
+}
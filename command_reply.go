@@ -0,0 +1,79 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+const (
+	// defaultCommentCommandDone is used when the comment_command_done is not configured
+	defaultCommentCommandDone = "@%s the CLA check you triggered has finished: **%s**. " +
+		"Please see [the CLA status](%s) for the details."
+
+	// reactionAcknowledged reacts to the command changing no label
+	reactionAcknowledged = "+1"
+	// reactionApplied reacts to the command changing the labels
+	reactionApplied = "rocket"
+)
+
+// commandOutcomes describe the decisions of the evaluation in the reply to the command
+var commandOutcomes = map[string]string{
+	prStatusSigned:             "all the contributors have signed the CLA",
+	prStatusUnsigned:           "some contributors have not signed the CLA",
+	prStatusUnknown:            "the CLA status of some contributors could not be checked",
+	decisionNoCommits:          "the pull request has no commits",
+	decisionCommitsUnavailable: "the commits of the pull request could not be read",
+	decisionStopped:            "the check was stopped and needs a manual review",
+	decisionOverridden:         "the check is overridden by a maintainer",
+}
+
+// replyToCommand replies to the commenter of the command triggering the evaluation with its outcome, and
+// links to the comment of the CLA result, so that the commenter does not need to look for the changed labels
+func (bot *robot) replyToCommand(pr *prSnapshot, repoCnf *repoConfig) {
+	outcome, ok := commandOutcomes[pr.stats.decision]
+	if !ok || pr.actor == "" {
+		return
+	}
+	// The reply is reserved for the checks changing the labels when the commands are reacted to
+	if !repoCnf.ReplyToCommand || (repoCnf.ReactToCommand && !pr.stats.labelsChanged()) {
+		bot.reactToCommand(pr, repoCnf)
+		return
+	}
+
+	bot.createPRComment(pr, repoCnf, bot.renderer(pr).commandDone(pr.actor, outcome, bot.resultCommentLink(pr)))
+}
+
+// resultCommentLink links to the latest comment of the CLA result, or the pull request if it is not found.
+// The comments are listed again since the result may have been posted by the evaluation
+func (bot *robot) resultCommentLink(pr *prSnapshot) string {
+	link := pr.htmlURL
+	if comments, success := bot.cli.ListPullRequestComments(pr.org, pr.repo, pr.number); success {
+		if ids := bot.resultCommentIDs(pr, comments); len(ids) != 0 && link != "" {
+			link += "#note_" + ids[len(ids)-1]
+		}
+	}
+	return link
+}
+
+// reactToCommand acknowledges the command with a reaction on its comment instead of a comment
+func (bot *robot) reactToCommand(pr *prSnapshot, repoCnf *repoConfig) {
+	if !repoCnf.ReactToCommand || pr.commentID == "" {
+		return
+	}
+
+	reaction := reactionAcknowledged
+	if pr.stats.labelsChanged() {
+		reaction = reactionApplied
+	}
+	pr.stats.writes++
+	ok := bot.cli.AddCommentReaction(pr.org, pr.repo, pr.commentID, reaction)
+	bot.auditMutation(pr, auditActionReactionAdd, pr.commentID+": "+reaction, ok)
+}
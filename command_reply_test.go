@@ -0,0 +1,80 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReplyToCommand(t *testing.T) {
+	mc := &mockClient{
+		successfulCreatePRComment:         true,
+		successfulListPullRequestComments: true,
+		prComments: []client.PRComment{
+			{ID: "1", Body: "### CLA Signature Pass  \n\nold"},
+			{ID: "2", Body: "lgtm"},
+			{ID: "3", Body: "### CLA Signature Pass  \n\nnew"},
+		},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{PlaceholderCLASignPassTitle: "### CLA Signature Pass"}}
+	repoCnf := &repoConfig{ReplyToCommand: true}
+	pr := newPRSnapshot(mc, org, repo, number).withActor(commenter)
+	pr.htmlURL = "https://gitcode.com/org1/repo1/pull/1"
+
+	// the reply is not posted without a decision
+	bot.replyToCommand(pr, repoCnf)
+	assert.Equal(t, "", mc.comment)
+
+	pr.stats.decision = prStatusSigned
+	bot.replyToCommand(pr, repoCnf)
+	assert.Contains(t, mc.comment, "@"+commenter+" the CLA check you triggered has finished: **"+
+		commandOutcomes[prStatusSigned]+"**")
+	assert.Contains(t, mc.comment, "(https://gitcode.com/org1/repo1/pull/1#note_3)")
+
+	// the reply is disabled by default
+	mc.comment = ""
+	bot.replyToCommand(pr, &repoConfig{})
+	assert.Equal(t, "", mc.comment)
+}
+
+func TestReactToCommand(t *testing.T) {
+	mc := &mockClient{successfulCreatePRComment: true, successfulAddCommentReaction: true}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	repoCnf := &repoConfig{ReplyToCommand: true, ReactToCommand: true}
+	pr := newPRSnapshot(mc, org, repo, number).withActor(commenter)
+	pr.commentID = "5"
+	pr.stats.decision = prStatusSigned
+
+	// the command changing no label is reacted to instead of replied to
+	bot.replyToCommand(pr, repoCnf)
+	assert.Equal(t, "", mc.comment)
+	assert.Equal(t, []string{"5: " + reactionAcknowledged}, mc.reactions)
+
+	// the reply is posted when the labels are changed
+	pr.stats.labelsAdded = []string{"cla/yes"}
+	bot.replyToCommand(pr, repoCnf)
+	assert.Contains(t, mc.comment, commandOutcomes[prStatusSigned])
+	assert.Equal(t, 1, len(mc.reactions))
+
+	// the command changing the labels is reacted to without the reply
+	bot.replyToCommand(pr, &repoConfig{ReactToCommand: true})
+	assert.Equal(t, []string{"5: " + reactionAcknowledged, "5: " + reactionApplied}, mc.reactions)
+
+	// nothing is reacted to without the comment of the command
+	pr.commentID = ""
+	bot.replyToCommand(pr, &repoConfig{ReactToCommand: true})
+	assert.Equal(t, 2, len(mc.reactions))
+}
@@ -0,0 +1,53 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeCommand(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		in      string
+		strict  string
+		relaxed string
+	}{
+		{"plain", "/check-cla", "/check-cla", "/check-cla"},
+		{"bom", "\uFEFF/cla recheck", "/cla recheck", "/cla recheck"},
+		{"zero width", "/cla\u200B recheck\u2060", "/cla recheck", "/cla recheck"},
+		{"crlf", "/check-cla\r\n", "/check-cla", "/check-cla"},
+		{"nbsp", "/cla\u00A0cancel", "/cla cancel", "/cla cancel"},
+		{"ideographic space", "/cla\u3000recheck", "/cla recheck", "/cla recheck"},
+		{"full-width slash", "／check-cla authors", "/check-cla authors", "/check-cla authors"},
+		{"full-width letters", "／ｃｌａ\u3000ｒｅｃｈｅｃｋ", "/ｃｌａ ｒｅｃｈｅｃｋ", "/cla recheck"},
+		{"upper case", "/CLA  Cancel", "/CLA  Cancel", "/cla cancel"},
+		{"multiple lines", "/check-cla\r\nplease", "/check-cla\nplease", "/check-cla\nplease"},
+	}
+	for i := range testCases {
+		c := &testCases[i]
+		t.Run(c.desc, func(t *testing.T) {
+			assert.Equal(t, c.strict, normalizeCommand(c.in, false))
+			assert.Equal(t, c.relaxed, normalizeCommand(c.in, true))
+		})
+	}
+
+	// the normalized commands are matched, while the other lines still make it not a command
+	assert.Equal(t, true, regexpRecheckCLAComment.MatchString(normalizeCommand("\uFEFF／cla\u3000recheck\r\n", false)))
+	assert.Equal(t, []string{"/check-cla authors", "authors"},
+		regexpCheckCLAComment.FindStringSubmatch(normalizeCommand("／ＣＨＥＣＫ－ＣＬＡ\u3000Authors", true)))
+	assert.Equal(t, false, regexpCheckCLAComment.MatchString(normalizeCommand("/check-cla\r\nplease", true)))
+	assert.Equal(t, true, regexpHelpCLAComment.MatchString(normalizeCommand("／ＣＬＡ　Help", true)))
+}
@@ -0,0 +1,149 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// queuedCommentsSeparator separates the comments of a pull request merged into one
+	queuedCommentsSeparator = "\n\n---\n\n"
+	// queuedCommentsRetryDelay is how long to wait for the rate limit lifting if it is still limited when flushing
+	queuedCommentsRetryDelay = time.Minute
+)
+
+// queuedComments are the comments of a pull request held while the rate limit of the comments is hit
+type queuedComments struct {
+	org      string
+	repo     string
+	number   string
+	actor    string
+	comments []string
+}
+
+// merged joins the comments into one, in which the repeated ones are kept once
+func (q *queuedComments) merged() string {
+	comments := make([]string, 0, len(q.comments))
+	for _, c := range q.comments {
+		if !slices.Contains(comments, c) {
+			comments = append(comments, c)
+		}
+	}
+	return strings.Join(comments, queuedCommentsSeparator)
+}
+
+// commentQueue holds the comments rejected by the secondary rate limit of the platform, and posts them in one
+// comment for each pull request once the limit lifts. All the methods are safe on a nil queue, which holds nothing
+type commentQueue struct {
+	mu        sync.Mutex
+	pending   []*queuedComments
+	scheduled bool
+	flush     func()
+	afterFunc func(time.Duration, func()) *time.Timer
+	now       func() time.Time
+}
+
+func newCommentQueue(flush func()) *commentQueue {
+	return &commentQueue{flush: flush, afterFunc: time.AfterFunc, now: time.Now}
+}
+
+// add queues the comment of the pull request, and schedules the flush when the rate limit lifts
+func (q *commentQueue) add(pr *prSnapshot, comment string, until time.Time) bool {
+	if q == nil {
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	i := slices.IndexFunc(q.pending, func(v *queuedComments) bool {
+		return v.org == pr.org && v.repo == pr.repo && v.number == pr.number
+	})
+	if i < 0 {
+		q.pending = append(q.pending, &queuedComments{org: pr.org, repo: pr.repo, number: pr.number, actor: pr.actor})
+		i = len(q.pending) - 1
+	}
+	q.pending[i].comments = append(q.pending[i].comments, comment)
+
+	q.schedule(until.Sub(q.now()))
+	return true
+}
+
+func (q *commentQueue) schedule(delay time.Duration) {
+	if !q.scheduled {
+		q.scheduled = true
+		q.afterFunc(delay, q.flush)
+	}
+}
+
+// take removes all the queued comments to be posted
+func (q *commentQueue) take() []*queuedComments {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending := q.pending
+	q.pending, q.scheduled = nil, false
+	return pending
+}
+
+// requeue puts back the comments which are still rate limited before the newer ones, and schedules the flush again
+func (q *commentQueue) requeue(items []*queuedComments, delay time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, item := range q.pending {
+		i := slices.IndexFunc(items, func(v *queuedComments) bool {
+			return v.org == item.org && v.repo == item.repo && v.number == item.number
+		})
+		if i < 0 {
+			items = append(items, item)
+		} else {
+			items[i].comments = append(items[i].comments, item.comments...)
+		}
+	}
+	q.pending = items
+	q.schedule(delay)
+}
+
+// commentRateLimited returns when the rate limit of the comments lifts if it is limited now
+func (bot *robot) commentRateLimited() (time.Time, bool) {
+	until := bot.cli.CommentsRateLimitedUntil()
+	return until, bot.comments != nil && until.After(bot.comments.now())
+}
+
+// flushQueuedComments posts the queued comments merged into one for each pull request. The comments are queued
+// again if the rate limit is hit again, and the failed ones are dropped
+func (bot *robot) flushQueuedComments() {
+	pending := bot.comments.take()
+	for i, item := range pending {
+		if until, limited := bot.commentRateLimited(); limited {
+			bot.comments.requeue(pending[i:], max(until.Sub(bot.comments.now()), queuedCommentsRetryDelay))
+			return
+		}
+
+		pr := newPRSnapshot(bot.cli, item.org, item.repo, item.number).withActor(item.actor)
+		_, ok := bot.cli.CreatePRComment(pr.org, pr.repo, pr.number, item.merged())
+		if !ok {
+			if _, limited := bot.commentRateLimited(); limited {
+				bot.comments.requeue(pending[i:], queuedCommentsRetryDelay)
+				return
+			}
+		}
+		bot.auditMutation(pr, auditActionCommentCreate, "", ok)
+	}
+}
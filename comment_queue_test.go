@@ -0,0 +1,91 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// commentRecordingClient records the comments posted
+type commentRecordingClient struct {
+	*mockClient
+	posted []string
+}
+
+func (c *commentRecordingClient) CreatePRComment(org, repo, number, comment string) (string, bool) {
+	c.posted = append(c.posted, prKey(org, repo, number)+": "+comment)
+	return strconv.Itoa(len(c.posted)), true
+}
+
+func TestCommentRateLimit(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}}
+	d, limited := commentRateLimit(resp, errors.New("too many requests"))
+	assert.Equal(t, true, limited)
+	assert.Equal(t, 30*time.Second, d)
+
+	resp = &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+	d, limited = commentRateLimit(resp, errors.New("You have exceeded a secondary Rate Limit"))
+	assert.Equal(t, true, limited)
+	assert.Equal(t, defaultCommentRateLimitBackoff, d)
+
+	_, limited = commentRateLimit(resp, errors.New("forbidden"))
+	assert.Equal(t, false, limited)
+	_, limited = commentRateLimit(nil, errors.New("timeout"))
+	assert.Equal(t, false, limited)
+}
+
+func TestQueueRateLimitedComments(t *testing.T) {
+	now := time.Now()
+	cli := &commentRecordingClient{mockClient: &mockClient{commentsLimitedUntil: now.Add(time.Minute)}}
+	bot := &robot{cli: cli, cnf: &configuration{}}
+	var delays []time.Duration
+	bot.comments = newCommentQueue(bot.flushQueuedComments)
+	bot.comments.now = func() time.Time { return now }
+	bot.comments.afterFunc = func(d time.Duration, f func()) *time.Timer {
+		delays = append(delays, d)
+		return nil
+	}
+
+	// the comments are queued while the rate limit is hit, and the flush is scheduled once
+	pr := newPRSnapshot(cli, org, repo, number)
+	assert.Equal(t, true, bot.postPRComment(pr, "first"))
+	assert.Equal(t, true, bot.postPRComment(pr, "second"))
+	assert.Equal(t, true, bot.postPRComment(pr, "first"))
+	assert.Equal(t, true, bot.postPRComment(newPRSnapshot(cli, org, repo, "2"), "other"))
+	assert.Equal(t, 0, len(cli.posted))
+	assert.Equal(t, []time.Duration{time.Minute}, delays)
+
+	// the flush is delayed again while it is still limited
+	bot.flushQueuedComments()
+	assert.Equal(t, 0, len(cli.posted))
+	assert.Equal(t, []time.Duration{time.Minute, time.Minute}, delays)
+
+	// the comments of each pull request are merged into one once the limit lifts
+	now = now.Add(2 * time.Minute)
+	bot.flushQueuedComments()
+	assert.Equal(t, []string{
+		prKey(org, repo, number) + ": first" + queuedCommentsSeparator + "second",
+		prKey(org, repo, "2") + ": other",
+	}, cli.posted)
+	assert.Equal(t, 0, len(bot.comments.take()))
+
+	// the comments are posted directly without the rate limit
+	assert.Equal(t, true, bot.postPRComment(pr, "third"))
+	assert.Equal(t, prKey(org, repo, number)+": third", cli.posted[2])
+}
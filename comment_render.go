@@ -0,0 +1,433 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// commentRenderer renders the comments of the decisions from the configuration. It is kept apart from the
+// decision code, so that every comment can be verified against its golden file without the code hosting platform.
+//
+// A comment containing `{{.` is a Go text/template executed with the commentData, such as
+// `{{.SignedUsers}}, thanks for your pull request to {{.Org}}/{{.Repo}}`. The other comments are the formats
+// of fmt with the placeholders, which are kept for the existing configs
+type commentRenderer struct {
+	cnf *configuration
+	pr  *prSnapshot
+	// template resolves the text of the config which may refer to a template in the templates file
+	template func(string) string
+}
+
+// renderer returns the commentRenderer of the pull request, which may be nil for the comments not of a pull request
+func (bot *robot) renderer(pr *prSnapshot) *commentRenderer {
+	return &commentRenderer{cnf: bot.prConfig(pr), pr: pr, template: bot.template}
+}
+
+// commentData is the variables of the comments written as the Go text/template. The users are marked by the
+// user_mark_format and joined by commas, and those beyond the limit of the user_mentions are summarized
+type commentData struct {
+	Org    string
+	Repo   string
+	Number string
+
+	SignedUsers   string
+	UnsignedUsers string
+	// Users are the users of the comment about the email domain denied, the missing email or the commits of
+	// the lite pull request, of the notes of the signed and unknown users, of the thanks for the first pass,
+	// or the contributor of the `/check-cla @username` not found
+	Users string
+	// Commits lists the commits which are not signed off in the dco mode, or not signed by verified signatures
+	Commits string
+	// Breakdown is the table of the contributors failing the cla_and_dco or cla_or_dco policy
+	Breakdown string
+
+	SignURL     string
+	FAQURL      string
+	CLAVersion  string
+	MailingList string
+	Maintainer  string
+
+	// CheckScope is the authors or the committers if the check was scoped by the command
+	CheckScope string
+	// Since is the sha of the `/check-cla --since`
+	Since string
+	// Commenter, Outcome and Link are the user commenting the command, the outcome of the check it triggered
+	// and the link to the comment of the CLA result, in the comment_command_done
+	Commenter string
+	Outcome   string
+	Link      string
+	// ExpiresAt is when the override expires, in the comment_override
+	ExpiresAt string
+	// RetryAt is when the labels failing to be updated will be updated again, in the comment_label_retry
+	RetryAt string
+	// WaitSeconds is how long to wait before commenting the command again, in the comment of the command_rate_limit
+	WaitSeconds int
+	// CheckedBy is the authors or the committers whose emails are checked for the repository, in the comment_help
+	CheckedBy string
+	// Reason is why the watchdog stopped the evaluation, why the maintainer overrode the check, or the instruction
+	// for the users whose CLA can not be checked in the comment_unknown_note
+	Reason string
+	// Problem is the misconfiguration of the bot for the repository
+	Problem string
+	// CommitEmail and AccountEmail are the masked emails of the commits and of the account of the author of the
+	// pull request, in the comment_account_email_mismatch and the comment_account_email_signed
+	CommitEmail  string
+	AccountEmail string
+	// Company is the company whose corporate CLA covers the users, in the comment_corporate_signed
+	Company string
+	// Recheck is the summary of the `/cla recheck-org`, such as {{.Recheck.Org}} and {{.Recheck.Total}}
+	Recheck orgRecheckSummary
+}
+
+// isCommentTemplate checks whether the comment is written as a Go text/template
+func isCommentTemplate(text string) bool {
+	return strings.Contains(text, "{{.")
+}
+
+// validateCommentTemplate parses and executes the comment against empty variables if it is a Go text/template,
+// so that a typo or an unknown variable is found when the config is loaded rather than when commenting
+func validateCommentTemplate(text string) error {
+	if !isCommentTemplate(text) {
+		return nil
+	}
+
+	_, err := executeCommentTemplate(text, &commentData{})
+	return err
+}
+
+func executeCommentTemplate(text string, data *commentData) (string, error) {
+	t, err := template.New("comment").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err = t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// data returns the variables of the comment with those of the pull request and the repository
+func (r *commentRenderer) data(repoCnf *repoConfig) *commentData {
+	d := &commentData{}
+	if r.pr != nil {
+		d.Org, d.Repo, d.Number = r.pr.org, r.pr.repo, r.pr.number
+	}
+	if repoCnf != nil {
+		d.SignURL, d.FAQURL, d.CLAVersion = repoCnf.SignURL, repoCnf.FAQURL, repoCnf.CLAVersion
+		d.MailingList, d.Maintainer = repoCnf.Contact.MailingList, repoCnf.Contact.Maintainer
+		d.CheckScope, d.Since = repoCnf.checkScope, repoCnf.since
+	}
+	return d
+}
+
+// render executes the format if it is a Go text/template, or renders it by the legacy otherwise.
+// The format is returned as it is if the execution fails, which the validation of the config prevents
+func (r *commentRenderer) render(format string, data *commentData, legacy func(string) string) string {
+	if !isCommentTemplate(format) {
+		return legacy(format)
+	}
+
+	if s, err := executeCommentTemplate(format, data); err == nil {
+		return s
+	}
+	return format
+}
+
+// format resolves the format of the comment, which is the fallback if it is not configured
+func (r *commentRenderer) format(text, fallback string) string {
+	if format := r.template(text); format != "" {
+		return format
+	}
+	return fallback
+}
+
+func (r *commentRenderer) plain(text string, repoCnf *repoConfig) string {
+	return r.render(r.template(text), r.data(repoCnf), func(s string) string { return s })
+}
+
+func (r *commentRenderer) updateLabelFailed() string {
+	return r.plain(r.cnf.CommentUpdateLabelFailed, nil)
+}
+
+func (r *commentRenderer) noCommits() string {
+	return r.plain(r.cnf.CommentPRNoCommits, nil)
+}
+
+func (r *commentRenderer) maxCommentsReached() string {
+	return r.render(r.format(r.cnf.CommentMaxCommentsReached, defaultCommentMaxCommentsReached), r.data(nil),
+		func(s string) string { return s })
+}
+
+// commandTrigger renders the comment asking to trigger the check again, preferring the one of the repoConfig
+func (r *commentRenderer) commandTrigger(repoCnf *repoConfig) string {
+	comment := r.template(r.cnf.CommentCommandTrigger)
+	if repoCnf.CommentCommandTrigger != "" {
+		comment = r.template(repoCnf.CommentCommandTrigger)
+	}
+
+	return r.render(comment, r.data(repoCnf), func(s string) string {
+		return strings.NewReplacer(
+			placeholderMailingList, repoCnf.Contact.MailingList,
+			placeholderMaintainer, repoCnf.Contact.Maintainer,
+		).Replace(s)
+	})
+}
+
+// checkScopeNote describes the scope of the check when it was overridden by the command
+func (r *commentRenderer) checkScopeNote(repoCnf *repoConfig) string {
+	note := ""
+	if repoCnf.checkScope != "" {
+		note = r.render(r.format(r.cnf.CommentCheckScope, defaultCommentCheckScope), r.data(repoCnf),
+			func(s string) string { return fmt.Sprintf(s, repoCnf.checkScope) })
+	}
+	if repoCnf.since != "" {
+		note += fmt.Sprintf(defaultCommentCheckSince, repoCnf.since)
+	}
+	return note
+}
+
+// allSigned renders the comment of the CLA result when all the contributors have signed. The firstSigned are
+// thanked for passing the CLA check the first time
+func (r *commentRenderer) allSigned(signedUsers, firstSigned []string, repoCnf *repoConfig) string {
+	data := r.data(repoCnf)
+	data.SignedUsers = r.userMarks(signedUsers)
+	return r.render(r.template(r.cnf.CommentAllSigned), data, func(s string) string {
+		return strings.ReplaceAll(s, r.cnf.PlaceholderCommitter, data.SignedUsers)
+	}) + r.checkScopeNote(repoCnf) + r.userDetails(signedUsers) + r.corporateSigned(signedUsers) +
+		r.firstSignedThanks(firstSigned)
+}
+
+// firstSignedThanks renders the line thanking the users passing the CLA check the first time
+func (r *commentRenderer) firstSignedThanks(users []string) string {
+	format := r.template(r.cnf.CommentFirstSigned)
+	if format == "" || len(users) == 0 {
+		return ""
+	}
+
+	data := r.data(nil)
+	data.Users = r.userMarks(users)
+	return "  \n\n" + r.render(format, data, func(s string) string { return fmt.Sprintf(s, data.Users) })
+}
+
+// someNeedSign renders the comment of the CLA result by the format chosen for the pull request,
+// when some contributors have not signed
+func (r *commentRenderer) someNeedSign(format string, unsignedUsers []string, repoCnf *repoConfig) string {
+	data := r.data(repoCnf)
+	data.UnsignedUsers = r.userMarks(unsignedUsers)
+	return r.render(format, data, func(s string) string {
+		return fmt.Sprintf(s, data.UnsignedUsers, repoCnf.SignURL, repoCnf.FAQURL)
+	}) + r.checkScopeNote(repoCnf) + r.userDetails(unsignedUsers)
+}
+
+// deniedEmailDomain renders the comment asking the users to commit with a valid email
+func (r *commentRenderer) deniedEmailDomain(users []string) string {
+	data := r.data(nil)
+	data.Users = r.userMarks(users)
+	return r.render(r.format(r.cnf.CommentDeniedEmailDomain, defaultCommentDeniedEmailDomain), data,
+		func(s string) string { return fmt.Sprintf(s, data.Users) }) + r.userDetails(users)
+}
+
+// missingEmail renders the comment asking the users to commit with an email
+func (r *commentRenderer) missingEmail(users []string) string {
+	data := r.data(nil)
+	data.Users = r.userMarks(users)
+	return r.render(r.format(r.cnf.CommentMissingEmail, defaultCommentMissingEmail), data,
+		func(s string) string { return fmt.Sprintf(s, data.Users) }) + r.userDetails(users)
+}
+
+// litePRCommitter renders the comment asking the users to commit by git instead of the web page
+func (r *commentRenderer) litePRCommitter(users []string) string {
+	data := r.data(nil)
+	data.Users = r.userMarks(users)
+	return r.render(r.format(r.cnf.CommentLitePRCommitter, defaultCommentLitePRCommitter), data,
+		func(s string) string { return fmt.Sprintf(s, data.Users) }) + r.userDetails(users)
+}
+
+// signedNote renders the note listing the signed users in the comment of the unsigned ones
+func (r *commentRenderer) signedNote(users []string) string {
+	if len(users) == 0 {
+		return ""
+	}
+
+	data := r.data(nil)
+	data.Users = r.userMarks(users)
+	return r.render(r.format(r.cnf.CommentSignedNote, defaultCommentSignedNote), data,
+		func(s string) string { return fmt.Sprintf(s, data.Users) })
+}
+
+// unknownNotes renders a note for each reason of the unknown users in the comment of the unsigned ones,
+// so that the users know how to get their CLA checked
+func (r *commentRenderer) unknownNotes(users []string, reasons map[string]string) string {
+	groups := map[string][]string{}
+	for _, user := range users {
+		groups[reasons[user]] = append(groups[reasons[user]], user)
+	}
+
+	var b strings.Builder
+	format := r.format(r.cnf.CommentUnknownNote, defaultCommentUnknownNote)
+	for _, reason := range []string{unknownReasonMissingEmail, unknownReasonLitePRCommitter,
+		unknownReasonDeniedDomain, unknownReasonCLAServer} {
+		if len(groups[reason]) == 0 {
+			continue
+		}
+
+		data := r.data(nil)
+		data.Users, data.Reason = r.userMarks(groups[reason]), r.unknownReason(reason)
+		b.WriteString(r.render(format, data, func(s string) string { return fmt.Sprintf(s, data.Users, data.Reason) }))
+	}
+	return b.String()
+}
+
+// unknownReason renders the instruction in the comment_unknown_note for the users unknown for the reason
+func (r *commentRenderer) unknownReason(reason string) string {
+	text, fallback := r.cnf.CommentUnknownReasonCLAServer, defaultCommentUnknownReasonCLAServer
+	switch reason {
+	case unknownReasonMissingEmail:
+		text, fallback = r.cnf.CommentUnknownReasonMissingEmail, defaultCommentUnknownReasonMissingEmail
+	case unknownReasonLitePRCommitter:
+		text, fallback = r.cnf.CommentUnknownReasonLitePRCommitter, defaultCommentUnknownReasonLitePRCommitter
+	case unknownReasonDeniedDomain:
+		text, fallback = r.cnf.CommentUnknownReasonDeniedDomain, defaultCommentUnknownReasonDeniedDomain
+	}
+	return r.render(r.format(text, fallback), r.data(nil), func(s string) string { return s })
+}
+
+func (r *commentRenderer) watchdogExceeded(reason string) string {
+	data := r.data(nil)
+	data.Reason = reason
+	return r.render(r.format(r.cnf.CommentWatchdogExceeded, defaultCommentWatchdogExceeded), data,
+		func(s string) string { return fmt.Sprintf(s, reason) })
+}
+
+func (r *commentRenderer) dcoSigned(signedUsers []string) string {
+	data := r.data(nil)
+	data.SignedUsers = r.userMarks(signedUsers)
+	return dcoTitleSigned + "  \n\n" + r.render(r.format(r.cnf.CommentDCOSigned, defaultCommentDCOSigned), data,
+		func(s string) string { return fmt.Sprintf(s, data.SignedUsers) }) + r.userDetails(signedUsers)
+}
+
+// dcoUnsigned renders the comment listing the commits which are not signed off
+func (r *commentRenderer) dcoUnsigned(failures []dcoFailure, repoCnf *repoConfig) string {
+	var b strings.Builder
+	for _, f := range failures {
+		sha := f.sha
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		fmt.Fprintf(&b, "- `%s` by %s (%s)\n", sha, escapeMarkdownName(f.author), maskEmail(f.email))
+	}
+
+	data := r.data(repoCnf)
+	data.Commits = b.String()
+	return dcoTitleUnsigned + "  \n\n" + r.render(r.format(r.cnf.CommentDCOUnsigned, defaultCommentDCOUnsigned), data,
+		func(s string) string { return fmt.Sprintf(s, data.Commits, repoCnf.FAQURL) })
+}
+
+func (r *commentRenderer) policySigned(signedUsers []string) string {
+	data := r.data(nil)
+	data.SignedUsers = r.userMarks(signedUsers)
+	return policyTitleSigned + "  \n\n" + r.render(r.format(r.cnf.CommentPolicySigned, defaultCommentPolicySigned), data,
+		func(s string) string { return fmt.Sprintf(s, data.SignedUsers) }) + r.userDetails(signedUsers)
+}
+
+// policyUnsigned renders the comment with the breakdown of the contributors failing the policy
+func (r *commentRenderer) policyUnsigned(failed []policyResult, repoCnf *repoConfig) string {
+	policy := repoCnf.policy()
+	var b strings.Builder
+	b.WriteString("| Contributor | CLA | DCO | Missing |\n| --- | --- | --- | --- |\n")
+	for i := range failed {
+		missing := failed[i].missing(policy)
+		if missing == "" {
+			missing = "-"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", escapeMarkdownName(failed[i].user),
+			policyStateText(failed[i].claState, signStateText), policyStateText(failed[i].dcoState, signOffText),
+			missing)
+	}
+
+	data := r.data(repoCnf)
+	data.Breakdown = b.String()
+	return policyTitleUnsigned + "  \n\n" + r.render(r.format(r.cnf.CommentPolicyUnsigned, defaultCommentPolicyUnsigned),
+		data, func(s string) string { return fmt.Sprintf(s, data.Breakdown, repoCnf.SignURL, repoCnf.FAQURL) })
+}
+
+// commandDone renders the reply to the command with the outcome of the check it triggered
+func (r *commentRenderer) commandDone(commenter, outcome, link string) string {
+	data := r.data(nil)
+	data.Commenter, data.Outcome, data.Link = commenter, outcome, link
+	return r.render(r.format(r.cnf.CommentCommandDone, defaultCommentCommandDone), data,
+		func(s string) string { return fmt.Sprintf(s, commenter, outcome, link) })
+}
+
+// override renders the comment recording the override of the CLA check, which is nil if it does not expire
+func (r *commentRenderer) override(commenter, reason string, o *claOverride) string {
+	data := r.data(nil)
+	data.Commenter, data.Reason = commenter, reason
+	note := defaultCommentOverrideUntilCheck
+	if o != nil {
+		data.ExpiresAt = o.ExpiresAt.UTC().Format(time.DateTime) + " UTC"
+		note = fmt.Sprintf(defaultCommentOverrideExpiry, data.ExpiresAt)
+	}
+	return r.render(r.format(r.cnf.CommentOverride, defaultCommentOverride), data,
+		func(s string) string { return fmt.Sprintf(s, commenter, reason) }) + note
+}
+
+// help renders the reply to the `/cla help`
+func (r *commentRenderer) help(repoCnf *repoConfig) string {
+	data := r.data(repoCnf)
+	data.CheckedBy = repoCnf.checkedBy()
+	return r.render(r.format(r.cnf.CommentHelp, defaultCommentHelp), data,
+		func(s string) string { return fmt.Sprintf(s, data.CheckedBy, repoCnf.SignURL, repoCnf.FAQURL) })
+}
+
+// misconfig renders the comment of the misconfiguration of the repository
+func (r *commentRenderer) misconfig(problem string) string {
+	data := r.data(nil)
+	data.Problem = problem
+	return r.render(r.format(r.cnf.MisconfigReport.Comment, defaultCommentMisconfig), data,
+		func(s string) string { return fmt.Sprintf(s, data.Org+"/"+data.Repo, problem) })
+}
+
+// orgRecheckDone renders the summary of the recheck of an organization
+func (r *commentRenderer) orgRecheckDone(s orgRecheckSummary) string {
+	data := r.data(nil)
+	data.Recheck = s
+	return r.render(r.format(r.cnf.CommentOrgRecheckDone, defaultCommentOrgRecheckDone), data,
+		func(format string) string {
+			return fmt.Sprintf(format, s.Org, s.Total, s.Signed, s.Unsigned, s.Unknown)
+		})
+}
+
+// recheckBreakdown renders the result of each email checked by the `/cla recheck`
+func (r *commentRenderer) recheckBreakdown(details []claSignDetail) string {
+	var b strings.Builder
+	b.WriteString(defaultCommentRecheckTitle)
+	b.WriteString("| Contributor | Email | State | CLA |\n| --- | --- | --- | --- |\n")
+	for _, d := range details {
+		claType := d.claType
+		if claType == "" {
+			claType = "-"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", escapeMarkdownName(d.user), maskEmail(d.email),
+			signStateText(d.signState), escapeMarkdownName(claType))
+	}
+	return b.String()
+}
@@ -0,0 +1,145 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"flag"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/server-common-lib/utils"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// updateGolden rewrites the golden files of the comments by `go test -run TestRenderComments -update`
+var updateGolden = flag.Bool("update", false, "update the golden files of the rendered comments")
+
+// TestRenderComments renders the comment of every decision path under each config, and compares them with
+// the golden files in testdata/comments/<config>/<case>.golden, so that a change of the templates or the rendering
+// is reviewed as a change of the golden files
+func TestRenderComments(t *testing.T) {
+	configs := map[string]string{
+		"en": configYaml,
+		"zh": filepath.Join("comments", "config_zh.yaml"),
+		// the comments written as the Go text/template
+		"tmpl": filepath.Join("comments", "config_tmpl.yaml"),
+	}
+	signed := []string{"alice", "bob_*dev*"}
+	unsigned := []string{"carol"}
+
+	for name, path := range configs {
+		cnf := &configuration{}
+		assert.Equal(t, nil, utils.LoadFromYaml(findTestdata(t, path), cnf))
+		repoCnf := &cnf.ConfigItems[0]
+		repoCnf.Contact = repoContact{MailingList: "dev@example.com", Maintainer: "@m1"}
+		assert.Equal(t, nil, cnf.validateCommentTemplates())
+		r := &commentRenderer{
+			cnf: cnf, pr: newPRSnapshot(nil, org, repo, number), template: func(s string) string { return s },
+		}
+
+		corporate := *r
+		corporate.pr = newPRSnapshot(nil, org, repo, number)
+		corporate.pr.addCorporateUser("alice", "Example Corp")
+
+		truncated := *r
+		truncatedCnf := *cnf
+		truncatedCnf.UserMentions = userMentionsConfig{Limit: 2, Details: true}
+		truncated.cnf = &truncatedCnf
+
+		cases := map[string]string{
+			"all_signed":            r.allSigned(signed, nil, repoCnf),
+			"all_signed_first_time": r.allSigned(signed, signed[1:], repoCnf),
+			"all_signed_exempt":     r.allSigned(nil, nil, repoCnf),
+			"all_signed_corporate":  corporate.allSigned(signed, nil, repoCnf),
+			"all_signed_scoped":     r.allSigned(signed, nil, repoCnf.withCheckScope(checkScopeAuthors).withSince("1a2b3c4")),
+			"some_unsigned":         r.someNeedSign(r.template(cnf.CommentSomeNeedSign), unsigned, repoCnf),
+			"some_unsigned_scoped": r.someNeedSign(r.template(cnf.CommentSomeNeedSign), unsigned,
+				repoCnf.withCheckScope(checkScopeCommitters)),
+			"some_unsigned_truncated": truncated.someNeedSign(r.template(cnf.CommentSomeNeedSign),
+				[]string{"carol", "dave", "erin", "frank"}, repoCnf),
+			"some_unsigned_mixed": r.someNeedSign(r.template(cnf.CommentSomeNeedSign), unsigned, repoCnf) +
+				r.signedNote(signed) + r.unknownNotes([]string{"dave", "erin"},
+				map[string]string{"dave": unknownReasonMissingEmail}),
+			"some_unsigned_account_email": r.someNeedSign(r.template(cnf.CommentSomeNeedSign), unsigned, repoCnf) +
+				r.accountEmailMismatch(&accountEmailMismatch{
+					user: "carol", commitEmail: "carol@example.com", accountEmail: "carol@corp.com", signed: true,
+				}),
+			"unknown":               r.commandTrigger(repoCnf),
+			"unknown_denied_domain": r.deniedEmailDomain(unsigned),
+			"unknown_missing_email": r.missingEmail(unsigned),
+			"unknown_lite_pr":       r.litePRCommitter(unsigned),
+			"help":                  r.help(repoCnf),
+			"some_unsigned_reconfirm": r.someNeedSign(r.template(cnf.CommentSomeNeedSign), unsigned, repoCnf) +
+				r.claReconfirm(unsigned, &repoConfig{CLAVersion: "2.0"}),
+			"override": r.override("alice", "imported from the history", nil),
+			"override_expiry": r.override("alice", "imported from the history", &claOverride{
+				ExpiresAt: time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC),
+			}),
+			"command_done": r.commandDone("alice", commandOutcomes[prStatusUnsigned],
+				"https://gitcode.com/org1/repo1/pull/1#note_2"),
+			"label_failed":         r.updateLabelFailed(),
+			"guide_resolved":       r.guideResolved(),
+			"label_failed_retry":   r.updateLabelFailed() + r.labelRetry(time.Date(2024, 7, 1, 8, 5, 0, 0, time.UTC)),
+			"no_commits":           r.noCommits(),
+			"max_comments_reached": r.maxCommentsReached(),
+			"watchdog_exceeded":    r.watchdogExceeded("it made more than 100 api calls"),
+			"command_throttled":    r.commandThrottled(44500 * time.Millisecond),
+			"dco_signed":           r.dcoSigned(signed),
+			"dco_unsigned": r.dcoUnsigned([]dcoFailure{
+				{sha: "0123456789abcdef", author: "carol", email: "carol@example.com"},
+			}, repoCnf),
+			"unverified_commits": r.unverifiedCommits([]unverifiedCommit{{sha: "0123456789abcdef", author: "carol"}}),
+			"verified_commits":   r.verifiedCommits(),
+			"policy_signed":      r.policySigned(signed),
+			"policy_unsigned": r.policyUnsigned([]policyResult{
+				{user: "carol", claState: client.CLASignStateNo, dcoState: client.CLASignStateNo},
+				{user: "dave", claState: client.CLASignStateYes, dcoState: client.CLASignStateNo},
+			}, repoCnf),
+			"misconfig":             r.misconfig(misconfigNoConfig),
+			"org_recheck_done":      r.orgRecheckDone(orgRecheckSummary{Org: org, Total: 3, Signed: 1, Unsigned: 1, Unknown: 1}),
+			"contributor_not_found": r.contributorNotFound(escapeMarkdownName("@bob_*dev*")),
+			"recheck_breakdown": r.recheckBreakdown([]claSignDetail{
+				{user: "alice", email: "alice@example.com", signState: client.CLASignStateYes, claType: "individual"},
+				{user: "carol", email: "carol@example.com", signState: client.CLASignStateNo},
+			}),
+		}
+
+		for c, got := range cases {
+			t.Run(name+"/"+c, func(t *testing.T) {
+				golden := filepath.Join("testdata", "comments", name, c+".golden")
+				if *updateGolden {
+					assert.Equal(t, nil, os.MkdirAll(filepath.Dir(golden), 0o755))
+					assert.Equal(t, nil, os.WriteFile(golden, []byte(got), 0o644))
+				}
+
+				want, err := os.ReadFile(golden)
+				assert.Equal(t, nil, err)
+				assert.Equal(t, string(want), got)
+			})
+		}
+	}
+}
+
+func TestValidateCommentTemplate(t *testing.T) {
+	assert.Equal(t, nil, validateCommentTemplate("%s, thanks for your pull request"))
+	assert.Equal(t, nil, validateCommentTemplate("[@{{committer}}](https://gitcode.com/{{committer}})"))
+	assert.Equal(t, nil, validateCommentTemplate("{{.SignedUsers}}, thanks for {{.Org}}/{{.Repo}}"))
+	assert.NotEqual(t, nil, validateCommentTemplate("{{.SignedUser}}, thanks"))
+	assert.NotEqual(t, nil, validateCommentTemplate("{{.SignedUsers"))
+
+	cnf := &configuration{CommentAllSigned: "{{.Signed}}"}
+	assert.ErrorContains(t, cnf.validateCommentTemplates(), "comment_all_signed")
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+const (
+	// defaultCommitStatusContext is used when the commit_status_context is not configured
+	defaultCommitStatusContext = "cla/check"
+
+	commitStatusSuccess = "success"
+	commitStatusFailure = "failure"
+	commitStatusPending = "pending"
+
+	commitStatusDescriptionSigned   = "All the contributors have signed the CLA"
+	commitStatusDescriptionUnsigned = "Some contributors have not signed the CLA"
+)
+
+// commitStatus is the status of a commit reported to the code hosting platform
+type commitStatus struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+	Context     string `json:"context"`
+}
+
+func (c *repoConfig) commitStatusContext() string {
+	switch {
+	case c.CommitStatusContext != "":
+		return c.CommitStatusContext
+	case c.policy() == policyDCO:
+		return defaultDCOStatusContext
+	}
+	return defaultCommitStatusContext
+}
+
+// reportCommitStatus reports the result of the CLA check as the status of the head commit of the pull request
+// under the block enforcement level. The status links to the sign page
+func (bot *robot) reportCommitStatus(pr *prSnapshot, repoCnf *repoConfig, state, description string) {
+	if !repoCnf.enforces(enforcementBlock) {
+		return
+	}
+
+	sha, ok := pr.headSHA()
+	if !ok {
+		return
+	}
+
+	pr.stats.writes++
+	if bot.cli.CreateCommitStatus(pr.org, pr.repo, sha, commitStatus{
+		State:       state,
+		TargetURL:   repoCnf.SignURL,
+		Description: description,
+		Context:     repoCnf.commitStatusContext(),
+	}) {
+		pr.stats.commitStatus = state
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReportCommitStatus(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestCommitMessages: true,
+		successfulCreateCommitStatus:           true,
+		commitMessages:                         []prCommitMessage{{SHA: "sha1"}, {SHA: "sha2"}},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	repoCnf := &repoConfig{SignURL: "https://cla/sign"}
+
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.reportCommitStatus(pr, repoCnf, commitStatusSuccess, commitStatusDescriptionSigned)
+	assert.Equal(t, "", mc.method)
+
+	repoCnf.CommitStatus = true
+	bot.reportCommitStatus(pr, repoCnf, commitStatusFailure, commitStatusDescriptionUnsigned)
+	assert.Equal(t, "CreateCommitStatus", mc.method)
+	assert.Equal(t, commitStatus{
+		State:       commitStatusFailure,
+		TargetURL:   "https://cla/sign",
+		Description: commitStatusDescriptionUnsigned,
+		Context:     defaultCommitStatusContext,
+	}, mc.status)
+	assert.Equal(t, commitStatusFailure, pr.stats.commitStatus)
+
+	// no status is reported if the head is unknown
+	mc.method = ""
+	mc.successfulGetPullRequestCommitMessages = false
+	bot.reportCommitStatus(newPRSnapshot(mc, org, repo, number), repoCnf, commitStatusSuccess, commitStatusDescriptionSigned)
+	assert.Equal(t, "GetPullRequestCommitMessages", mc.method)
+}
@@ -0,0 +1,51 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// compatibilityProfileLegacy reproduces the behaviors of the earlier releases of the bot, such as for the configs
+// converted by the migrate command, so that a community switches to this release with no visible change and then
+// adopts the newer behaviors all at once by removing the profile
+const compatibilityProfileLegacy = "legacy"
+
+// the commands of the earlier releases, which are matched exactly after trimming the spaces
+var legacyCommands = []*regexp.Regexp{regexp.MustCompile(`^/check-cla$`), regexpCancelCLAComment}
+
+func validateCompatibilityProfile(profile string) error {
+	if profile != "" && profile != compatibilityProfileLegacy {
+		return errors.New("the compatibility_profile can only be legacy")
+	}
+	return nil
+}
+
+// legacyProfile checks whether the behaviors of the earlier releases are reproduced
+func (c *configuration) legacyProfile() bool {
+	return c.CompatibilityProfile == compatibilityProfileLegacy
+}
+
+// legacyCommand returns the comment trimmed if it is a command of the earlier releases
+func legacyCommand(comment string) (string, bool) {
+	comment = strings.TrimSpace(comment)
+	for _, r := range legacyCommands {
+		if r.MatchString(comment) {
+			return comment, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLegacyCommand(t *testing.T) {
+	assert.NoError(t, validateCompatibilityProfile(""))
+	assert.NoError(t, validateCompatibilityProfile(compatibilityProfileLegacy))
+	assert.Error(t, validateCompatibilityProfile("openeuler"))
+
+	comment, ok := legacyCommand(" /check-cla \n")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "/check-cla", comment)
+	_, ok = legacyCommand("/cla cancel")
+	assert.Equal(t, true, ok)
+
+	// the commands added later are not accepted
+	for _, comment := range []string{"/check-cla committers", "/cla help", "/cla recheck", "／check-cla"} {
+		_, ok = legacyCommand(comment)
+		assert.Equal(t, false, ok, comment)
+	}
+}
+
+func TestLegacyProfileComments(t *testing.T) {
+	mc := &mockClient{
+		successfulListPullRequestComments: true,
+		successfulCreatePRComment:         true,
+		successfulDeletePRComment:         true,
+		successfulUpdatePRComment:         true,
+		successfulAddPRLabels:             true,
+		prComments: []client.PRComment{
+			{ID: "1", Body: "#guide the first check"},
+			{ID: "2", Body: "#pass the second check"},
+		},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{
+		CompatibilityProfile:         compatibilityProfileLegacy,
+		CommentSomeNeedSign:          "#guide %s, sign at %s, see %s",
+		PlaceholderCLASignGuideTitle: "#guide",
+		PlaceholderCLASignPassTitle:  "#pass",
+	}}
+
+	// the result comments are deleted and posted again, and the unsigned comment lists only the unsigned users
+	bot.waitCLASignature(newPRSnapshot(mc, org, repo, number), [3][]string{{"u1"}, {"u2"}, {"u3"}}, nil,
+		&repoConfig{CLALabelNo: labelNo, SignURL: "sign", FAQURL: "faq"})
+	assert.Empty(t, mc.updatedComments)
+	assert.Equal(t, []string{"1", "2"}, mc.deletedComments)
+	assert.Contains(t, mc.comment, "#guide u2, sign at sign, see faq")
+	assert.NotContains(t, mc.comment, "u1")
+	assert.NotContains(t, mc.comment, "u3")
+}
@@ -0,0 +1,134 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// adminPathComplianceStats is the CLA compliance of the repositories, optionally of one org by the query `org`
+const adminPathComplianceStats = "/api/v1/stats"
+
+// complianceCounts is the CLA compliance of the pull requests of a repository or an org kept in the state store
+type complianceCounts struct {
+	// BlockedPRs is the number of the open pull requests whose latest evaluation is unsigned
+	BlockedPRs int `json:"blocked_prs"`
+	// UnsignedEmails is the number of the distinct unsigned emails of the blocked pull requests
+	UnsignedEmails int `json:"unsigned_emails"`
+	// Resolved is the number of the times a pull request turned signed after being unsigned
+	Resolved int `json:"resolved"`
+	// AvgSecondsToSign is the average time from the unsigned evaluation of a pull request to the signed one
+	// following it, in the history kept for each pull request. It is 0 if none is resolved
+	AvgSecondsToSign float64 `json:"avg_seconds_to_sign"`
+}
+
+type repoCompliance struct {
+	Repo string `json:"repo"`
+	complianceCounts
+}
+
+type orgCompliance struct {
+	Org string `json:"org"`
+	complianceCounts
+	Repos []repoCompliance `json:"repos"`
+}
+
+// complianceStats is the CLA compliance of the orgs and their repositories, sorted by the names
+type complianceStats struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Orgs        []orgCompliance `json:"orgs"`
+}
+
+// complianceAccumulator collects the complianceCounts of the pull requests
+type complianceAccumulator struct {
+	blocked  int
+	emails   map[string]bool
+	resolved int
+	toSign   time.Duration
+}
+
+func newComplianceAccumulator() *complianceAccumulator {
+	return &complianceAccumulator{emails: map[string]bool{}}
+}
+
+func (a *complianceAccumulator) add(s *prState) {
+	if !s.Closed && s.Status == prStatusUnsigned {
+		a.blocked++
+		for _, email := range s.UnsignedEmails {
+			a.emails[email] = true
+		}
+	}
+
+	// the unknown evaluations between the unsigned and the signed ones are counted as unsigned
+	var unsignedAt time.Time
+	for _, e := range s.History {
+		switch {
+		case e.Status == prStatusUnsigned && unsignedAt.IsZero():
+			unsignedAt = e.Time
+		case e.Status == prStatusSigned && !unsignedAt.IsZero():
+			a.resolved++
+			a.toSign += e.Time.Sub(unsignedAt)
+			unsignedAt = time.Time{}
+		}
+	}
+}
+
+func (a *complianceAccumulator) counts() complianceCounts {
+	c := complianceCounts{BlockedPRs: a.blocked, UnsignedEmails: len(a.emails), Resolved: a.resolved}
+	if a.resolved != 0 {
+		c.AvgSecondsToSign = a.toSign.Seconds() / float64(a.resolved)
+	}
+	return c
+}
+
+// collectComplianceStats aggregates the states of the pull requests by org and repo. All the orgs are
+// collected if org is empty
+func collectComplianceStats(prs []prState, org string) complianceStats {
+	orgs := map[string]*complianceAccumulator{}
+	repos := map[string]map[string]*complianceAccumulator{}
+	for i := range prs {
+		s := &prs[i]
+		if org != "" && s.Org != org {
+			continue
+		}
+
+		if orgs[s.Org] == nil {
+			orgs[s.Org] = newComplianceAccumulator()
+			repos[s.Org] = map[string]*complianceAccumulator{}
+		}
+		if repos[s.Org][s.Repo] == nil {
+			repos[s.Org][s.Repo] = newComplianceAccumulator()
+		}
+		orgs[s.Org].add(s)
+		repos[s.Org][s.Repo].add(s)
+	}
+
+	stats := complianceStats{GeneratedAt: time.Now().UTC(), Orgs: []orgCompliance{}}
+	for name, acc := range orgs {
+		item := orgCompliance{Org: name, complianceCounts: acc.counts(), Repos: []repoCompliance{}}
+		for repo, repoAcc := range repos[name] {
+			item.Repos = append(item.Repos, repoCompliance{Repo: repo, complianceCounts: repoAcc.counts()})
+		}
+		sort.Slice(item.Repos, func(i, j int) bool { return item.Repos[i].Repo < item.Repos[j].Repo })
+		stats.Orgs = append(stats.Orgs, item)
+	}
+	sort.Slice(stats.Orgs, func(i, j int) bool { return stats.Orgs[i].Org < stats.Orgs[j].Org })
+	return stats
+}
+
+func (s *adminServer) handleComplianceStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, collectComplianceStats(s.store.exportSnapshot().PRs, r.URL.Query().Get("org")))
+}
@@ -0,0 +1,89 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCollectComplianceStats(t *testing.T) {
+	now := time.Now()
+	history := func(statuses ...string) []prEvaluation {
+		h := make([]prEvaluation, len(statuses))
+		for i, status := range statuses {
+			h[i] = prEvaluation{Time: now.Add(time.Duration(i) * time.Hour), Status: status}
+		}
+		return h
+	}
+	prs := []prState{
+		{Org: "org1", Repo: "repo1", Number: "1", Status: prStatusUnsigned, UnsignedEmails: []string{"a@x.com", "b@x.com"},
+			History: history(prStatusUnsigned)},
+		{Org: "org1", Repo: "repo1", Number: "2", Status: prStatusSigned,
+			History: history(prStatusUnsigned, prStatusUnknown, prStatusSigned)},
+		{Org: "org1", Repo: "repo2", Number: "1", Status: prStatusUnsigned, UnsignedEmails: []string{"a@x.com"},
+			History: history(prStatusUnsigned, prStatusSigned, prStatusUnsigned)},
+		{Org: "org2", Repo: "repo1", Number: "1", Status: prStatusSigned, History: history(prStatusSigned)},
+		// the closed pull request is not blocked
+		{Org: "org1", Repo: "repo2", Number: "2", Status: prStatusUnsigned, UnsignedEmails: []string{"c@x.com"},
+			History: history(prStatusUnsigned), Closed: true},
+	}
+
+	stats := collectComplianceStats(prs, "")
+	assert.Equal(t, 2, len(stats.Orgs))
+	org1 := stats.Orgs[0]
+	assert.Equal(t, "org1", org1.Org)
+	// the email blocking the pull requests of both repositories is counted once in the org
+	assert.Equal(t, complianceCounts{BlockedPRs: 2, UnsignedEmails: 2, Resolved: 2, AvgSecondsToSign: 5400},
+		org1.complianceCounts)
+	assert.Equal(t, []repoCompliance{
+		{Repo: "repo1", complianceCounts: complianceCounts{
+			BlockedPRs: 1, UnsignedEmails: 2, Resolved: 1, AvgSecondsToSign: 7200}},
+		{Repo: "repo2", complianceCounts: complianceCounts{
+			BlockedPRs: 1, UnsignedEmails: 1, Resolved: 1, AvgSecondsToSign: 3600}},
+	}, org1.Repos)
+	assert.Equal(t, complianceCounts{}, stats.Orgs[1].complianceCounts)
+
+	stats = collectComplianceStats(prs, "org2")
+	assert.Equal(t, 1, len(stats.Orgs))
+	assert.Equal(t, "org2", stats.Orgs[0].Org)
+}
+
+func TestComplianceStatsHandler(t *testing.T) {
+	store := newMemoryStateStore()
+	store.put(prState{Org: org, Repo: repo, Number: number, Status: prStatusUnsigned, UnsignedEmails: []string{"a@x.com"}})
+	mux := http.NewServeMux()
+	registerAdminHandlers(mux, &robot{store: store}, []adminTenant{{Name: "admin", APIKey: "secret"}},
+		framework.NewLogger())
+
+	req := httptest.NewRequest(http.MethodGet, adminPathComplianceStats+"?org="+org, nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stats complianceStats
+	assert.Equal(t, nil, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, 1, stats.Orgs[0].BlockedPRs)
+	assert.Equal(t, repo, stats.Orgs[0].Repos[0].Repo)
+}
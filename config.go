@@ -15,8 +15,10 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"github.com/opensourceways/server-common-lib/config"
 	"reflect"
+	"slices"
 	"strings"
 )
 
@@ -34,6 +36,178 @@ type configuration struct {
 	PlaceholderCLASignPassTitle  string       `json:"placeholder_cla_sign_pass_title" required:"true"`
 	SigInfoURL                   string       `json:"sig_info_url" required:"true"`
 	CommunityName                string       `json:"community_name" required:"true"`
+	// CommentCheckScope is appended to the result comment when `/check-cla` overrides the scope of the check.
+	// It has one %s for the scope, committers or authors. A default note is used if it is empty
+	CommentCheckScope string `json:"comment_check_scope"`
+	// AdminRepo is the repository, as org/repo, where the administration commands such as
+	// `/cla recheck-org` are accepted. The commands are disabled if it is empty
+	AdminRepo string `json:"admin_repo"`
+	// RelaxedCommandMatching also accepts the commands typed in the full-width forms, in upper case or
+	// with extra spaces, such as `／ＣＬＡ　 Recheck`
+	RelaxedCommandMatching bool `json:"relaxed_command_matching"`
+	// CompatibilityProfile reproduces the behaviors of the earlier releases if it is legacy: only `/check-cla`
+	// and `/cla cancel` are accepted and matched exactly, the unsigned comment lists only the unsigned users, and
+	// the result comments are deleted and posted again rather than edited in place
+	CompatibilityProfile string `json:"compatibility_profile"`
+	// RecheckRatePerMinute throttles the rechecks queued by the batch commands. Default is 30
+	RecheckRatePerMinute int `json:"recheck_rate_per_minute"`
+	// RecheckPriority orders the blocked pull requests to recheck by their activity and age, and bounds
+	// the number of them rechecked in one run
+	RecheckPriority recheckPriorityConfig `json:"recheck_priority"`
+	// PrivacyMode masks the emails of the contributors, as j***@example.com, in the logs, the audit records and
+	// the comments. It is read at the startup for the logs and the audit records
+	PrivacyMode bool `json:"privacy_mode"`
+	// EmailNotification emails the unsigned contributors with the sign url and the faq url. It is disabled by default
+	EmailNotification emailNotificationConfig `json:"email_notification"`
+	// BacklogReport posts the unsigned contributors blocking the pull requests to a webhook periodically.
+	// It is disabled by default, and the report is served by the admin api anyway
+	BacklogReport backlogReportConfig `json:"backlog_report"`
+	// RescanIntervalMinutes rescans the open pull requests labeled with the cla_label_no periodically, so that their
+	// labels are updated once the contributors sign. It is read at the startup, and it is disabled if it is 0
+	RescanIntervalMinutes int `json:"rescan_interval_minutes"`
+	// CLACacheSize bounds the number of the signed results of the CLA server cached for the repositories
+	// enabling cla_cache_ttl_seconds. The least recently used one is evicted when it is full. Default is 10000
+	CLACacheSize int `json:"cla_cache_size"`
+	// CLALookupConcurrency bounds the lookups of the distinct emails of a pull request sent to the CLA server
+	// at the same time. Default is 4
+	CLALookupConcurrency int `json:"cla_lookup_concurrency"`
+	// CommentOrgRecheckDone is the summary comment posted when the recheck of an organization is finished.
+	// It has one %s for the org and four %d for the total, signed, unsigned and unknown pull requests
+	CommentOrgRecheckDone string `json:"comment_org_recheck_done"`
+	// CommentSomeNeedSignAgain is the brief comment used instead of comment_some_need_sign when the check
+	// of the pull request has failed before. It has the same three %s for the users, sign url and faq url,
+	// which can be referred to as %[1]s, %[2]s and %[3]s to omit some of them. It should contain the
+	// placeholder_cla_sign_guide_title so that it is removed by the next check. Default is comment_some_need_sign
+	CommentSomeNeedSignAgain string `json:"comment_some_need_sign_again"`
+	// CommentMaxCommentsReached is the final note posted when the bot reaches the max_comments of a repository.
+	// A default note is used if it is empty
+	CommentMaxCommentsReached string `json:"comment_max_comments_reached"`
+	// Watchdog bounds the work of each evaluation. It is disabled by default
+	Watchdog watchdogConfig `json:"watchdog"`
+	// ErrorBudget tracks the error rates of the api calls, and pauses the non-essential operations
+	// while the code hosting platform fails too often. The pause is disabled by default
+	ErrorBudget errorBudgetConfig `json:"error_budget"`
+	// URLValidation tightens the validation of the urls of the config items. It is disabled by default
+	URLValidation urlValidationConfig `json:"url_validation"`
+	// EventDedup skips the webhook deliveries replayed within its window. It is disabled by default
+	EventDedup eventDedupConfig `json:"event_dedup"`
+	// CommandRateLimit throttles the commands checking the CLA per pull request and per user. It is disabled by default
+	CommandRateLimit commandRateLimitConfig `json:"command_rate_limit"`
+	// LabelBreaker stops calling the label apis for a while when they keep failing. It is disabled by default.
+	// It is read at the startup
+	LabelBreaker labelBreakerConfig `json:"label_breaker"`
+	// CLAWebhook authenticates the webhooks pushed by the CLA service, such as the /cla-signed-callback.
+	// The webhooks are rejected if it is not set
+	CLAWebhook claWebhookConfig `json:"cla_webhook"`
+	// Preflight checks the new and changed config items against the code hosting platform and the CLA server
+	// before a reloaded configuration is applied. It is one of enforce, which rejects the reload failing any check,
+	// warn, which applies it with the warnings, and skip. A rejected reload is retried once the configmap file is
+	// changed again. Default is enforce
+	Preflight string `json:"preflight"`
+	// MisconfigReport reports the repositories which the bot is misconfigured for on their pull requests,
+	// instead of only logging them
+	MisconfigReport misconfigReportConfig `json:"misconfig_report"`
+	// Platform is the code hosting platform the bot works on, which can only be gitcode. It is read at the startup.
+	// Default is gitcode
+	Platform string `json:"platform"`
+	// LabelNaming transforms the CLA labels and the manual_review_label of the watchdog into the names the code
+	// hosting platform accepts, keyed by the platform. Only gitcode is supported
+	LabelNaming map[string]labelNamingRules `json:"label_naming"`
+	// GraphQLURL is the GraphQL endpoint of a platform compatible with the schema of GitHub. The labels and the
+	// commits of a pull request are fetched in one query by it, and by the REST api if the query fails.
+	// The REST api is used if it is empty
+	GraphQLURL string `json:"graphql_url"`
+	// UserMentions bounds the users mentioned in each comment. They are unlimited by default
+	UserMentions userMentionsConfig `json:"user_mentions"`
+	// Audit records every label and comment mutation of the pull requests and every CLA verdict
+	Audit auditConfig `json:"audit"`
+	// CommentWatchdogExceeded is the comment posted when the watchdog stops an evaluation.
+	// It has one %s for the reason. A default comment is used if it is empty
+	CommentWatchdogExceeded string `json:"comment_watchdog_exceeded"`
+	// CommentDeniedEmailDomain is the comment posted when some contributors commit with the emails in the
+	// email_domain_denylist. It has one %s for the users. A default comment is used if it is empty
+	CommentDeniedEmailDomain string `json:"comment_denied_email_domain"`
+	// CommentMissingEmail is the comment posted when some contributors commit without an email.
+	// It has one %s for the users. A default comment is used if it is empty
+	CommentMissingEmail string `json:"comment_missing_email"`
+	// CommentLitePRCommitter is the comment posted when some commits are committed by the lite_pr_committer,
+	// whose email can not identify the contributors. It has one %s for the users. A default comment is used if it is empty
+	CommentLitePRCommitter string `json:"comment_lite_pr_committer"`
+	// CommentSignedNote is appended to the comment of the unsigned contributors to list the signed ones.
+	// It has one %s for the users. A default note is used if it is empty
+	CommentSignedNote string `json:"comment_signed_note"`
+	// CommentUnknownNote is appended to the comment of the unsigned contributors for each reason why the CLA of
+	// some contributors can not be checked. It has one %s for the users and one %s for the instruction.
+	// A default note is used if it is empty
+	CommentUnknownNote string `json:"comment_unknown_note"`
+	// CommentUnknownReasonCLAServer is the instruction in the comment_unknown_note for the contributors whose CLA
+	// can not be checked as the CLA server fails. A default instruction is used if it is empty
+	CommentUnknownReasonCLAServer string `json:"comment_unknown_reason_cla_server"`
+	// CommentUnknownReasonMissingEmail is the instruction in the comment_unknown_note for the contributors who
+	// commit without an email. A default instruction is used if it is empty
+	CommentUnknownReasonMissingEmail string `json:"comment_unknown_reason_missing_email"`
+	// CommentUnknownReasonLitePRCommitter is the instruction in the comment_unknown_note for the contributors
+	// whose commits are committed by the lite_pr_committer. A default instruction is used if it is empty
+	CommentUnknownReasonLitePRCommitter string `json:"comment_unknown_reason_lite_pr_committer"`
+	// CommentUnknownReasonDeniedDomain is the instruction in the comment_unknown_note for the contributors who
+	// commit with the emails in the email_domain_denylist. A default instruction is used if it is empty
+	CommentUnknownReasonDeniedDomain string `json:"comment_unknown_reason_denied_email_domain"`
+	// CommentContributorNotFound is the reply to the `/check-cla @username` or `/check-cla <email>` whose contributor
+	// is not in the commits. It has one %s for the contributor. A default comment is used if it is empty
+	CommentContributorNotFound string `json:"comment_contributor_not_found"`
+	// CommentAccountEmailMismatch is appended to the comment of the unsigned contributors under the
+	// check_author_account_email, when the emails of the commits of the author differ from the email of the account
+	// which has not signed the CLA either. It has one %s for the author, one %s for the email of the commits and
+	// one %s for the email of the account, which can be reordered as %[3]s. A default note is used if it is empty
+	CommentAccountEmailMismatch string `json:"comment_account_email_mismatch"`
+	// CommentAccountEmailSigned is the comment_account_email_mismatch when the email of the account has signed
+	// the CLA. It has the same %s. A default note is used if it is empty
+	CommentAccountEmailSigned string `json:"comment_account_email_signed"`
+	// CommentUnverifiedCommits is the comment posted when some commits are not signed by a verified signature under
+	// the require_signed_commits. It has one %s for the list of the commits. A default comment is used if it is empty
+	CommentUnverifiedCommits string `json:"comment_unverified_commits"`
+	// CommentVerifiedCommits replaces the comment_unverified_commits when all the commits are signed by verified
+	// signatures. A default comment is used if it is empty
+	CommentVerifiedCommits string `json:"comment_verified_commits"`
+	// CommentDCOUnsigned is the comment posted in the dco mode when some commits are not signed off.
+	// It has one %s for the list of the commits and one %s for the faq url. A default comment is used if it is empty
+	CommentDCOUnsigned string `json:"comment_dco_unsigned"`
+	// CommentDCOSigned is the comment posted in the dco mode when all the commits are signed off.
+	// It has one %s for the users. A default comment is used if it is empty
+	CommentDCOSigned string `json:"comment_dco_signed"`
+	// CommentPolicyUnsigned is the comment posted under the cla_and_dco and cla_or_dco policies when some
+	// contributors fail. It has one %s for the breakdown of the contributors, one %s for the sign url and one %s
+	// for the faq url. A default comment is used if it is empty
+	CommentPolicyUnsigned string `json:"comment_policy_unsigned"`
+	// CommentPolicySigned is the comment posted under the cla_and_dco and cla_or_dco policies when all the
+	// contributors pass. It has one %s for the users. A default comment is used if it is empty
+	CommentPolicySigned string `json:"comment_policy_signed"`
+	// CommentCommandDone is the reply to the command triggering the check, for the repositories enabling
+	// reply_to_command. It has one %s for the commenter, one %s for the outcome and one %s for the link to the
+	// comment of the CLA result. A default comment is used if it is empty
+	CommentCommandDone string `json:"comment_command_done"`
+	// CommentOverride is the comment recording the `/cla override <reason>`. It has one %s for the commenter
+	// and one %s for the reason. A default comment is used if it is empty
+	CommentOverride string `json:"comment_override"`
+	// CommentCLAReconfirm is appended to the comment of the CLA result for the contributors who signed an earlier
+	// cla_version. It has one %s for the cla_version and one %s for the users. A default note is used if it is empty
+	CommentCLAReconfirm string `json:"comment_cla_reconfirm"`
+	// CommentGuideResolved is appended to the sign guide kept for the repositories enabling the
+	// preserve_guide_comment when the check passes. A default note is used if it is empty
+	CommentGuideResolved string `json:"comment_guide_resolved"`
+	// CommentLabelRetry is appended to the comment_update_label_failed while the label_breaker is open. It has
+	// one %s for when the labels will be updated again. A default note is used if it is empty
+	CommentLabelRetry string `json:"comment_label_retry"`
+	// CommentHelp is the reply to the `/cla help`. It has one %s for the authors or the committers whose emails
+	// are checked, one %s for the sign url and one %s for the faq url. A default comment is used if it is empty
+	CommentHelp string `json:"comment_help"`
+	// CommentFirstSigned is appended to the comment of the CLA pass once for the contributors passing the CLA check
+	// the first time in the organization. It has one %s for the users. It is not appended if it is empty
+	CommentFirstSigned string `json:"comment_first_signed"`
+	// CommentCorporateSigned is appended to the comment of the CLA pass for each company whose corporate CLA in the
+	// corporate_cla_file of the config item covers the contributors. It has one %s for the users and one %s for the
+	// company. A default note is used if it is empty
+	CommentCorporateSigned string `json:"comment_corporate_signed"`
 }
 
 // Validate to check the configmap data's validation, returns an error if invalid
@@ -42,18 +216,182 @@ func (c *configuration) Validate() error {
 		return errors.New("configuration is nil")
 	}
 
+	if c.AdminRepo != "" {
+		if org, repo, found := strings.Cut(c.AdminRepo, "/"); !found || org == "" || repo == "" {
+			return errors.New("the admin_repo must be in the format of org/repo")
+		}
+	}
+
+	if err := c.Watchdog.validate(); err != nil {
+		return err
+	}
+
+	if c.RescanIntervalMinutes < 0 {
+		return errors.New("the rescan_interval_minutes can not be negative")
+	}
+
+	if err := c.EmailNotification.validate(); err != nil {
+		return err
+	}
+
+	if err := c.BacklogReport.validate(); err != nil {
+		return err
+	}
+
+	if c.CLALookupConcurrency < 0 {
+		return errors.New("the cla_lookup_concurrency can not be negative")
+	}
+
+	if err := c.MisconfigReport.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Audit.validate(); err != nil {
+		return err
+	}
+
+	if err := c.UserMentions.validate(); err != nil {
+		return err
+	}
+
+	if err := validatePlatform(c.Platform); err != nil {
+		return err
+	}
+
+	if err := validateCompatibilityProfile(c.CompatibilityProfile); err != nil {
+		return err
+	}
+
+	if err := validateLabelNaming(c.LabelNaming); err != nil {
+		return err
+	}
+
+	if err := c.validateCommentTemplates(); err != nil {
+		return err
+	}
+
+	if err := validatePreflight(c.Preflight); err != nil {
+		return err
+	}
+
+	if err := c.RecheckPriority.validate(); err != nil {
+		return err
+	}
+
+	if err := c.ErrorBudget.validate(); err != nil {
+		return err
+	}
+
+	if err := c.EventDedup.validate(); err != nil {
+		return err
+	}
+
+	if err := c.CommandRateLimit.validate(); err != nil {
+		return err
+	}
+
+	if err := c.LabelBreaker.validate(); err != nil {
+		return err
+	}
+
+	if err := c.CLAWebhook.validate(); err != nil {
+		return err
+	}
+
 	// Validate each repo configuration
 	items := c.ConfigItems
 	for i := range items {
-		if err := items[i].validateRepoConfig(); err != nil {
+		if items[i].InheritOrg {
+			if err := c.validateInheritedItem(i); err != nil {
+				return err
+			}
+		} else if err := items[i].validateRepoConfig(); err != nil {
 			return err
 		}
 	}
 
+	if err := c.validateOverlappedItems(); err != nil {
+		return err
+	}
+
+	if err := c.validateURLSchemes(); err != nil {
+		return err
+	}
+
 	return validateRequiredConfig(*c)
 }
 
+// validateOverlappedItems rejects the config item which is never used for some repositories,
+// because an earlier item listing the same org or org/repo always matches them first
+func (c *configuration) validateOverlappedItems() error {
+	items := c.ConfigItems
+	for j := range items {
+		for i := 0; i < j; i++ {
+			if entry := items[i].shadowedEntry(&items[j]); entry != "" {
+				return fmt.Errorf("config_items[%d] is never used for %s, since config_items[%d] lists it "+
+					"and matches first, narrow the latter by its conditions or merge them", j, entry, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// precedenceWarnings lists the org/repo items listed after an item of their org. Such an item is used for the
+// org/repo now, since the items listing the org/repo take precedence, while the earlier releases used the item
+// of the org which matches first
+func (c *configuration) precedenceWarnings() []string {
+	var warnings []string
+	items := c.ConfigItems
+	for j := range items {
+		for _, entry := range items[j].Repos {
+			org, _, found := strings.Cut(entry, "/")
+			if !found {
+				continue
+			}
+			for i := 0; i < j; i++ {
+				if ok, byOrg := items[i].RepoFilter.CanApply(org, entry); ok && byOrg {
+					warnings = append(warnings, fmt.Sprintf("config_items[%d] takes precedence over config_items[%d] "+
+						"for %s though it is listed later, since it lists the org/repo", j, i, entry))
+					break
+				}
+			}
+		}
+	}
+
+	return warnings
+}
+
 func validateRequiredConfig[C configuration | repoConfig](c C) error {
+	missing := missingRequiredConfig(c)
+	if len(missing) != 0 {
+		return errors.New("missing the follow config: " + strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// listMissingConfig lists the required config which are not set, including those of the config items
+func (c *configuration) listMissingConfig() []string {
+	if c == nil {
+		return nil
+	}
+
+	var missing []string
+	for i := range c.ConfigItems {
+		for _, cnf := range c.inheritedItems(i) {
+			for _, field := range cnf.missingConfig() {
+				if item := fmt.Sprintf("config_items[%d].%s", i, field); !slices.Contains(missing, item) {
+					missing = append(missing, item)
+				}
+			}
+		}
+	}
+
+	return append(missing, missingRequiredConfig(*c)...)
+}
+
+func missingRequiredConfig[C configuration | repoConfig](c C) []string {
 	k := reflect.TypeOf(c)
 	v := reflect.ValueOf(c)
 
@@ -69,28 +407,42 @@ func validateRequiredConfig[C configuration | repoConfig](c C) error {
 		}
 	}
 
-	if len(missing) != 0 {
-		return errors.New("missing the follow config: " + strings.Join(missing, ", "))
-	}
-
-	return nil
+	return missing
 }
 
 // getRepoConfig retrieves a repoConfig for a given organization and repository.
 // Returns the repoConfig if found, otherwise returns nil.
 func (c *configuration) getRepoConfig(org, repo string) *repoConfig {
+	return c.getMatchedRepoConfig(org, repo, nil)
+}
+
+// getMatchedRepoConfig retrieves a repoConfig for a given organization and repository
+// which also matches the metadata of the repository. The conditions on the metadata are
+// ignored when the metadata is nil. Returns the repoConfig if found, otherwise returns nil.
+//
+// The items listing the org/repo take precedence over those listing the org, whatever their order.
+// Among the items of the same precedence, the first matched one is used.
+func (c *configuration) getMatchedRepoConfig(org, repo string, meta *repoMetadata) *repoConfig {
 	if c == nil || len(c.ConfigItems) == 0 {
 		return nil
 	}
 
+	var orgMatched *repoConfig
 	for i := range c.ConfigItems {
-		ok, _ := c.ConfigItems[i].RepoFilter.CanApply(org, org+"/"+repo)
-		if ok {
-			return &c.ConfigItems[i]
+		ok, byOrg := c.ConfigItems[i].RepoFilter.CanApply(org, org+"/"+repo)
+		if !ok || !c.ConfigItems[i].matchRepoMetadata(meta) {
+			continue
+		}
+
+		if !byOrg {
+			return c.inheritOrg(&c.ConfigItems[i], org, meta)
+		}
+		if orgMatched == nil {
+			orgMatched = &c.ConfigItems[i]
 		}
 	}
 
-	return nil
+	return orgMatched
 }
 
 // repoConfig is a configuration struct for a organization and repository.
@@ -98,6 +450,12 @@ func (c *configuration) getRepoConfig(org, repo string) *repoConfig {
 type repoConfig struct {
 	// RepoFilter is used to filter repositories.
 	config.RepoFilter
+
+	// InheritOrg takes the fields not set by this item from the item listing the org of each repository, so that
+	// the item listing org/repo only sets the fields it overrides. A field set to false, 0 or empty can not
+	// override the one of the org
+	InheritOrg bool `json:"inherit_org"`
+
 	// CLALabelYes is the cla label name for org/repos indicating
 	// the cla has been signed
 	CLALabelYes string `json:"cla_label_yes" required:"true"`
@@ -106,10 +464,78 @@ type repoConfig struct {
 	// the cla has not been signed
 	CLALabelNo string `json:"cla_label_no" required:"true"`
 
+	// CreateCLALabels creates the cla_label_yes or the cla_label_no in the repository when adding it to a pull
+	// request fails, for the platforms requiring the labels to exist in the repository
+	CreateCLALabels bool `json:"create_cla_labels"`
+	// CLALabelYesStyle is the color and the description of the cla_label_yes created. Default color is #0e8a16
+	CLALabelYesStyle labelStyle `json:"cla_label_yes_style"`
+	// CLALabelNoStyle is the color and the description of the cla_label_no created. Default color is #d73a4a
+	CLALabelNoStyle labelStyle `json:"cla_label_no_style"`
+
+	// Mode is how the contributions are checked, cla (default) by the CLA server, or dco by the `Signed-off-by`
+	// trailer of each commit matching its author, as the Developer Certificate of Origin requires. In the dco mode,
+	// the cla_label_yes and cla_label_no are the labels of the DCO result such as dco-yes and dco-no, the faq_url
+	// guides to sign off the commits, and the check_url and sign_url are not required
+	Mode string `json:"mode"`
+
+	// CheckAuthorAccountEmail checks the primary email of the account of the author of the pull request against
+	// the CLA server too, when the author has not signed by the emails of the commits, and warns in the comment
+	// that the emails of the commits differ from the one of the account
+	CheckAuthorAccountEmail bool `json:"check_author_account_email"`
+
+	// RequireSignedCommits requires every commit of the pull request to be signed by a GPG or S/MIME signature
+	// verified by the platform, besides the CLA. Its result is labeled and commented apart from the CLA result
+	RequireSignedCommits bool `json:"require_signed_commits"`
+	// SignedCommitsLabelYes is the label of the pull requests whose commits are all verified.
+	// Default is signed-commits-yes
+	SignedCommitsLabelYes string `json:"signed_commits_label_yes"`
+	// SignedCommitsLabelNo is the label of the pull requests having unverified commits. Default is signed-commits-no
+	SignedCommitsLabelNo string `json:"signed_commits_label_no"`
+
+	// Policy combines the CLA and the DCO, one of cla, dco, cla_and_dco requiring both of them, and cla_or_dco
+	// requiring either of them from each contributor. It replaces the mode, which is used if it is not set
+	Policy string `json:"policy"`
+
 	// CheckURL is the url used to check whether the contributor has signed cla
-	// The url has the format as https://**/{{org}}:{{repo}}?email={{email}}
+	// The {{org}}, {{repo}} and {{email}} in the url are replaced by the escaped values of the pull request
+	// at check time, such as https://**/{{org}}:{{repo}}?email={{email}}, so that one config item can serve
+	// many repositories. The single braces as {org} also work. The email is added to the query if the url has
+	// no {{email}}
 	CheckURL string `json:"check_url" required:"true"`
 
+	// CommitStatus reports the result of the CLA check as a status of the head commit of the pull request
+	// besides the labels, for the repositories gating the merges by the statuses. It is the same as the block
+	// enforcement_level, and is kept for the existing configs
+	CommitStatus bool `json:"commit_status"`
+
+	// EnforcementLevel is how strictly the result of the CLA check is enforced, which lets the communities adopt it
+	// gradually. It is one of report (the comments only), label (the comments and the labels) and block (the
+	// comments, the labels and the commit status gating the merges). Default is label, or block if commit_status is set
+	EnforcementLevel string `json:"enforcement_level"`
+
+	// CommitStatusContext is the context of the commit status. Default is cla/check, or dco/check in the dco mode
+	CommitStatusContext string `json:"commit_status_context"`
+
+	// MergeGate also reports a pending commit status when the check can not decide, such as for the unknown
+	// contributors, so that the status is always present on the head commit and can be required by the branch
+	// protection. It implies the block enforcement_level
+	MergeGate bool `json:"merge_gate"`
+
+	// ShareSameHeadResult reuses the result of the other pull request of the repository at the same head sha,
+	// such as the stacked or duplicated ones, instead of querying the CLA server again, and keeps their labels
+	// in sync. The commits are fetched with their sha for it
+	ShareSameHeadResult bool `json:"share_same_head_result"`
+
+	// LoginCheckURL is the url of the CLA server checking the sign state by the login of the contributor on the
+	// code hosting platform, as https://**?login={{login}}, with the {{org}} and {{repo}} of the check_url.
+	// It is the fallback for the commits whose email is missing or in the email_domain_denylist, such as the
+	// noreply ones. They are unknown if it is empty
+	LoginCheckURL string `json:"login_check_url"`
+
+	// CheckURLs are the urls of the other CLA services checked after the check_url, such as the one of the
+	// corporation CLA besides the one of the individual CLA. The contributor signing any of them is signed
+	CheckURLs []string `json:"check_urls"`
+
 	// SignURL is the url used to sign the cla
 	SignURL string `json:"sign_url" required:"true"`
 
@@ -124,6 +550,118 @@ type repoConfig struct {
 
 	// FAQURL is the url of faq which is corresponding to the way of checking CLA
 	FAQURL string `json:"faq_url" required:"true"`
+
+	// Visibility restricts the config to the repositories with the visibility, public or private.
+	// The repositories are matched regardless of the visibility if it is empty.
+	Visibility string `json:"visibility"`
+
+	// DefaultBranches restricts the config to the repositories whose default branch is in the list.
+	// The repositories are matched regardless of the default branch if it is empty.
+	DefaultBranches []string `json:"default_branches"`
+
+	// IncludeArchived makes the config apply to the archived repositories, which are skipped by default.
+	IncludeArchived bool `json:"include_archived"`
+
+	// SignatureTrailer is the key of the commit trailer carrying the id of a CLA signature,
+	// such as `CLA` for the trailer `CLA: <signature-id>`. A contributor declaring a valid
+	// signature id is treated as signed. The check by trailer is disabled if it is empty.
+	SignatureTrailer string `json:"signature_trailer"`
+
+	// SignatureVerifyURL is the url used to verify the signature id declared in the commit trailer.
+	// The signature_id, email, org and repo are added to its query, and the CLA server must confirm that
+	// the signature id belongs to the email. It must be set when `signature_trailer` is set.
+	SignatureVerifyURL string `json:"signature_verify_url"`
+
+	// CommentCommandTrigger overrides the global comment_command_trigger for the repositories.
+	// Both of them can refer to the contact by the placeholders {{mailing_list}} and {{maintainer}}
+	CommentCommandTrigger string `json:"comment_command_trigger"`
+
+	// Contact is the escalation channel of the repositories for the questions about the CLA server
+	Contact repoContact `json:"contact"`
+
+	// CLAAdmins are the users and the teams authorized to run the `/cla cancel` and the `/check-cla --since`,
+	// besides those with the permission on the repository
+	CLAAdmins claAdmins `json:"cla_admins"`
+
+	// CLAVersion is the version of the text of the CLA, which is passed to the CLA server as the version in the
+	// query, so that only the contributors who signed this version are signed. When it is changed by a reload,
+	// the signed pull requests of the repository are checked again. It is not passed if it is empty
+	CLAVersion string `json:"cla_version"`
+
+	// ReplyToCommand replies to the commenter of the `/check-cla` and the `/cla recheck` with the outcome of
+	// the check and the link to the comment of the CLA result
+	ReplyToCommand bool `json:"reply_to_command"`
+
+	// ReactToCommand acknowledges the `/check-cla`, the `/cla recheck` and the `/cla cancel` with a reaction on
+	// the comment of the command, 👍 if the labels are not changed and 🚀 otherwise. The reply_to_command is
+	// then only posted when the labels are changed, to reduce the noise on the pull request
+	ReactToCommand bool `json:"react_to_command"`
+
+	// PreserveGuideComment keeps the sign guide with the comment_guide_resolved appended when the check passes,
+	// instead of replacing it with the comment of the passed check
+	PreserveGuideComment bool `json:"preserve_guide_comment"`
+
+	// AttributeBackportsToCommitter makes the cherry-picked and reverted commits attributed to
+	// their committers, who perform the backports, when checking CLA by the email of author.
+	AttributeBackportsToCommitter bool `json:"attribute_backports_to_committer"`
+
+	// ReviewerTrailers are the keys of the commit trailers recording the reviewers whose suggestions
+	// were applied, such as `Reviewed-by` and `Suggested-by`. The users in the trailers in the format
+	// `name <email>` are also required to sign the CLA. It is disabled if it is empty.
+	ReviewerTrailers []string `json:"reviewer_trailers"`
+
+	// MaxComments is the max number of the comments the bot posts on a pull request, which defends against
+	// flooding the pull request. The bot only updates the labels after it is reached. It is unlimited if it is 0.
+	MaxComments int `json:"max_comments"`
+
+	// CLACacheTTLSeconds is how long the signed result of the CLA server is reused for the same email,
+	// which saves the requests of the repeated checks. The result is not cached if it is 0.
+	CLACacheTTLSeconds int `json:"cla_cache_ttl_seconds"`
+
+	// BatchCheckURL is the url of the CLA server checking the sign states of all the emails of a pull request
+	// in one request, with the {{org}} and {{repo}} of the check_url. The emails are checked one by one by the
+	// check_url if it is empty.
+	BatchCheckURL string `json:"batch_check_url"`
+
+	// ExemptionFile is the path of the file in the repository listing the SHAs of the historical commits,
+	// one per line, whose authors are exempt from the CLA check. The lines starting with # are ignored.
+	// It is useful for the repositories importing history. The check of exemption is disabled if it is empty.
+	ExemptionFile string `json:"exemption_file"`
+
+	// ExemptionRef is the ref where the exemption_file is read, such as refs/notes/cla-exemptions.
+	// Default is the default branch of the repository
+	ExemptionRef string `json:"exemption_ref"`
+
+	// ExemptUsers are the accounts of the automation, such as dependabot or the release bot, whose commits
+	// are not checked. The author or the committer is matched by the check_by_committer
+	ExemptUsers []string `json:"exempt_users"`
+
+	// ExemptEmails are the emails of the automation whose commits are not checked, matched case-insensitively
+	ExemptEmails []string `json:"exempt_emails"`
+
+	// MailmapFile is the path of the mailmap file in the repository, such as .mailmap, which canonicalizes
+	// the emails of the commits before checking the CLA as git does. It is read from the base branch of the pull
+	// request, never from its head. It is disabled if it is empty
+	MailmapFile string `json:"mailmap_file"`
+
+	// CorporateCLAFile is the path of the yaml file on the host of the bot, mapping the companies which signed
+	// the corporate CLA of the community to the email domains and emails of their employees, who pass the CLA
+	// check without the check_url. The changes of the file are reloaded. It is disabled if it is empty
+	CorporateCLAFile string `json:"corporate_cla_file"`
+
+	// EmailDomainAllowlist are the email domains covered by a blanket corporate CLA, whose contributors are
+	// signed without querying the CLA server. A domain also matches its subdomains
+	EmailDomainAllowlist []string `json:"email_domain_allowlist"`
+
+	// EmailDomainDenylist are the email domains which can not identify a contributor, such as the noreply ones.
+	// Their contributors are never signed and are asked to commit with a valid email by comment_denied_email_domain.
+	// A domain also matches its subdomains, and it precedes the email_domain_allowlist
+	EmailDomainDenylist []string `json:"email_domain_denylist"`
+
+	// checkScope is set when a command overrides the CheckByCommitter for a single run
+	checkScope string
+	// since is set when a command checks only the commits after the commit of the sha for a single run
+	since string
 }
 
 // validateRepoConfig to check the repoConfig data's validation, returns an error if invalid
@@ -137,7 +675,170 @@ func (c *repoConfig) validateRepoConfig() error {
 		return err
 	}
 
-	return validateRequiredConfig(*c)
+	if c.Visibility != "" && c.Visibility != repoVisibilityPublic && c.Visibility != repoVisibilityPrivate {
+		return errors.New("the visibility must be one of public and private")
+	}
+
+	if err := c.validateEnforcementLevel(); err != nil {
+		return err
+	}
+
+	if err := c.CLALabelYesStyle.validate("cla_label_yes_style"); err != nil {
+		return err
+	}
+	if err := c.CLALabelNoStyle.validate("cla_label_no_style"); err != nil {
+		return err
+	}
+
+	if slices.Contains(c.CheckURLs, "") {
+		return errors.New("the check_urls can not contain an empty url")
+	}
+
+	if err := c.validateURLs(); err != nil {
+		return err
+	}
+
+	if slices.Contains(c.ExemptUsers, "") || slices.Contains(c.ExemptEmails, "") {
+		return errors.New("the exempt_users and exempt_emails can not contain an empty item")
+	}
+
+	if err := validateEmailDomains(c.EmailDomainAllowlist, c.EmailDomainDenylist); err != nil {
+		return err
+	}
+
+	if c.SignatureTrailer != "" && c.SignatureVerifyURL == "" {
+		return errors.New("the signature_verify_url must be set when the signature_trailer is set")
+	}
+
+	if c.MaxComments < 0 {
+		return errors.New("the max_comments can not be negative")
+	}
+
+	if err := c.CLAAdmins.validate(); err != nil {
+		return err
+	}
+
+	if c.CLACacheTTLSeconds < 0 {
+		return errors.New("the cla_cache_ttl_seconds can not be negative")
+	}
+
+	if c.Mode != "" && c.Mode != modeCLA && c.Mode != modeDCO {
+		return errors.New("the mode must be one of cla and dco")
+	}
+
+	if err := validatePolicy(c.Mode, c.Policy); err != nil {
+		return err
+	}
+
+	if missing := c.missingConfig(); len(missing) != 0 {
+		return errors.New("missing the follow config: " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// missingConfig lists the required config of the repoConfig which are not set.
+// The urls of the CLA server are not required by the dco policy
+func (c *repoConfig) missingConfig() []string {
+	missing := missingRequiredConfig(*c)
+	if c.policy() == policyDCO {
+		missing = slices.DeleteFunc(missing, func(v string) bool { return v == "check_url" || v == "sign_url" })
+	}
+	return missing
+}
+
+const (
+	checkScopeCommitters = "committers"
+	checkScopeAuthors    = "authors"
+)
+
+// withCheckScope returns a copy of the repoConfig which checks CLA by the emails of the scope
+func (c *repoConfig) withCheckScope(scope string) *repoConfig {
+	cnf := *c
+	cnf.checkScope = scope
+	cnf.CheckByCommitter = scope == checkScopeCommitters
+	return &cnf
+}
+
+// withSince returns a copy of the repoConfig which checks CLA by the commits after the commit of the sha only
+func (c *repoConfig) withSince(sha string) *repoConfig {
+	cnf := *c
+	cnf.since = sha
+	return &cnf
+}
+
+// matchRepoMetadata checks whether the metadata of the repository satisfies the conditions of the repoConfig
+func (c *repoConfig) matchRepoMetadata(meta *repoMetadata) bool {
+	if meta == nil {
+		return true
+	}
+
+	if meta.Archived && !c.IncludeArchived {
+		return false
+	}
+
+	if c.Visibility != "" && c.Visibility != meta.visibility() {
+		return false
+	}
+
+	return len(c.DefaultBranches) == 0 || slices.Contains(c.DefaultBranches, meta.DefaultBranch)
+}
+
+// checkURLs returns the check_url and the check_urls
+func (c *repoConfig) checkURLs() []string {
+	return append([]string{c.CheckURL}, c.CheckURLs...)
+}
+
+// shadowedEntry returns the org or org/repo listed by both c and the later item,
+// for which c always matches before the later item. It returns empty if there is none
+func (c *repoConfig) shadowedEntry(later *repoConfig) string {
+	if !c.coversMetadataOf(later) {
+		return ""
+	}
+
+	for _, entry := range later.Repos {
+		if !slices.Contains(c.Repos, entry) {
+			continue
+		}
+
+		// An org is shadowed only if c excludes none of the repositories the later item applies to
+		if !strings.Contains(entry, "/") && slices.ContainsFunc(c.ExcludedRepos, func(v string) bool {
+			return strings.HasPrefix(v, entry+"/") && !slices.Contains(later.ExcludedRepos, v)
+		}) {
+			continue
+		}
+
+		return entry
+	}
+
+	return ""
+}
+
+// coversMetadataOf checks whether c matches all the repository metadata the other item matches
+func (c *repoConfig) coversMetadataOf(other *repoConfig) bool {
+	if other.IncludeArchived && !c.IncludeArchived {
+		return false
+	}
+
+	if c.Visibility != "" && c.Visibility != other.Visibility {
+		return false
+	}
+
+	if len(c.DefaultBranches) == 0 {
+		return true
+	}
+
+	return len(other.DefaultBranches) != 0 && !slices.ContainsFunc(other.DefaultBranches, func(v string) bool {
+		return !slices.Contains(c.DefaultBranches, v)
+	})
+}
+
+// repoContact is the escalation channel of the repositories
+type repoContact struct {
+	// MailingList is the mailing list of the community
+	MailingList string `json:"mailing_list"`
+
+	// Maintainer is the handle of the maintainer to contact, such as @someone
+	Maintainer string `json:"maintainer"`
 }
 
 type litePRCommiter struct {
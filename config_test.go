@@ -15,6 +15,7 @@ package main
 
 import (
 	"errors"
+	"github.com/opensourceways/server-common-lib/config"
 	"github.com/opensourceways/server-common-lib/utils"
 	"github.com/stretchr/testify/assert"
 	"os"
@@ -159,3 +160,129 @@ retry:
 	t.Log(path + " not found")
 	return ""
 }
+
+func TestGetMatchedRepoConfig(t *testing.T) {
+	cnf := &configuration{
+		ConfigItems: []repoConfig{
+			{CLALabelYes: "private", Visibility: repoVisibilityPrivate},
+			{CLALabelYes: "master", DefaultBranches: []string{"master"}},
+			{CLALabelYes: "archived", IncludeArchived: true},
+		},
+	}
+	for i := range cnf.ConfigItems {
+		cnf.ConfigItems[i].Repos = []string{"org1"}
+	}
+
+	testCases := []struct {
+		desc string
+		in   *repoMetadata
+		out  string
+	}{
+		{"metadata is unknown", nil, "private"},
+		{"private repository", &repoMetadata{Private: true}, "private"},
+		{"public repository on master", &repoMetadata{DefaultBranch: "master"}, "master"},
+		{"public repository on main", &repoMetadata{DefaultBranch: "main"}, "archived"},
+		{"archived repository", &repoMetadata{Private: true, Archived: true, DefaultBranch: "master"}, "archived"},
+	}
+	for i := range testCases {
+		t.Run(testCases[i].desc, func(t *testing.T) {
+			got := cnf.getMatchedRepoConfig("org1", "repo1", testCases[i].in)
+			assert.Equal(t, testCases[i].out, got.CLALabelYes)
+		})
+	}
+
+	cnf.ConfigItems = cnf.ConfigItems[:2]
+	assert.Equal(t, (*repoConfig)(nil), cnf.getMatchedRepoConfig("org1", "repo1", &repoMetadata{Archived: true}))
+}
+
+func TestListMissingConfig(t *testing.T) {
+	cnf := &configuration{}
+	err := utils.LoadFromYaml(findTestdata(t, configYaml), cnf)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ([]string)(nil), cnf.listMissingConfig())
+
+	cnf.ConfigItems[0].CheckURL = ""
+	cnf.CommunityName = ""
+	assert.Equal(t, []string{"config_items[0].check_url", "community_name"}, cnf.listMissingConfig())
+}
+
+func TestGetMatchedRepoConfigPrecedence(t *testing.T) {
+	cnf := &configuration{
+		ConfigItems: []repoConfig{
+			{RepoFilter: config.RepoFilter{Repos: []string{"org1"}}, CLALabelYes: "org"},
+			{RepoFilter: config.RepoFilter{Repos: []string{"org1/repo1"}}, CLALabelYes: "repo"},
+		},
+	}
+
+	assert.Equal(t, "repo", cnf.getMatchedRepoConfig("org1", "repo1", nil).CLALabelYes)
+	assert.Equal(t, "org", cnf.getMatchedRepoConfig("org1", "repo2", nil).CLALabelYes)
+	assert.Equal(t, []string{"config_items[1] takes precedence over config_items[0] for org1/repo1 " +
+		"though it is listed later, since it lists the org/repo"}, cnf.precedenceWarnings())
+
+	// nothing changes for the org/repo listed first, or excluded by the item of the org
+	cnf.ConfigItems[0], cnf.ConfigItems[1] = cnf.ConfigItems[1], cnf.ConfigItems[0]
+	assert.Empty(t, cnf.precedenceWarnings())
+	cnf.ConfigItems[0], cnf.ConfigItems[1] = cnf.ConfigItems[1], cnf.ConfigItems[0]
+	cnf.ConfigItems[0].ExcludedRepos = []string{"org1/repo1"}
+	assert.Empty(t, cnf.precedenceWarnings())
+}
+
+func TestValidateOverlappedItems(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		items []repoConfig
+		err   string
+	}{
+		{
+			desc: "the same org",
+			items: []repoConfig{
+				{RepoFilter: config.RepoFilter{Repos: []string{"org1"}}},
+				{RepoFilter: config.RepoFilter{Repos: []string{"org2", "org1"}}},
+			},
+			err: "config_items[1] is never used for org1, since config_items[0] lists it " +
+				"and matches first, narrow the latter by its conditions or merge them",
+		},
+		{
+			desc: "the same org/repo",
+			items: []repoConfig{
+				{RepoFilter: config.RepoFilter{Repos: []string{"org1/repo1"}}, DefaultBranches: []string{"master", "main"}},
+				{RepoFilter: config.RepoFilter{Repos: []string{"org1/repo1"}}, DefaultBranches: []string{"main"}},
+			},
+			err: "config_items[1] is never used for org1/repo1, since config_items[0] lists it " +
+				"and matches first, narrow the latter by its conditions or merge them",
+		},
+		{
+			desc: "the org and its repo",
+			items: []repoConfig{
+				{RepoFilter: config.RepoFilter{Repos: []string{"org1"}}},
+				{RepoFilter: config.RepoFilter{Repos: []string{"org1/repo1"}}},
+			},
+		},
+		{
+			desc: "narrowed by the metadata",
+			items: []repoConfig{
+				{RepoFilter: config.RepoFilter{Repos: []string{"org1"}}, Visibility: repoVisibilityPrivate},
+				{RepoFilter: config.RepoFilter{Repos: []string{"org1"}}, DefaultBranches: []string{"master"}},
+				{RepoFilter: config.RepoFilter{Repos: []string{"org1"}}, IncludeArchived: true},
+			},
+		},
+		{
+			desc: "narrowed by the excluded repos",
+			items: []repoConfig{
+				{RepoFilter: config.RepoFilter{Repos: []string{"org1"}, ExcludedRepos: []string{"org1/repo1"}}},
+				{RepoFilter: config.RepoFilter{Repos: []string{"org1"}}},
+			},
+		},
+	}
+	for i := range testCases {
+		t.Run(testCases[i].desc, func(t *testing.T) {
+			cnf := &configuration{ConfigItems: testCases[i].items}
+			err := cnf.validateOverlappedItems()
+			if testCases[i].err == "" {
+				assert.Equal(t, nil, err)
+			} else {
+				assert.EqualError(t, err, testCases[i].err)
+			}
+		})
+	}
+}
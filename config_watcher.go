@@ -0,0 +1,252 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/opensourceways/server-common-lib/utils"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// configStatusPath serves the version and hash of the active configuration
+	configStatusPath = "/config/status"
+	// configReloadInterval is the interval to check whether the configmap file is changed
+	configReloadInterval = 10 * time.Second
+)
+
+// configStatus describes the active configuration and the last rejected reload
+type configStatus struct {
+	Version      int       `json:"version"`
+	Hash         string    `json:"hash"`
+	LoadedAt     time.Time `json:"loaded_at"`
+	CheckedAt    time.Time `json:"checked_at"`
+	RejectedHash string    `json:"rejected_hash,omitempty"`
+	RejectedAt   time.Time `json:"rejected_at,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	// Preflight is the report of the preflight of the last reload, applied or rejected
+	Preflight *preflightReport `json:"preflight,omitempty"`
+}
+
+// configWatcher reloads the configmap file when it is changed. The new configuration is validated and then
+// swapped in as a whole, so that an event is never handled with a partial one. A reload failing the validation
+// is rejected, and the previous configuration is kept. The fields read at the startup, such as the cla_cache_size,
+// the recheck_rate_per_minute, the error_budget, the cla_webhook and the rescan_interval_minutes, take effect
+// after a restart only
+type configWatcher struct {
+	path    string
+	current atomic.Pointer[configuration]
+
+	mu     sync.Mutex
+	status configStatus
+
+	// check runs on the validated configuration before it is applied, and the reload is rejected if it fails
+	check func(old, c *configuration) error
+	// preflight checks the changed configuration against the live repositories before it is applied
+	preflight func(old, c *configuration) *preflightReport
+	// applied runs after the configuration is applied, with the previous one
+	applied func(old, c *configuration)
+
+	now func() time.Time
+	log *logrus.Entry
+}
+
+func hashConfig(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newConfigWatcher watches the configmap file from which the configuration c is loaded
+func newConfigWatcher(path string, c *configuration, logger *logrus.Entry) (*configWatcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &configWatcher{path: path, now: time.Now, log: logger}
+	w.current.Store(c)
+	now := w.now()
+	w.status = configStatus{Version: 1, Hash: hashConfig(data), LoadedAt: now, CheckedAt: now}
+	return w, nil
+}
+
+func (w *configWatcher) get() *configuration {
+	return w.current.Load()
+}
+
+func (w *configWatcher) getStatus() configStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.status
+}
+
+// reload applies the configmap file if it is changed. It returns whether a new configuration is applied
+func (w *configWatcher) reload() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.status.CheckedAt = w.now()
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		w.reject("", fmt.Errorf("read the configmap file: %w", err))
+		return false
+	}
+	hash := hashConfig(data)
+	if hash == w.status.Hash || hash == w.status.RejectedHash {
+		return false
+	}
+
+	c := new(configuration)
+	if err = utils.LoadFromYaml(w.path, c); err == nil {
+		err = c.Validate()
+	}
+	if err == nil && w.check != nil {
+		err = w.check(w.get(), c)
+	}
+	if err == nil && w.preflight != nil && c.preflightMode() != preflightOff {
+		report := w.preflight(w.get(), c)
+		w.status.Preflight = report
+		if err = report.err(); err != nil && c.preflightMode() == preflightWarn {
+			w.log.WithError(err).Warning("apply the configuration in spite of the preflight")
+			err = nil
+		}
+	}
+	if err != nil {
+		w.reject(hash, err)
+		return false
+	}
+
+	old := w.get()
+	w.current.Store(c)
+	w.status.Version++
+	w.status.Hash, w.status.LoadedAt = hash, w.status.CheckedAt
+	w.status.RejectedHash, w.status.RejectedAt, w.status.LastError = "", time.Time{}, ""
+	if w.applied != nil {
+		w.applied(old, c)
+	}
+	w.log.Infof("reload the configuration of version %d from %s", w.status.Version, w.path)
+	return true
+}
+
+// reject records the failed reload. The same content is not reloaded again until it is changed
+func (w *configWatcher) reject(hash string, err error) {
+	w.log.WithError(err).Error("reject the reload of the configuration, keep the previous one")
+	w.status.RejectedHash, w.status.RejectedAt, w.status.LastError = hash, w.status.CheckedAt, err.Error()
+}
+
+// start checks the configmap file periodically in the background
+func (w *configWatcher) start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			w.reload()
+		}
+	}()
+}
+
+// config returns the active configuration, which may be replaced by a reload between two calls.
+// It is the initial configuration if the configmap file is not watched. The handling of a pull request
+// uses bot.prConfig instead
+func (bot *robot) config() *configuration {
+	if bot.watcher != nil {
+		return bot.watcher.get()
+	}
+	return bot.cnf
+}
+
+// prConfig returns the configuration the pull request is handled with. It is the active configuration when it is
+// called the first time for the pull request, and the same one after, in spite of the reloads. It is the active
+// configuration if the pr is nil
+func (bot *robot) prConfig(pr *prSnapshot) *configuration {
+	if pr == nil {
+		return bot.config()
+	}
+	if pr.cnf == nil {
+		pr.cnf = bot.config()
+	}
+	return pr.cnf
+}
+
+// checkReloadedConfig rejects the configuration referring to the templates which do not exist, or to
+// the corporate CLA mapping files which can not be loaded
+func (bot *robot) checkReloadedConfig(_, c *configuration) error {
+	if field, err := bot.corporateCLA.load(c); err != nil {
+		return fmt.Errorf("load the %s failed: %w", field, err)
+	}
+
+	refs := c.templateRefs()
+	if len(refs) == 0 {
+		return nil
+	}
+	if bot.templates == nil {
+		return fmt.Errorf("the templates-path is not set, but the config refers to the templates by %v",
+			missingTemplates(refs, nil))
+	}
+	if missing := bot.templates.missing(refs); len(missing) != 0 {
+		return fmt.Errorf("the templates referred to by %v are missing", missing)
+	}
+	return nil
+}
+
+// watchConfig reloads the configuration of the bot when the configmap file is changed
+func watchConfig(bot *robot, path string, logger *logrus.Entry) {
+	logPrecedenceWarnings(bot.cnf, logger)
+	w, err := newConfigWatcher(path, bot.cnf, logger)
+	if err != nil {
+		logger.WithError(err).Error("the configuration is not reloaded because the configmap file can not be read")
+		return
+	}
+
+	w.check = bot.checkReloadedConfig
+	w.preflight = bot.preflight
+	w.applied = func(old, c *configuration) {
+		logPrecedenceWarnings(c, logger)
+		if bot.templates != nil {
+			bot.templates.setRefs(c.templateRefs())
+		}
+		bot.recheckCLAVersionChanges(old, c, logger)
+	}
+	bot.watcher = w
+	w.start(configReloadInterval)
+}
+
+// logPrecedenceWarnings warns of the items whose precedence differs from the earlier releases
+func logPrecedenceWarnings(c *configuration, logger *logrus.Entry) {
+	for _, w := range c.precedenceWarnings() {
+		logger.Warning(w)
+	}
+}
+
+// registerConfigStatusHandler serves the status of the active configuration, which has no secret
+func registerConfigStatusHandler(mux *http.ServeMux, bot *robot) {
+	mux.HandleFunc(configStatusPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		if bot.watcher == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "the configuration is not watched"})
+			return
+		}
+		writeJSON(w, http.StatusOK, bot.watcher.getStatus())
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	data, err := os.ReadFile(findTestdata(t, configYaml))
+	assert.Equal(t, nil, err)
+	path := filepath.Join(t.TempDir(), configYaml)
+	assert.Equal(t, nil, os.WriteFile(path, data, 0o600))
+
+	initial := new(configuration)
+	bot := &robot{cnf: initial}
+	assert.Equal(t, initial, bot.config())
+	mux := http.NewServeMux()
+	registerConfigStatusHandler(mux, bot)
+	status := func() (int, configStatus) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, configStatusPath, nil))
+		var s configStatus
+		_ = json.Unmarshal(rec.Body.Bytes(), &s)
+		return rec.Code, s
+	}
+	code, _ := status()
+	assert.Equal(t, http.StatusNotFound, code)
+
+	w, err := newConfigWatcher(path, initial, framework.NewLogger())
+	assert.Equal(t, nil, err)
+	w.check = bot.checkReloadedConfig
+	bot.watcher = w
+
+	// the unchanged file is not reloaded
+	assert.Equal(t, false, w.reload())
+	code, s := status()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, s.Version)
+	assert.Equal(t, hashConfig(data), s.Hash)
+
+	// the new repository is applied without restart
+	pr := newPRSnapshot(nil, org, repo, number)
+	handling := bot.prConfig(pr)
+	changed := strings.Replace(string(data), "      - org2\n", "      - org2\n      - org3\n", 1)
+	assert.Equal(t, nil, os.WriteFile(path, []byte(changed), 0o600))
+	assert.Equal(t, true, w.reload())
+	assert.NotEqual(t, nil, bot.getRepoConfig("org3", "repo3"))
+	// the pull request being handled keeps the configuration it started with
+	assert.Equal(t, true, handling == bot.prConfig(pr))
+	assert.Equal(t, false, handling == bot.config())
+	_, s = status()
+	assert.Equal(t, 2, s.Version)
+	assert.Equal(t, hashConfig([]byte(changed)), s.Hash)
+
+	// the invalid configuration is rejected, and the previous one is kept
+	invalid := strings.Replace(changed, "check_url: http://localhost:7003/cla\n", "", 1)
+	assert.Equal(t, nil, os.WriteFile(path, []byte(invalid), 0o600))
+	assert.Equal(t, false, w.reload())
+	assert.NotEqual(t, nil, bot.getRepoConfig("org3", "repo3"))
+	_, s = status()
+	assert.Equal(t, 2, s.Version)
+	assert.Equal(t, hashConfig([]byte(invalid)), s.RejectedHash)
+	assert.NotEqual(t, "", s.LastError)
+
+	// the configuration referring to the templates is rejected without the templates
+	withTemplate := strings.Replace(changed, "comment_pr_no_commits: ", "comment_pr_no_commits: template:no_commits\nx: ", 1)
+	assert.Equal(t, nil, os.WriteFile(path, []byte(withTemplate), 0o600))
+	assert.Equal(t, false, w.reload())
+	_, s = status()
+	assert.Contains(t, s.LastError, "comment_pr_no_commits")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, configStatusPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
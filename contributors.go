@@ -0,0 +1,26 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import "time"
+
+// markFirstSigned records the milestone of the users passing the CLA check the first time in the organization,
+// and returns them. No one is returned without the state store, which tells the first pass
+func (bot *robot) markFirstSigned(pr *prSnapshot, signedUsers []string) []string {
+	if bot.store == nil || len(signedUsers) == 0 {
+		return nil
+	}
+
+	return bot.store.markFirstSigned(pr.org, signedUsers, pr.key(), time.Now().UTC())
+}
@@ -0,0 +1,70 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestContributorMilestones(t *testing.T) {
+	store := newMemoryStateStore()
+	seen := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	store.seeContributors(org, []string{"u1", "u2"}, seen)
+	store.seeContributors(org, []string{"u1"}, seen.Add(time.Hour))
+
+	assert.Equal(t, []string{"u1"}, store.markFirstSigned(org, []string{"u1"}, "org1/repo1/1", seen.Add(time.Hour)))
+	// the first pass is thanked once, and it is per organization
+	assert.Equal(t, []string{"u2", "u3"}, store.markFirstSigned(org, []string{"u1", "u2", "u3"}, "org1/repo2/1", seen))
+	assert.Equal(t, []string{"u1"}, store.markFirstSigned("org2", []string{"u1"}, "org2/repo1/1", seen))
+
+	snapshot := store.exportSnapshot()
+	assert.Equal(t, 4, len(snapshot.Contributors))
+	assert.Equal(t, contributorState{
+		Org: org, User: "u1", FirstSeen: seen, FirstSigned: seen.Add(time.Hour), FirstSignedPR: "org1/repo1/1",
+	}, snapshot.Contributors[0])
+
+	another := newMemoryStateStore()
+	assert.Equal(t, nil, another.importSnapshot(snapshot))
+	assert.Equal(t, 0, len(another.markFirstSigned(org, []string{"u1", "u2"}, "org1/repo1/2", seen)))
+
+	snapshot.Contributors = append(snapshot.Contributors, contributorState{Org: org})
+	assert.NotEqual(t, nil, another.importSnapshot(snapshot))
+}
+
+func TestThankFirstSigned(t *testing.T) {
+	mc := &mockClient{successfulAddPRLabels: true, successfulCreatePRComment: true}
+	bot := &robot{
+		cli: mc,
+		cnf: &configuration{
+			CommentAllSigned: "signed", CommentFirstSigned: "welcome %s", UserMarkFormat: "@{{c}}", PlaceholderCommitter: "{{c}}",
+		},
+		store: newMemoryStateStore(),
+	}
+	repoCnf := &repoConfig{CLALabelYes: labelYes, CLALabelNo: labelNo}
+
+	bot.passCLASignature(newPRSnapshot(mc, org, repo, number), []string{"u1"}, nil, repoCnf)
+	assert.Equal(t, withResultMarker(resultKindAllSigned, "signed  \n\nwelcome @u1"), mc.comment)
+
+	bot.passCLASignature(newPRSnapshot(mc, org, repo, "2"), []string{"u1"}, nil, repoCnf)
+	assert.Equal(t, withResultMarker(resultKindAllSigned, "signed"), mc.comment)
+
+	// no one is thanked while the label can not be added, they are thanked once it is
+	mc.successfulAddPRLabels = false
+	bot.passCLASignature(newPRSnapshot(mc, org, repo, "3"), []string{"u2"}, nil, repoCnf)
+	mc.successfulAddPRLabels = true
+	bot.passCLASignature(newPRSnapshot(mc, org, repo, "3"), []string{"u2"}, nil, repoCnf)
+	assert.Equal(t, withResultMarker(resultKindAllSigned, "signed  \n\nwelcome @u2"), mc.comment)
+}
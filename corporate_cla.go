@@ -0,0 +1,268 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/opensourceways/server-common-lib/utils"
+	"github.com/sirupsen/logrus"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// claTypeCorporate is the CLA type of the contributors matching the corporate CLA mapping
+	claTypeCorporate = "corporate"
+	// corporateCLAReloadInterval is the min interval to check whether the corporate CLA mapping file is changed
+	corporateCLAReloadInterval = 10 * time.Second
+	// defaultCommentCorporateSigned is used when the comment_corporate_signed is not configured
+	defaultCommentCorporateSigned = "%s passed the CLA check under the corporate CLA of %s."
+)
+
+// corporateCLACompany is a company which signed the corporate CLA, covering its employees by the email domains
+// and the explicit emails
+type corporateCLACompany struct {
+	Name    string   `json:"name"`
+	Domains []string `json:"domains,omitempty"`
+	Emails  []string `json:"emails,omitempty"`
+}
+
+// corporateCLAMapping is the content of the corporate CLA mapping file
+type corporateCLAMapping struct {
+	Companies []corporateCLACompany `json:"companies"`
+}
+
+func (m *corporateCLAMapping) validate() error {
+	domains, emails := map[string]string{}, map[string]string{}
+	for i := range m.Companies {
+		c := &m.Companies[i]
+		if c.Name == "" {
+			return fmt.Errorf("the name of the company %d is missing", i)
+		}
+		if len(c.Domains) == 0 && len(c.Emails) == 0 {
+			return errors.New("the company " + c.Name + " has neither domains nor emails")
+		}
+
+		for _, domain := range c.Domains {
+			if domain == "" || strings.ContainsAny(domain, "@ ") {
+				return fmt.Errorf("the email domain %q of the company %s is invalid", domain, c.Name)
+			}
+			if other, ok := domains[strings.ToLower(domain)]; ok {
+				return fmt.Errorf("the email domain %s is mapped to both %s and %s", domain, other, c.Name)
+			}
+			domains[strings.ToLower(domain)] = c.Name
+		}
+		for _, email := range c.Emails {
+			if !strings.Contains(email, "@") {
+				return fmt.Errorf("the email %q of the company %s is invalid", email, c.Name)
+			}
+			if other, ok := emails[strings.ToLower(email)]; ok {
+				return fmt.Errorf("the email %s is mapped to both %s and %s", email, other, c.Name)
+			}
+			emails[strings.ToLower(email)] = c.Name
+		}
+	}
+	return nil
+}
+
+// lookup returns the company covering the email. The explicit emails take precedence over the domains
+func (m *corporateCLAMapping) lookup(email string) (string, bool) {
+	for i := range m.Companies {
+		for _, v := range m.Companies[i].Emails {
+			if strings.EqualFold(v, email) {
+				return m.Companies[i].Name, true
+			}
+		}
+	}
+	for i := range m.Companies {
+		if matchEmailDomain(email, m.Companies[i].Domains) {
+			return m.Companies[i].Name, true
+		}
+	}
+	return "", false
+}
+
+// readCorporateCLAMapping reads the mapping file, and returns the fingerprint of the file to detect the changes
+func readCorporateCLAMapping(path string) (*corporateCLAMapping, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	m := &corporateCLAMapping{}
+	if err = utils.LoadFromYaml(path, m); err != nil {
+		return nil, "", fmt.Errorf("load the corporate CLA mapping file %s failed: %w", path, err)
+	}
+	if err = m.validate(); err != nil {
+		return nil, "", fmt.Errorf("invalid corporate CLA mapping in %s: %w", path, err)
+	}
+	return m, fmt.Sprintf("%s@%d", path, info.ModTime().UnixNano()), nil
+}
+
+// corporateCLAStore holds the corporate CLA mapping, and reloads it when the file is changed. An invalid
+// reload is rejected, and the previous mapping is kept. All the methods are safe on a nil corporateCLAStore,
+// which maps no email
+type corporateCLAStore struct {
+	mu          sync.Mutex
+	path        string
+	mapping     *corporateCLAMapping
+	fingerprint string
+	checkedAt   time.Time
+	now         func() time.Time
+	log         *logrus.Entry
+}
+
+func newCorporateCLAStore(path string, logger *logrus.Entry) (*corporateCLAStore, error) {
+	mapping, fingerprint, err := readCorporateCLAMapping(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &corporateCLAStore{
+		path:        path,
+		mapping:     mapping,
+		fingerprint: fingerprint,
+		checkedAt:   time.Now(),
+		now:         time.Now,
+		log:         logger,
+	}, nil
+}
+
+// lookup returns the company whose corporate CLA covers the email
+func (s *corporateCLAStore) lookup(email string) (string, bool) {
+	if s == nil || email == "" {
+		return "", false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.now().Sub(s.checkedAt) >= corporateCLAReloadInterval {
+		s.checkedAt = s.now()
+		s.reload()
+	}
+	return s.mapping.lookup(email)
+}
+
+func (s *corporateCLAStore) reload() {
+	mapping, fingerprint, err := readCorporateCLAMapping(s.path)
+	if err != nil {
+		s.log.WithError(err).Error("reload the corporate CLA mapping failed, keep the previous one")
+		return
+	}
+	if fingerprint == s.fingerprint {
+		return
+	}
+
+	s.mapping, s.fingerprint = mapping, fingerprint
+	s.log.Infof("reload %d companies of the corporate CLA from %s", len(mapping.Companies), s.path)
+}
+
+// corporateCLAStores holds the corporate CLA mappings of the config items, keyed by the corporate_cla_file, so
+// that a company is mapped only for the communities whose CLA it signed. All the methods are safe on a nil
+// corporateCLAStores, which maps no email
+type corporateCLAStores struct {
+	mu     sync.Mutex
+	stores map[string]*corporateCLAStore
+	log    *logrus.Entry
+}
+
+func newCorporateCLAStores(logger *logrus.Entry) *corporateCLAStores {
+	return &corporateCLAStores{stores: map[string]*corporateCLAStore{}, log: logger}
+}
+
+// load loads the corporate_cla_file of each config item which is not loaded yet. It returns the field of the
+// config item whose file can not be loaded
+func (s *corporateCLAStores) load(c *configuration) (string, error) {
+	for i := range c.ConfigItems {
+		path := c.ConfigItems[i].CorporateCLAFile
+		if path == "" {
+			continue
+		}
+		field := fmt.Sprintf("config_items[%d].corporate_cla_file", i)
+		if s == nil {
+			return field, errors.New("the corporate CLA mappings are not supported")
+		}
+
+		s.mu.Lock()
+		_, ok := s.stores[path]
+		s.mu.Unlock()
+		if ok {
+			continue
+		}
+
+		store, err := newCorporateCLAStore(path, s.log)
+		if err != nil {
+			return field, err
+		}
+		s.mu.Lock()
+		s.stores[path] = store
+		s.mu.Unlock()
+	}
+	return "", nil
+}
+
+// lookup returns the company whose corporate CLA in the mapping file covers the email
+func (s *corporateCLAStores) lookup(path, email string) (string, bool) {
+	if s == nil || path == "" {
+		return "", false
+	}
+
+	s.mu.Lock()
+	store := s.stores[path]
+	s.mu.Unlock()
+	return store.lookup(email)
+}
+
+// addCorporateUser records the user signed under the corporate CLA of the company, for the comment of the CLA pass
+func (pr *prSnapshot) addCorporateUser(user, company string) {
+	if pr.corporateUsers == nil {
+		pr.corporateUsers = map[string]string{}
+	}
+	pr.corporateUsers[user] = company
+}
+
+// corporateSigned renders the lines naming the companies whose corporate CLA covers the signed users
+func (r *commentRenderer) corporateSigned(signedUsers []string) string {
+	if r.pr == nil || len(r.pr.corporateUsers) == 0 {
+		return ""
+	}
+
+	users := map[string][]string{}
+	for _, user := range signedUsers {
+		if company, ok := r.pr.corporateUsers[user]; ok {
+			users[company] = append(users[company], user)
+		}
+	}
+	companies := make([]string, 0, len(users))
+	for company := range users {
+		companies = append(companies, company)
+	}
+	sort.Strings(companies)
+
+	format := r.format(r.cnf.CommentCorporateSigned, defaultCommentCorporateSigned)
+	var b strings.Builder
+	for _, company := range companies {
+		data := r.data(nil)
+		data.Users, data.Company = r.userMarks(users[company]), escapeMarkdownName(company)
+		b.WriteString("  \n\n" + r.render(format, data, func(s string) string {
+			return fmt.Sprintf(s, data.Users, data.Company)
+		}))
+	}
+	return b.String()
+}
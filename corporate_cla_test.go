@@ -0,0 +1,142 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCorporateCLAMapping(t *testing.T) {
+	m := &corporateCLAMapping{Companies: []corporateCLACompany{
+		{Name: "Corp", Domains: []string{"corp.com"}},
+		{Name: "Partner", Domains: []string{"partner.com"}, Emails: []string{"Jane@corp.com"}},
+	}}
+	assert.Equal(t, nil, m.validate())
+
+	company, ok := m.lookup("u1@dev.corp.com")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "Corp", company)
+	// the explicit emails take precedence over the domains
+	company, _ = m.lookup("jane@corp.com")
+	assert.Equal(t, "Partner", company)
+	_, ok = m.lookup("u1@example.com")
+	assert.Equal(t, false, ok)
+
+	assert.Error(t, (&corporateCLAMapping{Companies: []corporateCLACompany{{Domains: []string{"corp.com"}}}}).validate())
+	assert.Error(t, (&corporateCLAMapping{Companies: []corporateCLACompany{{Name: "Corp"}}}).validate())
+	assert.Error(t, (&corporateCLAMapping{Companies: []corporateCLACompany{
+		{Name: "Corp", Domains: []string{"@corp.com"}},
+	}}).validate())
+	assert.Error(t, (&corporateCLAMapping{Companies: []corporateCLACompany{
+		{Name: "Corp", Domains: []string{"corp.com"}}, {Name: "Other", Domains: []string{"CORP.com"}},
+	}}).validate())
+}
+
+func TestCorporateCLAStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corporate.yaml")
+	write := func(content string) {
+		assert.Equal(t, nil, os.WriteFile(path, []byte(content), 0600))
+	}
+	write("companies:\n- name: Corp\n  domains: [corp.com]\n")
+
+	store, err := newCorporateCLAStore(path, framework.NewLogger())
+	assert.Equal(t, nil, err)
+	company, ok := store.lookup("u1@corp.com")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "Corp", company)
+
+	// the change is reloaded after the interval
+	now := time.Now()
+	store.now = func() time.Time { return now.Add(time.Minute) }
+	write("companies:\n- name: Corp Inc.\n  domains: [corp.com]\n")
+	assert.Equal(t, nil, os.Chtimes(path, now.Add(time.Second), now.Add(time.Second)))
+	company, _ = store.lookup("u1@corp.com")
+	assert.Equal(t, "Corp Inc.", company)
+
+	// the invalid reload is rejected
+	store.now = func() time.Time { return now.Add(time.Hour) }
+	write("companies:\n- domains: [corp.com]\n")
+	assert.Equal(t, nil, os.Chtimes(path, now.Add(time.Minute), now.Add(time.Minute)))
+	company, _ = store.lookup("u1@corp.com")
+	assert.Equal(t, "Corp Inc.", company)
+
+	_, err = newCorporateCLAStore(path, framework.NewLogger())
+	assert.NotEqual(t, nil, err)
+
+	var nilStore *corporateCLAStore
+	_, ok = nilStore.lookup("u1@corp.com")
+	assert.Equal(t, false, ok)
+}
+
+func TestCheckCLASignResultByCorporateCLA(t *testing.T) {
+	mc := &mockClient{successfulCheckCLASignature: true, CLAState: client.CLASignStateNo}
+	bot := &robot{cli: mc, cnf: &configuration{UserMarkFormat: "@ddd", PlaceholderCommitter: "ddd",
+		CommentAllSigned: "thanks ddd"}}
+	bot.corporateCLA = newCorporateCLAStores(framework.NewLogger())
+	bot.corporateCLA.stores["corporate.yaml"] = &corporateCLAStore{
+		mapping: &corporateCLAMapping{Companies: []corporateCLACompany{{Name: "Corp", Domains: []string{"corp.com"}}}},
+		now:     time.Now, checkedAt: time.Now(),
+	}
+
+	// the employees of the company are signed without querying the CLA server
+	pr := newPRSnapshot(mc, org, repo, number)
+	commits := []client.PRCommit{{AuthorName: "u1", AuthorEmail: "u1@corp.com"}}
+	allSigned, result := bot.checkCLASignResult(pr, commits, &repoConfig{CorporateCLAFile: "corporate.yaml"})
+	assert.Equal(t, true, allSigned)
+	assert.Equal(t, []string{"u1"}, result[0])
+	assert.Equal(t, "", mc.method)
+
+	comment := bot.renderer(pr).allSigned(result[0], nil, &repoConfig{})
+	assert.Equal(t, "thanks @u1  \n\n@u1 passed the CLA check under the corporate CLA of Corp.", comment)
+
+	// the mapping is not applied to the config item of another community
+	pr = newPRSnapshot(mc, org, repo, number)
+	allSigned, _ = bot.checkCLASignResult(pr, commits, &repoConfig{})
+	assert.Equal(t, false, allSigned)
+	assert.Equal(t, "CheckCLASignature", mc.method)
+}
+
+func TestCorporateCLAStores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corporate.yaml")
+	assert.Equal(t, nil, os.WriteFile(path, []byte("companies:\n- name: Corp\n  domains: [corp.com]\n"), 0600))
+
+	stores := newCorporateCLAStores(framework.NewLogger())
+	cnf := &configuration{ConfigItems: []repoConfig{{}, {CorporateCLAFile: path}}}
+	field, err := stores.load(cnf)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "", field)
+
+	company, ok := stores.lookup(path, "u1@corp.com")
+	assert.Equal(t, true, ok)
+	assert.Equal(t, "Corp", company)
+	_, ok = stores.lookup("", "u1@corp.com")
+	assert.Equal(t, false, ok)
+
+	cnf.ConfigItems[0].CorporateCLAFile = filepath.Join(t.TempDir(), "missing.yaml")
+	field, err = stores.load(cnf)
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, "config_items[0].corporate_cla_file", field)
+
+	var nilStores *corporateCLAStores
+	_, err = nilStores.load(cnf)
+	assert.NotEqual(t, nil, err)
+	_, ok = nilStores.lookup(path, "u1@corp.com")
+	assert.Equal(t, false, ok)
+}
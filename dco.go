@@ -0,0 +1,141 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+)
+
+// the modes of checking the contributions
+const (
+	modeCLA = "cla"
+	modeDCO = "dco"
+)
+
+const (
+	// the titles of the comments of the DCO result, by which the comment of the previous check is replaced
+	dcoTitleUnsigned = "### DCO Sign-off Guide"
+	dcoTitleSigned   = "### DCO Sign-off Pass"
+
+	// defaultCommentDCOUnsigned is used when the comment_dco_unsigned is not configured
+	defaultCommentDCOUnsigned = "Thanks for your pull request. The following commits are not signed off by their " +
+		"authors as the [Developer Certificate of Origin](https://developercertificate.org) requires:  \n\n%s\n" +
+		"Please sign them off by `git rebase --signoff HEAD~<the number of the commits>` with the email of the " +
+		"author, and push them again. See the [FAQs](%s) for more."
+	// defaultCommentDCOSigned is used when the comment_dco_signed is not configured
+	defaultCommentDCOSigned = "%s, thanks for your pull request. All the commits are signed off. :wave:"
+
+	defaultDCOStatusContext          = "dco/check"
+	commitStatusDescriptionSignedOff = "All the commits are signed off"
+	commitStatusDescriptionNotSigned = "Some commits are not signed off"
+)
+
+// dcoFailure is a commit which is not signed off by its author
+type dcoFailure struct {
+	sha    string
+	author string
+	email  string
+}
+
+// isSignedOff checks whether the commit message has a `Signed-off-by` trailer of the email
+func isSignedOff(message, email string) bool {
+	return email != "" && slices.ContainsFunc(commitTrailerValues(message, "Signed-off-by"), func(v string) bool {
+		_, signer, ok := parseTrailerIdentity(v)
+		return ok && strings.EqualFold(signer, email)
+	})
+}
+
+// checkSignOffs checks the sign-off of every commit except those of the exempt accounts.
+// The authors of the commits not signed off are unsigned
+func checkSignOffs(messages []prCommitMessage, repoCnf *repoConfig) (signedUsers, unsignedUsers []string,
+	failures []dcoFailure) {
+	for i := range messages {
+		author, email := messages[i].AuthorName, messages[i].AuthorEmail
+		if repoCnf.isExemptContributor(author, email) {
+			continue
+		}
+
+		if !isSignedOff(messages[i].Message, email) {
+			failures = append(failures, dcoFailure{sha: messages[i].SHA, author: author, email: email})
+			if !slices.Contains(unsignedUsers, author) {
+				unsignedUsers = append(unsignedUsers, author)
+			}
+		} else if !slices.Contains(signedUsers, author) {
+			signedUsers = append(signedUsers, author)
+		}
+	}
+	return
+}
+
+// checkDCO checks that every commit of the pull request is signed off by its author instead of querying the
+// CLA server, and applies the result as the CLA result is applied. The commits of the exempt accounts are skipped
+func (bot *robot) checkDCO(pr *prSnapshot, repoCnf *repoConfig, logger *logrus.Entry) {
+	messages, success := pr.getCommitMessages()
+	if success && len(messages) == 0 {
+		success = bot.waitForLaggingCommits(pr, func() (int, bool) {
+			messages, success = pr.getCommitMessages()
+			return len(messages), success
+		})
+	}
+	if !success {
+		pr.stats.decision = decisionCommitsUnavailable
+		bot.createPRComment(pr, repoCnf, bot.commandTriggerComment(pr, repoCnf))
+		return
+	}
+
+	if len(messages) == 0 {
+		pr.stats.decision = decisionNoCommits
+		bot.createPRComment(pr, repoCnf, bot.renderer(pr).noCommits())
+		return
+	}
+
+	signedUsers, unsignedUsers, failures := checkSignOffs(messages, repoCnf)
+	allSigned := len(failures) == 0
+	signResult := [3][]string{signedUsers}
+	if !allSigned {
+		signResult = [3][]string{nil, unsignedUsers}
+	}
+	logger.Infof("%d of %d commits of %s are not signed off", len(failures), len(messages), pr.key())
+
+	pr.stats.decision, pr.stats.signResult = evaluationStatus(allSigned, signResult), signResult
+	prLabels, _ := pr.getLabels()
+	if allSigned {
+		bot.passDCO(pr, signedUsers, prLabels, repoCnf)
+	} else {
+		bot.waitDCO(pr, failures, prLabels, repoCnf)
+	}
+	bot.recordPRState(pr, allSigned, signResult)
+}
+
+func (bot *robot) passDCO(pr *prSnapshot, signedUsers, prLabels []string, repoCnf *repoConfig) {
+	r := bot.renderer(pr)
+	comment, post := r.updateLabelFailed(), bot.labelUpdateFailed
+	if bot.applyCLALabel(pr, prLabels, repoCnf, repoCnf.CLALabelYes, repoCnf.CLALabelNo) {
+		comment, post = r.dcoSigned(signedUsers), bot.replaceCLAResultComment
+	}
+	post(pr, repoCnf, comment)
+	bot.reportCommitStatus(pr, repoCnf, commitStatusSuccess, commitStatusDescriptionSignedOff)
+}
+
+func (bot *robot) waitDCO(pr *prSnapshot, failures []dcoFailure, prLabels []string, repoCnf *repoConfig) {
+	r := bot.renderer(pr)
+	comment, post := r.updateLabelFailed(), bot.labelUpdateFailed
+	if bot.applyCLALabel(pr, prLabels, repoCnf, repoCnf.CLALabelNo, repoCnf.CLALabelYes) {
+		comment, post = r.dcoUnsigned(failures, repoCnf), bot.replaceCLAResultComment
+	}
+	post(pr, repoCnf, comment)
+	bot.reportCommitStatus(pr, repoCnf, commitStatusFailure, commitStatusDescriptionNotSigned)
+}
@@ -0,0 +1,84 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIsSignedOff(t *testing.T) {
+	message := "fix the typo\n\nSigned-off-by: Jane Doe <Jane@example.com>\nReviewed-by: u2 <u2@example.com>"
+	assert.Equal(t, true, isSignedOff(message, "jane@example.com"))
+	assert.Equal(t, false, isSignedOff(message, "u2@example.com"))
+	assert.Equal(t, false, isSignedOff("fix the typo\n\nSigned-off-by: jane@example.com", "jane@example.com"))
+	assert.Equal(t, false, isSignedOff("Signed-off-by: Jane Doe <jane@example.com>", "jane@example.com"))
+	assert.Equal(t, false, isSignedOff(message, ""))
+}
+
+func TestCheckDCO(t *testing.T) {
+	signed := "feat\n\nSigned-off-by: u1 <u1@example.com>"
+	mc := &mockClient{
+		successfulGetPullRequestCommitMessages: true,
+		successfulAddPRLabels:                  true,
+		successfulRemovePRLabels:               true,
+		successfulCreatePRComment:              true,
+		successfulGetPullRequestLabels:         true,
+		labels:                                 []string{"dco-yes"},
+		commitMessages: []prCommitMessage{
+			{PRCommit: client.PRCommit{AuthorName: "u1", AuthorEmail: "u1@example.com"}, SHA: "abcdef123", Message: signed},
+			{PRCommit: client.PRCommit{AuthorName: "u2", AuthorEmail: "u2@example.com"}, SHA: "0123456789", Message: signed},
+			{PRCommit: client.PRCommit{AuthorName: "bot", AuthorEmail: "bot@example.com"}, SHA: "fedcba987", Message: "bump"},
+		},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{}, store: newMemoryStateStore()}
+	repoCnf := &repoConfig{Mode: modeDCO, CLALabelYes: "dco-yes", CLALabelNo: "dco-no", FAQURL: "https://dco/faq",
+		ExemptUsers: []string{"bot"}}
+
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf, logrus.NewEntry(logrus.New()))
+	assert.Equal(t, prStatusUnsigned, pr.stats.decision)
+	assert.Equal(t, []string{"dco-no"}, pr.stats.labelsAdded)
+	assert.Equal(t, []string{"dco-yes"}, pr.stats.labelsRemoved)
+	assert.Contains(t, mc.comment, dcoTitleUnsigned)
+	assert.Contains(t, mc.comment, "`0123456` by u2 (u***@example.com)")
+	assert.NotContains(t, mc.comment, "abcdef1")
+	assert.NotContains(t, mc.comment, "fedcba9")
+	assert.Contains(t, mc.comment, "https://dco/faq")
+	s, _ := bot.store.get(org, repo, number)
+	assert.Equal(t, []string{"u2"}, s.UnsignedUsers)
+
+	mc.commitMessages = mc.commitMessages[:1]
+	mc.labels = []string{"dco-no"}
+	pr = newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf, logrus.NewEntry(logrus.New()))
+	assert.Equal(t, prStatusSigned, pr.stats.decision)
+	assert.Equal(t, []string{"dco-yes"}, pr.stats.labelsAdded)
+	assert.Contains(t, mc.comment, dcoTitleSigned)
+}
+
+func TestValidateDCOMode(t *testing.T) {
+	repoCnf := repoConfig{Mode: modeDCO, CLALabelYes: "dco-yes", CLALabelNo: "dco-no", FAQURL: "https://dco/faq"}
+	repoCnf.Repos = []string{org}
+	assert.NoError(t, repoCnf.validateRepoConfig())
+	assert.Equal(t, defaultDCOStatusContext, repoCnf.commitStatusContext())
+
+	repoCnf.Mode = modeCLA
+	assert.Error(t, repoCnf.validateRepoConfig())
+
+	repoCnf.Mode = "sign-off"
+	assert.Error(t, repoCnf.validateRepoConfig())
+}
@@ -0,0 +1,86 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// the classes of the startup failures
+const (
+	diagnosticClassOptions  = "invalid_options"
+	diagnosticClassConfig   = "invalid_config"
+	diagnosticClassSecret   = "secret_unavailable"
+	diagnosticClassPlatform = "platform_unreachable"
+)
+
+// diagnosticExitCodes maps the class of a startup failure to the exit code of the process
+var diagnosticExitCodes = map[string]int{
+	diagnosticClassOptions:  2,
+	diagnosticClassConfig:   3,
+	diagnosticClassSecret:   4,
+	diagnosticClassPlatform: 5,
+}
+
+var diagnosticSuggestions = map[string][]string{
+	diagnosticClassOptions: {
+		"check the command line flags with --help",
+		"the config file must be given by an absolute path",
+	},
+	diagnosticClassConfig: {
+		"check that the config file exists and is valid yaml",
+		"set the fields listed in offending_fields",
+	},
+	diagnosticClassSecret: {
+		"check that the secret files are mounted and readable",
+		"the token file is deleted after being loaded unless --del-token=false",
+	},
+	diagnosticClassPlatform: {
+		"check that the token is valid and not expired",
+		"check the network connectivity to the code hosting platform",
+	},
+}
+
+// startupDiagnostic is the machine-readable description of a startup failure
+type startupDiagnostic struct {
+	Class           string    `json:"class"`
+	Message         string    `json:"message"`
+	OffendingFields []string  `json:"offending_fields,omitempty"`
+	Suggestions     []string  `json:"suggestions,omitempty"`
+	ExitCode        int       `json:"exit_code"`
+	Time            time.Time `json:"time"`
+}
+
+func newStartupDiagnostic(class string, err error, fields []string) *startupDiagnostic {
+	return &startupDiagnostic{
+		Class:           class,
+		Message:         err.Error(),
+		OffendingFields: fields,
+		Suggestions:     diagnosticSuggestions[class],
+		ExitCode:        diagnosticExitCodes[class],
+		Time:            time.Now().UTC(),
+	}
+}
+
+// write saves the diagnostic as json into the file of the path
+func (d *startupDiagnostic) write(path string) error {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0o644)
+}
@@ -0,0 +1,66 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// defaultCommentDeniedEmailDomain is used when the comment_denied_email_domain is not configured
+const defaultCommentDeniedEmailDomain = "The CLA can not be checked for %s, whose commits use an email " +
+	"which can not identify the contributor, such as a noreply one. Please amend the commits with the email " +
+	"used to sign the CLA, and push them again."
+
+// validateEmailDomains checks the domains of the email_domain_allowlist and the email_domain_denylist
+func validateEmailDomains(allowlist, denylist []string) error {
+	for _, domain := range append(slices.Clip(allowlist), denylist...) {
+		if domain == "" || strings.ContainsAny(domain, "@ ") {
+			return fmt.Errorf("the email domain %q of the email_domain_allowlist or email_domain_denylist is invalid", domain)
+		}
+	}
+
+	for _, domain := range allowlist {
+		if slices.ContainsFunc(denylist, func(v string) bool { return strings.EqualFold(v, domain) }) {
+			return errors.New("the email domain " + domain + " can not be in both the allowlist and the denylist")
+		}
+	}
+	return nil
+}
+
+// matchEmailDomain checks whether the domain of the email is one of the domains or their subdomains
+func matchEmailDomain(email string, domains []string) bool {
+	i := strings.LastIndexByte(email, '@')
+	if i < 0 || len(domains) == 0 {
+		return false
+	}
+
+	host := strings.ToLower(email[i+1:])
+	return slices.ContainsFunc(domains, func(domain string) bool {
+		domain = strings.ToLower(domain)
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	})
+}
+
+// emailDomainDenied checks whether the email is in the email_domain_denylist
+func (c *repoConfig) emailDomainDenied(email string) bool {
+	return matchEmailDomain(email, c.EmailDomainDenylist)
+}
+
+// emailDomainAllowed checks whether the email is in the email_domain_allowlist and not denied
+func (c *repoConfig) emailDomainAllowed(email string) bool {
+	return !c.emailDomainDenied(email) && matchEmailDomain(email, c.EmailDomainAllowlist)
+}
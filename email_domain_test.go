@@ -0,0 +1,106 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMatchEmailDomain(t *testing.T) {
+	domains := []string{"corp.com", "users.noreply.example.com"}
+	assert.Equal(t, true, matchEmailDomain("jane@corp.com", domains))
+	assert.Equal(t, true, matchEmailDomain("jane@Dev.CORP.com", domains))
+	assert.Equal(t, true, matchEmailDomain("12+jane@users.noreply.example.com", domains))
+	assert.Equal(t, false, matchEmailDomain("jane@notcorp.com", domains))
+	assert.Equal(t, false, matchEmailDomain("jane@example.com", domains))
+	assert.Equal(t, false, matchEmailDomain("corp.com", domains))
+	assert.Equal(t, false, matchEmailDomain("jane@corp.com", nil))
+
+	repoCnf := &repoConfig{EmailDomainAllowlist: []string{"corp.com"}, EmailDomainDenylist: []string{"bot.corp.com"}}
+	assert.Equal(t, true, repoCnf.emailDomainAllowed("jane@corp.com"))
+	assert.Equal(t, false, repoCnf.emailDomainAllowed("ci@bot.corp.com"))
+	assert.Equal(t, true, repoCnf.emailDomainDenied("ci@bot.corp.com"))
+}
+
+func TestValidateEmailDomains(t *testing.T) {
+	assert.NoError(t, validateEmailDomains([]string{"corp.com"}, []string{"noreply.com"}))
+	assert.Error(t, validateEmailDomains([]string{""}, nil))
+	assert.Error(t, validateEmailDomains(nil, []string{"@noreply.com"}))
+	assert.Error(t, validateEmailDomains([]string{"corp.com"}, []string{"CORP.com"}))
+}
+
+func TestCheckCLASignResultByEmailDomain(t *testing.T) {
+	mc := &mockClient{successfulCheckCLASignature: true, CLAState: client.CLASignStateNo, successfulCreatePRComment: true}
+	bot := &robot{cli: mc, cnf: &configuration{
+		UserMarkFormat:        "@ddd",
+		PlaceholderCommitter:  "ddd",
+		CommentCommandTrigger: "check again",
+	}}
+	repoCnf := &repoConfig{
+		EmailDomainAllowlist: []string{"corp.com"},
+		EmailDomainDenylist:  []string{"noreply.com"},
+	}
+
+	// the contributors of the allowed domain are signed without querying the CLA server
+	commits := []client.PRCommit{{AuthorName: "u1", AuthorEmail: "u1@corp.com"}}
+	allSigned, result := bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	assert.Equal(t, true, allSigned)
+	assert.Equal(t, []string{"u1"}, result[0])
+	assert.Equal(t, "", mc.method)
+
+	// the contributors of the denied domain are unknown, and are asked to use a valid email
+	commits = append(commits, client.PRCommit{AuthorName: "u2", AuthorEmail: "u2@noreply.com"})
+	allSigned, result = bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	assert.Equal(t, false, allSigned)
+	assert.Equal(t, []string{"u2"}, result[2])
+	assert.Equal(t, "CreatePRComment", mc.method)
+	assert.Contains(t, mc.comment, "@u2")
+	assert.NotEqual(t, "check again", mc.comment)
+}
+
+func TestPostUnknownStateCommentsByReason(t *testing.T) {
+	mc := &commentRecordingClient{mockClient: &mockClient{CLAState: client.CLASignStateUnknown}}
+	bot := &robot{cli: mc, cnf: &configuration{
+		UserMarkFormat:        "@ddd",
+		PlaceholderCommitter:  "ddd",
+		CommentCommandTrigger: "check again",
+	}}
+	repoCnf := &repoConfig{
+		EmailDomainDenylist: []string{"noreply.com"},
+		LitePRCommitter:     litePRCommiter{Email: "lite@gitcode.com"},
+	}
+
+	// each reason is explained once, and only the failure of the CLA server asks to check again
+	commits := []client.PRCommit{
+		{AuthorName: "u1", AuthorEmail: ""},
+		{AuthorName: "u2", AuthorEmail: "lite@gitcode.com"},
+		{AuthorName: "u3", AuthorEmail: "u3@noreply.com"},
+	}
+	allSigned, result := bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	assert.Equal(t, false, allSigned)
+	assert.Equal(t, []string{"u1", "u2", "u3"}, result[2])
+	assert.Equal(t, 3, len(mc.posted))
+	assert.Contains(t, mc.posted[0], "@u3")
+	assert.Contains(t, mc.posted[1], "@u1, whose commits have no email")
+	assert.Contains(t, mc.posted[2], "@u2, whose commits were made on the web page")
+
+	mc.posted = nil
+	commits = append(commits, client.PRCommit{AuthorName: "u4", AuthorEmail: "u4@example.com"})
+	_, result = bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	assert.Equal(t, []string{"u1", "u2", "u3", "u4"}, result[2])
+	assert.Equal(t, 4, len(mc.posted))
+	assert.Contains(t, mc.posted[3], "check again")
+}
@@ -0,0 +1,202 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"mime"
+	"net"
+	"net/smtp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultEmailNotificationIntervalDays is used when the interval_days of the email_notification is not configured
+	defaultEmailNotificationIntervalDays = 7
+	// defaultEmailNotificationMaxPerPR is used when the max_per_pr of the email_notification is not configured
+	defaultEmailNotificationMaxPerPR = 3
+
+	defaultEmailNotificationSubject = "Please sign the CLA for your contribution"
+	// defaultEmailNotificationBody is used when the body of the email_notification is not configured
+	defaultEmailNotificationBody = "Hello,\n\nYour commits in the pull request %s can not be merged, because the " +
+		"email of the commits has not signed the CLA. Please sign it at %s, and comment /check-cla on the pull " +
+		"request after signing.\n\nIf you have any questions, please see %s.\n"
+)
+
+// emailNotificationConfig emails the unsigned contributors in addition to the comment on the pull request,
+// which they may not see if they commit with another account. Only the emails of the commits linked to an account
+// of the platform are emailed, since the others may be anyone's. It is read at the startup, and the password
+// of the smtp server is read from the file of the flag smtp-password-path
+type emailNotificationConfig struct {
+	// SMTPAddr is the host:port of the smtp server. The emails are not sent if it is empty
+	SMTPAddr string `json:"smtp_addr"`
+	// Username authenticates to the smtp server with the password if it is set
+	Username string `json:"username"`
+	// From is the sender of the emails
+	From string `json:"from"`
+	// IntervalDays is the min interval to email the same contributor again. Default is 7
+	IntervalDays int `json:"interval_days"`
+	// MaxPerPR is the max number of the emails sent for a pull request. Default is 3
+	MaxPerPR int `json:"max_per_pr"`
+	// Subject is the subject of the emails. A default subject is used if it is empty
+	Subject string `json:"subject"`
+	// Body is the text of the emails. It has one %s for the pull request, one %s for the sign url and one %s
+	// for the faq url. A default body is used if it is empty
+	Body string `json:"body"`
+}
+
+func (c *emailNotificationConfig) validate() error {
+	if c.SMTPAddr == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(c.SMTPAddr); err != nil {
+		return fmt.Errorf("the smtp_addr of the email_notification must be host:port: %w", err)
+	}
+	if c.From == "" {
+		return errors.New("the from of the email_notification is required by the smtp_addr")
+	}
+	if c.IntervalDays < 0 || c.MaxPerPR < 0 {
+		return errors.New("the interval_days and max_per_pr of the email_notification can not be negative")
+	}
+	return nil
+}
+
+func (c *emailNotificationConfig) maxPerPR() int {
+	if c.MaxPerPR == 0 {
+		return defaultEmailNotificationMaxPerPR
+	}
+	return c.MaxPerPR
+}
+
+func (c *emailNotificationConfig) interval() time.Duration {
+	if c.IntervalDays == 0 {
+		return defaultEmailNotificationIntervalDays * 24 * time.Hour
+	}
+	return time.Duration(c.IntervalDays) * 24 * time.Hour
+}
+
+// emailNotifier emails the unsigned contributors. All the methods are safe on a nil emailNotifier, which is disabled
+type emailNotifier struct {
+	cfg  emailNotificationConfig
+	auth smtp.Auth
+	log  *logrus.Entry
+
+	now func() time.Time
+	// send is smtp.SendMail, and run sends the email in the background
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	run  func(f func())
+}
+
+func newEmailNotifier(cfg *emailNotificationConfig, password []byte, logger *logrus.Entry) *emailNotifier {
+	if cfg.SMTPAddr == "" {
+		return nil
+	}
+
+	n := &emailNotifier{
+		cfg:  *cfg,
+		log:  logger,
+		now:  time.Now,
+		send: smtp.SendMail,
+		run:  func(f func()) { go f() },
+	}
+	if cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(cfg.SMTPAddr)
+		n.auth = smtp.PlainAuth("", cfg.Username, string(password), host)
+	}
+	return n
+}
+
+func (n *emailNotifier) message(to, pr, signURL, faqURL string) []byte {
+	subject, body := n.cfg.Subject, n.cfg.Body
+	if subject == "" {
+		subject = defaultEmailNotificationSubject
+	}
+	if body == "" {
+		body = defaultEmailNotificationBody
+	}
+
+	headers := []string{
+		"From: " + n.cfg.From,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+	}
+	body = strings.ReplaceAll(fmt.Sprintf(body, pr, signURL, faqURL), "\n", "\r\n")
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body)
+}
+
+// notify emails each of the contributors in the background
+func (n *emailNotifier) notify(emails []string, pr, signURL, faqURL string) {
+	for _, email := range emails {
+		msg := n.message(email, pr, signURL, faqURL)
+		n.run(func() {
+			if err := n.send(n.cfg.SMTPAddr, n.auth, n.cfg.From, []string{email}, msg); err != nil {
+				n.log.WithError(err).Errorf("failed to email the unsigned contributor %s", maskEmailText(email))
+			}
+		})
+	}
+}
+
+// linkedUnsignedEmails lists the unsigned emails of the commits linked to an account of the platform
+func linkedUnsignedEmails(pr *prSnapshot) []string {
+	var emails []string
+	for user, email := range pr.unsignedUserEmails {
+		if user != "" && !slices.Contains(emails, email) {
+			emails = append(emails, email)
+		}
+	}
+	slices.Sort(emails)
+	return emails
+}
+
+// notifyUnsignedContributors emails the unsigned contributors of the evaluation with the sign url and faq url.
+// Each email is notified at most once per interval, and at most max_per_pr emails are sent for a pull request,
+// both of which are kept in the state store
+func (bot *robot) notifyUnsignedContributors(pr *prSnapshot, repoCnf *repoConfig) {
+	n := bot.notifier
+	if n == nil || bot.store == nil {
+		return
+	}
+
+	s, _ := bot.store.get(pr.org, pr.repo, pr.number)
+	var emails []string
+	for _, email := range linkedUnsignedEmails(pr) {
+		if s.EmailsSent+len(emails) >= n.cfg.maxPerPR() {
+			break
+		}
+		// the email of a commit is not trusted as a header of the message
+		if email != "" && !strings.ContainsAny(email, "\r\n") &&
+			bot.store.markEmailed(email, n.now(), n.cfg.interval()) {
+			emails = append(emails, email)
+		}
+	}
+	if len(emails) == 0 {
+		return
+	}
+
+	s.Org, s.Repo, s.Number = pr.org, pr.repo, pr.number
+	s.EmailsSent += len(emails)
+	bot.store.put(s)
+
+	link := pr.htmlURL
+	if link == "" {
+		link = pr.key()
+	}
+	n.notify(emails, link, repoCnf.SignURL, repoCnf.FAQURL)
+}
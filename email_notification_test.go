@@ -0,0 +1,83 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/stretchr/testify/assert"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailNotification(t *testing.T) {
+	cfg := &emailNotificationConfig{SMTPAddr: "smtp.example.com:587", Username: "bot", From: "cla@example.com",
+		MaxPerPR: 2}
+	n := newEmailNotifier(cfg, []byte("secret"), framework.NewLogger())
+	now := time.Now()
+	n.now = func() time.Time { return now }
+	n.run = func(f func()) { f() }
+	var sent []string
+	var msgs []string
+	n.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sent = append(sent, to...)
+		msgs = append(msgs, string(msg))
+		return nil
+	}
+
+	bot := &robot{notifier: n, store: newMemoryStateStore()}
+	pr := newPRSnapshot(nil, org, repo, number)
+	pr.addUnsignedUser("u1", "u1@example.com")
+	pr.addUnsignedUser("u2", "u2@example.com\r\nBcc: x@example.com")
+	// the commit not linked to an account is not emailed
+	pr.addUnsignedUser("", "unlinked@example.com")
+	repoCnf := &repoConfig{SignURL: "https://cla/sign", FAQURL: "https://cla/faq"}
+	bot.notifyUnsignedContributors(pr, repoCnf)
+	assert.Equal(t, []string{"u1@example.com"}, sent)
+	assert.Equal(t, true, strings.HasPrefix(msgs[0], "From: cla@example.com\r\nTo: u1@example.com\r\n"))
+	assert.Contains(t, msgs[0], "the pull request org1/repo1/1 can not be merged")
+	assert.Contains(t, msgs[0], "sign it at https://cla/sign")
+	assert.Contains(t, msgs[0], "please see https://cla/faq.\r\n")
+
+	// the contributor is not emailed again within the interval, which is kept in the state store
+	n.now = func() time.Time { return now.Add(6 * 24 * time.Hour) }
+	bot.notifyUnsignedContributors(pr, repoCnf)
+	assert.Equal(t, 1, len(sent))
+	assert.Contains(t, bot.store.exportSnapshot().Emailed, "u1@example.com")
+
+	n.now = func() time.Time { return now.Add(7 * 24 * time.Hour) }
+	bot.notifyUnsignedContributors(pr, repoCnf)
+	assert.Equal(t, 2, len(sent))
+
+	// the emails of the pull request are capped by the max_per_pr
+	n.now = func() time.Time { return now.Add(14 * 24 * time.Hour) }
+	bot.notifyUnsignedContributors(pr, repoCnf)
+	assert.Equal(t, 2, len(sent))
+	s, _ := bot.store.get(org, repo, number)
+	assert.Equal(t, 2, s.EmailsSent)
+
+	// the notification is disabled without the smtp_addr
+	assert.Nil(t, newEmailNotifier(&emailNotificationConfig{}, nil, framework.NewLogger()))
+	(&robot{}).notifyUnsignedContributors(pr, repoCnf)
+}
+
+func TestValidateEmailNotification(t *testing.T) {
+	assert.NoError(t, (&emailNotificationConfig{}).validate())
+	assert.NoError(t, (&emailNotificationConfig{SMTPAddr: "smtp:25", From: "cla@example.com"}).validate())
+	assert.ErrorContains(t, (&emailNotificationConfig{SMTPAddr: "smtp"}).validate(), "must be host:port")
+	assert.ErrorContains(t, (&emailNotificationConfig{SMTPAddr: "smtp:25"}).validate(), "the from of the email_notification")
+	assert.ErrorContains(t, (&emailNotificationConfig{SMTPAddr: "smtp:25", From: "cla@example.com", MaxPerPR: -1}).validate(),
+		"can not be negative")
+}
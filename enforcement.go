@@ -0,0 +1,66 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"slices"
+)
+
+// the enforcement levels of the result of the CLA check, each one enforces what the previous one does
+const (
+	enforcementReport = "report"
+	enforcementLabel  = "label"
+	enforcementBlock  = "block"
+)
+
+func (c *repoConfig) validateEnforcementLevel() error {
+	switch c.EnforcementLevel {
+	case "", enforcementBlock:
+		return nil
+	case enforcementReport, enforcementLabel:
+		if c.CommitStatus || c.MergeGate {
+			return errors.New("the commit_status and the merge_gate can only be set with the block enforcement_level")
+		}
+		return nil
+	}
+	return errors.New("the enforcement_level must be one of report, label and block")
+}
+
+// enforcementLevel returns the enforcement_level, which defaults to block if the commit_status or the merge_gate is set
+func (c *repoConfig) enforcementLevel() string {
+	if c.EnforcementLevel != "" {
+		return c.EnforcementLevel
+	}
+	if c.CommitStatus || c.MergeGate {
+		return enforcementBlock
+	}
+	return enforcementLabel
+}
+
+// enforces checks whether the enforcement_level of the repository enforces what the level does. It is the one
+// check all the writes of the CLA labels and the commit statuses go through
+func (c *repoConfig) enforces(level string) bool {
+	levels := []string{enforcementReport, enforcementLabel, enforcementBlock}
+	return slices.Index(levels, c.enforcementLevel()) >= slices.Index(levels, level)
+}
+
+// applyCLALabel replaces the CLA label of the other result by the one of the result. The labels are
+// left untouched under the report enforcement level. It returns false if adding the label failed
+func (bot *robot) applyCLALabel(pr *prSnapshot, prLabels []string, repoCnf *repoConfig, label, other string) bool {
+	if !bot.removeCLALabel(pr, prLabels, repoCnf, other) {
+		bot.labelUpdateFailed(pr, repoCnf, bot.renderer(pr).updateLabelFailed())
+	}
+	return bot.addCLALabel(pr, repoCnf, label)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEnforcementLevel(t *testing.T) {
+	assert.Equal(t, enforcementLabel, (&repoConfig{}).enforcementLevel())
+	assert.Equal(t, enforcementBlock, (&repoConfig{CommitStatus: true}).enforcementLevel())
+	assert.Equal(t, enforcementReport, (&repoConfig{EnforcementLevel: enforcementReport}).enforcementLevel())
+
+	assert.NoError(t, (&repoConfig{EnforcementLevel: enforcementBlock, CommitStatus: true}).validateEnforcementLevel())
+	assert.Error(t, (&repoConfig{EnforcementLevel: enforcementLabel, CommitStatus: true}).validateEnforcementLevel())
+	assert.Error(t, (&repoConfig{EnforcementLevel: "strict"}).validateEnforcementLevel())
+
+	assert.Equal(t, true, (&repoConfig{}).enforces(enforcementLabel))
+	assert.Equal(t, false, (&repoConfig{}).enforces(enforcementBlock))
+	assert.Equal(t, true, (&repoConfig{MergeGate: true}).enforces(enforcementBlock))
+	assert.Equal(t, false, (&repoConfig{EnforcementLevel: enforcementReport}).enforces(enforcementLabel))
+}
+
+func TestEnforcementLevelOfResult(t *testing.T) {
+	newBot := func() (*robot, *mockClient) {
+		mc := &mockClient{
+			successfulAddPRLabels:                  true,
+			successfulRemovePRLabels:               true,
+			successfulCreatePRComment:              true,
+			successfulCreateCommitStatus:           true,
+			successfulGetPullRequestCommitMessages: true,
+			commitMessages:                         []prCommitMessage{{SHA: "sha1"}},
+		}
+		return &robot{cli: mc, cnf: &configuration{CommentSomeNeedSign: "%s need to sign %s %s"}}, mc
+	}
+
+	// the report level only comments
+	bot, mc := newBot()
+	repoCnf := &repoConfig{CLALabelYes: labelYes, CLALabelNo: labelNo, EnforcementLevel: enforcementReport}
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.waitCLASignature(pr, [3][]string{1: {"u1"}}, []string{labelYes}, repoCnf)
+	assert.Empty(t, pr.stats.labelsAdded)
+	assert.Empty(t, pr.stats.labelsRemoved)
+	assert.Equal(t, "", pr.stats.commitStatus)
+	assert.Equal(t, withResultMarker(resultKindNeedSign, "u1 need to sign  "), mc.comment)
+
+	// nor are the labels created, removed by the watchdog or by the `/cla cancel`
+	bot, mc = newBot()
+	mc.successfulAddPRLabels, mc.successfulCreateRepoLabel = false, true
+	repoCnf.CreateCLALabels = true
+	pr = newPRSnapshot(mc, org, repo, number)
+	assert.Equal(t, true, bot.addCLALabel(pr, repoCnf, labelNo))
+	assert.Equal(t, true, bot.removeCLALabel(pr, []string{labelYes}, repoCnf, labelYes))
+	assert.Empty(t, mc.repoLabels)
+	assert.Empty(t, pr.stats.labelsRemoved)
+	assert.Equal(t, 0, pr.stats.writes)
+	repoCnf.CreateCLALabels = false
+
+	// the label level switches the labels
+	bot, mc = newBot()
+	repoCnf.EnforcementLevel = enforcementLabel
+	pr = newPRSnapshot(mc, org, repo, number)
+	bot.waitCLASignature(pr, [3][]string{1: {"u1"}}, []string{labelYes}, repoCnf)
+	assert.Equal(t, []string{labelNo}, pr.stats.labelsAdded)
+	assert.Equal(t, []string{labelYes}, pr.stats.labelsRemoved)
+	assert.Equal(t, "", pr.stats.commitStatus)
+
+	// the block level also reports the commit status
+	bot, mc = newBot()
+	repoCnf.EnforcementLevel = enforcementBlock
+	pr = newPRSnapshot(mc, org, repo, number)
+	bot.passCLASignature(pr, []string{"u1"}, []string{labelNo}, repoCnf)
+	assert.Equal(t, []string{labelYes}, pr.stats.labelsAdded)
+	assert.Equal(t, commitStatusSuccess, pr.stats.commitStatus)
+}
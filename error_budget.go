@@ -0,0 +1,418 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// the platforms called by the bot, whose errors are tracked separately
+const (
+	platformCodeHosting = "code-hosting"
+	platformCLAServer   = "cla-server"
+)
+
+const (
+	// defaultErrorBudgetWindowSeconds is used when the window_seconds of the error_budget is not configured
+	defaultErrorBudgetWindowSeconds = 300
+	// defaultErrorBudgetMinRequests is used when the min_requests of the error_budget is not configured
+	defaultErrorBudgetMinRequests = 20
+	// errorBudgetBuckets is the number of the buckets the window is divided into, the window slides by one bucket
+	errorBudgetBuckets = 10
+)
+
+var (
+	apiRequestsDesc = prometheus.NewDesc(
+		"cla_api_window_requests",
+		"The number of the api calls in the sliding window of the error budget, by the platform and the endpoint.",
+		[]string{"platform", "endpoint"}, nil,
+	)
+	apiErrorRateDesc = prometheus.NewDesc(
+		"cla_api_window_error_rate",
+		"The error rate of the api calls in the sliding window of the error budget, by the platform and the endpoint.",
+		[]string{"platform", "endpoint"}, nil,
+	)
+	apiDegradedDesc = prometheus.NewDesc(
+		"cla_api_degraded",
+		"Whether the non-essential operations are paused because of the error rate of the platform.",
+		[]string{"platform"}, nil,
+	)
+)
+
+// errorBudgetConfig tracks the error rates of the api calls over a sliding window, and optionally pauses
+// the non-essential operations, such as deleting the outdated comments and the rechecks of organizations,
+// while the error rate of the code hosting platform is too high
+type errorBudgetConfig struct {
+	// WindowSeconds is the length of the sliding window. Default is 300
+	WindowSeconds int `json:"window_seconds"`
+	// MinRequests is the min number of the calls in the window before the error rate is considered. Default is 20
+	MinRequests int `json:"min_requests"`
+	// DegradeErrorRate is the error rate in (0, 1] of the platform at which the non-essential operations
+	// are paused. The degradation is disabled if it is 0
+	DegradeErrorRate float64 `json:"degrade_error_rate"`
+}
+
+func (c *errorBudgetConfig) validate() error {
+	if c.WindowSeconds < 0 || c.MinRequests < 0 {
+		return errors.New("the window_seconds and min_requests of the error_budget can not be negative")
+	}
+	if c.DegradeErrorRate < 0 || c.DegradeErrorRate > 1 {
+		return errors.New("the degrade_error_rate of the error_budget must be in [0, 1]")
+	}
+	return nil
+}
+
+func (c *errorBudgetConfig) window() time.Duration {
+	if c.WindowSeconds == 0 {
+		return defaultErrorBudgetWindowSeconds * time.Second
+	}
+	return time.Duration(c.WindowSeconds) * time.Second
+}
+
+func (c *errorBudgetConfig) minRequests() int {
+	if c.MinRequests == 0 {
+		return defaultErrorBudgetMinRequests
+	}
+	return c.MinRequests
+}
+
+// apiErrorRate is the error rate of an endpoint in the sliding window
+type apiErrorRate struct {
+	Platform  string  `json:"platform"`
+	Endpoint  string  `json:"endpoint"`
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+type errorBucket struct {
+	index    int64
+	requests int
+	errors   int
+}
+
+type errorWindowKey struct {
+	platform string
+	endpoint string
+}
+
+// apiErrorBudget counts the api calls and their errors per endpoint in a sliding window.
+// All the methods are safe on a nil budget, which tracks nothing
+type apiErrorBudget struct {
+	cfg *errorBudgetConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	windows map[errorWindowKey]*[errorBudgetBuckets]errorBucket
+}
+
+func newAPIErrorBudget(cfg *errorBudgetConfig) *apiErrorBudget {
+	return &apiErrorBudget{cfg: cfg, now: time.Now, windows: map[errorWindowKey]*[errorBudgetBuckets]errorBucket{}}
+}
+
+// bucketIndex returns the index of the bucket the current time falls in
+func (b *apiErrorBudget) bucketIndex() int64 {
+	return b.now().UnixNano() / int64(b.cfg.window()/errorBudgetBuckets)
+}
+
+func (b *apiErrorBudget) record(platform, endpoint string, success bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := errorWindowKey{platform: platform, endpoint: endpoint}
+	w := b.windows[key]
+	if w == nil {
+		w = new([errorBudgetBuckets]errorBucket)
+		b.windows[key] = w
+	}
+
+	index := b.bucketIndex()
+	bucket := &w[index%errorBudgetBuckets]
+	if bucket.index != index {
+		*bucket = errorBucket{index: index}
+	}
+	bucket.requests++
+	if !success {
+		bucket.errors++
+	}
+}
+
+// snapshot returns the error rates of the endpoints called in the window, sorted by the platform and the endpoint
+func (b *apiErrorBudget) snapshot() []apiErrorRate {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	index := b.bucketIndex()
+	var rates []apiErrorRate
+	for key, w := range b.windows {
+		rate := apiErrorRate{Platform: key.platform, Endpoint: key.endpoint}
+		for i := range w {
+			if w[i].index > index-errorBudgetBuckets {
+				rate.Requests += w[i].requests
+				rate.Errors += w[i].errors
+			}
+		}
+		if rate.Requests == 0 {
+			continue
+		}
+		rate.ErrorRate = float64(rate.Errors) / float64(rate.Requests)
+		rates = append(rates, rate)
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Platform != rates[j].Platform {
+			return rates[i].Platform < rates[j].Platform
+		}
+		return rates[i].Endpoint < rates[j].Endpoint
+	})
+	return rates
+}
+
+// degradedPlatforms lists the platforms whose error rate in the window reaches the degrade_error_rate
+func (b *apiErrorBudget) degradedPlatforms() []string {
+	if b == nil || b.cfg.DegradeErrorRate == 0 {
+		return nil
+	}
+
+	requests, errs := map[string]int{}, map[string]int{}
+	for _, rate := range b.snapshot() {
+		requests[rate.Platform] += rate.Requests
+		errs[rate.Platform] += rate.Errors
+	}
+
+	var platforms []string
+	for platform, n := range requests {
+		if n >= b.cfg.minRequests() && float64(errs[platform])/float64(n) >= b.cfg.DegradeErrorRate {
+			platforms = append(platforms, platform)
+		}
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+// degraded checks whether the non-essential operations on the platform should be paused
+func (b *apiErrorBudget) degraded(platform string) bool {
+	return slices.Contains(b.degradedPlatforms(), platform)
+}
+
+func (b *apiErrorBudget) Describe(ch chan<- *prometheus.Desc) {
+	ch <- apiRequestsDesc
+	ch <- apiErrorRateDesc
+	ch <- apiDegradedDesc
+}
+
+func (b *apiErrorBudget) Collect(ch chan<- prometheus.Metric) {
+	for _, rate := range b.snapshot() {
+		ch <- prometheus.MustNewConstMetric(apiRequestsDesc, prometheus.GaugeValue,
+			float64(rate.Requests), rate.Platform, rate.Endpoint)
+		ch <- prometheus.MustNewConstMetric(apiErrorRateDesc, prometheus.GaugeValue,
+			rate.ErrorRate, rate.Platform, rate.Endpoint)
+	}
+	for _, platform := range b.degradedPlatforms() {
+		ch <- prometheus.MustNewConstMetric(apiDegradedDesc, prometheus.GaugeValue, 1, platform)
+	}
+}
+
+// errorBudgetClient records the results of the api calls of the wrapped client into the error budget.
+// GetRepoFileContent is not recorded, since it fails for the absent files as well
+type errorBudgetClient struct {
+	iClient
+	budget *apiErrorBudget
+}
+
+func wrapErrorBudgetClient(cli iClient, budget *apiErrorBudget) iClient {
+	return &errorBudgetClient{iClient: cli, budget: budget}
+}
+
+func (c *errorBudgetClient) CreatePRComment(org, repo, number, comment string) (string, bool) {
+	commentID, success := c.iClient.CreatePRComment(org, repo, number, comment)
+	c.budget.record(platformCodeHosting, "CreatePRComment", success)
+	return commentID, success
+}
+
+func (c *errorBudgetClient) GetPullRequestLabels(org, repo, number string) ([]string, bool) {
+	result, success := c.iClient.GetPullRequestLabels(org, repo, number)
+	c.budget.record(platformCodeHosting, "GetPullRequestLabels", success)
+	return result, success
+}
+
+func (c *errorBudgetClient) AddPRLabels(org, repo, number string, labels []string) bool {
+	success := c.iClient.AddPRLabels(org, repo, number, labels)
+	c.budget.record(platformCodeHosting, "AddPRLabels", success)
+	return success
+}
+
+func (c *errorBudgetClient) RemovePRLabels(org, repo, number string, labels []string) bool {
+	success := c.iClient.RemovePRLabels(org, repo, number, labels)
+	c.budget.record(platformCodeHosting, "RemovePRLabels", success)
+	return success
+}
+
+func (c *errorBudgetClient) GetPullRequestCommits(org, repo, number string) ([]client.PRCommit, bool) {
+	result, success := c.iClient.GetPullRequestCommits(org, repo, number)
+	c.budget.record(platformCodeHosting, "GetPullRequestCommits", success)
+	return result, success
+}
+
+func (c *errorBudgetClient) ListPullRequestComments(org, repo, number string) ([]prComment, bool) {
+	result, success := c.iClient.ListPullRequestComments(org, repo, number)
+	c.budget.record(platformCodeHosting, "ListPullRequestComments", success)
+	return result, success
+}
+
+func (c *errorBudgetClient) DeletePRComment(org, repo, commentID string) bool {
+	success := c.iClient.DeletePRComment(org, repo, commentID)
+	c.budget.record(platformCodeHosting, "DeletePRComment", success)
+	return success
+}
+
+func (c *errorBudgetClient) UpdatePRComment(org, repo, commentID, comment string) bool {
+	success := c.iClient.UpdatePRComment(org, repo, commentID, comment)
+	c.budget.record(platformCodeHosting, "UpdatePRComment", success)
+	return success
+}
+
+func (c *errorBudgetClient) CheckCLASignature(urlStr string) (string, bool) {
+	signState, success := c.iClient.CheckCLASignature(urlStr)
+	c.budget.record(platformCLAServer, "CheckCLASignature", success)
+	return signState, success
+}
+
+func (c *errorBudgetClient) CheckCLASignatures(urlStr string, emails []string) (map[string]string, bool) {
+	signStates, success := c.iClient.CheckCLASignatures(urlStr, emails)
+	c.budget.record(platformCLAServer, "CheckCLASignatures", success)
+	return signStates, success
+}
+
+func (c *errorBudgetClient) CheckCLASignatureDetail(urlStr string) (string, string, bool) {
+	signState, claType, success := c.iClient.CheckCLASignatureDetail(urlStr)
+	c.budget.record(platformCLAServer, "CheckCLASignatureDetail", success)
+	return signState, claType, success
+}
+
+func (c *errorBudgetClient) GetPullRequestCommitMessages(org, repo, number string) ([]prCommitMessage, bool) {
+	result, success := c.iClient.GetPullRequestCommitMessages(org, repo, number)
+	c.budget.record(platformCodeHosting, "GetPullRequestCommitMessages", success)
+	return result, success
+}
+
+func (c *errorBudgetClient) GetPullRequestChangedFileCount(org, repo, number string) (int, bool) {
+	count, success := c.iClient.GetPullRequestChangedFileCount(org, repo, number)
+	c.budget.record(platformCodeHosting, "GetPullRequestChangedFileCount", success)
+	return count, success
+}
+
+func (c *errorBudgetClient) ListOpenPullRequestsWithLabel(org, repo, label string) ([]string, bool) {
+	numbers, success := c.iClient.ListOpenPullRequestsWithLabel(org, repo, label)
+	c.budget.record(platformCodeHosting, "ListOpenPullRequestsWithLabel", success)
+	return numbers, success
+}
+
+func (c *errorBudgetClient) VerifyCLASignatureID(urlStr, email string) (string, bool) {
+	signState, success := c.iClient.VerifyCLASignatureID(urlStr, email)
+	c.budget.record(platformCLAServer, "VerifyCLASignatureID", success)
+	return signState, success
+}
+
+func (c *errorBudgetClient) GetRepoMetadata(org, repo string) (repoMetadata, bool) {
+	result, success := c.iClient.GetRepoMetadata(org, repo)
+	c.budget.record(platformCodeHosting, "GetRepoMetadata", success)
+	return result, success
+}
+
+func (c *errorBudgetClient) GetRepoPushPermission(org, repo string) (bool, bool) {
+	pass, success := c.iClient.GetRepoPushPermission(org, repo)
+	c.budget.record(platformCodeHosting, "GetRepoPushPermission", success)
+	return pass, success
+}
+
+func (c *errorBudgetClient) CreateIssue(org, repo, title, body string) bool {
+	success := c.iClient.CreateIssue(org, repo, title, body)
+	c.budget.record(platformCodeHosting, "CreateIssue", success)
+	return success
+}
+
+func (c *errorBudgetClient) GetCommitVerificationStatus(org, repo, sha string) (bool, bool) {
+	verified, success := c.iClient.GetCommitVerificationStatus(org, repo, sha)
+	c.budget.record(platformCodeHosting, "GetCommitVerificationStatus", success)
+	return verified, success
+}
+
+func (c *errorBudgetClient) GetUserEmail(login string) (string, bool) {
+	email, success := c.iClient.GetUserEmail(login)
+	c.budget.record(platformCodeHosting, "GetUserEmail", success)
+	return email, success
+}
+
+func (c *errorBudgetClient) GetBotLogin() (string, bool) {
+	login, success := c.iClient.GetBotLogin()
+	c.budget.record(platformCodeHosting, "GetBotLogin", success)
+	return login, success
+}
+
+func (c *errorBudgetClient) AddCommentReaction(org, repo, commentID, reaction string) bool {
+	success := c.iClient.AddCommentReaction(org, repo, commentID, reaction)
+	c.budget.record(platformCodeHosting, "AddCommentReaction", success)
+	return success
+}
+
+func (c *errorBudgetClient) CreateRepoLabel(org, repo string, label repoLabel) bool {
+	success := c.iClient.CreateRepoLabel(org, repo, label)
+	c.budget.record(platformCodeHosting, "CreateRepoLabel", success)
+	return success
+}
+
+func (c *errorBudgetClient) CreateCommitStatus(org, repo, sha string, status commitStatus) bool {
+	success := c.iClient.CreateCommitStatus(org, repo, sha, status)
+	c.budget.record(platformCodeHosting, "CreateCommitStatus", success)
+	return success
+}
+
+func (c *errorBudgetClient) ListOrgRepos(org string) ([]string, bool) {
+	repos, success := c.iClient.ListOrgRepos(org)
+	c.budget.record(platformCodeHosting, "ListOrgRepos", success)
+	return repos, success
+}
+
+func (c *errorBudgetClient) ListTeamMembers(org, team string) ([]string, bool) {
+	logins, success := c.iClient.ListTeamMembers(org, team)
+	c.budget.record(platformCodeHosting, "ListTeamMembers", success)
+	return logins, success
+}
+
+func (c *errorBudgetClient) GetPullRequestGraph(urlStr, org, repo, number string) (prGraph, bool) {
+	result, success := c.iClient.GetPullRequestGraph(urlStr, org, repo, number)
+	c.budget.record(platformCodeHosting, "GetPullRequestGraph", success)
+	return result, success
+}
+
+func (c *errorBudgetClient) CheckPermission(org, repo, username string) (bool, bool) {
+	pass, success := c.iClient.CheckPermission(org, repo, username)
+	c.budget.record(platformCodeHosting, "CheckPermission", success)
+	return pass, success
+}
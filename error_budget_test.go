@@ -0,0 +1,78 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorBudget(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	budget := newAPIErrorBudget(&errorBudgetConfig{WindowSeconds: 100, MinRequests: 4, DegradeErrorRate: 0.5})
+	budget.now = func() time.Time { return now }
+
+	cli := wrapErrorBudgetClient(&mockClient{successfulAddPRLabels: true}, budget)
+	cli.AddPRLabels(org, repo, number, []string{"cla/yes"})
+	cli.RemovePRLabels(org, repo, number, []string{"cla/no"})
+	cli.CheckCLASignature("https://cla/check")
+	assert.Equal(t, []apiErrorRate{
+		{Platform: platformCLAServer, Endpoint: "CheckCLASignature", Requests: 1, Errors: 1, ErrorRate: 1},
+		{Platform: platformCodeHosting, Endpoint: "AddPRLabels", Requests: 1},
+		{Platform: platformCodeHosting, Endpoint: "RemovePRLabels", Requests: 1, Errors: 1, ErrorRate: 1},
+	}, budget.snapshot())
+	// Too few requests to consider the error rate
+	assert.Equal(t, false, budget.degraded(platformCodeHosting))
+
+	now = now.Add(50 * time.Second)
+	cli.RemovePRLabels(org, repo, number, []string{"cla/no"})
+	cli.RemovePRLabels(org, repo, number, []string{"cla/no"})
+	assert.Equal(t, true, budget.degraded(platformCodeHosting))
+	assert.Equal(t, false, budget.degraded(platformCLAServer))
+
+	// The calls of the first bucket slide out of the window
+	now = now.Add(55 * time.Second)
+	assert.Equal(t, []apiErrorRate{
+		{Platform: platformCodeHosting, Endpoint: "RemovePRLabels", Requests: 2, Errors: 2, ErrorRate: 1},
+	}, budget.snapshot())
+	assert.Equal(t, false, budget.degraded(platformCodeHosting))
+
+	budget.cfg.DegradeErrorRate = 0
+	budget.cfg.MinRequests = 1
+	assert.Equal(t, false, budget.degraded(platformCodeHosting))
+
+	var nilBudget *apiErrorBudget
+	nilBudget.record(platformCodeHosting, "AddPRLabels", false)
+	assert.Equal(t, ([]apiErrorRate)(nil), nilBudget.snapshot())
+	assert.Equal(t, false, nilBudget.degraded(platformCodeHosting))
+}
+
+func TestErrorBudgetPausesCommentRemoval(t *testing.T) {
+	cnf := &configuration{ErrorBudget: errorBudgetConfig{MinRequests: 1, DegradeErrorRate: 0.5}}
+	cnf.PlaceholderCLASignGuideTitle = "guide"
+	mc := &mockClient{
+		successfulListPullRequestComments: true,
+		prComments:                        []client.PRComment{{ID: "1", Body: "guide"}},
+	}
+	bot := &robot{cli: mc, cnf: cnf, budget: newAPIErrorBudget(&cnf.ErrorBudget)}
+
+	bot.budget.record(platformCodeHosting, "AddPRLabels", false)
+	bot.removeCLASignGuideComment(newPRSnapshot(mc, org, repo, number))
+	assert.Equal(t, "", mc.method)
+
+	assert.NotEqual(t, nil, (&errorBudgetConfig{DegradeErrorRate: 1.5}).validate())
+	assert.NotEqual(t, nil, (&errorBudgetConfig{WindowSeconds: -1}).validate())
+}
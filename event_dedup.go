@@ -0,0 +1,100 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eventDedupConfig skips the webhook deliveries replayed by the platform, which would post the comments again
+type eventDedupConfig struct {
+	// WindowSeconds is how long a delivery is remembered. The dedup is disabled if it is 0
+	WindowSeconds int `json:"window_seconds"`
+}
+
+func (c *eventDedupConfig) validate() error {
+	if c.WindowSeconds < 0 {
+		return errors.New("the window_seconds of the event_dedup can not be negative")
+	}
+	return nil
+}
+
+// eventKey is the idempotency key of the event. It is the id of the delivery if the platform provides one,
+// or the hash of the pull request, the head, the event type and what changes in the next event otherwise
+func eventKey(evt *client.GenericEvent) string {
+	if id := utils.GetString(evt.EventGUID); id != "" {
+		return id
+	}
+
+	h := sha256.Sum256([]byte(strings.Join([]string{
+		utils.GetString(evt.Org), utils.GetString(evt.Repo), utils.GetString(evt.Number), utils.GetString(evt.Head),
+		utils.GetString(evt.EventType), utils.GetString(evt.Action), utils.GetString(evt.ActionDetail),
+		utils.GetString(evt.CommentID), utils.GetString(evt.UpdateTime),
+	}, "\n")))
+	return hex.EncodeToString(h[:])
+}
+
+// eventDeduper remembers the keys of the events handled in the window.
+// All the methods are safe on a nil deduper, which remembers nothing
+type eventDeduper struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+	now  func() time.Time
+}
+
+func newEventDeduper() *eventDeduper {
+	return &eventDeduper{seen: map[string]time.Time{}, now: time.Now}
+}
+
+// duplicate checks whether the event of the key has been seen in the window, and remembers it otherwise.
+// The keys out of the window are forgotten
+func (d *eventDeduper) duplicate(key string, window time.Duration) bool {
+	if d == nil || window <= 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	for k, t := range d.seen {
+		if now.Sub(t) >= window {
+			delete(d.seen, k)
+		}
+	}
+
+	if _, ok := d.seen[key]; ok {
+		return true
+	}
+	d.seen[key] = now
+	return false
+}
+
+// duplicateEvent checks whether the event is a replayed delivery to be skipped
+func (bot *robot) duplicateEvent(evt *client.GenericEvent) bool {
+	window := time.Duration(bot.config().EventDedup.WindowSeconds) * time.Second
+	if !bot.events.duplicate(eventKey(evt), window) {
+		return false
+	}
+
+	duplicateEventsTotal.Inc()
+	return true
+}
@@ -0,0 +1,69 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEventKey(t *testing.T) {
+	s := func(v string) *string { return &v }
+	assert.Equal(t, "guid1", eventKey(&client.GenericEvent{EventGUID: s("guid1"), Org: s(org)}))
+
+	comment := &client.GenericEvent{Org: s(org), Repo: s(repo), Number: s(number), CommentID: s("1")}
+	assert.Equal(t, eventKey(comment), eventKey(&client.GenericEvent{
+		Org: s(org), Repo: s(repo), Number: s(number), CommentID: s("1"),
+	}))
+	assert.NotEqual(t, eventKey(comment), eventKey(&client.GenericEvent{
+		Org: s(org), Repo: s(repo), Number: s(number), CommentID: s("2"),
+	}))
+}
+
+func TestEventDeduper(t *testing.T) {
+	var nilDeduper *eventDeduper
+	assert.Equal(t, false, nilDeduper.duplicate("k", time.Minute))
+
+	now := time.Now()
+	d := newEventDeduper()
+	d.now = func() time.Time { return now }
+	assert.Equal(t, false, d.duplicate("k", 0))
+	assert.Equal(t, false, d.duplicate("k", 0))
+
+	assert.Equal(t, false, d.duplicate("k", time.Minute))
+	assert.Equal(t, true, d.duplicate("k", time.Minute))
+
+	// the key is forgotten out of the window
+	now = now.Add(time.Minute)
+	assert.Equal(t, false, d.duplicate("k", time.Minute))
+	assert.Len(t, d.seen, 1)
+}
+
+func TestDuplicateEvent(t *testing.T) {
+	guid := "guid1"
+	evt := &client.GenericEvent{EventGUID: &guid}
+	bot := &robot{cnf: &configuration{EventDedup: eventDedupConfig{WindowSeconds: 60}}, events: newEventDeduper()}
+	before := testutil.ToFloat64(duplicateEventsTotal)
+
+	assert.Equal(t, false, bot.duplicateEvent(evt))
+	assert.Equal(t, true, bot.duplicateEvent(evt))
+	assert.Equal(t, before+1, testutil.ToFloat64(duplicateEventsTotal))
+
+	// the dedup is disabled by default
+	bot.cnf.EventDedup.WindowSeconds = 0
+	assert.Equal(t, false, bot.duplicateEvent(evt))
+}
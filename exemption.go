@@ -0,0 +1,75 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"slices"
+	"strings"
+)
+
+// parseExemptions parses the SHAs of the exempt commits, one per line. The blank lines and the lines
+// starting with # are ignored, and anything after the SHA in a line is treated as a remark
+func parseExemptions(content []byte) map[string]bool {
+	shas := map[string]bool{}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		shas[strings.ToLower(fields[0])] = true
+	}
+
+	return shas
+}
+
+// filterExemptCommits removes the commits listed in the exemption file of the repository, so that
+// their authors are not checked. The commits are kept if the exemption file can not be read
+func (bot *robot) filterExemptCommits(pr *prSnapshot, commits []client.PRCommit, repoCnf *repoConfig) []client.PRCommit {
+	messages, success := pr.getCommitMessages()
+	if !success || len(messages) != len(commits) {
+		return commits
+	}
+
+	content, success := bot.cli.GetRepoFileContent(pr.org, pr.repo, repoCnf.ExemptionFile, repoCnf.ExemptionRef)
+	if !success {
+		return commits
+	}
+
+	exemptions := parseExemptions(content)
+	result := make([]client.PRCommit, 0, len(commits))
+	for i := range messages {
+		if !exemptions[strings.ToLower(messages[i].SHA)] {
+			result = append(result, commits[i])
+		}
+	}
+
+	return result
+}
+
+// filterExemptContributors removes the commits of the automation accounts in the exempt_users or exempt_emails
+func filterExemptContributors(commits []client.PRCommit, repoCnf *repoConfig) []client.PRCommit {
+	return slices.DeleteFunc(slices.Clone(commits), func(c client.PRCommit) bool {
+		if repoCnf.CheckByCommitter {
+			return repoCnf.isExemptContributor(c.CommitterName, c.CommitterEmail)
+		}
+		return repoCnf.isExemptContributor(c.AuthorName, c.AuthorEmail)
+	})
+}
+
+// isExemptContributor checks whether the contributor is an automation account in the exempt_users or exempt_emails
+func (c *repoConfig) isExemptContributor(name, email string) bool {
+	return slices.Contains(c.ExemptUsers, name) ||
+		slices.ContainsFunc(c.ExemptEmails, func(v string) bool { return strings.EqualFold(v, email) })
+}
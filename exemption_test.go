@@ -0,0 +1,57 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseExemptions(t *testing.T) {
+	got := parseExemptions([]byte("# imported from svn\n\nABC123 initial import\r\ndef456\n"))
+	assert.Equal(t, map[string]bool{"abc123": true, "def456": true}, got)
+}
+
+func TestFilterExemptCommits(t *testing.T) {
+	mc := &mockClient{successfulGetPullRequestCommitMessages: true}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	commits := []client.PRCommit{{AuthorName: "u1", AuthorEmail: "e1"}, {AuthorName: "u2", AuthorEmail: "e2"}}
+	mc.commitMessages = []prCommitMessage{{PRCommit: commits[0], SHA: "abc123"}, {PRCommit: commits[1], SHA: "def456"}}
+	repoCnf := &repoConfig{ExemptionFile: "CLA_EXEMPTIONS"}
+
+	// failed to read the exemption file
+	assert.Equal(t, commits, bot.filterExemptCommits(newPRSnapshot(mc, org, repo, number), commits, repoCnf))
+
+	mc.successfulGetRepoFileContent = true
+	mc.fileContent = []byte("ABC123\n")
+	got := bot.filterExemptCommits(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	assert.Equal(t, commits[1:], got)
+}
+
+func TestFilterExemptContributors(t *testing.T) {
+	commits := []client.PRCommit{
+		{AuthorName: "dependabot", AuthorEmail: "e1", CommitterName: "u1", CommitterEmail: "e2"},
+		{AuthorName: "u2", AuthorEmail: "Release-Bot@example.com", CommitterName: "dependabot", CommitterEmail: "e3"},
+		{AuthorName: "u3", AuthorEmail: "e4", CommitterName: "u3", CommitterEmail: "e4"},
+	}
+	repoCnf := &repoConfig{ExemptUsers: []string{"dependabot"}, ExemptEmails: []string{"release-bot@example.com"}}
+
+	assert.Equal(t, commits[2:], filterExemptContributors(commits, repoCnf))
+	assert.Equal(t, "dependabot", commits[0].AuthorName)
+
+	// the committers are matched when checking CLA by the email of committer
+	repoCnf.CheckByCommitter = true
+	assert.Equal(t, []client.PRCommit{commits[0], commits[2]}, filterExemptContributors(commits, repoCnf))
+}
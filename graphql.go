@@ -0,0 +1,173 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"net/http"
+	"strconv"
+)
+
+// pullRequestGraphQuery fetches the labels and a page of the commits with their messages of a pull request,
+// by the GraphQL schema of GitHub
+const pullRequestGraphQuery = `query($owner: String!, $name: String!, $number: Int!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      labels(first: 100) { nodes { name } }
+      commits(first: 100, after: $cursor) {
+        pageInfo { hasNextPage endCursor }
+        nodes { commit { oid message
+          author { name email user { login } }
+          committer { name email user { login } } } }
+      }
+    }
+  }
+}`
+
+// prGraph is the labels and the commits with their messages of a pull request fetched in one query
+type prGraph struct {
+	Labels  []string
+	Commits []prCommitMessage
+}
+
+type graphActor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	User  *struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+// login returns the login of the account of the actor, or empty if the commit is not linked to one.
+// The name of the commit is not a login, anyone can set it to the login of another user
+func (a *graphActor) login() string {
+	if a.User == nil {
+		return ""
+	}
+	return a.User.Login
+}
+
+type pullRequestGraphResponse struct {
+	Data struct {
+		Repository struct {
+			PullRequest *struct {
+				Labels struct {
+					Nodes []struct {
+						Name string `json:"name"`
+					} `json:"nodes"`
+				} `json:"labels"`
+				Commits struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []struct {
+						Commit struct {
+							OID       string     `json:"oid"`
+							Message   string     `json:"message"`
+							Author    graphActor `json:"author"`
+							Committer graphActor `json:"committer"`
+						} `json:"commit"`
+					} `json:"nodes"`
+				} `json:"commits"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// GetPullRequestGraph fetches the labels and the commits with their messages of a pull request from the GraphQL
+// endpoint of the url, which takes one request for every 100 commits instead of one for each of them
+func (c *robotClient) GetPullRequestGraph(urlStr, org, repo, number string) (result prGraph, success bool) {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return result, false
+	}
+
+	variables := map[string]any{"owner": org, "name": repo, "number": n}
+	for page := 1; ; page++ {
+		data, err := c.queryPullRequestGraph(urlStr, variables)
+		if err != nil {
+			c.log.WithError(err).Errorf("GraphQL query of %s/%s/%s failed", org, repo, number)
+			return prGraph{}, false
+		}
+
+		pr := data.Data.Repository.PullRequest
+		if page == 1 {
+			for _, label := range pr.Labels.Nodes {
+				result.Labels = append(result.Labels, label.Name)
+			}
+		}
+		for _, node := range pr.Commits.Nodes {
+			commit := &node.Commit
+			result.Commits = append(result.Commits, prCommitMessage{
+				PRCommit: client.PRCommit{
+					AuthorName:     commit.Author.login(),
+					AuthorEmail:    commit.Author.Email,
+					CommitterName:  commit.Committer.login(),
+					CommitterEmail: commit.Committer.Email,
+				},
+				SHA:     commit.OID,
+				Message: commit.Message,
+			})
+		}
+
+		if !pr.Commits.PageInfo.HasNextPage {
+			return result, true
+		}
+		variables["cursor"] = pr.Commits.PageInfo.EndCursor
+	}
+}
+
+func (c *robotClient) queryPullRequestGraph(urlStr string, variables map[string]any) (*pullRequestGraphResponse, error) {
+	resp, err := c.graphQL.R().SetBody(map[string]any{"query": pullRequestGraphQuery, "variables": variables}).Post(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return nil, errors.New("unexpected status " + resp.Status())
+	}
+
+	data := &pullRequestGraphResponse{}
+	if err = json.Unmarshal(resp.Body(), data); err != nil {
+		return nil, err
+	}
+	if len(data.Errors) != 0 {
+		return nil, errors.New(data.Errors[0].Message)
+	}
+	if data.Data.Repository.PullRequest == nil {
+		return nil, errors.New("the pull request is not found")
+	}
+	return data, nil
+}
+
+// loadGraph fetches the labels and the commits with their messages of the pull request in one query, so that they
+// are not fetched one by one. They are left to be fetched by the REST api if the query fails
+func (pr *prSnapshot) loadGraph(urlStr string) {
+	if pr.labelsLoaded || pr.messagesLoaded {
+		return
+	}
+
+	pr.watchdog.countAPICall()
+	graph, success := pr.cli.GetPullRequestGraph(urlStr, pr.org, pr.repo, pr.number)
+	if !success {
+		return
+	}
+	pr.labels, pr.labelsOK, pr.labelsLoaded = graph.Labels, true, true
+	pr.messages, pr.messagesOK, pr.messagesLoaded = graph.Commits, true, true
+}
@@ -0,0 +1,100 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetPullRequestGraph(t *testing.T) {
+	var cursors []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Variables map[string]any `json:"variables"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		cursors = append(cursors, body.Variables["cursor"])
+
+		commit := `{"commit": {"oid": "%s", "message": "fix", "author": {"name": "Alice", "email": "a@example.com",
+			"user": {"login": "alice"}}, "committer": {"name": "Bob", "email": "b@example.com", "user": null}}}`
+		if body.Variables["cursor"] == nil {
+			_, _ = fmt.Fprintf(w, `{"data": {"repository": {"pullRequest": {"labels": {"nodes": [{"name": "cla-no"}]},
+				"commits": {"pageInfo": {"hasNextPage": true, "endCursor": "c1"}, "nodes": [`+commit+`]}}}}}`, "sha1")
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"data": {"repository": {"pullRequest": {"labels": {"nodes": [{"name": "cla-no"}]},
+			"commits": {"pageInfo": {"hasNextPage": false}, "nodes": [`+commit+`]}}}}}`, "sha2")
+	}))
+	defer srv.Close()
+
+	c := &robotClient{graphQL: resty.New(), log: logrus.NewEntry(logrus.New())}
+	graph, success := c.GetPullRequestGraph(srv.URL, org, repo, number)
+	assert.Equal(t, true, success)
+	assert.Equal(t, []any{nil, "c1"}, cursors)
+	assert.Equal(t, []string{"cla-no"}, graph.Labels)
+	assert.Equal(t, 2, len(graph.Commits))
+	assert.Equal(t, "sha2", graph.Commits[1].SHA)
+	assert.Equal(t, "alice", graph.Commits[0].AuthorName)
+	// the committer not linked to an account has no login
+	assert.Equal(t, "", graph.Commits[0].CommitterName)
+	assert.Equal(t, "b@example.com", graph.Commits[0].CommitterEmail)
+
+	errSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errors": [{"message": "Could not resolve to a Repository"}]}`))
+	}))
+	defer errSrv.Close()
+	_, success = c.GetPullRequestGraph(errSrv.URL, org, repo, number)
+	assert.Equal(t, false, success)
+}
+
+func TestLoadGraph(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestGraph: true,
+		graph:                         prGraph{Labels: []string{labelNo}, Commits: []prCommitMessage{{SHA: "sha1"}}},
+	}
+
+	// the labels and the commits are taken from the graph without the REST api
+	pr := newPRSnapshot(mc, org, repo, number)
+	pr.loadGraph("http://localhost/graphql")
+	labels, _ := pr.getLabels()
+	commits, success := pr.getCommits()
+	assert.Equal(t, []string{labelNo}, labels)
+	assert.Equal(t, true, success)
+	assert.Equal(t, 1, len(commits))
+	assert.Equal(t, "GetPullRequestGraph", mc.method)
+
+	// they are fetched by the REST api if the query fails
+	mc.successfulGetPullRequestGraph = false
+	pr = newPRSnapshot(mc, org, repo, number)
+	pr.loadGraph("http://localhost/graphql")
+	_, _ = pr.getLabels()
+	assert.Equal(t, "GetPullRequestLabels", mc.method)
+}
+
+func TestGraphActorNotLinkedIsNotExempt(t *testing.T) {
+	actor := graphActor{Name: "ci-bot", Email: "someone@example.com"}
+	assert.Equal(t, "", actor.login())
+
+	repoCnf := &repoConfig{ExemptUsers: []string{"ci-bot"}}
+	commits := []client.PRCommit{{AuthorName: actor.login(), AuthorEmail: actor.Email}}
+	assert.Equal(t, commits, filterExemptContributors(commits, repoCnf))
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// defaultCommentGuideResolved is used when the comment_guide_resolved is not configured
+const defaultCommentGuideResolved = "  \n\n**Resolved**: all the contributors of this pull request have signed the CLA."
+
+// resultKindResolvedGuide marks the sign guide kept with the resolved note, which is not a result comment any more
+const resultKindResolvedGuide = "resolved-guide"
+
+// guideResolved renders the note appended to the sign guide kept after the check passes
+func (r *commentRenderer) guideResolved() string {
+	return r.render(r.format(r.cnf.CommentGuideResolved, defaultCommentGuideResolved), r.data(nil),
+		func(s string) string { return s })
+}
+
+// preserveCLASignGuideComments posts the comment of the passed check for the repositories enabling the
+// preserve_guide_comment. The sign guides are kept with the resolved note appended instead of being replaced,
+// and only the earlier comments of the passed check are replaced
+func (bot *robot) preserveCLASignGuideComments(pr *prSnapshot, repoCnf *repoConfig, comment string) {
+	if !bot.botLoginKnown(pr) {
+		return
+	}
+
+	comments, _ := pr.getComments()
+	ids := bot.resultCommentIDs(pr, comments)
+	var passed []string
+	for i := range comments {
+		if !slices.Contains(ids, comments[i].ID) {
+			continue
+		}
+		if !bot.isCLASignGuide(pr, comments[i].Body) {
+			passed = append(passed, comments[i].ID)
+			continue
+		}
+
+		body := strings.TrimRight(regexpResultMarker.ReplaceAllString(comments[i].Body, ""), "\n")
+		bot.updatePRComment(pr, comments[i].ID,
+			withResultMarker(resultKindResolvedGuide, body+bot.renderer(pr).guideResolved()))
+	}
+
+	bot.recordResultComments(pr, bot.replaceResultComments(pr, repoCnf, passed, comment))
+}
+
+// isCLASignGuide checks whether the result comment is the sign guide of the failed check
+func (bot *robot) isCLASignGuide(pr *prSnapshot, body string) bool {
+	if kind := resultCommentKind(body); kind != "" {
+		return kind == resultKindNeedSign
+	}
+	return strings.Contains(body, bot.prConfig(pr).PlaceholderCLASignGuideTitle)
+}
@@ -0,0 +1,62 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"testing"
+)
+
+func TestPreserveGuideComment(t *testing.T) {
+	guide := withResultMarker(resultKindNeedSign, "#guide u1 needs to sign")
+	mc := &mockClient{
+		successfulListPullRequestComments: true,
+		successfulCreatePRComment:         true,
+		successfulDeletePRComment:         true,
+		successfulUpdatePRComment:         true,
+		successfulAddPRLabels:             true,
+		prComments: []client.PRComment{
+			{ID: "1", Body: "#guide the legacy guide"},
+			{ID: "2", Body: "#pass the earlier pass"},
+			{ID: "3", Body: "thanks for the review"},
+			{ID: "4", Body: guide},
+		},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{
+		CommentAllSigned:             "#pass all signed",
+		PlaceholderCommitter:         "ccc",
+		CommentGuideResolved:         "  \n\nresolved",
+		PlaceholderCLASignGuideTitle: "#guide",
+		PlaceholderCLASignPassTitle:  "#pass",
+	}}
+	repoCnf := &repoConfig{CLALabelYes: labelYes, CLALabelNo: labelNo, PreserveGuideComment: true}
+
+	// the guides are kept with the resolved note, and the earlier pass is replaced
+	passed := withResultMarker(resultKindAllSigned, "#pass all signed")
+	bot.passCLASignature(newPRSnapshot(mc, org, repo, number), []string{"u1"}, nil, repoCnf)
+	assert.Equal(t, map[string]string{
+		"1": withResultMarker(resultKindResolvedGuide, "#guide the legacy guide  \n\nresolved"),
+		"2": passed,
+		"4": withResultMarker(resultKindResolvedGuide, "#guide u1 needs to sign  \n\nresolved"),
+	}, mc.updatedComments)
+	assert.Empty(t, mc.deletedComments)
+
+	// the kept guides are not result comments any more
+	for id, body := range mc.updatedComments {
+		mc.prComments[slices.IndexFunc(mc.prComments, func(c client.PRComment) bool { return c.ID == id })].Body = body
+	}
+	assert.Equal(t, []string{"2"}, bot.claResultCommentIDs(newPRSnapshot(mc, org, repo, number)))
+}
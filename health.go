@@ -0,0 +1,134 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+
+	// readinessProbeTimeout bounds the probe of a host of the check urls
+	readinessProbeTimeout = 3 * time.Second
+	// readinessProbeTTL is how long the result of the probe of a host is reused, so that the frequent readiness
+	// checks do not load the CLA servers
+	readinessProbeTTL = 15 * time.Second
+)
+
+// hostProbe is the result of probing a host of the check urls
+type hostProbe struct {
+	ok     bool
+	reason string
+	at     time.Time
+}
+
+// readinessProbe checks whether the hosts of the check urls are reachable. Any response of a host, even an error
+// status, means it is reachable, since only the connectivity matters
+type readinessProbe struct {
+	cli *http.Client
+	now func() time.Time
+
+	mu      sync.Mutex
+	results map[string]hostProbe
+}
+
+func newReadinessProbe() *readinessProbe {
+	return &readinessProbe{
+		cli:     &http.Client{Timeout: readinessProbeTimeout},
+		now:     time.Now,
+		results: map[string]hostProbe{},
+	}
+}
+
+// probe returns whether the host is reachable, and the reason if it is not
+func (p *readinessProbe) probe(host string) (bool, string) {
+	p.mu.Lock()
+	r, ok := p.results[host]
+	p.mu.Unlock()
+	if ok && p.now().Sub(r.at) < readinessProbeTTL {
+		return r.ok, r.reason
+	}
+
+	r = hostProbe{ok: true, at: p.now()}
+	resp, err := p.cli.Head(host)
+	if err != nil {
+		r.ok, r.reason = false, err.Error()
+	} else {
+		_ = resp.Body.Close()
+	}
+
+	p.mu.Lock()
+	p.results[host] = r
+	p.mu.Unlock()
+	return r.ok, r.reason
+}
+
+// checkURLHosts lists the scheme and host of each check url of the configuration, sorted
+func checkURLHosts(c *configuration) []string {
+	seen := map[string]bool{}
+	var hosts []string
+	for i := range c.ConfigItems {
+		for _, item := range c.inheritedItems(i) {
+			for _, checkURL := range item.checkURLs() {
+				u, err := url.Parse(checkURL)
+				if err != nil || u.Host == "" {
+					continue
+				}
+				if host := u.Scheme + "://" + u.Host; !seen[host] {
+					seen[host] = true
+					hosts = append(hosts, host)
+				}
+			}
+		}
+	}
+
+	sort.Strings(hosts)
+	return hosts
+}
+
+// registerHealthHandlers serves the liveness at /healthz, and the readiness at /readyz which requires the hosts
+// of all the check urls to be reachable, so that the webhooks are not routed to an instance which can not check
+// the CLA
+func registerHealthHandlers(mux *http.ServeMux, bot *robot, probe *readinessProbe) {
+	mux.HandleFunc(healthzPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc(readyzPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		code, status, hosts := http.StatusOK, "ready", map[string]string{}
+		for _, host := range checkURLHosts(bot.config()) {
+			if ok, reason := probe.probe(host); ok {
+				hosts[host] = "ok"
+			} else {
+				code, status, hosts[host] = http.StatusServiceUnavailable, "not ready", reason
+			}
+		}
+		writeJSON(w, code, map[string]any{"status": status, "hosts": hosts})
+	})
+}
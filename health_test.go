@@ -0,0 +1,75 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckURLHosts(t *testing.T) {
+	c := &configuration{ConfigItems: []repoConfig{
+		{CheckURL: "https://cla.example.com/icla", CheckURLs: []string{"https://cla.example.com/ccla"}},
+		{CheckURL: "http://localhost:7003/cla"},
+		{CheckURL: "not a url"},
+	}}
+	assert.Equal(t, []string{"http://localhost:7003", "https://cla.example.com"}, checkURLHosts(c))
+}
+
+func TestHealthHandlers(t *testing.T) {
+	cla := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer cla.Close()
+
+	bot := &robot{cnf: &configuration{ConfigItems: []repoConfig{
+		{RepoFilter: config.RepoFilter{Repos: []string{org}}, CheckURL: cla.URL + "/cla"},
+	}}}
+	mux := http.NewServeMux()
+	probe := newReadinessProbe()
+	registerHealthHandlers(mux, bot, probe)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, healthzPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the host responding with any status is reachable
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, readyzPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// the result of the probe is reused within the ttl
+	cla.Close()
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, readyzPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	now := time.Now().Add(readinessProbeTTL)
+	probe.now = func() time.Time { return now }
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, readyzPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var body struct {
+		Status string            `json:"status"`
+		Hosts  map[string]string `json:"hosts"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "not ready", body.Status)
+	assert.NotEqual(t, "ok", body.Hosts[cla.URL])
+}
@@ -0,0 +1,43 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+// defaultCommentHelp is used when the comment_help is not configured
+const defaultCommentHelp = "### CLA Bot Commands  \n\n" +
+	"- `/check-cla`: check the CLA status again after signing the CLA\n" +
+	"- `/check-cla authors` or `/check-cla committers`: check the CLA by the emails of the authors or " +
+	"the committers for once, by the maintainers\n" +
+	"- `/check-cla --since <sha>`: check only the commits after the commit, by the maintainers\n" +
+	"- `/check-cla @<username>` or `/check-cla <email>`: check only the contributor, and show the result of " +
+	"each email\n" +
+	"- `/cla recheck`: check the CLA status bypassing the caches, and show the result of each email\n" +
+	"- `/cla cancel`: remove the CLA label, by the maintainers\n" +
+	"- `/cla override [30d|12h] <reason>`: add the CLA label regardless of the check, for a while if a lifetime " +
+	"is given, by the maintainers\n" +
+	"- `/cla help`: show this help\n\n" +
+	"The CLA is checked by the emails of the **%s** of the commits. [You can click here to sign the CLA](%s), " +
+	"and please check the [FAQs](%s) first."
+
+// checkedBy returns the contributors whose emails are checked, the authors or the committers
+func (c *repoConfig) checkedBy() string {
+	if c.CheckByCommitter {
+		return checkScopeCommitters
+	}
+	return checkScopeAuthors
+}
+
+// postHelp replies to the `/cla help` with the commands and how the CLA is checked for the repository
+func (bot *robot) postHelp(pr *prSnapshot, repoCnf *repoConfig) {
+	bot.createPRComment(pr, repoCnf, bot.renderer(pr).help(repoCnf))
+}
@@ -0,0 +1,193 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultLabelBreakerCooldownSeconds is used when the cooldown_seconds of the label_breaker is not configured
+	defaultLabelBreakerCooldownSeconds = 300
+	// defaultCommentLabelRetry is used when the comment_label_retry is not configured
+	defaultCommentLabelRetry = "  \n\nThe code hosting platform is failing to update the labels, " +
+		"the bot will try again after %s."
+)
+
+// labelBreakerConfig stops calling the label apis for a while once they fail too many times in a row, so that
+// the bot does not hammer the failing platform nor comment the failure on every event
+type labelBreakerConfig struct {
+	// FailureThreshold is the number of the consecutive failures of the label apis which opens the breaker.
+	// The breaker is disabled if it is 0
+	FailureThreshold int `json:"failure_threshold"`
+	// CooldownSeconds is how long the breaker stays open before the held pull requests are checked again.
+	// Default is 300
+	CooldownSeconds int `json:"cooldown_seconds"`
+}
+
+func (c *labelBreakerConfig) validate() error {
+	if c.FailureThreshold < 0 || c.CooldownSeconds < 0 {
+		return errors.New("the failure_threshold and cooldown_seconds of the label_breaker can not be negative")
+	}
+	return nil
+}
+
+func (c *labelBreakerConfig) cooldown() time.Duration {
+	if c.CooldownSeconds == 0 {
+		return defaultLabelBreakerCooldownSeconds * time.Second
+	}
+	return time.Duration(c.CooldownSeconds) * time.Second
+}
+
+// heldPR is a pull request whose labels failed to be updated while the breaker is open
+type heldPR struct {
+	org    string
+	repo   string
+	number string
+}
+
+// labelBreaker is the circuit breaker of the label apis. It opens after the failure_threshold consecutive failures,
+// and half opens after the cooldown, when the held pull requests are checked again and one more failure opens it
+// again. A success closes it. All the methods are safe on a nil breaker, which never opens
+type labelBreaker struct {
+	cfg *labelBreakerConfig
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	held      []heldPR
+	// notified are the pull requests commented about the failure since the breaker opened
+	notified map[heldPR]bool
+
+	retry     func()
+	afterFunc func(time.Duration, func()) *time.Timer
+	now       func() time.Time
+}
+
+func newLabelBreaker(cfg *labelBreakerConfig, retry func()) *labelBreaker {
+	return &labelBreaker{cfg: cfg, retry: retry, afterFunc: time.AfterFunc, now: time.Now}
+}
+
+func (b *labelBreaker) enabled() bool {
+	return b != nil && b.cfg.FailureThreshold != 0
+}
+
+// allow checks whether the label apis can be called, which is false while the breaker is open
+func (b *labelBreaker) allow() bool {
+	if !b.enabled() {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !b.now().Before(b.openUntil)
+}
+
+// record counts the result of a call of the label apis, and opens the breaker on too many failures
+func (b *labelBreaker) record(success bool) {
+	if !b.enabled() {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.failures, b.notified = 0, nil
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.cfg.FailureThreshold && !b.now().Before(b.openUntil) {
+		b.openUntil = b.now().Add(b.cfg.cooldown())
+		b.afterFunc(b.cfg.cooldown(), b.retry)
+	}
+}
+
+// hold keeps the pull request to be checked again after the cooldown if the breaker is open, and returns when it
+// is checked. The notify is false if the failure of the pull request has been commented since the breaker opened
+func (b *labelBreaker) hold(pr *prSnapshot) (retryAt time.Time, notify bool) {
+	if !b.enabled() {
+		return time.Time{}, true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.cfg.FailureThreshold {
+		return time.Time{}, true
+	}
+
+	key := heldPR{org: pr.org, repo: pr.repo, number: pr.number}
+	if !slices.Contains(b.held, key) {
+		b.held = append(b.held, key)
+	}
+	if b.notified[key] {
+		return b.openUntil, false
+	}
+	if b.notified == nil {
+		b.notified = map[heldPR]bool{}
+	}
+	b.notified[key] = true
+	return b.openUntil, true
+}
+
+// take removes all the held pull requests to be checked again
+func (b *labelBreaker) take() []heldPR {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	held := b.held
+	b.held = nil
+	return held
+}
+
+// labelUpdateFailed posts the comment of the failure to update the labels. While the breaker of the label apis
+// is open, it is posted once for the pull request with when the labels will be updated again
+func (bot *robot) labelUpdateFailed(pr *prSnapshot, repoCnf *repoConfig, comment string) {
+	retryAt, notify := bot.labels.hold(pr)
+	if !notify {
+		return
+	}
+	if !retryAt.IsZero() {
+		comment += bot.renderer(pr).labelRetry(retryAt)
+	}
+	bot.createPRComment(pr, repoCnf, comment)
+}
+
+// retryHeldLabels checks the pull requests held by the breaker again once it half opens
+func (bot *robot) retryHeldLabels() {
+	for _, h := range bot.labels.take() {
+		repoCnf := bot.getRepoConfig(h.org, h.repo)
+		if repoCnf == nil {
+			continue
+		}
+
+		pr := newPRSnapshot(bot.cli, h.org, h.repo, h.number).withActor(auditActorLabelRetry)
+		bot.recheckSerialized(pr, repoCnf, bot.log.WithField("label-retry", pr.key()))
+	}
+}
+
+// labelRetry renders the note of when the labels failing to be updated will be updated again
+func (r *commentRenderer) labelRetry(retryAt time.Time) string {
+	data := r.data(nil)
+	data.RetryAt = retryAt.UTC().Format(time.DateTime) + " UTC"
+	return r.render(r.format(r.cnf.CommentLabelRetry, defaultCommentLabelRetry), data,
+		func(s string) string { return fmt.Sprintf(s, data.RetryAt) })
+}
@@ -0,0 +1,103 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLabelBreaker(t *testing.T) {
+	var nilBreaker *labelBreaker
+	nilBreaker.record(false)
+	assert.Equal(t, true, nilBreaker.allow())
+
+	now := time.Now()
+	cli := &commentRecordingClient{mockClient: &mockClient{}}
+	bot := &robot{cli: cli, cnf: &configuration{CommentUpdateLabelFailed: "label failed"}}
+	var delays []time.Duration
+	bot.labels = newLabelBreaker(&labelBreakerConfig{FailureThreshold: 2, CooldownSeconds: 60}, bot.retryHeldLabels)
+	bot.labels.now = func() time.Time { return now }
+	bot.labels.afterFunc = func(d time.Duration, f func()) *time.Timer {
+		delays = append(delays, d)
+		return nil
+	}
+	pr1, pr2 := newPRSnapshot(cli, org, repo, number), newPRSnapshot(cli, org, repo, "2")
+	repoCnf := &repoConfig{}
+
+	// the failure is commented on every event until the breaker opens
+	assert.Equal(t, false, bot.addPRLabels(pr1, []string{labelYes}))
+	bot.labelUpdateFailed(pr1, repoCnf, "label failed")
+	assert.Equal(t, []string{"org1/repo1/1: label failed"}, cli.posted)
+
+	// the breaker opens, and the failure is commented once for each pull request with when it is retried
+	assert.Equal(t, false, bot.removePRLabels(pr1, []string{labelNo}))
+	assert.Equal(t, []time.Duration{time.Minute}, delays)
+	cli.posted = nil
+	bot.labelUpdateFailed(pr1, repoCnf, "label failed")
+	bot.labelUpdateFailed(pr1, repoCnf, "label failed")
+	retry := "label failed" + bot.renderer(pr1).labelRetry(now.Add(time.Minute))
+	assert.Equal(t, []string{"org1/repo1/1: " + retry}, cli.posted)
+
+	// the label apis are not called while the breaker is open
+	cli.method = ""
+	assert.Equal(t, false, bot.addPRLabels(pr2, []string{labelYes}))
+	assert.Equal(t, "", cli.method)
+	bot.labelUpdateFailed(pr2, repoCnf, "label failed")
+	assert.Equal(t, []string{"org1/repo1/1: " + retry, "org1/repo1/2: " + retry}, cli.posted)
+
+	// the breaker half opens after the cooldown, and one more failure opens it again without commenting again
+	now = now.Add(time.Minute)
+	assert.Equal(t, true, bot.labels.allow())
+	assert.Equal(t, false, bot.addPRLabels(pr1, []string{labelYes}))
+	assert.Equal(t, false, bot.labels.allow())
+	assert.Len(t, delays, 2)
+	cli.posted = nil
+	bot.labelUpdateFailed(pr1, repoCnf, "label failed")
+	assert.Empty(t, cli.posted)
+	assert.Equal(t, []heldPR{{org, repo, number}, {org, repo, "2"}}, bot.labels.take())
+
+	// a success closes the breaker
+	now = now.Add(time.Minute)
+	cli.successfulAddPRLabels = true
+	assert.Equal(t, true, bot.addPRLabels(pr1, []string{labelYes}))
+	bot.labelUpdateFailed(pr1, repoCnf, "label failed")
+	assert.Equal(t, []string{"org1/repo1/1: label failed"}, cli.posted)
+	assert.Empty(t, bot.labels.take())
+}
+
+func TestRetryHeldLabels(t *testing.T) {
+	cli := &commentRecordingClient{mockClient: &mockClient{}}
+	bot := &robot{cli: cli, log: logrus.NewEntry(logrus.New()), cnf: &configuration{
+		CommentPRNoCommits: "no commits",
+		ConfigItems:        []repoConfig{{RepoFilter: config.RepoFilter{Repos: []string{org}}}},
+	}}
+	bot.labels = newLabelBreaker(&labelBreakerConfig{FailureThreshold: 1}, bot.retryHeldLabels)
+	bot.labels.afterFunc = func(time.Duration, func()) *time.Timer { return nil }
+
+	bot.labels.record(false)
+	bot.labels.hold(newPRSnapshot(cli, org, repo, number))
+	bot.labels.hold(newPRSnapshot(cli, "org2", repo, number))
+
+	// the held pull requests of the configured repositories are checked again
+	cli.successfulGetPullRequestCommits, cli.commits = true, []client.PRCommit{}
+	cli.successfulGetChangedFileCount = true
+	bot.retryHeldLabels()
+	assert.Equal(t, []string{"org1/repo1/1: no commits"}, cli.posted)
+	assert.Empty(t, bot.labels.take())
+}
@@ -0,0 +1,85 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// platformGitCode is the code hosting platform of gitcode.com
+const platformGitCode = "gitcode"
+
+// labelNamingRules transforms the label names of the config into those the code hosting platform accepts.
+// They are applied to every label the bot reads or writes, so that the label added is the one found and removed
+type labelNamingRules struct {
+	// Namespace is prepended to each label, such as `openeuler-` for `openeuler-cla/yes`
+	Namespace string `json:"namespace"`
+	// Replace replaces the characters the platform does not accept in the labels, such as `"/": "-"`.
+	// The namespace is replaced too
+	Replace map[string]string `json:"replace"`
+}
+
+func validateLabelNaming(naming map[string]labelNamingRules) error {
+	for platform, rules := range naming {
+		if platform != platformGitCode {
+			return fmt.Errorf("unsupported platform %q of the label_naming, it can only be %s", platform, platformGitCode)
+		}
+		for old := range rules.Replace {
+			if old == "" {
+				return errors.New("the replaced string of the label_naming can not be empty")
+			}
+		}
+	}
+	return nil
+}
+
+// apply transforms the label. The replacements are applied from the longest one, so that the result does not
+// depend on the order of the map
+func (r *labelNamingRules) apply(label string) string {
+	label = r.Namespace + label
+	if len(r.Replace) == 0 {
+		return label
+	}
+
+	olds := make([]string, 0, len(r.Replace))
+	for old := range r.Replace {
+		olds = append(olds, old)
+	}
+	sort.Slice(olds, func(i, j int) bool {
+		if len(olds[i]) != len(olds[j]) {
+			return len(olds[i]) > len(olds[j])
+		}
+		return olds[i] < olds[j]
+	})
+
+	pairs := make([]string, 0, 2*len(olds))
+	for _, old := range olds {
+		pairs = append(pairs, old, r.Replace[old])
+	}
+	return strings.NewReplacer(pairs...).Replace(label)
+}
+
+// labelName returns the name of the label of the config on the code hosting platform, by the configuration of
+// the pull request. The pr may be nil for the labels not of a pull request
+func (bot *robot) labelName(pr *prSnapshot, label string) string {
+	cnf := bot.prConfig(pr)
+	rules, ok := cnf.LabelNaming[cnf.platform()]
+	if !ok {
+		return label
+	}
+	return rules.apply(label)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// labelRecordingClient records the labels added and removed
+type labelRecordingClient struct {
+	*mockClient
+	added   []string
+	removed []string
+}
+
+func (c *labelRecordingClient) AddPRLabels(org, repo, number string, labels []string) bool {
+	c.added = append(c.added, labels...)
+	return true
+}
+
+func (c *labelRecordingClient) RemovePRLabels(org, repo, number string, labels []string) bool {
+	c.removed = append(c.removed, labels...)
+	return true
+}
+
+func TestLabelNaming(t *testing.T) {
+	rules := labelNamingRules{Namespace: "openeuler/", Replace: map[string]string{"/": "-", "//": "_"}}
+	assert.Equal(t, "openeuler-cla-yes", rules.apply("cla/yes"))
+	assert.Equal(t, "openeuler-a_b", rules.apply("a//b"))
+	assert.Equal(t, "cla/yes", (&labelNamingRules{}).apply("cla/yes"))
+
+	assert.Equal(t, nil, validateLabelNaming(map[string]labelNamingRules{platformGitCode: rules}))
+	assert.NotEqual(t, nil, validateLabelNaming(map[string]labelNamingRules{"gitee": rules}))
+	assert.NotEqual(t, nil, validateLabelNaming(map[string]labelNamingRules{
+		platformGitCode: {Replace: map[string]string{"": "-"}},
+	}))
+
+	cli := &labelRecordingClient{mockClient: &mockClient{}}
+	bot := &robot{cli: cli, cnf: &configuration{
+		LabelNaming: map[string]labelNamingRules{platformGitCode: {Replace: map[string]string{"/": "-"}}},
+	}}
+	repoCnf := &repoConfig{CLALabelYes: "cla/yes", CLALabelNo: "cla/no"}
+	pr := newPRSnapshot(cli, org, repo, number)
+
+	// the label read from the platform is matched by its transformed name
+	assert.Equal(t, true, bot.applyCLALabel(pr, []string{"cla-no"}, repoCnf, repoCnf.CLALabelYes, repoCnf.CLALabelNo))
+	assert.Equal(t, []string{"cla-yes"}, cli.added)
+	assert.Equal(t, []string{"cla-no"}, cli.removed)
+
+	// the removed labels are escaped by the client of gitcode since they are in the path of the api
+	bot.cnf.LabelNaming = nil
+	cli.removed = nil
+	assert.Equal(t, true, bot.applyCLALabel(pr, []string{"cla/yes"}, repoCnf, repoCnf.CLALabelNo, repoCnf.CLALabelYes))
+	assert.Equal(t, []string{"cla/yes"}, cli.removed)
+	assert.Equal(t, []string{"cla%2Fyes"}, escapePathLabels(cli.removed))
+}
@@ -0,0 +1,81 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// the structured fields of the logs, so that the logs of a pull request or a delivery can be queried
+const (
+	// logFieldDeliveryID correlates the logs of the handling of one webhook delivery, or of one evaluation
+	// triggered by the bot itself
+	logFieldDeliveryID = "delivery_id"
+	logFieldOrg        = "org"
+	logFieldRepo       = "repo"
+	logFieldNumber     = "number"
+	logFieldVerdict    = "verdict"
+)
+
+// deliveryLogger attaches the correlation id of the webhook delivery and the pull request to the logger.
+// The id is the guid of the delivery, or a generated one if the platform provides none
+func deliveryLogger(evt *client.GenericEvent, logger *logrus.Entry) *logrus.Entry {
+	id := utils.GetString(evt.EventGUID)
+	if id == "" {
+		id = newCorrelationID()
+	}
+	return logger.WithFields(logrus.Fields{
+		logFieldDeliveryID: id,
+		logFieldOrg:        utils.GetString(evt.Org),
+		logFieldRepo:       utils.GetString(evt.Repo),
+		logFieldNumber:     utils.GetString(evt.Number),
+	})
+}
+
+// withLogger attaches the pull request to the logger, which keeps the correlation id of the delivery handled,
+// or gets a new one for the evaluations triggered by the bot itself
+func (pr *prSnapshot) withLogger(logger *logrus.Entry) *prSnapshot {
+	fields := logrus.Fields{logFieldOrg: pr.org, logFieldRepo: pr.repo, logFieldNumber: pr.number}
+	if _, ok := logger.Data[logFieldDeliveryID]; !ok {
+		fields[logFieldDeliveryID] = newCorrelationID()
+	}
+	pr.log = logger.WithFields(fields)
+	return pr
+}
+
+// logger returns the logger of the pull request, or the standard logger if none is attached
+func (pr *prSnapshot) logger() *logrus.Entry {
+	if pr.log == nil {
+		return logrus.NewEntry(logrus.StandardLogger())
+	}
+	return pr.log
+}
+
+// logReadFailure logs the failure to read the data of the pull request with the correlation id, since the client
+// logging the cause is shared by the deliveries
+func (pr *prSnapshot) logReadFailure(data string, success bool) {
+	if !success {
+		pr.logger().Warningf("failed to read the %s of the pull request", data)
+	}
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,49 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDeliveryLogger(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	guid, o, r, n := "guid-1", org, repo, number
+	evt := &client.GenericEvent{EventGUID: &guid, Org: &o, Repo: &r, Number: &n}
+
+	entry := deliveryLogger(evt, logrus.NewEntry(logger))
+	assert.Equal(t, logrus.Fields{logFieldDeliveryID: guid, logFieldOrg: org, logFieldRepo: repo, logFieldNumber: number},
+		entry.Data)
+
+	// the delivery without a guid gets a generated id
+	evt.EventGUID = nil
+	id := deliveryLogger(evt, logrus.NewEntry(logger)).Data[logFieldDeliveryID]
+	assert.Equal(t, 16, len(id.(string)))
+	assert.NotEqual(t, id, deliveryLogger(evt, logrus.NewEntry(logger)).Data[logFieldDeliveryID])
+
+	// the pull request of the delivery keeps its id, such as the pull requests at the same head
+	pr := newPRSnapshot(nil, org, repo, "2").withLogger(entry)
+	pr.logReadFailure("labels", false)
+	assert.Equal(t, guid, hook.LastEntry().Data[logFieldDeliveryID])
+	assert.Equal(t, "2", hook.LastEntry().Data[logFieldNumber])
+
+	// the evaluation triggered by the bot itself gets a new id
+	pr = newPRSnapshot(nil, org, repo, number).withLogger(logrus.NewEntry(logger))
+	assert.Equal(t, 16, len(pr.logger().Data[logFieldDeliveryID].(string)))
+	assert.NotNil(t, newPRSnapshot(nil, org, repo, number).logger())
+}
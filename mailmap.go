@@ -0,0 +1,173 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxMailmapCacheSize bounds the number of the mailmaps cached, the cache is reset when it is exceeded
+	maxMailmapCacheSize = 256
+	// mailmapCacheTTL is how long the mailmap of a base branch is reused before reading it again
+	mailmapCacheTTL = 10 * time.Minute
+)
+
+// a compiled regular expression for an optional name followed by an email in a line of the mailmap
+var regexpMailmapIdentity = regexp.MustCompile(`([^<>]*)<([^<>]*)>`)
+
+// mailmapEntry maps the commit email, optionally with the commit name, to the proper email.
+// The proper email is empty if the entry only maps the name
+type mailmapEntry struct {
+	properEmail string
+	commitName  string
+}
+
+// mailmap canonicalizes the emails of the commits as `git check-mailmap` does. The names are not canonicalized,
+// since the names of the contributors are the logins on the code hosting platform
+type mailmap map[string][]mailmapEntry
+
+// parseMailmap parses the content of a mailmap file, such as
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+func parseMailmap(content []byte) mailmap {
+	m := mailmap{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		matches := regexpMailmapIdentity.FindAllStringSubmatch(line, 2)
+		switch len(matches) {
+		case 1:
+			email := strings.ToLower(strings.TrimSpace(matches[0][2]))
+			m[email] = append(m[email], mailmapEntry{})
+		case 2:
+			email := strings.ToLower(strings.TrimSpace(matches[1][2]))
+			m[email] = append(m[email], mailmapEntry{
+				properEmail: strings.TrimSpace(matches[0][2]),
+				commitName:  strings.TrimSpace(matches[1][1]),
+			})
+		}
+	}
+
+	return m
+}
+
+// resolve returns the proper email of the identity in the commit.
+// The entry matching both the name and email is preferred to the one matching the email only
+func (m mailmap) resolve(name, email string) string {
+	var matched *mailmapEntry
+	entries := m[strings.ToLower(email)]
+	for i := range entries {
+		if entries[i].commitName == "" && matched == nil {
+			matched = &entries[i]
+		}
+		if entries[i].commitName != "" && strings.EqualFold(entries[i].commitName, name) {
+			matched = &entries[i]
+			break
+		}
+	}
+
+	if matched == nil || matched.properEmail == "" {
+		return email
+	}
+	return matched.properEmail
+}
+
+type mailmapCacheEntry struct {
+	m         mailmap
+	expiredAt time.Time
+}
+
+// mailmapCache caches the mailmaps by the repository, path and ref for the mailmapCacheTTL
+type mailmapCache struct {
+	mu      sync.Mutex
+	entries map[string]mailmapCacheEntry
+	now     func() time.Time
+}
+
+func newMailmapCache() *mailmapCache {
+	return &mailmapCache{entries: map[string]mailmapCacheEntry{}, now: time.Now}
+}
+
+func (c *mailmapCache) get(key string) (mailmap, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || !c.now().Before(e.expiredAt) {
+		return nil, false
+	}
+	return e.m, true
+}
+
+func (c *mailmapCache) put(key string, m mailmap) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxMailmapCacheSize {
+		c.entries = map[string]mailmapCacheEntry{}
+	}
+	c.entries[key] = mailmapCacheEntry{m: m, expiredAt: c.now().Add(mailmapCacheTTL)}
+}
+
+// loadMailmap loads the mailmap of the repository on the base branch of the pull request, or on the default branch
+// if the base is unknown. It is never read from the head, which the author of the pull request controls
+func (bot *robot) loadMailmap(pr *prSnapshot, repoCnf *repoConfig) (mailmap, bool) {
+	ref := pr.base
+
+	key := pr.org + "/" + pr.repo + "/" + repoCnf.MailmapFile + "@" + ref
+	if bot.mailmaps != nil {
+		if m, ok := bot.mailmaps.get(key); ok {
+			return m, true
+		}
+	}
+
+	content, success := bot.cli.GetRepoFileContent(pr.org, pr.repo, repoCnf.MailmapFile, ref)
+	if !success {
+		return nil, false
+	}
+
+	m := parseMailmap(content)
+	if bot.mailmaps != nil {
+		bot.mailmaps.put(key, m)
+	}
+	return m, true
+}
+
+// canonicalizeIdentities maps the emails of the authors and committers by the mailmap of the repository
+func (bot *robot) canonicalizeIdentities(pr *prSnapshot, commits []client.PRCommit, repoCnf *repoConfig) []client.PRCommit {
+	m, success := bot.loadMailmap(pr, repoCnf)
+	if !success || len(m) == 0 {
+		return commits
+	}
+
+	result := make([]client.PRCommit, len(commits))
+	for i := range commits {
+		c := commits[i]
+		c.AuthorEmail = m.resolve(c.AuthorName, c.AuthorEmail)
+		c.CommitterEmail = m.resolve(c.CommitterName, c.CommitterEmail)
+		result[i] = c
+	}
+
+	return result
+}
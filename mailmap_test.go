@@ -0,0 +1,75 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMailmapResolve(t *testing.T) {
+	m := parseMailmap([]byte(`# the identities of the contributors
+Jane Doe <jane@old.example.com>
+<jane@example.com> <Jane@Old.example.com>
+Joe Dev <joe@example.com> <dev@example.com>
+Bot Owner <owner@example.com> ci-bot <dev@example.com>
+`))
+
+	// the entry mapping the name only keeps the email
+	assert.Equal(t, "jane@old.example.com", m.resolve("jane", "jane@old.example.com"))
+	assert.Equal(t, "joe@example.com", m.resolve("joe", "dev@example.com"))
+	assert.Equal(t, "owner@example.com", m.resolve("CI-Bot", "dev@example.com"))
+	assert.Equal(t, "e1", m.resolve("u1", "e1"))
+}
+
+func TestCanonicalizeIdentities(t *testing.T) {
+	mc := &refRecordingClient{mockClient: &mockClient{
+		successfulGetRepoFileContent: true,
+		fileContent:                  []byte("Joe Dev <joe@example.com> <dev@example.com>\n"),
+	}}
+	bot := &robot{cli: mc, cnf: &configuration{}, mailmaps: newMailmapCache()}
+	commits := []client.PRCommit{{AuthorName: "joe", AuthorEmail: "dev@example.com", CommitterName: "u1", CommitterEmail: "e1"}}
+	repoCnf := &repoConfig{MailmapFile: ".mailmap"}
+	pr := newPRSnapshot(mc, org, repo, number)
+	pr.base = "main"
+
+	// only the emails are canonicalized, by the mailmap of the base branch
+	got := bot.canonicalizeIdentities(pr, commits, repoCnf)
+	assert.Equal(t, []client.PRCommit{
+		{AuthorName: "joe", AuthorEmail: "joe@example.com", CommitterName: "u1", CommitterEmail: "e1"},
+	}, got)
+	assert.Equal(t, []string{"main"}, mc.refs)
+
+	// the mailmap of the same base is cached until it expires
+	mc.successfulGetRepoFileContent = false
+	assert.Equal(t, got, bot.canonicalizeIdentities(pr, commits, repoCnf))
+	assert.Equal(t, 1, len(mc.refs))
+
+	bot.mailmaps.now = func() time.Time { return time.Now().Add(mailmapCacheTTL) }
+	assert.Equal(t, commits, bot.canonicalizeIdentities(pr, commits, repoCnf))
+	assert.Equal(t, 2, len(mc.refs))
+}
+
+// refRecordingClient records the refs the files are read at
+type refRecordingClient struct {
+	*mockClient
+	refs []string
+}
+
+func (c *refRecordingClient) GetRepoFileContent(org, repo, path, ref string) ([]byte, bool) {
+	c.refs = append(c.refs, ref)
+	return c.mockClient.GetRepoFileContent(org, repo, path, ref)
+}
@@ -16,20 +16,57 @@ package main
 import (
 	"flag"
 	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/sirupsen/logrus"
+	"net/http"
 	"os"
 )
 
 const component = "robot-universal-cla"
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == migrateCommand {
+		if err := runMigrate(os.Args[2:], os.Stdout); err != nil {
+			logrus.WithError(err).Fatal("failed to migrate the legacy config")
+		}
+		return
+	}
 
 	opt := new(robotOptions)
 	// Gather the necessary arguments from command line for project startup
 	cnf, token := opt.gatherOptions(flag.NewFlagSet(os.Args[0], flag.ExitOnError), os.Args[1:]...)
 	if opt.interrupt {
-		return
+		opt.exit()
 	}
 
-	bot := newRobot(cnf, token)
+	bot, err := newRobot(cnf, token, opt.readToken)
+	if err != nil {
+		opt.abort(diagnosticClassPlatform, err, "fatal error occurred while connecting to the platform")
+		opt.exit()
+	}
+	stopTracing, err := startTracing(bot.log)
+	if err != nil {
+		bot.log.WithError(err).Fatal("failed to export the traces")
+	}
+	defer stopTracing()
+
+	bot.templates = opt.templates
+	bot.corporateCLA = opt.corporateCLA
+	bot.notifier = newEmailNotifier(&cnf.EmailNotification, opt.smtpPassword, bot.log)
+	watchConfig(bot, opt.service.ConfigFile, bot.log)
+	registerConfigStatusHandler(http.DefaultServeMux, bot)
+	registerHealthHandlers(http.DefaultServeMux, bot, newReadinessProbe())
+	registerAdminHandlers(http.DefaultServeMux, bot, opt.adminTenants, bot.log)
+	registerUIHandlers(http.DefaultServeMux, bot, opt.uiToken, opt.uiPublic, bot.log)
+	registerCLASignedCallback(http.DefaultServeMux, bot, bot.log)
+	registerMetricsHandler(http.DefaultServeMux, opt.metricsPath, bot.budget)
+	if opt.serverless {
+		err = runServerless(bot, opt.service.HandlePath, opt.stateFile, http.DefaultServeMux)
+		// Fatal exits without running the deferred functions, so the traces are flushed before it
+		stopTracing()
+		bot.log.WithError(err).Fatal("the serverless function stopped")
+	}
+	startRescanScheduler(bot, bot.log)
+	startOverrideExpiryScheduler(bot, bot.log)
+	startBacklogReportScheduler(bot, bot.log)
 	framework.StartupServer(framework.NewServer(bot, opt.service), opt.service)
 }
@@ -0,0 +1,68 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// markdownSpecialChars are escaped in the names, so that a name can not format or break the comment
+	markdownSpecialChars = "\\`*_{}[]()<>#+!|~"
+
+	// bidiFirstStrongIsolate and bidiPopDirectionalIsolate isolate the direction of a right-to-left name
+	// from the text around it
+	bidiFirstStrongIsolate    = '⁨'
+	bidiPopDirectionalIsolate = '⁩'
+)
+
+// isBidiControl checks whether the rune is an invisible formatting character changing the text direction
+func isBidiControl(r rune) bool {
+	return r == '؜' || r == '‎' || r == '‏' ||
+		(r >= '‪' && r <= '‮') || (r >= '⁦' && r <= '⁩')
+}
+
+// isRightToLeft checks whether the rune belongs to a script written from right to left
+func isRightToLeft(r rune) bool {
+	return unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+// escapeMarkdownName makes a contributor name safe to be interpolated into a markdown comment.
+// The markdown characters are escaped, the line breaks and the other control characters are replaced by
+// spaces, and the direction overrides are dropped. A right-to-left name is isolated, so that it does not
+// reorder the text around it. The other scripts, such as CJK, are kept as they are
+func escapeMarkdownName(name string) string {
+	var b strings.Builder
+	rtl := false
+	for _, r := range name {
+		switch {
+		case isBidiControl(r):
+			continue
+		case unicode.IsControl(r):
+			r = ' '
+		case strings.ContainsRune(markdownSpecialChars, r):
+			b.WriteByte('\\')
+		case isRightToLeft(r):
+			rtl = true
+		}
+		b.WriteRune(r)
+	}
+
+	s := strings.TrimSpace(b.String())
+	if rtl {
+		return string(bidiFirstStrongIsolate) + s + string(bidiPopDirectionalIsolate)
+	}
+	return s
+}
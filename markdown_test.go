@@ -0,0 +1,64 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestEscapeMarkdownName(t *testing.T) {
+	testCases := []struct {
+		desc string
+		in   string
+		out  string
+	}{
+		{"plain", "Jane Doe", "Jane Doe"},
+		{"code span", "`rm -rf`", "\\`rm -rf\\`"},
+		{"table cell", "a | b", "a \\| b"},
+		{"link", "[click](https://evil)", "\\[click\\]\\(https://evil\\)"},
+		{"emphasis", "**bold** _it_", "\\*\\*bold\\*\\* \\_it\\_"},
+		{"html", "<img src=x>", "\\<img src=x\\>"},
+		{"heading", "# title", "\\# title"},
+		{"line breaks", "jane\n\n### doe\r", "jane  \\#\\#\\# doe"},
+		{"direction override", "jane‮doe", "janedoe"},
+		{"chinese", "张三", "张三"},
+		{"japanese", "山田_太郎", "山田\\_太郎"},
+		{"korean", "김철수", "김철수"},
+		{"hebrew", "שלום", "⁨שלום⁩"},
+		{"arabic with latin", "محمد Ali", "⁨محمد Ali⁩"},
+		{"isolate injected", "⁨محمد⁩⁩", "⁨محمد⁩"},
+	}
+	for i := range testCases {
+		t.Run(testCases[i].desc, func(t *testing.T) {
+			assert.Equal(t, testCases[i].out, escapeMarkdownName(testCases[i].in))
+		})
+	}
+}
+
+func TestWaitCLASignatureEscapesNames(t *testing.T) {
+	mc := &mockClient{successfulAddPRLabels: true}
+	bot := &robot{cli: mc, cnf: &configuration{
+		CommentSomeNeedSign:  "%s need to sign at %s, %s",
+		PlaceholderCommitter: "ddd",
+		UserMarkFormat:       "@ddd",
+	}}
+	repoCnf := &repoConfig{CLALabelYes: labelYes, CLALabelNo: labelNo}
+
+	bot.waitCLASignature(newPRSnapshot(mc, org, repo, number), [3][]string{1: {"eve|](x)\n# boom", "李四"}}, nil, repoCnf)
+	assert.Equal(t, withResultMarker(resultKindNeedSign, "@eve\\|\\]\\(x\\) \\# boom, @李四 need to sign at , "),
+		mc.comment)
+	assert.NotContains(t, strings.TrimSuffix(mc.comment, withResultMarker(resultKindNeedSign, "")), "\n")
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// adminPathPRCLAStatus is the CLA status of a pull request for the merge tooling, ?org=&repo=&number=[&sha=].
+// The sha is the head to merge, which is not mergeable unless it is the head evaluated
+const adminPathPRCLAStatus = "/api/v1/pr-cla-status"
+
+// pendingStatusDescriptions describe the decisions neither passing nor failing the check in the pending status
+var pendingStatusDescriptions = map[string]string{
+	prStatusUnknown:            "The CLA status of some contributors could not be checked",
+	decisionNoCommits:          "The pull request has no commits",
+	decisionCommitsUnavailable: "The commits of the pull request could not be read",
+	decisionStopped:            "The CLA check was stopped and needs a manual review",
+}
+
+// reportPendingStatus reports the pending commit status under the merge_gate when the evaluation reported none,
+// so that the required status does not stay missing or keep the result of an earlier head
+func (bot *robot) reportPendingStatus(pr *prSnapshot, repoCnf *repoConfig) {
+	description, ok := pendingStatusDescriptions[pr.stats.decision]
+	if !repoCnf.MergeGate || !ok || pr.stats.commitStatus != "" {
+		return
+	}
+
+	bot.reportCommitStatus(pr, repoCnf, commitStatusPending, description)
+}
+
+// prCLAStatus is the CLA status of a pull request in its latest evaluation, for the merge tooling to admit the merge
+// without parsing the labels
+type prCLAStatus struct {
+	Org    string `json:"org"`
+	Repo   string `json:"repo"`
+	Number string `json:"number"`
+	Status string `json:"status"`
+	// Overridden is true if an override of the CLA check with a ttl is active
+	Overridden bool `json:"overridden"`
+	// SignedCommits is the result of the require_signed_commits, which is empty if it is not required
+	SignedCommits string `json:"signed_commits,omitempty"`
+	// Mergeable is true if all the contributors have signed or the check is overridden, the commits are signed
+	// if required, and the head to merge is the one evaluated
+	Mergeable bool `json:"mergeable"`
+	// HeadSHA is the head commit evaluated, the merge tooling should check it is still the head
+	HeadSHA        string    `json:"head_sha,omitempty"`
+	LastEvaluation time.Time `json:"last_evaluation"`
+}
+
+func (s *adminServer) handlePRCLAStatus(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	org, repo, number := q.Get("org"), q.Get("repo"), q.Get("number")
+	if org == "" || repo == "" || number == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing the org, the repo or the number"})
+		return
+	}
+
+	state, ok := s.store.get(org, repo, number)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "the pull request has not been evaluated"})
+		return
+	}
+
+	result := prCLAStatus{
+		Org: org, Repo: repo, Number: number, Status: state.Status, Overridden: state.Override.active(time.Now()),
+		SignedCommits: state.SignedCommits, HeadSHA: state.HeadSHA, LastEvaluation: state.LastEvaluation,
+	}
+	sha := q.Get("sha")
+	result.Mergeable = (result.Overridden || state.Status == prStatusSigned) &&
+		(state.SignedCommits == "" || state.SignedCommits == signedCommitsVerified) &&
+		(sha == "" || strings.EqualFold(sha, state.HeadSHA))
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReportPendingStatus(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestCommitMessages: true,
+		successfulCreateCommitStatus:           true,
+		commitMessages:                         []prCommitMessage{{SHA: "sha1"}},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	repoCnf := &repoConfig{MergeGate: true}
+	assert.Equal(t, enforcementBlock, repoCnf.enforcementLevel())
+	assert.Error(t, (&repoConfig{EnforcementLevel: enforcementLabel, MergeGate: true}).validateEnforcementLevel())
+
+	pr := newPRSnapshot(mc, org, repo, number)
+	pr.stats.decision = prStatusUnknown
+	bot.reportPendingStatus(pr, repoCnf)
+	assert.Equal(t, commitStatus{
+		State:       commitStatusPending,
+		Description: pendingStatusDescriptions[prStatusUnknown],
+		Context:     defaultCommitStatusContext,
+	}, mc.status)
+
+	// the status reported by the evaluation is kept
+	mc.method = ""
+	pr = newPRSnapshot(mc, org, repo, number)
+	pr.stats.decision, pr.stats.commitStatus = prStatusUnsigned, commitStatusFailure
+	bot.reportPendingStatus(pr, repoCnf)
+	assert.Equal(t, "", mc.method)
+
+	// the overridden pull request is not pending
+	pr.stats.decision, pr.stats.commitStatus = decisionOverridden, ""
+	bot.reportPendingStatus(pr, repoCnf)
+	assert.Equal(t, "", mc.method)
+
+	// the pending status is only reported under the merge_gate
+	pr.stats.decision = decisionNoCommits
+	bot.reportPendingStatus(pr, &repoConfig{CommitStatus: true})
+	assert.Equal(t, "", mc.method)
+}
+
+func TestAdminPRCLAStatusHandler(t *testing.T) {
+	now := time.Now().UTC()
+	store := newMemoryStateStore()
+	store.put(prState{Org: org, Repo: repo, Number: "1", Status: prStatusSigned, HeadSHA: "sha1", LastEvaluation: now})
+	store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusUnsigned})
+	store.put(prState{Org: org, Repo: repo, Number: "3", Status: prStatusUnsigned,
+		Override: &claOverride{By: "m1", Reason: "imported", At: now, ExpiresAt: now.Add(time.Hour)}})
+	store.put(prState{Org: org, Repo: repo, Number: "5", Status: prStatusSigned, HeadSHA: "sha5",
+		SignedCommits: signedCommitsUnverified})
+	mux := http.NewServeMux()
+	registerAdminHandlers(mux, &robot{store: store}, []adminTenant{{Name: "admin", APIKey: "secret"}}, framework.NewLogger())
+
+	request := func(query string) (int, prCLAStatus) {
+		req := httptest.NewRequest(http.MethodGet, adminPathPRCLAStatus+query, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		result := prCLAStatus{}
+		_ = json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result
+	}
+
+	code, result := request("?org=org1&repo=repo1&number=1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, prCLAStatus{Org: org, Repo: repo, Number: "1", Status: prStatusSigned, Mergeable: true,
+		HeadSHA: "sha1", LastEvaluation: now}, result)
+
+	// the head to merge must be the one evaluated
+	_, result = request("?org=org1&repo=repo1&number=1&sha=SHA1")
+	assert.Equal(t, true, result.Mergeable)
+	_, result = request("?org=org1&repo=repo1&number=1&sha=sha2")
+	assert.Equal(t, false, result.Mergeable)
+
+	_, result = request("?org=org1&repo=repo1&number=2")
+	assert.Equal(t, false, result.Mergeable)
+
+	// the commits must be signed if required
+	_, result = request("?org=org1&repo=repo1&number=5")
+	assert.Equal(t, signedCommitsUnverified, result.SignedCommits)
+	assert.Equal(t, false, result.Mergeable)
+
+	_, result = request("?org=org1&repo=repo1&number=3")
+	assert.Equal(t, true, result.Overridden)
+	assert.Equal(t, true, result.Mergeable)
+
+	code, _ = request("?org=org1&repo=repo1&number=4")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = request("?org=org1&repo=repo1")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
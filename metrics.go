@@ -0,0 +1,188 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+// defaultMetricsPath is the path serving the metrics when the --metrics-path is not set
+const defaultMetricsPath = "/metrics"
+
+// the result label values of the metrics
+const (
+	metricsResultSuccess = "success"
+	metricsResultFailure = "failure"
+)
+
+var (
+	claChecksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cla_checks_total",
+		Help: "The number of the CLA checks of the pull requests, by the decision.",
+	}, []string{"decision"})
+
+	claSignStatesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cla_sign_states_total",
+		Help: "The number of the emails checked, by the sign state.",
+	}, []string{"state"})
+
+	claServerRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cla_server_request_duration_seconds",
+		Help:    "The latency of the requests to the CLA server, by the method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+
+	claServerErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cla_server_errors_total",
+		Help: "The number of the failed requests to the CLA server, by the method.",
+	}, []string{"method"})
+
+	labelUpdateFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cla_label_update_failures_total",
+		Help: "The number of the failed label updates, by the operation.",
+	}, []string{"operation"})
+
+	commentOperationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cla_comment_operations_total",
+		Help: "The number of the comment operations, by the operation and the result.",
+	}, []string{"operation", "result"})
+
+	serializedWaitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cla_serialized_waits_total",
+		Help: "The number of the handlings which waited for another one of the same pull request.",
+	})
+
+	duplicateEventsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cla_duplicate_events_total",
+		Help: "The number of the webhook deliveries skipped as replays.",
+	})
+
+	watchdogExceededTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cla_watchdog_exceeded_total",
+		Help: "The number of the evaluations stopped by the watchdog.",
+	})
+)
+
+// registerMetricsHandler serves the metrics with those of the collectors on the path,
+// the metrics are not served if the path is empty
+func registerMetricsHandler(mux *http.ServeMux, path string, collectors ...prometheus.Collector) {
+	if path == "" {
+		return
+	}
+
+	prometheus.MustRegister(collectors...)
+	mux.Handle(path, promhttp.Handler())
+}
+
+// observeEvaluation records the decision and the sign states of a finished evaluation
+func observeEvaluation(pr *prSnapshot) {
+	claChecksTotal.WithLabelValues(pr.stats.decision).Inc()
+
+	states := [3]string{prStatusSigned, prStatusUnsigned, prStatusUnknown}
+	for i := range states {
+		if n := len(pr.stats.signResult[i]); n != 0 {
+			claSignStatesTotal.WithLabelValues(states[i]).Add(float64(n))
+		}
+	}
+}
+
+func metricsResult(success bool) string {
+	if success {
+		return metricsResultSuccess
+	}
+	return metricsResultFailure
+}
+
+// metricsClient records the metrics of the calls of the wrapped client
+type metricsClient struct {
+	iClient
+}
+
+func wrapMetricsClient(cli iClient) iClient {
+	return &metricsClient{iClient: cli}
+}
+
+// observeCLAServer records the latency and the failure of a request to the CLA server
+func observeCLAServer(method string, start time.Time, success bool) {
+	claServerRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if !success {
+		claServerErrorsTotal.WithLabelValues(method).Inc()
+	}
+}
+
+func (c *metricsClient) CheckCLASignature(urlStr string) (string, bool) {
+	start := time.Now()
+	signState, success := c.iClient.CheckCLASignature(urlStr)
+	observeCLAServer("CheckCLASignature", start, success)
+	return signState, success
+}
+
+func (c *metricsClient) CheckCLASignatures(urlStr string, emails []string) (map[string]string, bool) {
+	start := time.Now()
+	signStates, success := c.iClient.CheckCLASignatures(urlStr, emails)
+	observeCLAServer("CheckCLASignatures", start, success)
+	return signStates, success
+}
+
+func (c *metricsClient) CheckCLASignatureDetail(urlStr string) (string, string, bool) {
+	start := time.Now()
+	signState, claType, success := c.iClient.CheckCLASignatureDetail(urlStr)
+	observeCLAServer("CheckCLASignatureDetail", start, success)
+	return signState, claType, success
+}
+
+func (c *metricsClient) VerifyCLASignatureID(urlStr, email string) (string, bool) {
+	start := time.Now()
+	signState, success := c.iClient.VerifyCLASignatureID(urlStr, email)
+	observeCLAServer("VerifyCLASignatureID", start, success)
+	return signState, success
+}
+
+func (c *metricsClient) AddPRLabels(org, repo, number string, labels []string) bool {
+	success := c.iClient.AddPRLabels(org, repo, number, labels)
+	if !success {
+		labelUpdateFailuresTotal.WithLabelValues("add").Inc()
+	}
+	return success
+}
+
+func (c *metricsClient) RemovePRLabels(org, repo, number string, labels []string) bool {
+	success := c.iClient.RemovePRLabels(org, repo, number, labels)
+	if !success {
+		labelUpdateFailuresTotal.WithLabelValues("remove").Inc()
+	}
+	return success
+}
+
+func (c *metricsClient) CreatePRComment(org, repo, number, comment string) (string, bool) {
+	commentID, success := c.iClient.CreatePRComment(org, repo, number, comment)
+	commentOperationsTotal.WithLabelValues("create", metricsResult(success)).Inc()
+	return commentID, success
+}
+
+func (c *metricsClient) DeletePRComment(org, repo, commentID string) bool {
+	success := c.iClient.DeletePRComment(org, repo, commentID)
+	commentOperationsTotal.WithLabelValues("delete", metricsResult(success)).Inc()
+	return success
+}
+
+func (c *metricsClient) UpdatePRComment(org, repo, commentID, comment string) bool {
+	success := c.iClient.UpdatePRComment(org, repo, commentID, comment)
+	commentOperationsTotal.WithLabelValues("update", metricsResult(success)).Inc()
+	return success
+}
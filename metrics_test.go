@@ -0,0 +1,74 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsClient(t *testing.T) {
+	mc := &mockClient{CLAState: "signed", successfulCheckCLASignature: false, successfulCreatePRComment: true}
+	cli := wrapMetricsClient(mc)
+
+	errors := testutil.ToFloat64(claServerErrorsTotal.WithLabelValues("CheckCLASignature"))
+	created := testutil.ToFloat64(commentOperationsTotal.WithLabelValues("create", metricsResultSuccess))
+	addFailures := testutil.ToFloat64(labelUpdateFailuresTotal.WithLabelValues("add"))
+
+	signState, success := cli.CheckCLASignature("https://cla/check?email=a")
+	assert.Equal(t, "signed", signState)
+	assert.Equal(t, false, success)
+	assert.Equal(t, errors+1, testutil.ToFloat64(claServerErrorsTotal.WithLabelValues("CheckCLASignature")))
+
+	_, success = cli.CreatePRComment("org1", "repo1", "1", "comment")
+	assert.Equal(t, true, success)
+	assert.Equal(t, created+1, testutil.ToFloat64(commentOperationsTotal.WithLabelValues("create", metricsResultSuccess)))
+
+	assert.Equal(t, false, cli.AddPRLabels("org1", "repo1", "1", []string{"cla/yes"}))
+	assert.Equal(t, addFailures+1, testutil.ToFloat64(labelUpdateFailuresTotal.WithLabelValues("add")))
+}
+
+func TestObserveEvaluation(t *testing.T) {
+	checks := testutil.ToFloat64(claChecksTotal.WithLabelValues(prStatusUnsigned))
+	unsigned := testutil.ToFloat64(claSignStatesTotal.WithLabelValues(prStatusUnsigned))
+
+	pr := newPRSnapshot(nil, "org1", "repo1", "1")
+	pr.stats.decision = prStatusUnsigned
+	pr.stats.signResult[1] = []string{"a@example.com", "b@example.com"}
+	observeEvaluation(pr)
+
+	assert.Equal(t, checks+1, testutil.ToFloat64(claChecksTotal.WithLabelValues(prStatusUnsigned)))
+	assert.Equal(t, unsigned+2, testutil.ToFloat64(claSignStatesTotal.WithLabelValues(prStatusUnsigned)))
+}
+
+func TestRegisterMetricsHandler(t *testing.T) {
+	claChecksTotal.WithLabelValues(prStatusSigned)
+	mux := http.NewServeMux()
+	registerMetricsHandler(mux, defaultMetricsPath)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, defaultMetricsPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, true, strings.Contains(w.Body.String(), "cla_checks_total"))
+
+	mux = http.NewServeMux()
+	registerMetricsHandler(mux, "")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, defaultMetricsPath, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
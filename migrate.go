@@ -0,0 +1,159 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/opensourceways/server-common-lib/utils"
+	"io"
+	"os"
+	"reflect"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// migrateCommand is the first argument running the bot as the converter of the legacy configs, such as
+// `robot-universal-cla migrate -from legacy.yaml -out config.yaml`
+const migrateCommand = "migrate"
+
+// legacySomeNeedSignVariables map the %s of the legacy comment_some_need_sign to the variables of the
+// Go text/template, in order
+var legacySomeNeedSignVariables = []string{"{{.UnsignedUsers}}", "{{.SignURL}}", "{{.FAQURL}}"}
+
+// migrateLegacySomeNeedSign rewrites the printf format of the legacy comment_some_need_sign with the variables
+// of the Go text/template, the %% is unescaped
+func migrateLegacySomeNeedSign(comment string) (string, error) {
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(comment); i++ {
+		if comment[i] != '%' || i+1 == len(comment) {
+			b.WriteByte(comment[i])
+			continue
+		}
+
+		switch i++; comment[i] {
+		case '%':
+			b.WriteByte('%')
+		case 's':
+			if n == len(legacySomeNeedSignVariables) {
+				return "", fmt.Errorf("the legacy comment_some_need_sign has more than %d %%s",
+					len(legacySomeNeedSignVariables))
+			}
+			b.WriteString(legacySomeNeedSignVariables[n])
+			n++
+		default:
+			return "", fmt.Errorf("the legacy comment_some_need_sign has the unsupported verb %%%c", comment[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// migrateLegacyConfig converts the config of the earlier releases of the bot, whose comment_some_need_sign is a
+// printf format of the unsigned users, the sign url and the faq url, and whose comment_all_signed marks the
+// signed users by the placeholder_committer. The comments are rewritten as the Go text/templates of the same
+// texts, those already rewritten are kept, and the legacy compatibility_profile keeps the behaviors of those
+// releases until it is removed. The migrated config is validated as it is loaded
+func migrateLegacyConfig(c *configuration) (*configuration, error) {
+	if c.CommentSomeNeedSign != "" && !isCommentTemplate(c.CommentSomeNeedSign) {
+		comment, err := migrateLegacySomeNeedSign(c.CommentSomeNeedSign)
+		if err != nil {
+			return nil, err
+		}
+		c.CommentSomeNeedSign = comment
+	}
+	if c.CommentAllSigned != "" && !isCommentTemplate(c.CommentAllSigned) && c.PlaceholderCommitter != "" {
+		c.CommentAllSigned = strings.ReplaceAll(c.CommentAllSigned, c.PlaceholderCommitter, "{{.SignedUsers}}")
+	}
+
+	if c.CompatibilityProfile == "" {
+		c.CompatibilityProfile = compatibilityProfileLegacy
+	}
+
+	if missing := c.listMissingConfig(); len(missing) != 0 {
+		return nil, fmt.Errorf("the migrated config misses %s", strings.Join(missing, ", "))
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// marshalMigratedConfig writes the configuration as yaml without the fields of the zero values
+func marshalMigratedConfig(c *configuration) ([]byte, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var v map[string]any
+	if err = json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(pruneZeroValues(v))
+}
+
+// pruneZeroValues removes the zero values from the maps and the slices decoded from json, recursively
+func pruneZeroValues(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k := range t {
+			if t[k] = pruneZeroValues(t[k]); t[k] == nil || reflect.ValueOf(t[k]).IsZero() {
+				delete(t, k)
+			} else if m, ok := t[k].(map[string]any); ok && len(m) == 0 {
+				delete(t, k)
+			}
+		}
+	case []any:
+		for i := range t {
+			t[i] = pruneZeroValues(t[i])
+		}
+	}
+	return v
+}
+
+// runMigrate converts the legacy config of the -from into the configuration, and writes it to the -out
+func runMigrate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet(migrateCommand, flag.ContinueOnError)
+	from := fs.String("from", "", "Path to the config file of an earlier release of the bot.")
+	out := fs.String("out", "", "Path to the file the migrated config is written to. It is the stdout if not set.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *from == "" {
+		return errors.New("the -from is required")
+	}
+
+	legacy := &configuration{}
+	if err := utils.LoadFromYaml(*from, legacy); err != nil {
+		return err
+	}
+	c, err := migrateLegacyConfig(legacy)
+	if err != nil {
+		return err
+	}
+	data, err := marshalMigratedConfig(c)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*out, data, 0o644)
+}
@@ -0,0 +1,72 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"github.com/opensourceways/server-common-lib/utils"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateLegacySomeNeedSign(t *testing.T) {
+	comment, err := migrateLegacySomeNeedSign("%s, sign at %s, see %s, 100%% signed")
+	assert.NoError(t, err)
+	assert.Equal(t, "{{.UnsignedUsers}}, sign at {{.SignURL}}, see {{.FAQURL}}, 100% signed", comment)
+
+	_, err = migrateLegacySomeNeedSign("%s, %s, %s and %s")
+	assert.ErrorContains(t, err, "more than 3 %s")
+
+	_, err = migrateLegacySomeNeedSign("%d users")
+	assert.ErrorContains(t, err, "unsupported verb %d")
+}
+
+func TestRunMigrate(t *testing.T) {
+	legacy := findTestdata(t, filepath.Join("migrate", "legacy.yaml"))
+	assert.ErrorContains(t, runMigrate(nil, nil), "the -from is required")
+
+	out := filepath.Join(t.TempDir(), "config.yaml")
+	var stdout bytes.Buffer
+	assert.NoError(t, runMigrate([]string{"-from", legacy, "-out", out}, &stdout))
+	assert.Empty(t, stdout.String())
+
+	// the migrated config is loaded as it is by the bot
+	c := &configuration{}
+	assert.NoError(t, utils.LoadFromYaml(out, c))
+	assert.NoError(t, c.Validate())
+	assert.Empty(t, c.listMissingConfig())
+	assert.Equal(t, "openubmc", c.CommunityName)
+	assert.Equal(t, compatibilityProfileLegacy, c.CompatibilityProfile)
+	assert.Equal(t, "### CLA Signature Guide  \n\n {{.UnsignedUsers}} , thanks for your pull request. \n\n"+
+		"[You can click here to sign the CLA]({{.SignURL}}). :pray:  \n\nPlease check the [**FAQs**]({{.FAQURL}}) first. "+
+		"100% of the authors must sign.", c.CommentSomeNeedSign)
+	assert.Equal(t, "### CLA Signature Pass  \n\n{{.SignedUsers}}, thanks for your pull request. "+
+		"All authors of the commits have signed the CLA. :wave: ", c.CommentAllSigned)
+	assert.Equal(t, "[@【committer】](https://gitcode.com/【committer】)", c.UserMarkFormat)
+
+	item := c.ConfigItems[0]
+	assert.Equal(t, []string{"org1"}, item.Repos)
+	assert.Equal(t, []string{"org1/infrastructure"}, item.ExcludedRepos)
+	assert.Equal(t, "org-cla/yes", item.CLALabelYes)
+	assert.Equal(t, true, item.CheckByCommitter)
+	assert.Equal(t, "noreply@gitcode.com", item.LitePRCommitter.Email)
+
+	// the migrated config is migrated as it is
+	again := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, runMigrate([]string{"-from", out, "-out", again}, nil))
+	migrated := &configuration{}
+	assert.NoError(t, utils.LoadFromYaml(again, migrated))
+	assert.Equal(t, c.CommentSomeNeedSign, migrated.CommentSomeNeedSign)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMisconfigReportIntervalHours is used when the interval_hours of the misconfig_report is not configured
+	defaultMisconfigReportIntervalHours = 24
+
+	// defaultCommentMisconfig is the comment posted on the pull request whose repository is misconfigured
+	defaultCommentMisconfig = "### CLA Bot Misconfiguration  \n\nThe CLA check of this pull request is skipped, " +
+		"because the bot is misconfigured for %s: %s. Please ask the administrators of the bot to fix it."
+	defaultMisconfigIssueTitle = "CLA bot misconfiguration of %s"
+	defaultMisconfigIssueBody  = "The CLA check of the pull requests of %s is skipped, because the bot is " +
+		"misconfigured: %s.\n\nIt is found on the pull request %s."
+
+	misconfigNoConfig = "no config item matches the repository"
+)
+
+// misconfigReportConfig reports the misconfiguration of the bot found when handling the pull requests,
+// which is only logged if neither of comment_on_pr and ops_repo is set
+type misconfigReportConfig struct {
+	// CommentOnPR posts a comment for the maintainers on the affected pull request
+	CommentOnPR bool `json:"comment_on_pr"`
+	// OpsRepo is the repository in the format of org/repo where an issue is created for the misconfiguration
+	OpsRepo string `json:"ops_repo"`
+	// IntervalHours is the min interval to report the same misconfiguration of a pull request by comment,
+	// or of a repository by issue, again. Default is 24
+	IntervalHours int `json:"interval_hours"`
+	// Comment is the comment posted on the pull request. It has one %s for the repository and one %s for
+	// the misconfiguration. A default comment is used if it is empty
+	Comment string `json:"comment"`
+}
+
+func (c *misconfigReportConfig) validate() error {
+	if c.OpsRepo != "" {
+		if org, repo, found := strings.Cut(c.OpsRepo, "/"); !found || org == "" || repo == "" {
+			return errors.New("the ops_repo of the misconfig_report must be in the format of org/repo")
+		}
+	}
+	if c.IntervalHours < 0 {
+		return errors.New("the interval_hours of the misconfig_report can not be negative")
+	}
+	return nil
+}
+
+func (c *misconfigReportConfig) interval() time.Duration {
+	if c.IntervalHours == 0 {
+		return defaultMisconfigReportIntervalHours * time.Hour
+	}
+	return time.Duration(c.IntervalHours) * time.Hour
+}
+
+// misconfigReporter remembers when each misconfiguration was reported, so that it is not reported on every event
+type misconfigReporter struct {
+	mu       sync.Mutex
+	reported map[string]time.Time
+	now      func() time.Time
+}
+
+func newMisconfigReporter() *misconfigReporter {
+	return &misconfigReporter{reported: map[string]time.Time{}, now: time.Now}
+}
+
+// due records the report of the key and returns true, unless the key was reported within the interval
+func (r *misconfigReporter) due(key string, interval time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if t, ok := r.reported[key]; ok && now.Sub(t) < interval {
+		return false
+	}
+	r.reported[key] = now
+	return true
+}
+
+// usableRepoConfig returns the repoConfig of the pull request, or nil if there is none or it is invalid.
+// Such a misconfiguration is reported by the misconfig_report
+func (bot *robot) usableRepoConfig(pr *prSnapshot, logger *logrus.Entry) *repoConfig {
+	repoCnf, ok := bot.matchRepoConfig(bot.prConfig(pr), pr.org, pr.repo)
+	if !ok {
+		// it is not a misconfiguration, the event is handled again on the next one
+		logger.Warningf("skip the event, the metadata of the repo %s/%s can not be looked up", pr.org, pr.repo)
+		return nil
+	}
+	if repoCnf == nil {
+		// If the specified repository not match any repository  in the repoConfig list, it logs the warning and returns
+		logger.Warningf("no config for the repo: " + pr.org + "/" + pr.repo)
+		bot.reportMisconfig(pr, misconfigNoConfig, logger)
+		return nil
+	}
+
+	if err := repoCnf.validateRepoConfig(); err != nil {
+		logger.WithError(err).Warningf("invalid config for the repo: " + pr.org + "/" + pr.repo)
+		bot.reportMisconfig(pr, err.Error(), logger)
+		return nil
+	}
+	return repoCnf
+}
+
+// reportMisconfig reports the misconfiguration of the repository of the pull request by a comment on it
+// and an issue in the ops_repo
+func (bot *robot) reportMisconfig(pr *prSnapshot, problem string, logger *logrus.Entry) {
+	cfg := &bot.prConfig(pr).MisconfigReport
+	if bot.misconfigs == nil || (!cfg.CommentOnPR && cfg.OpsRepo == "") {
+		return
+	}
+
+	repoName := pr.org + "/" + pr.repo
+	if cfg.CommentOnPR && bot.misconfigs.due(pr.key()+"#"+problem, cfg.interval()) {
+		if !bot.postPRComment(pr, bot.renderer(pr).misconfig(problem)) {
+			logger.Errorf("failed to comment the misconfiguration on %s", pr.key())
+		}
+	}
+
+	if cfg.OpsRepo != "" && bot.misconfigs.due(repoName+"#"+problem, cfg.interval()) {
+		org, repo, _ := strings.Cut(cfg.OpsRepo, "/")
+		title := fmt.Sprintf(defaultMisconfigIssueTitle, repoName)
+		if !bot.cli.CreateIssue(org, repo, title, fmt.Sprintf(defaultMisconfigIssueBody, repoName, problem, pr.key())) {
+			logger.Errorf("failed to create the issue of the misconfiguration of %s in %s", repoName, cfg.OpsRepo)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestReportMisconfig(t *testing.T) {
+	mc := &mockClient{successfulCreatePRComment: true, successfulCreateIssue: true}
+	item := repoConfig{
+		RepoFilter: config.RepoFilter{Repos: []string{org}}, CLALabelYes: labelYes, CLALabelNo: labelNo,
+		CheckURL: "http://cla/check", SignURL: "http://cla/sign", FAQURL: "http://cla/faq",
+	}
+	bot := &robot{
+		cli: mc,
+		cnf: &configuration{
+			ConfigItems:     []repoConfig{item},
+			MisconfigReport: misconfigReportConfig{CommentOnPR: true, OpsRepo: "ops/cla-bot"},
+		},
+		misconfigs: newMisconfigReporter(),
+	}
+	logger := framework.NewLogger()
+
+	assert.Equal(t, &bot.cnf.ConfigItems[0], bot.usableRepoConfig(newPRSnapshot(mc, org, repo, number), logger))
+	assert.Equal(t, "", mc.comment)
+
+	pr := newPRSnapshot(mc, "org2", "repo2", number)
+	assert.Nil(t, bot.usableRepoConfig(pr, logger))
+	assert.Equal(t, fmt.Sprintf(defaultCommentMisconfig, "org2/repo2", misconfigNoConfig), mc.comment)
+	assert.Equal(t, []string{"ops/cla-bot: " + fmt.Sprintf(defaultMisconfigIssueTitle, "org2/repo2")}, mc.issues)
+
+	// the same misconfiguration is not reported again within the interval
+	mc.comment = ""
+	assert.Nil(t, bot.usableRepoConfig(pr, logger))
+	assert.Equal(t, "", mc.comment)
+	assert.Equal(t, 1, len(mc.issues))
+
+	// another pull request of the repository is commented, but no issue is created for it
+	assert.Nil(t, bot.usableRepoConfig(newPRSnapshot(mc, "org2", "repo2", "2"), logger))
+	assert.NotEqual(t, "", mc.comment)
+	assert.Equal(t, 1, len(mc.issues))
+
+	now := time.Now().Add(defaultMisconfigReportIntervalHours * time.Hour)
+	bot.misconfigs.now = func() time.Time { return now }
+	assert.Nil(t, bot.usableRepoConfig(pr, logger))
+	assert.Equal(t, 2, len(mc.issues))
+
+	// the invalid config item is reported
+	bot.cnf.ConfigItems[0].CheckURL = ""
+	mc.comment = ""
+	assert.Nil(t, bot.usableRepoConfig(newPRSnapshot(mc, org, repo, number), logger))
+	assert.Contains(t, mc.comment, "check_url")
+
+	// only logged if the misconfig_report is not enabled
+	bot.cnf.MisconfigReport = misconfigReportConfig{}
+	mc.comment = ""
+	assert.Nil(t, bot.usableRepoConfig(newPRSnapshot(mc, "org3", "repo3", number), logger))
+	assert.Equal(t, "", mc.comment)
+
+	assert.NotEqual(t, nil, (&misconfigReportConfig{OpsRepo: "ops"}).validate())
+	assert.NotEqual(t, nil, (&misconfigReportConfig{IntervalHours: -1}).validate())
+}
+
+func TestRepoMetadataLookupError(t *testing.T) {
+	mc := &mockClient{successfulCreatePRComment: true, successfulCreateIssue: true}
+	item := repoConfig{
+		RepoFilter: config.RepoFilter{Repos: []string{org}}, CLALabelYes: labelYes, CLALabelNo: labelNo,
+		CheckURL: "http://cla/check", SignURL: "http://cla/sign", FAQURL: "http://cla/faq",
+	}
+	bot := &robot{
+		cli: mc,
+		cnf: &configuration{
+			ConfigItems:     []repoConfig{item},
+			MisconfigReport: misconfigReportConfig{CommentOnPR: true, OpsRepo: "ops/cla-bot"},
+		},
+		misconfigs: newMisconfigReporter(),
+		repos:      newRepoMetadataCache(time.Minute, mc.GetRepoMetadata),
+		log:        framework.NewLogger(),
+	}
+	logger := framework.NewLogger()
+
+	// the event is skipped rather than matching the archived repository, and it is not reported
+	assert.Nil(t, bot.usableRepoConfig(newPRSnapshot(mc, org, repo, number), logger))
+	assert.Nil(t, bot.getRepoConfig(org, repo))
+	assert.Equal(t, "", mc.comment)
+	assert.Empty(t, mc.issues)
+
+	mc.successfulGetRepoMetadata, mc.repoMeta = true, repoMetadata{Archived: true}
+	assert.Nil(t, bot.getRepoConfig(org, "repo2"))
+	mc.repoMeta = repoMetadata{}
+	assert.Equal(t, &bot.cnf.ConfigItems[0], bot.usableRepoConfig(newPRSnapshot(mc, org, repo, number), logger))
+}
@@ -14,19 +14,40 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"github.com/opensourceways/robot-framework-lib/client"
 	"github.com/opensourceways/robot-framework-lib/config"
 	"github.com/opensourceways/server-common-lib/secret"
+	"github.com/opensourceways/server-common-lib/utils"
 	"github.com/sirupsen/logrus"
 	"os"
+	"sort"
 )
 
 type robotOptions struct {
-	service   config.FrameworkOptions
-	delToken  bool
-	interrupt bool
-	tokenPath string
+	service          config.FrameworkOptions
+	delToken         bool
+	interrupt        bool
+	tokenPath        string
+	readTokenPath    string
+	readToken        []byte
+	adminTokenPath   string
+	adminTenantsPath string
+	adminTenants     []adminTenant
+	diagnosticPath   string
+	uiTokenPath      string
+	uiToken          []byte
+	uiPublic         bool
+	smtpPasswordPath string
+	smtpPassword     []byte
+	templatesPath    string
+	templates        *templateStore
+	corporateCLA     *corporateCLAStores
+	metricsPath      string
+	serverless       bool
+	stateFile        string
+	diagnostic       *startupDiagnostic
 }
 
 func (o *robotOptions) addFlags(fs *flag.FlagSet) {
@@ -35,39 +56,131 @@ func (o *robotOptions) addFlags(fs *flag.FlagSet) {
 		&o.tokenPath, "token-path", "",
 		"Path to the file containing the token secret.",
 	)
+	fs.StringVar(
+		&o.readTokenPath, "read-token-path", "",
+		"Path to the file containing the low-privilege token secret used for the read operations. "+
+			"The token of token-path is used for all the operations if it is not set.",
+	)
 	fs.BoolVar(
 		&o.delToken, "del-token", true,
-		"An flag to delete token secret file.",
+		"An flag to delete token secret files.",
+	)
+	fs.StringVar(
+		&o.adminTokenPath, "admin-token-path", "",
+		"Path to the file containing the admin api token secret, which is the api key of the tenant `admin` without quota.",
+	)
+	fs.StringVar(
+		&o.adminTenantsPath, "admin-tenants-path", "",
+		"Path to the file listing the tenants of the admin api with their api keys and quotas. "+
+			"The admin api is disabled if neither it nor admin-token-path is set.",
+	)
+	fs.StringVar(
+		&o.uiTokenPath, "ui-token-path", "",
+		"Path to the file containing the token required to read the status pages at /ui/{org}/{repo}/{number}.",
+	)
+	fs.BoolVar(
+		&o.uiPublic, "ui-public", false,
+		"An flag to make the status pages readable without token. "+
+			"The status pages are disabled if neither it nor ui-token-path is set.",
+	)
+	fs.StringVar(
+		&o.smtpPasswordPath, "smtp-password-path", "",
+		"Path to the file containing the password of the smtp server of the email_notification.",
+	)
+	fs.StringVar(
+		&o.templatesPath, "templates-path", "",
+		"Path to the yaml file, or the directory of yaml files, mapping the template names to the comment texts. "+
+			"The comments of the config refer to them as template:<name>. The changes of the files are reloaded.",
+	)
+	fs.StringVar(
+		&o.metricsPath, "metrics-path", defaultMetricsPath,
+		"The path serving the prometheus metrics. The metrics are not served if it is empty.",
+	)
+	fs.BoolVar(
+		&o.serverless, "serverless", false,
+		"An flag to serve the invocations of the api gateway as a serverless function instead of running the server. "+
+			"The function must not run concurrently, and the rescan of the unsigned pull requests is not scheduled.",
+	)
+	fs.StringVar(
+		&o.stateFile, "state-file", "",
+		"Path to the file keeping the state of the pull requests between the invocations, "+
+			"such as one on a mounted network file system. It is required by serverless.",
 	)
+	fs.StringVar(
+		&o.diagnosticPath, "diagnostic-path", "",
+		"Path to the file where a json diagnostic is written when the startup fails.",
+	)
+}
+
+// abort logs the startup failure, interrupts the startup and keeps the diagnostic of the first failure
+func (o *robotOptions) abort(class string, err error, msg string, fields ...string) {
+	logrus.WithError(err).Error(msg)
+	o.interrupt = true
+	if o.diagnostic == nil {
+		o.diagnostic = newStartupDiagnostic(class, err, fields)
+	}
+}
+
+// exit writes the diagnostic of the startup failure and exits with the code of its class
+func (o *robotOptions) exit() {
+	code := 1
+	if o.diagnostic != nil {
+		code = o.diagnostic.ExitCode
+		if o.diagnosticPath != "" {
+			if err := o.diagnostic.write(o.diagnosticPath); err != nil {
+				logrus.WithError(err).Error("failed to write the startup diagnostic")
+			}
+		}
+	}
+	os.Exit(code)
 }
 
 func (o *robotOptions) validateFlags() (*configuration, []byte) {
 	if err := o.service.ValidateComposite(); err != nil {
-		logrus.WithError(err).Errorf("invalid service options")
-		o.interrupt = true
+		o.abort(diagnosticClassOptions, err, "invalid service options")
+		return nil, nil
+	}
+	if o.serverless && o.stateFile == "" {
+		o.abort(diagnosticClassOptions, errors.New("the state-file is required by serverless"),
+			"invalid serverless options", "state-file")
 		return nil, nil
 	}
 
 	configmap, err := config.NewConfigmapAgent(&configuration{}, o.service.ConfigFile)
 	if err != nil {
-		logrus.WithError(err).Error("fatal error occurred while loading and parsing configmap")
-		o.interrupt = true
+		var fields []string
+		if cnf, ok := configmap.GetConfigmap().(*configuration); ok {
+			fields = cnf.listMissingConfig()
+		}
+		o.abort(diagnosticClassConfig, err, "fatal error occurred while loading and parsing configmap", fields...)
 		return nil, nil
 	}
 
 	token, err := secret.LoadSingleSecret(o.tokenPath)
 	if err != nil {
-		logrus.WithError(err).Error("fatal error occurred while loading token")
-		o.interrupt = true
+		o.abort(diagnosticClassSecret, err, "fatal error occurred while loading token", "token-path")
 	}
 	if o.delToken {
 		if err = os.Remove(o.tokenPath); err != nil {
-			logrus.WithError(err).Error("fatal error occurred while deleting token")
-			o.interrupt = true
+			o.abort(diagnosticClassSecret, err, "fatal error occurred while deleting token", "token-path")
+		}
+	}
+
+	cnf := configmap.GetConfigmap().(*configuration)
+	o.loadReadToken()
+	o.loadAdminTenants()
+	o.loadUIToken()
+	o.loadSMTPPassword()
+	o.loadTemplates(cnf)
+	o.loadCorporateCLA(cnf)
+	if cnf.URLValidation.CheckConnectivity {
+		if err = cnf.checkConnectivity(newReadinessProbe()); err != nil {
+			o.abort(diagnosticClassConfig, err, "fatal error occurred while checking the connectivity of the check urls",
+				"url_validation.check_connectivity")
 		}
 	}
 
-	return configmap.GetConfigmap().(*configuration), token
+	return cnf, token
 }
 
 // gatherOptions gather the necessary arguments from command line for project startup.
@@ -84,3 +197,113 @@ func (o *robotOptions) gatherOptions(fs *flag.FlagSet, args ...string) (*configu
 
 	return cnf, token
 }
+
+// loadReadToken loads the optional read-only token
+func (o *robotOptions) loadReadToken() {
+	if o.readTokenPath == "" {
+		return
+	}
+
+	token, err := secret.LoadSingleSecret(o.readTokenPath)
+	if err != nil {
+		o.abort(diagnosticClassSecret, err, "fatal error occurred while loading read-only token", "read-token-path")
+		return
+	}
+	if o.delToken {
+		if err = os.Remove(o.readTokenPath); err != nil {
+			o.abort(diagnosticClassSecret, err, "fatal error occurred while deleting read-only token", "read-token-path")
+		}
+	}
+	o.readToken = token
+}
+
+// loadAdminTenants loads the tenants of the admin api from the admin token and the tenants file
+func (o *robotOptions) loadAdminTenants() {
+	tenants := &adminTenants{}
+	if o.adminTenantsPath != "" {
+		if err := utils.LoadFromYaml(o.adminTenantsPath, tenants); err != nil {
+			o.abort(diagnosticClassSecret, err, "fatal error occurred while loading admin tenants", "admin-tenants-path")
+			return
+		}
+	}
+
+	if o.adminTokenPath != "" {
+		token, err := secret.LoadSingleSecret(o.adminTokenPath)
+		if err != nil {
+			o.abort(diagnosticClassSecret, err, "fatal error occurred while loading admin token", "admin-token-path")
+			return
+		}
+		tenants.Tenants = append(tenants.Tenants, adminTenant{Name: "admin", APIKey: string(token)})
+	}
+
+	if err := tenants.Validate(); err != nil {
+		o.abort(diagnosticClassConfig, err, "invalid admin tenants", "admin-tenants-path")
+		return
+	}
+	o.adminTenants = tenants.Tenants
+}
+
+// loadUIToken loads the token of the status pages
+func (o *robotOptions) loadUIToken() {
+	if o.uiTokenPath == "" {
+		return
+	}
+
+	token, err := secret.LoadSingleSecret(o.uiTokenPath)
+	if err != nil {
+		o.abort(diagnosticClassSecret, err, "fatal error occurred while loading ui token", "ui-token-path")
+		return
+	}
+	o.uiToken = token
+}
+
+// loadSMTPPassword loads the password of the smtp server
+func (o *robotOptions) loadSMTPPassword() {
+	if o.smtpPasswordPath == "" {
+		return
+	}
+
+	password, err := secret.LoadSingleSecret(o.smtpPasswordPath)
+	if err != nil {
+		o.abort(diagnosticClassSecret, err, "fatal error occurred while loading smtp password", "smtp-password-path")
+		return
+	}
+	o.smtpPassword = password
+}
+
+// loadTemplates loads the templates file, which must have all the templates referred to by the config
+func (o *robotOptions) loadTemplates(cnf *configuration) {
+	refs := cnf.templateRefs()
+	if o.templatesPath == "" {
+		if len(refs) != 0 {
+			fields := make([]string, 0, len(refs))
+			for field := range refs {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			o.abort(diagnosticClassConfig, errors.New("the templates-path is not set"),
+				"the config refers to the templates", fields...)
+		}
+		return
+	}
+
+	templates, missing, err := newTemplateStore(o.templatesPath, refs, logrus.WithField("component", component))
+	if err != nil {
+		if len(missing) == 0 {
+			missing = []string{"templates-path"}
+		}
+		o.abort(diagnosticClassConfig, err, "fatal error occurred while loading templates", missing...)
+		return
+	}
+	o.templates = templates
+}
+
+// loadCorporateCLA loads the corporate CLA mapping files of the config items
+func (o *robotOptions) loadCorporateCLA(cnf *configuration) {
+	stores := newCorporateCLAStores(logrus.WithField("component", component))
+	if field, err := stores.load(cnf); err != nil {
+		o.abort(diagnosticClassConfig, err, "fatal error occurred while loading the corporate CLA mapping", field)
+		return
+	}
+	o.corporateCLA = stores
+}
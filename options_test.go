@@ -14,9 +14,12 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"github.com/opensourceways/server-common-lib/utils"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -76,3 +79,62 @@ func TestGatherOptions(t *testing.T) {
 	assert.Equal(t, *want, *got)
 	assert.Equal(t, "1231****55324", string(token))
 }
+
+func TestStartupDiagnostic(t *testing.T) {
+	args := []string{
+		commandExecFile,
+		commandPort,
+	}
+	opt := new(robotOptions)
+	_, _ = opt.gatherOptions(flag.NewFlagSet(args[0], flag.ExitOnError), args[1:]...)
+	assert.Equal(t, diagnosticClassOptions, opt.diagnostic.Class)
+
+	args = []string{
+		commandExecFile,
+		commandConfigFilePrefix + findTestdata(t, "config1.yaml"),
+	}
+	opt = new(robotOptions)
+	_, _ = opt.gatherOptions(flag.NewFlagSet(args[0], flag.ExitOnError), args[1:]...)
+	assert.Equal(t, diagnosticClassConfig, opt.diagnostic.Class)
+	assert.Equal(t, 3, opt.diagnostic.ExitCode)
+
+	args = []string{
+		commandExecFile,
+		commandConfigFilePrefix + findTestdata(t, configYaml),
+		commandTokenFilePrefix + "/token1",
+		commandDelToken,
+	}
+	opt = new(robotOptions)
+	_, _ = opt.gatherOptions(flag.NewFlagSet(args[0], flag.ExitOnError), args[1:]...)
+	assert.Equal(t, diagnosticClassSecret, opt.diagnostic.Class)
+	assert.Equal(t, []string{"token-path"}, opt.diagnostic.OffendingFields)
+
+	path := filepath.Join(t.TempDir(), "diagnostic.json")
+	assert.Equal(t, nil, opt.diagnostic.write(path))
+	b, err := os.ReadFile(path)
+	assert.Equal(t, nil, err)
+	got := startupDiagnostic{}
+	assert.Equal(t, nil, json.Unmarshal(b, &got))
+	assert.Equal(t, diagnosticClassSecret, got.Class)
+	assert.Equal(t, diagnosticExitCodes[diagnosticClassSecret], got.ExitCode)
+}
+
+func TestLoadReadToken(t *testing.T) {
+	args := []string{
+		commandExecFile,
+		commandConfigFilePrefix + findTestdata(t, configYaml),
+		commandTokenFilePrefix + findTestdata(t, "token"),
+		"--read-token-path=" + findTestdata(t, "token"),
+		commandDelToken,
+	}
+	opt := new(robotOptions)
+	_, token := opt.gatherOptions(flag.NewFlagSet(args[0], flag.ExitOnError), args[1:]...)
+	assert.Equal(t, false, opt.interrupt)
+	assert.Equal(t, token, opt.readToken)
+
+	args[3] = "--read-token-path=/token1"
+	opt = new(robotOptions)
+	_, _ = opt.gatherOptions(flag.NewFlagSet(args[0], flag.ExitOnError), args[1:]...)
+	assert.Equal(t, true, opt.interrupt)
+	assert.Equal(t, []string{"read-token-path"}, opt.diagnostic.OffendingFields)
+}
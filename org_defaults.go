@@ -0,0 +1,96 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// orgItem returns the item listing the org, whose fields are inherited by the items with the inherit_org.
+// The excluded_repos of the item are ignored, since the repositories are excluded to be overridden usually
+func (c *configuration) orgItem(org string, meta *repoMetadata) *repoConfig {
+	for i := range c.ConfigItems {
+		item := &c.ConfigItems[i]
+		if !item.InheritOrg && slices.Contains(item.Repos, org) && item.matchRepoMetadata(meta) {
+			return item
+		}
+	}
+	return nil
+}
+
+// inheritOrg returns a copy of the item whose fields not set are taken from the item listing the org.
+// The item is returned as it is if it does not inherit the org or the org is not listed
+func (c *configuration) inheritOrg(item *repoConfig, org string, meta *repoMetadata) *repoConfig {
+	if !item.InheritOrg {
+		return item
+	}
+
+	base := c.orgItem(org, meta)
+	if base == nil {
+		return item
+	}
+
+	cnf := *item
+	dst, src := reflect.ValueOf(&cnf).Elem(), reflect.ValueOf(base).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		// the repositories are those of the item, and the unexported fields are set at runtime only
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+		if dst.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return &cnf
+}
+
+// inheritedItems returns the item with the fields inherited from each org it lists
+func (c *configuration) inheritedItems(i int) []*repoConfig {
+	item := &c.ConfigItems[i]
+	if !item.InheritOrg {
+		return []*repoConfig{item}
+	}
+
+	var items []*repoConfig
+	for _, entry := range item.Repos {
+		org, _, _ := strings.Cut(entry, "/")
+		items = append(items, c.inheritOrg(item, org, nil))
+	}
+	return items
+}
+
+// validateInheritedItem validates the item with the inherit_org as the fields inherited from each org it lists
+func (c *configuration) validateInheritedItem(i int) error {
+	item := &c.ConfigItems[i]
+	for _, entry := range item.Repos {
+		org, _, found := strings.Cut(entry, "/")
+		if !found {
+			return fmt.Errorf("config_items[%d] inherits the org, so it can only list org/repo, but lists %s", i, entry)
+		}
+		if c.orgItem(org, nil) == nil {
+			return fmt.Errorf("config_items[%d] inherits the org %s, but no config item lists the org", i, org)
+		}
+	}
+
+	for _, cnf := range c.inheritedItems(i) {
+		if err := cnf.validateRepoConfig(); err != nil {
+			return fmt.Errorf("config_items[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInheritOrg(t *testing.T) {
+	cnf := &configuration{
+		ConfigItems: []repoConfig{
+			{RepoFilter: config.RepoFilter{Repos: []string{"org1"}, ExcludedRepos: []string{"org1/repo1"}},
+				CLALabelYes: "cla-yes", CLALabelNo: "cla-no", CheckURL: "https://cla/check",
+				SignURL: "https://cla/sign", FAQURL: "https://cla/faq", CommitStatus: true},
+			{RepoFilter: config.RepoFilter{Repos: []string{"org1/repo1"}}, InheritOrg: true,
+				FAQURL: "https://repo1/faq"},
+		},
+	}
+
+	cnf1 := cnf.getMatchedRepoConfig("org1", "repo1", nil)
+	assert.Equal(t, "https://repo1/faq", cnf1.FAQURL)
+	assert.Equal(t, "https://cla/check", cnf1.CheckURL)
+	assert.Equal(t, "cla-yes", cnf1.CLALabelYes)
+	assert.Equal(t, true, cnf1.CommitStatus)
+	assert.Equal(t, []string{"org1/repo1"}, cnf1.Repos)
+	// the item itself is not changed
+	assert.Equal(t, "", cnf.ConfigItems[1].CheckURL)
+	assert.Equal(t, "https://cla/faq", cnf.getMatchedRepoConfig("org1", "repo2", nil).FAQURL)
+
+	assert.NoError(t, cnf.validateInheritedItem(1))
+	assert.Empty(t, cnf.inheritedItems(1)[0].missingConfig())
+
+	cnf.ConfigItems[1].Repos = []string{"org1"}
+	assert.EqualError(t, cnf.validateInheritedItem(1),
+		"config_items[1] inherits the org, so it can only list org/repo, but lists org1")
+
+	cnf.ConfigItems[1].Repos = []string{"org2/repo1"}
+	assert.EqualError(t, cnf.validateInheritedItem(1),
+		"config_items[1] inherits the org org2, but no config item lists the org")
+	// the item is used as it is without the org
+	assert.Equal(t, "", cnf.getMatchedRepoConfig("org2", "repo1", nil).CheckURL)
+
+	cnf.ConfigItems[1].Repos = []string{"org1/repo1"}
+	cnf.ConfigItems[1].Mode = "sign-off"
+	assert.EqualError(t, cnf.validateInheritedItem(1), "config_items[1]: the mode must be one of cla and dco")
+}
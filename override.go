@@ -0,0 +1,180 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultCommentOverride is used when the comment_override is not configured
+	defaultCommentOverride = "### CLA Override  \n\nThe CLA check of this pull request was overridden by @%s, " +
+		"because: %s"
+	// defaultCommentOverrideUntilCheck and defaultCommentOverrideExpiry are appended to the comment of the override
+	// to tell how long it lasts
+	defaultCommentOverrideUntilCheck = "  \n\nThe override lasts until the pull request is checked again, such as by a push."
+	defaultCommentOverrideExpiry     = "  \n\nThe override expires at %s or once new commits are pushed, when the " +
+		"pull request is checked again. The pull request is not checked until then."
+
+	commitStatusDescriptionOverridden = "The CLA check was overridden by a maintainer"
+
+	// decisionOverridden is the decision of the evaluation skipped by an unexpired override
+	decisionOverridden = "overridden"
+
+	// overrideExpiryInterval is how often the expired overrides are revoked
+	overrideExpiryInterval = time.Minute
+	// maxOverrideTTL caps the lifetime of the `/cla override`, the longer ones are cut to it
+	maxOverrideTTL = 30 * 24 * time.Hour
+)
+
+// claOverride is the override of the CLA check of a pull request which expires
+type claOverride struct {
+	By        string    `json:"by"`
+	Reason    string    `json:"reason"`
+	At        time.Time `json:"at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// HeadSHA is the head the override is pinned to, the pull request is checked again once its head changes
+	HeadSHA string `json:"head_sha"`
+}
+
+func (o *claOverride) active(now time.Time) bool {
+	return o != nil && now.Before(o.ExpiresAt)
+}
+
+// parseOverrideTTL parses the lifetime of the `/cla override`, such as 30d or 12h, which is capped by maxOverrideTTL
+func parseOverrideTTL(n, unit string) time.Duration {
+	v, err := strconv.Atoi(n)
+	if err != nil {
+		return 0
+	}
+	if unit == "d" {
+		return min(time.Duration(v)*24*time.Hour, maxOverrideTTL)
+	}
+	return min(time.Duration(v)*time.Hour, maxOverrideTTL)
+}
+
+// overrideCLA forces the cla_label_yes on the pull request by the `/cla override [<ttl>] <reason>` of a maintainer,
+// such as for the historical imports or the contributors whose employers signed but are not in the CLA server yet.
+// The reason is recorded in a comment and the audit. The override with a ttl is pinned to the head of the pull
+// request, and skips the checks of the pull request until it expires or the head changes
+func (bot *robot) overrideCLA(pr *prSnapshot, repoCnf *repoConfig, commenter, reason string, ttl time.Duration,
+	logger *logrus.Entry) {
+	if !bot.isCLAAdmin(pr.org, pr.repo, commenter, repoCnf) {
+		logger.Warningf("ignore the /cla override of %s who is not a maintainer", commenter)
+		return
+	}
+
+	prLabels, _ := pr.getLabels()
+	if !bot.applyCLALabel(pr, prLabels, repoCnf, repoCnf.CLALabelYes, repoCnf.CLALabelNo) {
+		bot.labelUpdateFailed(pr, repoCnf, bot.renderer(pr).updateLabelFailed())
+		return
+	}
+
+	var override *claOverride
+	if now := time.Now().UTC(); ttl > 0 && bot.store != nil {
+		// the override can not be pinned without the head, which lasts until the next check then
+		if sha, ok := pr.headSHA(); ok {
+			override = &claOverride{By: commenter, Reason: reason, At: now, ExpiresAt: now.Add(ttl), HeadSHA: sha}
+		} else {
+			logger.Warningf("the override of %s lasts until the next check, since its head is unknown", pr.key())
+		}
+	}
+	bot.recordOverride(pr, override)
+
+	bot.audit.record(auditRecord{
+		Org: pr.org, Repo: pr.repo, Number: pr.number, Actor: commenter,
+		Action: auditActionOverride, Outcome: auditOutcomeSuccess, Detail: reason,
+	})
+	bot.createPRComment(pr, repoCnf, bot.renderer(pr).override(commenter, reason, override))
+	bot.reportCommitStatus(pr, repoCnf, commitStatusSuccess, commitStatusDescriptionOverridden)
+}
+
+// recordOverride keeps the override in the state of the pull request, or clears it if it is nil
+func (bot *robot) recordOverride(pr *prSnapshot, override *claOverride) {
+	if bot.store == nil {
+		return
+	}
+
+	s, ok := bot.store.get(pr.org, pr.repo, pr.number)
+	if !ok && override == nil {
+		return
+	}
+	s.Org, s.Repo, s.Number = pr.org, pr.repo, pr.number
+	s.Override = override
+	bot.store.put(s)
+}
+
+// overridden checks whether the pull request has an unexpired override at the same head. The override is
+// cleared once the head changes, so that the new commits are checked
+func (bot *robot) overridden(pr *prSnapshot) bool {
+	if bot.store == nil {
+		return false
+	}
+
+	s, _ := bot.store.get(pr.org, pr.repo, pr.number)
+	if !s.Override.active(time.Now()) {
+		return false
+	}
+
+	sha, ok := pr.headSHA()
+	if ok && sha == s.Override.HeadSHA {
+		return true
+	}
+	if ok {
+		pr.logger().Infof("clear the override of %s pinned to %s, since the head is changed", pr.key(), s.Override.HeadSHA)
+		bot.recordOverride(pr, nil)
+	}
+	return false
+}
+
+// startOverrideExpiryScheduler revokes the expired overrides in the background
+func startOverrideExpiryScheduler(bot *robot, logger *logrus.Entry) {
+	ticker := time.NewTicker(overrideExpiryInterval)
+	go func() {
+		for range ticker.C {
+			if n := bot.revokeExpiredOverrides(logger.WithField("override-expiry", true)); n != 0 {
+				logger.Infof("revoked %d expired overrides", n)
+			}
+		}
+	}()
+}
+
+// revokeExpiredOverrides clears the expired overrides and checks their open pull requests again, which flips
+// the label back to the cla_label_no if the contributors still have not signed. It returns the number of the
+// revoked ones
+func (bot *robot) revokeExpiredOverrides(logger *logrus.Entry) int {
+	if bot.store == nil {
+		return 0
+	}
+
+	n, now := 0, time.Now()
+	for _, s := range bot.store.exportSnapshot().PRs {
+		if s.Override == nil || s.Override.active(now) {
+			continue
+		}
+
+		pr := newPRSnapshot(bot.cli, s.Org, s.Repo, s.Number).withActor(auditActorOverrideExpiry)
+		bot.recordOverride(pr, nil)
+		n++
+		if s.Closed {
+			continue
+		}
+		if repoCnf := bot.getRepoConfig(s.Org, s.Repo); repoCnf != nil {
+			bot.recheckSerialized(pr, repoCnf, logger.WithField("override-pr", s.key()))
+		}
+	}
+	return n
+}
@@ -0,0 +1,119 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestOverrideCLA(t *testing.T) {
+	cli := &labelRecordingClient{mockClient: &mockClient{
+		labels: []string{labelNo}, successfulGetPullRequestLabels: true, successfulCreatePRComment: true,
+	}}
+	bot := &robot{cli: cli, cnf: &configuration{}}
+	repoCnf := &repoConfig{CLALabelYes: labelYes, CLALabelNo: labelNo}
+	logger := logrus.NewEntry(logrus.New())
+
+	// the users who are not the maintainers can not override
+	assert.Equal(t, true, regexpOverrideCLAComment.MatchString("/cla override imported from the history"))
+	assert.Equal(t, false, regexpOverrideCLAComment.MatchString("/cla override"))
+	bot.overrideCLA(newPRSnapshot(cli, org, repo, number), repoCnf, commenter, "imported", 0, logger)
+	assert.Equal(t, 0, len(cli.added))
+	assert.Equal(t, "", cli.comment)
+
+	cli.permission, cli.successfulCheckPermission = true, true
+	bot.overrideCLA(newPRSnapshot(cli, org, repo, number), repoCnf, commenter, "imported", 0, logger)
+	assert.Equal(t, []string{labelYes}, cli.added)
+	assert.Equal(t, []string{labelNo}, cli.removed)
+	assert.Contains(t, cli.comment, "overridden by @"+commenter+", because: imported")
+}
+
+func TestOverrideExpiry(t *testing.T) {
+	m := regexpOverrideCLAComment.FindStringSubmatch("/cla override 30d signed by the employer")
+	assert.Equal(t, []string{"30", "d", "signed by the employer"}, m[1:])
+	assert.Equal(t, 30*24*time.Hour, parseOverrideTTL(m[1], m[2]))
+	assert.Equal(t, 12*time.Hour, parseOverrideTTL("12", "h"))
+	// the ttl is capped
+	assert.Equal(t, maxOverrideTTL, parseOverrideTTL("9999", "d"))
+	assert.Equal(t, maxOverrideTTL, parseOverrideTTL("9999", "h"))
+	m = regexpOverrideCLAComment.FindStringSubmatch("/cla override 30 days")
+	assert.Equal(t, []string{"", "", "30 days"}, m[1:])
+
+	cli := &labelRecordingClient{mockClient: &mockClient{
+		labels: []string{labelNo}, successfulGetPullRequestLabels: true, successfulCreatePRComment: true,
+		permission: true, successfulCheckPermission: true,
+		successfulGetPullRequestCommitMessages: true, commitMessages: []prCommitMessage{{SHA: "sha1"}},
+	}}
+	bot := &robot{cli: cli, cnf: &configuration{}, store: newMemoryStateStore()}
+	repoCnf := &repoConfig{CLALabelYes: labelYes, CLALabelNo: labelNo}
+	logger := logrus.NewEntry(logrus.New())
+
+	// the pull request is not checked while the override is unexpired at the same head
+	bot.overrideCLA(newPRSnapshot(cli, org, repo, number), repoCnf, commenter, "imported", time.Hour, logger)
+	assert.Contains(t, cli.comment, "The override expires at ")
+	s, _ := bot.store.get(org, repo, number)
+	assert.Equal(t, "sha1", s.Override.HeadSHA)
+	pr := newPRSnapshot(cli, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf, logger)
+	assert.Equal(t, decisionOverridden, pr.stats.decision)
+	assert.Equal(t, 0, bot.revokeExpiredOverrides(logger))
+
+	// the expired override is revoked
+	s, _ = bot.store.get(org, repo, number)
+	s.Override.ExpiresAt = time.Now().Add(-time.Minute)
+	bot.store.put(s)
+	assert.Equal(t, 1, bot.revokeExpiredOverrides(logger))
+	assert.Equal(t, false, bot.overridden(newPRSnapshot(cli, org, repo, number)))
+
+	// the override is cleared once the head changes
+	bot.overrideCLA(newPRSnapshot(cli, org, repo, number), repoCnf, commenter, "imported", time.Hour, logger)
+	cli.commitMessages = []prCommitMessage{{SHA: "sha1"}, {SHA: "sha2"}}
+	assert.Equal(t, false, bot.overridden(newPRSnapshot(cli, org, repo, number)))
+	s, _ = bot.store.get(org, repo, number)
+	assert.Nil(t, s.Override)
+
+	// the override without a ttl clears the one with
+	bot.overrideCLA(newPRSnapshot(cli, org, repo, number), repoCnf, commenter, "imported", time.Hour, logger)
+	bot.overrideCLA(newPRSnapshot(cli, org, repo, number), repoCnf, commenter, "imported", 0, logger)
+	assert.Equal(t, false, bot.overridden(newPRSnapshot(cli, org, repo, number)))
+
+	// the closed pull request is not checked again when its override expires
+	bot.overrideCLA(newPRSnapshot(cli, org, repo, number), repoCnf, commenter, "imported", time.Hour, logger)
+	s, _ = bot.store.get(org, repo, number)
+	s.Override.ExpiresAt, s.Closed = time.Now().Add(-time.Minute), true
+	bot.store.put(s)
+	cli.added = nil
+	assert.Equal(t, 1, bot.revokeExpiredOverrides(logger))
+	assert.Empty(t, cli.added)
+	assert.Equal(t, 0, bot.revokeExpiredOverrides(logger))
+}
+
+func TestOverrideUnderReport(t *testing.T) {
+	cli := &labelRecordingClient{mockClient: &mockClient{
+		labels: []string{labelNo}, successfulGetPullRequestLabels: true, successfulCreatePRComment: true,
+		permission: true, successfulCheckPermission: true,
+	}}
+	bot := &robot{cli: cli, cnf: &configuration{}}
+	repoCnf := &repoConfig{CLALabelYes: labelYes, CLALabelNo: labelNo, EnforcementLevel: enforcementReport}
+
+	// the labels are left untouched, while the override is still commented
+	bot.overrideCLA(newPRSnapshot(cli, org, repo, number), repoCnf, commenter, "imported", 0,
+		logrus.NewEntry(logrus.New()))
+	assert.Empty(t, cli.added)
+	assert.Empty(t, cli.removed)
+	assert.Contains(t, cli.comment, "because: imported")
+}
@@ -0,0 +1,54 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+)
+
+// validatePlatform checks that the config is for gitcode, which is the only platform the bot has a client for and
+// the robot framework delivers the webhooks of
+func validatePlatform(platform string) error {
+	if platform != "" && platform != platformGitCode {
+		return fmt.Errorf("unsupported platform %q, the bot only works on %s", platform, platformGitCode)
+	}
+	return nil
+}
+
+// platform returns the code hosting platform the bot works on. Default is gitcode
+func (c *configuration) platform() string {
+	if c.Platform == "" {
+		return platformGitCode
+	}
+	return c.Platform
+}
+
+// newPlatformClient creates the client of gitcode, which keeps the quirks of its api such as how the labels
+// are escaped
+func newPlatformClient(platform string, token, readToken []byte, logger *logrus.Entry) (iClient, error) {
+	if err := validatePlatform(platform); err != nil {
+		return nil, err
+	}
+
+	rc := newRobotClient(token, readToken, logger)
+	if rc.Client == nil {
+		return nil, errors.New("failed to connect to the code hosting platform with the token")
+	}
+	if rc.reader == nil {
+		return nil, errors.New("failed to connect to the code hosting platform with the read-only token")
+	}
+	return rc, nil
+}
@@ -0,0 +1,32 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPlatform(t *testing.T) {
+	c := &configuration{}
+	assert.Equal(t, platformGitCode, c.platform())
+	assert.NoError(t, validatePlatform(c.Platform))
+
+	c.Platform = "gitee"
+	assert.Error(t, validatePlatform(c.Platform))
+	_, err := newPlatformClient(c.platform(), []byte("token"), nil, nil)
+	assert.ErrorContains(t, err, `unsupported platform "gitee", the bot only works on gitcode`)
+
+	assert.Equal(t, []string{"cla%2Fyes", "cla-no"}, escapePathLabels([]string{"cla/yes", "cla-no"}))
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+)
+
+// plugin injects the custom logic of a downstream fork into the evaluation, such as the extra exemptions or
+// the custom notifications. It is compiled in by a file of the fork calling registerPlugin in its init, so that
+// the core files are not patched. The hooks of the plugins are called in order of the registration
+type plugin interface {
+	// Name identifies the plugin in the logs
+	Name() string
+	// BeforeCheck is called before checking the CLA, and returns the commits to check
+	BeforeCheck(pr *prSnapshot, commits []client.PRCommit, repoCnf *repoConfig) []client.PRCommit
+	// AfterDecision is called after the decision of an evaluation is applied to the pull request
+	AfterDecision(pr *prSnapshot, decision string, signResult [3][]string)
+	// BeforeComment is called before posting or editing a comment, and returns the comment.
+	// The comment is dropped if it returns an empty one
+	BeforeComment(pr *prSnapshot, comment string) string
+}
+
+// basePlugin implements every hook of the plugin doing nothing, which is embedded by the plugins
+// implementing some of the hooks only
+type basePlugin struct{}
+
+func (basePlugin) BeforeCheck(_ *prSnapshot, commits []client.PRCommit, _ *repoConfig) []client.PRCommit {
+	return commits
+}
+
+func (basePlugin) AfterDecision(*prSnapshot, string, [3][]string) {}
+
+func (basePlugin) BeforeComment(_ *prSnapshot, comment string) string {
+	return comment
+}
+
+// registeredPlugins are the plugins compiled in, which are only registered in the init of the files
+var registeredPlugins []plugin
+
+// registerPlugin compiles the plugin into the robot. It must be called in the init of the file of the plugin
+func registerPlugin(p plugin) {
+	registeredPlugins = append(registeredPlugins, p)
+}
+
+func (bot *robot) runBeforeCheck(pr *prSnapshot, commits []client.PRCommit, repoCnf *repoConfig) []client.PRCommit {
+	for _, p := range bot.plugins {
+		commits = p.BeforeCheck(pr, commits, repoCnf)
+	}
+	return commits
+}
+
+func (bot *robot) runAfterDecision(pr *prSnapshot) {
+	for _, p := range bot.plugins {
+		p.AfterDecision(pr, pr.stats.decision, pr.stats.signResult)
+	}
+}
+
+// runBeforeComment returns the comment changed by the plugins, it is false if any plugin drops the comment
+func (bot *robot) runBeforeComment(pr *prSnapshot, comment string) (string, bool) {
+	for _, p := range bot.plugins {
+		if comment = p.BeforeComment(pr, comment); comment == "" {
+			return "", false
+		}
+	}
+	return comment, true
+}
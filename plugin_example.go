@@ -0,0 +1,53 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build example_plugin
+
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// botAccountPlugin is the example of the plugin, which is only built with the `example_plugin` tag.
+// It skips the commits of the accounts ending with [bot], and logs the pull requests waiting for the CLA
+type botAccountPlugin struct {
+	basePlugin
+}
+
+func init() {
+	registerPlugin(botAccountPlugin{})
+}
+
+func (botAccountPlugin) Name() string {
+	return "bot-account"
+}
+
+func (botAccountPlugin) BeforeCheck(_ *prSnapshot, commits []client.PRCommit, _ *repoConfig) []client.PRCommit {
+	result := make([]client.PRCommit, 0, len(commits))
+	for i := range commits {
+		if !strings.HasSuffix(commits[i].AuthorName, "[bot]") {
+			result = append(result, commits[i])
+		}
+	}
+	return result
+}
+
+func (botAccountPlugin) AfterDecision(pr *prSnapshot, decision string, signResult [3][]string) {
+	if decision == prStatusUnsigned {
+		logrus.WithFields(logrus.Fields{"pr": pr.key(), "unsigned": signResult[1]}).Info("waiting for the CLA")
+	}
+}
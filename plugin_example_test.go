@@ -0,0 +1,31 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build example_plugin
+
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBotAccountPlugin(t *testing.T) {
+	assert.Contains(t, registeredPlugins, plugin(botAccountPlugin{}))
+
+	commits := []client.PRCommit{{AuthorName: "u1"}, {AuthorName: "dependabot[bot]"}}
+	assert.Equal(t, commits[:1], botAccountPlugin{}.BeforeCheck(nil, commits, &repoConfig{}))
+	assert.Equal(t, "comment", botAccountPlugin{}.BeforeComment(nil, "comment"))
+}
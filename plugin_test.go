@@ -0,0 +1,89 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// recordingPlugin exempts the user, records the decisions, and drops the comments containing the word
+type recordingPlugin struct {
+	basePlugin
+	exempt    string
+	drop      string
+	decisions []string
+}
+
+func (p *recordingPlugin) Name() string {
+	return "recording"
+}
+
+func (p *recordingPlugin) BeforeCheck(_ *prSnapshot, commits []client.PRCommit, _ *repoConfig) []client.PRCommit {
+	var result []client.PRCommit
+	for i := range commits {
+		if commits[i].AuthorName != p.exempt {
+			result = append(result, commits[i])
+		}
+	}
+	return result
+}
+
+func (p *recordingPlugin) AfterDecision(_ *prSnapshot, decision string, _ [3][]string) {
+	p.decisions = append(p.decisions, decision)
+}
+
+func (p *recordingPlugin) BeforeComment(_ *prSnapshot, comment string) string {
+	if p.drop != "" && strings.Contains(comment, p.drop) {
+		return ""
+	}
+	return comment + "\n\nposted by the fork"
+}
+
+func TestPluginHooks(t *testing.T) {
+	mc := &multiServerClient{
+		mockClient: &mockClient{
+			successfulGetPullRequestCommits: true,
+			successfulGetPullRequestLabels:  true,
+			successfulAddPRLabels:           true,
+			successfulCreatePRComment:       true,
+			commits: []client.PRCommit{
+				{AuthorName: "u1", AuthorEmail: "u1@example.com"},
+				{AuthorName: "u2", AuthorEmail: "u2@example.com"},
+			},
+		},
+		states: map[string]string{"icla?email=u1%40example.com": client.CLASignStateYes},
+	}
+	p := &recordingPlugin{exempt: "u2"}
+	bot := &robot{cli: mc, cnf: &configuration{
+		CommentAllSigned: "${user}, all signed", UserMarkFormat: "@${user}", PlaceholderCommitter: "${user}",
+	}, plugins: []plugin{p}}
+	repoCnf := &repoConfig{CheckURL: "icla", CLALabelYes: "cla-yes", CLALabelNo: "cla-no"}
+
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf, logrus.NewEntry(logrus.New()))
+	assert.Equal(t, []string{prStatusSigned}, p.decisions)
+	assert.Equal(t, withResultMarker(resultKindAllSigned, "@u1, all signed")+"\n\nposted by the fork", mc.comment)
+	assert.Equal(t, 1, pr.stats.commentsPosted)
+
+	// the dropped comment is not posted
+	p.drop = "all signed"
+	pr = newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf, logrus.NewEntry(logrus.New()))
+	assert.Equal(t, []string{prStatusSigned, prStatusSigned}, p.decisions)
+	assert.Equal(t, 0, pr.stats.commentsPosted)
+}
@@ -0,0 +1,251 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+)
+
+// the policies of checking the contributions, which combine the CLA and the DCO
+const (
+	policyCLA       = modeCLA
+	policyDCO       = modeDCO
+	policyCLAAndDCO = "cla_and_dco"
+	policyCLAOrDCO  = "cla_or_dco"
+)
+
+const (
+	// the titles of the comments of the result under the cla_and_dco and cla_or_dco policies
+	policyTitleUnsigned = "### CLA and DCO Check Guide"
+	policyTitleSigned   = "### CLA and DCO Check Pass"
+
+	// defaultCommentPolicyUnsigned is used when the comment_policy_unsigned is not configured
+	defaultCommentPolicyUnsigned = "Thanks for your pull request. The following contributors do not meet the " +
+		"requirements yet:  \n\n%s\nPlease sign the CLA [here](%s), or sign off the commits by " +
+		"`git rebase --signoff HEAD~<the number of the commits>` with the email of the author and push them again, " +
+		"as the missing requirements show. See the [FAQs](%s) for more."
+	// defaultCommentPolicySigned is used when the comment_policy_signed is not configured
+	defaultCommentPolicySigned = "%s, thanks for your pull request. All the contributors meet the requirements. :wave:"
+
+	commitStatusDescriptionPolicyMet    = "All the contributors meet the CLA and DCO requirements"
+	commitStatusDescriptionPolicyFailed = "Some contributors do not meet the CLA and DCO requirements"
+)
+
+// resultTitles are the titles of the result comments besides those of the CLA, which are configured
+var resultTitles = []string{dcoTitleUnsigned, dcoTitleSigned, policyTitleUnsigned, policyTitleSigned}
+
+func validatePolicy(mode, policy string) error {
+	switch policy {
+	case "":
+		return nil
+	case policyCLA, policyDCO, policyCLAAndDCO, policyCLAOrDCO:
+	default:
+		return errors.New("the policy must be one of cla, dco, cla_and_dco and cla_or_dco")
+	}
+
+	if mode != "" && mode != policy {
+		return errors.New("the mode conflicts with the policy, set the policy only")
+	}
+	return nil
+}
+
+// policy returns the policy of the repository, which is the mode if it is not set
+func (c *repoConfig) policy() string {
+	switch {
+	case c.Policy != "":
+		return c.Policy
+	case c.Mode == modeDCO:
+		return policyDCO
+	}
+	return policyCLA
+}
+
+// combinesCLAAndDCO checks whether the policy requires the CLA and the DCO together
+func (c *repoConfig) combinesCLAAndDCO() bool {
+	p := c.policy()
+	return p == policyCLAAndDCO || p == policyCLAOrDCO
+}
+
+// policyResult is the CLA and DCO states of a contributor. The state is empty if the contributor is not checked by
+// it, such as the committer who is not an author when the CLA is checked by the committers
+type policyResult struct {
+	user     string
+	claState string
+	dcoState string
+}
+
+// decide returns the sign state of the contributor under the policy
+func (r *policyResult) decide(policy string) string {
+	if policy == policyCLAOrDCO {
+		switch {
+		case r.claState == client.CLASignStateYes || r.dcoState == client.CLASignStateYes:
+			return client.CLASignStateYes
+		case r.claState == client.CLASignStateUnknown:
+			return client.CLASignStateUnknown
+		}
+		return client.CLASignStateNo
+	}
+
+	switch {
+	case r.claState == client.CLASignStateNo || r.dcoState == client.CLASignStateNo:
+		return client.CLASignStateNo
+	case r.claState == client.CLASignStateUnknown:
+		return client.CLASignStateUnknown
+	}
+	return client.CLASignStateYes
+}
+
+// missing lists the requirements the contributor fails under the policy
+func (r *policyResult) missing(policy string) string {
+	var items []string
+	if r.claState != "" && r.claState != client.CLASignStateYes {
+		items = append(items, "CLA")
+	}
+	if r.dcoState == client.CLASignStateNo {
+		items = append(items, "DCO")
+	}
+
+	if policy == policyCLAOrDCO {
+		return strings.Join(items, " or ")
+	}
+	return strings.Join(items, ", ")
+}
+
+// newPolicyResults merges the CLA states and the DCO sign-offs of the contributors, the ones checked by the CLA
+// come first. The contributor with any commit not signed off fails the DCO
+func newPolicyResults(claStates [3][]string, dcoSigned, dcoUnsigned []string) []policyResult {
+	var results []policyResult
+	index := func(user string) *policyResult {
+		i := slices.IndexFunc(results, func(r policyResult) bool { return r.user == user })
+		if i < 0 {
+			results = append(results, policyResult{user: user})
+			i = len(results) - 1
+		}
+		return &results[i]
+	}
+
+	for i, state := range []string{client.CLASignStateYes, client.CLASignStateNo, client.CLASignStateUnknown} {
+		for _, user := range claStates[i] {
+			index(user).claState = state
+		}
+	}
+	for _, user := range dcoSigned {
+		if !slices.Contains(dcoUnsigned, user) {
+			index(user).dcoState = client.CLASignStateYes
+		}
+	}
+	for _, user := range dcoUnsigned {
+		index(user).dcoState = client.CLASignStateNo
+	}
+	return results
+}
+
+// checkPolicy checks both the CLA and the DCO of the contributors under the cla_and_dco and cla_or_dco policies.
+// A failed contributor fails the pull request even if the others are unknown, since checking again does not help
+func (bot *robot) checkPolicy(pr *prSnapshot, commits []client.PRCommit, prLabels []string, repoCnf *repoConfig,
+	logger *logrus.Entry) {
+	messages, success := pr.getCommitMessages()
+	if !success {
+		pr.stats.decision = decisionCommitsUnavailable
+		bot.createPRComment(pr, repoCnf, bot.commandTriggerComment(pr, repoCnf))
+		return
+	}
+
+	claStates, reasons, stopped := bot.classifySignStates(pr, commits, repoCnf)
+	if stopped {
+		pr.stats.decision = decisionStopped
+		bot.finalizeTrippedEvaluation(pr, repoCnf, logger)
+		return
+	}
+
+	dcoSigned, dcoUnsigned, _ := checkSignOffs(messages, repoCnf)
+	policy := repoCnf.policy()
+	results := newPolicyResults(claStates, dcoSigned, dcoUnsigned)
+	var signResult [3][]string
+	var failed []policyResult
+	for i := range results {
+		switch results[i].decide(policy) {
+		case client.CLASignStateYes:
+			signResult[0] = append(signResult[0], results[i].user)
+		case client.CLASignStateNo:
+			signResult[1] = append(signResult[1], results[i].user)
+			failed = append(failed, results[i])
+		default:
+			signResult[2] = append(signResult[2], results[i].user)
+			failed = append(failed, results[i])
+		}
+	}
+	logger.Infof("%d of %d contributors of %s fail the %s policy", len(failed), len(results), pr.key(), policy)
+
+	allSigned := len(failed) == 0
+	switch {
+	case allSigned:
+		signResult = [3][]string{signResult[0]}
+	case len(signResult[1]) != 0:
+		signResult[0] = nil
+	default:
+		signResult = [3][]string{nil, nil, signResult[2]}
+	}
+
+	pr.stats.decision, pr.stats.signResult = evaluationStatus(allSigned, signResult), signResult
+	switch {
+	case allSigned:
+		bot.passPolicy(pr, signResult[0], prLabels, repoCnf)
+	case len(signResult[1]) != 0:
+		bot.waitPolicy(pr, failed, prLabels, repoCnf)
+	default:
+		bot.postUnknownStateComments(pr, repoCnf, signResult[2], reasons)
+	}
+	bot.recordPRState(pr, allSigned, signResult)
+}
+
+func (bot *robot) passPolicy(pr *prSnapshot, signedUsers, prLabels []string, repoCnf *repoConfig) {
+	r := bot.renderer(pr)
+	comment, post := r.updateLabelFailed(), bot.labelUpdateFailed
+	if bot.applyCLALabel(pr, prLabels, repoCnf, repoCnf.CLALabelYes, repoCnf.CLALabelNo) {
+		comment, post = r.policySigned(signedUsers), bot.replaceCLAResultComment
+	}
+	post(pr, repoCnf, comment)
+	bot.reportCommitStatus(pr, repoCnf, commitStatusSuccess, commitStatusDescriptionPolicyMet)
+}
+
+func (bot *robot) waitPolicy(pr *prSnapshot, failed []policyResult, prLabels []string, repoCnf *repoConfig) {
+	r := bot.renderer(pr)
+	comment, post := r.updateLabelFailed(), bot.labelUpdateFailed
+	if bot.applyCLALabel(pr, prLabels, repoCnf, repoCnf.CLALabelNo, repoCnf.CLALabelYes) {
+		comment, post = r.policyUnsigned(failed, repoCnf), bot.replaceCLAResultComment
+	}
+	post(pr, repoCnf, comment)
+	bot.reportCommitStatus(pr, repoCnf, commitStatusFailure, commitStatusDescriptionPolicyFailed)
+	bot.notifyUnsignedContributors(pr, repoCnf)
+}
+
+// policyStateText shows the state in the breakdown, which is `-` if the contributor is not checked by it
+func policyStateText(state string, text func(string) string) string {
+	if state == "" {
+		return "-"
+	}
+	return text(state)
+}
+
+func signOffText(state string) string {
+	if state == client.CLASignStateYes {
+		return "signed off"
+	}
+	return "not signed off"
+}
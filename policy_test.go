@@ -0,0 +1,98 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidatePolicy(t *testing.T) {
+	assert.NoError(t, validatePolicy("", ""))
+	assert.NoError(t, validatePolicy("", policyCLAOrDCO))
+	assert.NoError(t, validatePolicy(modeDCO, policyDCO))
+	assert.Error(t, validatePolicy("", "cla_dco"))
+	assert.Error(t, validatePolicy(modeDCO, policyCLAAndDCO))
+
+	repoCnf := repoConfig{Mode: modeDCO}
+	assert.Equal(t, policyDCO, repoCnf.policy())
+	repoCnf = repoConfig{Policy: policyCLAAndDCO}
+	assert.Equal(t, true, repoCnf.combinesCLAAndDCO())
+	assert.Contains(t, repoCnf.missingConfig(), "check_url")
+}
+
+func TestCheckPolicy(t *testing.T) {
+	signedOff := func(user string) string { return "feat\n\nSigned-off-by: " + user + " <" + user + "@example.com>" }
+	messages := []prCommitMessage{
+		{PRCommit: client.PRCommit{AuthorName: "u1", AuthorEmail: "u1@example.com"}, SHA: "sha1", Message: signedOff("u1")},
+		{PRCommit: client.PRCommit{AuthorName: "u2", AuthorEmail: "u2@example.com"}, SHA: "sha2", Message: signedOff("u2")},
+		{PRCommit: client.PRCommit{AuthorName: "u3", AuthorEmail: "u3@example.com"}, SHA: "sha3", Message: "fix"},
+	}
+	mc := &multiServerClient{
+		mockClient: &mockClient{
+			successfulGetPullRequestCommits:        true,
+			successfulGetPullRequestCommitMessages: true,
+			successfulGetPullRequestLabels:         true,
+			successfulAddPRLabels:                  true,
+			successfulRemovePRLabels:               true,
+			successfulCreatePRComment:              true,
+			commitMessages:                         messages,
+		},
+		states: map[string]string{
+			"icla?email=u1%40example.com": client.CLASignStateYes,
+			"icla?email=u2%40example.com": client.CLASignStateNo,
+			"icla?email=u3%40example.com": client.CLASignStateYes,
+			"icla?email=u4%40example.com": client.CLASignStateNo,
+		},
+	}
+	for i := range messages {
+		mc.commits = append(mc.commits, messages[i].PRCommit)
+	}
+	bot := &robot{cli: mc, cnf: &configuration{UserMarkFormat: "@${user}", PlaceholderCommitter: "${user}"}}
+	repoCnf := &repoConfig{CheckURL: "icla", SignURL: "https://cla/sign", FAQURL: "https://cla/faq",
+		CLALabelYes: "cla-yes", CLALabelNo: "cla-no", Policy: policyCLAOrDCO}
+
+	// every contributor meets either of the requirements
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf, logrus.NewEntry(logrus.New()))
+	assert.Equal(t, prStatusSigned, pr.stats.decision)
+	assert.Equal(t, []string{"cla-yes"}, pr.stats.labelsAdded)
+	assert.Contains(t, mc.comment, policyTitleSigned)
+	assert.Contains(t, mc.comment, "@u1, @u3, @u2")
+
+	// the breakdown shows the requirements each contributor fails
+	repoCnf.Policy = policyCLAAndDCO
+	mc.labels = []string{"cla-yes"}
+	pr = newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf, logrus.NewEntry(logrus.New()))
+	assert.Equal(t, prStatusUnsigned, pr.stats.decision)
+	assert.Equal(t, []string{"u3", "u2"}, pr.stats.signResult[1])
+	assert.Contains(t, mc.comment, policyTitleUnsigned)
+	assert.Contains(t, mc.comment, "| u2 | unsigned | signed off | CLA |")
+	assert.Contains(t, mc.comment, "| u3 | signed | not signed off | DCO |")
+	assert.NotContains(t, mc.comment, "| u1 |")
+	assert.Contains(t, mc.comment, "https://cla/sign")
+
+	repoCnf.Policy = policyCLAOrDCO
+	mc.commitMessages = append(mc.commitMessages, prCommitMessage{
+		PRCommit: client.PRCommit{AuthorName: "u4", AuthorEmail: "u4@example.com"}, SHA: "sha4", Message: "fix",
+	})
+	mc.commits = append(mc.commits, mc.commitMessages[3].PRCommit)
+	pr = newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf, logrus.NewEntry(logrus.New()))
+	assert.Equal(t, []string{"u4"}, pr.stats.signResult[1])
+	assert.Contains(t, mc.comment, "| u4 | unsigned | not signed off | CLA or DCO |")
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"sync"
+)
+
+// prSerializer runs the handlings of the same pull request one by one in the order they arrive, while those of the
+// different pull requests run concurrently, so that a push and a comment arriving together do not race on the
+// labels. All the methods are safe on a nil serializer, which runs the handlings right away
+type prSerializer struct {
+	mu sync.Mutex
+	// tails are closed when the latest handling of each pull request is done, keyed by the pull request
+	tails map[string]chan struct{}
+}
+
+func newPRSerializer() *prSerializer {
+	return &prSerializer{tails: map[string]chan struct{}{}}
+}
+
+// do runs the f after the earlier handlings of the pull request of the key are done
+func (s *prSerializer) do(key string, f func()) {
+	if s == nil {
+		f()
+		return
+	}
+
+	done := make(chan struct{})
+	s.mu.Lock()
+	prev := s.tails[key]
+	s.tails[key] = done
+	s.mu.Unlock()
+
+	defer func() {
+		close(done)
+		s.mu.Lock()
+		if s.tails[key] == done {
+			delete(s.tails, key)
+		}
+		s.mu.Unlock()
+	}()
+
+	if prev != nil {
+		serializedWaitsTotal.Inc()
+		<-prev
+	}
+	f()
+}
+
+// recheckSerialized checks the pull request again after the handlings of its events. It is for the rechecks
+// triggered by the bot itself, the evaluations nested in the handling of an event must not call it
+func (bot *robot) recheckSerialized(pr *prSnapshot, repoCnf *repoConfig, logger *logrus.Entry) {
+	bot.serial.do(pr.key(), func() { bot.checkIfAllSignedCLA(pr, repoCnf, logger) })
+}
@@ -0,0 +1,69 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPRSerializer(t *testing.T) {
+	var nilSerializer *prSerializer
+	ran := false
+	nilSerializer.do("k", func() { ran = true })
+	assert.Equal(t, true, ran)
+
+	s := newPRSerializer()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	record := func(v string) {
+		mu.Lock()
+		order = append(order, v)
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.do("org1/repo1/1", func() {
+			close(started)
+			<-release
+			record("push")
+		})
+	}()
+	<-started
+
+	// the handling of the same pull request waits for the earlier one
+	waits := testutil.ToFloat64(serializedWaitsTotal)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.do("org1/repo1/1", func() { record("comment") })
+	}()
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(serializedWaitsTotal) > waits }, time.Second, time.Millisecond)
+
+	// the handling of another pull request runs right away
+	s.do("org1/repo1/2", func() { record("other") })
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, []string{"other", "push", "comment"}, order)
+	assert.Empty(t, s.tails)
+}
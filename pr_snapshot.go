@@ -0,0 +1,262 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// prSnapshot is the view of a pull request during the handling of one event.
+// The labels, commits and comments are fetched lazily at the first use and reused afterward,
+// so that the helpers see the same data and no data is fetched twice
+type prSnapshot struct {
+	cli    iClient
+	org    string
+	repo   string
+	number string
+
+	// eventTime and head describe the webhook event triggering the handling, they are empty for the rechecks
+	eventTime time.Time
+	head      string
+	// base is the base branch of the pull request, it is empty for the rechecks
+	base string
+	// htmlURL is the page of the pull request, it is empty for the rechecks
+	htmlURL string
+	// actor is the user or the trigger of the bot causing the handling, which is recorded in the audit
+	actor string
+	// author is the user creating the pull request, it is empty for the rechecks
+	author string
+	// commentID is the comment of the command triggering the handling, it is empty for the other events
+	commentID string
+	// cnf is the configuration the pull request is handled with, which is taken once by bot.prConfig so that
+	// a reload during the handling does not mix two configurations
+	cnf *configuration
+	// log carries the correlation id and the pull request, it is attached by withLogger
+	log *logrus.Entry
+	// ctx carries the span of the handling, it is attached by withContext or withSpan
+	ctx context.Context
+
+	// watchdog bounds the work of the evaluation, it is nil if the work is not bounded
+	watchdog *evaluationWatchdog
+	// stats records the writes of the evaluation for its summary
+	stats evaluationStats
+
+	// recheck bypasses the caches and collects the signDetails of each email, for the `/cla recheck`
+	recheck     bool
+	signDetails []claSignDetail
+
+	// since scopes the commits and commit messages to those after the commit of the sha, for the `/check-cla --since`
+	since string
+	// scope is the resultScope of the repository the pull request is evaluated in
+	scope string
+	// signedCommits is the result of the require_signed_commits, which is recorded in the state
+	signedCommits string
+
+	// signStates memoizes the sign state of each email looked up by the evaluation, keyed by the lower case email
+	signStates   map[string]emailSignResult
+	signStatesMu sync.Mutex
+
+	// unsignedEmails are the emails of the unsigned users of the evaluation, which are recorded in the state
+	unsignedEmails []string
+	// unsignedUserEmails maps each unsigned user of the evaluation to the email, which is recorded in the state
+	unsignedUserEmails map[string]string
+	// unknownReasons maps each unknown user of the evaluation to the reason, for the comment of the unsigned users
+	unknownReasons map[string]string
+	// corporateUsers maps each user signed under a corporate CLA of the mapping to the company
+	corporateUsers map[string]string
+
+	labels       []string
+	labelsLoaded bool
+	labelsOK     bool
+
+	commits       []client.PRCommit
+	commitsLoaded bool
+	commitsOK     bool
+
+	messages       []prCommitMessage
+	messagesLoaded bool
+	messagesOK     bool
+
+	comments       []prComment
+	commentsLoaded bool
+	commentsOK     bool
+	// postedCommentID is the id of the latest comment posted on the pull request, which is empty if the
+	// comment is queued or the platform does not return it
+	postedCommentID string
+}
+
+func newPRSnapshot(cli iClient, org, repo, number string) *prSnapshot {
+	return &prSnapshot{cli: cli, org: org, repo: repo, number: number}
+}
+
+// addUnsignedUser records an unsigned user with the email, the emails differing only in case are the same one
+func (pr *prSnapshot) addUnsignedUser(user, email string) {
+	if email = strings.ToLower(email); !slices.Contains(pr.unsignedEmails, email) {
+		pr.unsignedEmails = append(pr.unsignedEmails, email)
+	}
+	if pr.unsignedUserEmails == nil {
+		pr.unsignedUserEmails = map[string]string{}
+	}
+	if _, ok := pr.unsignedUserEmails[user]; !ok {
+		pr.unsignedUserEmails[user] = email
+	}
+}
+
+// withEvent records the time and head of the webhook event triggering the handling
+func (pr *prSnapshot) withEvent(evt *client.GenericEvent) *prSnapshot {
+	pr.eventTime = parseEventTime(evt)
+	pr.head = utils.GetString(evt.Head)
+	pr.base = utils.GetString(evt.Base)
+	pr.htmlURL = utils.GetString(evt.HtmlURL)
+	pr.author = utils.GetString(evt.Author)
+	pr.commentID = utils.GetString(evt.CommentID)
+	if pr.actor = utils.GetString(evt.Commenter); pr.actor == "" {
+		pr.actor = utils.GetString(evt.Author)
+	}
+	return pr
+}
+
+// withContext attaches the context carrying the span of the handling, whose child spans the evaluation starts
+func (pr *prSnapshot) withContext(ctx context.Context) *prSnapshot {
+	pr.ctx = ctx
+	return pr
+}
+
+// traceContext returns the context carrying the span of the handling, or the background one if it is not traced
+func (pr *prSnapshot) traceContext() context.Context {
+	if pr.ctx == nil {
+		return context.Background()
+	}
+	return pr.ctx
+}
+
+// withActor records the trigger of the bot causing the handling, for the evaluations without an event
+func (pr *prSnapshot) withActor(actor string) *prSnapshot {
+	pr.actor = actor
+	return pr
+}
+
+// parseEventTime returns the update time of the event, or the zero time if it is absent or invalid
+func parseEventTime(evt *client.GenericEvent) time.Time {
+	t, err := time.Parse(time.RFC3339, utils.GetString(evt.UpdateTime))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func (pr *prSnapshot) key() string {
+	return prKey(pr.org, pr.repo, pr.number)
+}
+
+func (pr *prSnapshot) getLabels() ([]string, bool) {
+	if !pr.labelsLoaded {
+		pr.watchdog.countAPICall()
+		pr.labels, pr.labelsOK = pr.cli.GetPullRequestLabels(pr.org, pr.repo, pr.number)
+		pr.labelsLoaded = true
+		pr.logReadFailure("labels", pr.labelsOK)
+	}
+	return pr.labels, pr.labelsOK
+}
+
+// getCommits returns the commits of the pull request. They are taken from the commit messages
+// if those have been fetched, since both come from the same api
+func (pr *prSnapshot) getCommits() ([]client.PRCommit, bool) {
+	if !pr.commitsLoaded {
+		// the commits are scoped by the shas of the commit messages
+		if pr.since != "" {
+			pr.getCommitMessages()
+		}
+		if pr.messagesLoaded && (pr.messagesOK || pr.since != "") {
+			messages := pr.scopedMessages()
+			pr.commits, pr.commitsOK = make([]client.PRCommit, len(messages)), pr.messagesOK
+			for i := range messages {
+				pr.commits[i] = messages[i].PRCommit
+			}
+		} else {
+			pr.watchdog.countAPICall()
+			pr.commits, pr.commitsOK = pr.cli.GetPullRequestCommits(pr.org, pr.repo, pr.number)
+			pr.logReadFailure("commits", pr.commitsOK)
+		}
+		pr.commitsLoaded = true
+	}
+	return pr.commits, pr.commitsOK
+}
+
+func (pr *prSnapshot) getCommitMessages() ([]prCommitMessage, bool) {
+	if !pr.messagesLoaded {
+		pr.watchdog.countAPICall()
+		pr.messages, pr.messagesOK = pr.cli.GetPullRequestCommitMessages(pr.org, pr.repo, pr.number)
+		pr.messagesLoaded = true
+		pr.logReadFailure("commit messages", pr.messagesOK)
+	}
+	return pr.scopedMessages(), pr.messagesOK
+}
+
+// scopedMessages returns the fetched commit messages after the commit of the since, or all of them if it is not set
+func (pr *prSnapshot) scopedMessages() []prCommitMessage {
+	if pr.since == "" {
+		return pr.messages
+	}
+	if i := pr.commitIndex(pr.since); i >= 0 {
+		return pr.messages[i+1:]
+	}
+	return nil
+}
+
+// commitIndex returns the index of the fetched commit message whose sha starts with the sha, or -1 if none
+func (pr *prSnapshot) commitIndex(sha string) int {
+	sha = strings.ToLower(sha)
+	return slices.IndexFunc(pr.messages, func(m prCommitMessage) bool {
+		return strings.HasPrefix(strings.ToLower(m.SHA), sha)
+	})
+}
+
+// reloadCommits drops the fetched commits and commit messages, so that they are fetched again at the next use
+func (pr *prSnapshot) reloadCommits() {
+	pr.commitsLoaded, pr.messagesLoaded = false, false
+}
+
+// headSHA returns the sha of the head commit, which is the last of the commits of the pull request
+func (pr *prSnapshot) headSHA() (string, bool) {
+	if _, ok := pr.getCommitMessages(); !ok || len(pr.messages) == 0 {
+		return "", false
+	}
+	return pr.messages[len(pr.messages)-1].SHA, true
+}
+
+// fetchedHeadSHA returns the sha of the head commit if the commit messages have been fetched
+func (pr *prSnapshot) fetchedHeadSHA() string {
+	if !pr.messagesLoaded || !pr.messagesOK || len(pr.messages) == 0 {
+		return ""
+	}
+	return pr.messages[len(pr.messages)-1].SHA
+}
+
+func (pr *prSnapshot) getComments() ([]prComment, bool) {
+	if !pr.commentsLoaded {
+		pr.watchdog.countAPICall()
+		pr.comments, pr.commentsOK = pr.cli.ListPullRequestComments(pr.org, pr.repo, pr.number)
+		pr.commentsLoaded = true
+		pr.logReadFailure("comments", pr.commentsOK)
+	}
+	return pr.comments, pr.commentsOK
+}
@@ -0,0 +1,63 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPRSnapshot(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestLabels:         true,
+		successfulGetPullRequestCommitMessages: true,
+		labels:                                 []string{labelYes},
+		commitMessages: []prCommitMessage{
+			{PRCommit: client.PRCommit{AuthorName: "u1", AuthorEmail: "e1"}, SHA: "s1"},
+		},
+	}
+	pr := newPRSnapshot(mc, org, repo, number)
+
+	labels, ok := pr.getLabels()
+	assert.Equal(t, true, ok)
+	assert.Equal(t, []string{labelYes}, labels)
+	assert.Equal(t, "GetPullRequestLabels", mc.method)
+
+	// the labels are fetched only once
+	mc.method = ""
+	mc.labels = nil
+	labels, _ = pr.getLabels()
+	assert.Equal(t, []string{labelYes}, labels)
+	assert.Equal(t, "", mc.method)
+
+	_, _ = pr.getCommitMessages()
+	assert.Equal(t, "GetPullRequestCommitMessages", mc.method)
+
+	// the commits are taken from the commit messages
+	mc.method = ""
+	commits, ok := pr.getCommits()
+	assert.Equal(t, true, ok)
+	assert.Equal(t, []client.PRCommit{{AuthorName: "u1", AuthorEmail: "e1"}}, commits)
+	assert.Equal(t, "", mc.method)
+
+	// the failure is also kept
+	_, ok = pr.getComments()
+	assert.Equal(t, false, ok)
+	mc.method = ""
+	mc.successfulListPullRequestComments = true
+	_, ok = pr.getComments()
+	assert.Equal(t, false, ok)
+	assert.Equal(t, "", mc.method)
+}
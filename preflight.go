@@ -0,0 +1,153 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	preflightEnforce = "enforce"
+	preflightWarn    = "warn"
+	preflightOff     = "skip"
+
+	preflightCheckRepoExists        = "repo_exists"
+	preflightCheckLabelsCreatable   = "labels_creatable"
+	preflightCheckCheckURLReachable = "check_url_reachable"
+
+	// preflightProbeEmail is the email looked up to verify that a check url is reachable, whose sign state
+	// does not matter
+	preflightProbeEmail = "preflight@example.com"
+)
+
+func validatePreflight(mode string) error {
+	switch mode {
+	case "", preflightEnforce, preflightWarn, preflightOff:
+		return nil
+	}
+	return fmt.Errorf("unsupported preflight %q, it must be one of %s, %s and %s",
+		mode, preflightEnforce, preflightWarn, preflightOff)
+}
+
+func (c *configuration) preflightMode() string {
+	if c.Preflight == "" {
+		return preflightEnforce
+	}
+	return c.Preflight
+}
+
+// preflightIssue is a failed check of an entry of a config item
+type preflightIssue struct {
+	Item   int    `json:"item"`
+	Entry  string `json:"entry"`
+	Check  string `json:"check"`
+	Reason string `json:"reason"`
+}
+
+func (i *preflightIssue) String() string {
+	return fmt.Sprintf("config_items[%d] %s: %s, %s", i.Item, i.Entry, i.Check, i.Reason)
+}
+
+// preflightReport is the result of checking the new and changed config items of a reloaded configuration
+type preflightReport struct {
+	CheckedAt time.Time        `json:"checked_at"`
+	Items     []int            `json:"items"`
+	Issues    []preflightIssue `json:"issues,omitempty"`
+}
+
+func (r *preflightReport) add(item int, entry, check, reason string) {
+	r.Issues = append(r.Issues, preflightIssue{Item: item, Entry: entry, Check: check, Reason: reason})
+}
+
+func (r *preflightReport) err() error {
+	if len(r.Issues) == 0 {
+		return nil
+	}
+
+	issues := make([]string, len(r.Issues))
+	for i := range r.Issues {
+		issues[i] = r.Issues[i].String()
+	}
+	return errors.New("the preflight failed: " + strings.Join(issues, "; "))
+}
+
+// changedConfigItems lists the indexes of the config items of c which are not in the old configuration
+func changedConfigItems(old, c *configuration) []int {
+	var changed []int
+	for i := range c.ConfigItems {
+		found := false
+		for j := range old.ConfigItems {
+			if reflect.DeepEqual(c.ConfigItems[i], old.ConfigItems[j]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			changed = append(changed, i)
+		}
+	}
+	return changed
+}
+
+// preflight checks the new and changed config items of c against the code hosting platform and the CLA server.
+// Each org/repo they list must exist and allow the bot to create the labels, and each of their check urls must
+// be reachable. The repositories of the entries listing an org are not checked
+func (bot *robot) preflight(old, c *configuration) *preflightReport {
+	report := &preflightReport{CheckedAt: time.Now(), Items: changedConfigItems(old, c)}
+	reachable := map[string]bool{}
+	for _, i := range report.Items {
+		item := &c.ConfigItems[i]
+		for _, entry := range item.Repos {
+			org, repo, found := strings.Cut(entry, "/")
+			if !found {
+				continue
+			}
+
+			if _, ok := bot.cli.GetRepoMetadata(org, repo); !ok {
+				report.add(i, entry, preflightCheckRepoExists, "the repository does not exist or can not be read")
+				continue
+			}
+			if pass, ok := bot.cli.GetRepoPushPermission(org, repo); !ok {
+				report.add(i, entry, preflightCheckLabelsCreatable, "the permission on the repository can not be read")
+			} else if !pass {
+				report.add(i, entry, preflightCheckLabelsCreatable, "the bot can not push to the repository")
+			}
+		}
+
+		// the variables of the check urls are replaced by the first repository of the config item
+		var probeOrg, probeRepo string
+		if len(item.Repos) != 0 {
+			probeOrg, probeRepo, _ = strings.Cut(item.Repos[0], "/")
+		}
+		reported := map[string]bool{}
+		for _, effective := range c.inheritedItems(i) {
+			for _, checkURL := range effective.checkURLs() {
+				ok, checked := reachable[checkURL]
+				if !checked {
+					_, ok = bot.cli.CheckCLASignature(claCheckURL(checkURL, probeOrg, probeRepo, preflightProbeEmail))
+					reachable[checkURL] = ok
+				}
+				if !ok && !reported[checkURL] {
+					reported[checkURL] = true
+					report.add(i, checkURL, preflightCheckCheckURLReachable, "the CLA server does not respond")
+				}
+			}
+		}
+	}
+	return report
+}
@@ -0,0 +1,97 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/opensourceways/server-common-lib/utils"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	existing := repoConfig{RepoFilter: config.RepoFilter{Repos: []string{org}}, CheckURL: "http://cla/check"}
+	added := repoConfig{RepoFilter: config.RepoFilter{Repos: []string{"org2", "org2/repo2"}}, CheckURL: "http://cla2/check"}
+	old := &configuration{ConfigItems: []repoConfig{existing}}
+	c := &configuration{ConfigItems: []repoConfig{existing, added}}
+	assert.Equal(t, []int{1}, changedConfigItems(old, c))
+
+	mc := &mockClient{
+		successfulGetRepoMetadata:       true,
+		successfulGetRepoPushPermission: true,
+		pushPermission:                  true,
+		successfulCheckCLASignature:     true,
+	}
+	bot := &robot{cli: mc}
+	report := bot.preflight(old, c)
+	assert.Equal(t, []int{1}, report.Items)
+	assert.Empty(t, report.Issues)
+	assert.Equal(t, nil, report.err())
+
+	mc.pushPermission = false
+	mc.successfulCheckCLASignature = false
+	report = bot.preflight(old, c)
+	assert.Equal(t, []preflightIssue{
+		{Item: 1, Entry: "org2/repo2", Check: preflightCheckLabelsCreatable, Reason: "the bot can not push to the repository"},
+		{Item: 1, Entry: "http://cla2/check", Check: preflightCheckCheckURLReachable, Reason: "the CLA server does not respond"},
+	}, report.Issues)
+
+	mc.successfulGetRepoMetadata = false
+	report = bot.preflight(old, c)
+	assert.Equal(t, preflightCheckRepoExists, report.Issues[0].Check)
+	assert.Contains(t, report.err().Error(), "config_items[1] org2/repo2: repo_exists")
+}
+
+func TestConfigWatcherPreflight(t *testing.T) {
+	data, err := os.ReadFile(findTestdata(t, configYaml))
+	assert.Equal(t, nil, err)
+	path := filepath.Join(t.TempDir(), configYaml)
+	assert.Equal(t, nil, os.WriteFile(path, data, 0o600))
+
+	initial := new(configuration)
+	assert.Equal(t, nil, utils.LoadFromYaml(path, initial))
+	bot := &robot{cli: &mockClient{successfulGetRepoMetadata: true, successfulCheckCLASignature: true}, cnf: initial}
+	w, err := newConfigWatcher(path, initial, framework.NewLogger())
+	assert.Equal(t, nil, err)
+	w.preflight = bot.preflight
+	bot.watcher = w
+
+	// the bot can not push to the added repository
+	changed := strings.Replace(string(data), "      - org2\n", "      - org2\n      - org3/repo3\n", 1)
+	assert.Equal(t, nil, os.WriteFile(path, []byte(changed), 0o600))
+	assert.Equal(t, false, w.reload())
+	s := w.getStatus()
+	assert.Equal(t, 1, s.Version)
+	assert.Equal(t, preflightCheckLabelsCreatable, s.Preflight.Issues[0].Check)
+	assert.Contains(t, s.LastError, "org3/repo3")
+
+	// it is applied with the warnings in the warn mode
+	changed += "\npreflight: warn\n"
+	assert.Equal(t, nil, os.WriteFile(path, []byte(changed), 0o600))
+	assert.Equal(t, true, w.reload())
+	s = w.getStatus()
+	assert.Equal(t, 2, s.Version)
+	assert.Equal(t, 1, len(s.Preflight.Issues))
+	assert.NotEqual(t, nil, bot.getRepoConfig("org3", "repo3"))
+
+	// no check is run in the skip mode
+	changed = strings.Replace(changed, "preflight: warn\n", "preflight: skip\n", 1)
+	assert.Equal(t, nil, os.WriteFile(path, []byte(changed), 0o600))
+	assert.Equal(t, true, w.reload())
+	assert.Equal(t, 3, w.getStatus().Version)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// recheckPriorityConfig decides which blocked pull requests are rechecked first, and how many of them
+// are rechecked in one run, so that a run on a huge organization completes the useful work first
+type recheckPriorityConfig struct {
+	// ActivityWeight weighs how recently the pull request was updated. Default is 1
+	ActivityWeight *float64 `json:"activity_weight"`
+	// AgeWeight weighs how long the pull request has been blocked. Default is 1
+	AgeWeight *float64 `json:"age_weight"`
+	// Budget is the max number of the pull requests rechecked in one run, the others are left to the next run.
+	// It is unlimited if it is 0
+	Budget int `json:"budget"`
+}
+
+func (c *recheckPriorityConfig) validate() error {
+	if (c.ActivityWeight != nil && *c.ActivityWeight < 0) || (c.AgeWeight != nil && *c.AgeWeight < 0) {
+		return errors.New("the weights of the recheck_priority can not be negative")
+	}
+	if c.Budget < 0 {
+		return errors.New("the budget of the recheck_priority can not be negative")
+	}
+	return nil
+}
+
+func weightOrDefault(w *float64) float64 {
+	if w == nil {
+		return 1
+	}
+	return *w
+}
+
+// blockedSince returns when the pull request became blocked, which is the first evaluation
+// of the latest consecutive blocked ones in the history
+func blockedSince(s *prState) time.Time {
+	since := s.LastEvaluation
+	for i := len(s.History) - 1; i >= 0 && s.History[i].Status != prStatusSigned; i-- {
+		since = s.History[i].Time
+	}
+	return since
+}
+
+// recheckScore scores the pull request in [0, activity_weight + age_weight), the higher the earlier.
+// The activity decays by the hours since the latest update, and the age grows with the days blocked
+func recheckScore(s *prState, cfg *recheckPriorityConfig, now time.Time) float64 {
+	updatedAt := s.LastEventTime
+	if updatedAt.IsZero() {
+		updatedAt = s.LastEvaluation
+	}
+	idle := now.Sub(updatedAt).Hours()
+	blocked := now.Sub(blockedSince(s)).Hours()
+
+	activity := 1 / (1 + max(idle, 0))
+	age := max(blocked, 0) / (max(blocked, 0) + 24)
+	return weightOrDefault(cfg.ActivityWeight)*activity + weightOrDefault(cfg.AgeWeight)*age
+}
+
+// prioritizePRs sorts the pull requests by their scores and cuts them by the budget
+func prioritizePRs(prs []prState, cfg *recheckPriorityConfig, now time.Time) []prState {
+	scores := make(map[string]float64, len(prs))
+	for i := range prs {
+		scores[prs[i].key()] = recheckScore(&prs[i], cfg, now)
+	}
+
+	sort.SliceStable(prs, func(i, j int) bool {
+		return scores[prs[i].key()] > scores[prs[j].key()]
+	})
+
+	if cfg.Budget != 0 && len(prs) > cfg.Budget {
+		prs = prs[:cfg.Budget]
+	}
+	return prs
+}
@@ -0,0 +1,58 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPrioritizePRs(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	prs := []prState{
+		// idle and recently blocked
+		{Org: org, Repo: repo, Number: "1", Status: prStatusUnsigned, LastEvaluation: now.Add(-10 * day),
+			History: []prEvaluation{{Time: now.Add(-10 * day), Status: prStatusUnsigned}}},
+		// just updated
+		{Org: org, Repo: repo, Number: "2", Status: prStatusUnsigned, LastEvaluation: now,
+			LastEventTime: now, History: []prEvaluation{{Time: now, Status: prStatusUnsigned}}},
+		// blocked for a long time
+		{Org: org, Repo: repo, Number: "3", Status: prStatusUnknown, LastEvaluation: now.Add(-20 * day),
+			History: []prEvaluation{
+				{Time: now.Add(-90 * day), Status: prStatusSigned},
+				{Time: now.Add(-60 * day), Status: prStatusUnsigned},
+				{Time: now.Add(-20 * day), Status: prStatusUnknown},
+			}},
+	}
+	assert.Equal(t, now.Add(-60*day), blockedSince(&prs[2]))
+
+	keys := func(prs []prState) []string {
+		r := make([]string, len(prs))
+		for i := range prs {
+			r[i] = prs[i].Number
+		}
+		return r
+	}
+
+	assert.Equal(t, []string{"2", "3", "1"}, keys(prioritizePRs(prs, &recheckPriorityConfig{}, now)))
+
+	zero := 0.0
+	cfg := &recheckPriorityConfig{ActivityWeight: &zero, Budget: 2}
+	assert.Equal(t, []string{"3", "1"}, keys(prioritizePRs(prs, cfg, now)))
+
+	negative := -1.0
+	assert.NotEqual(t, nil, (&recheckPriorityConfig{AgeWeight: &negative}).validate())
+}
@@ -0,0 +1,73 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"regexp"
+	"strings"
+)
+
+// regexpEmail matches the emails in the logs, the audit records and the comments, which are masked in the
+// privacy_mode, including those escaped in the query of the urls of the CLA server. The masked emails such as
+// j***@example.com are not matched again
+var regexpEmail = regexp.MustCompile(`[A-Za-z0-9._%+\-]+(?:@|%40)[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)+`)
+
+// maskEmails masks all the emails in the text
+func maskEmails(text string) string {
+	return regexpEmail.ReplaceAllStringFunc(text, func(email string) string {
+		if strings.Contains(email, "@") {
+			return maskEmailText(email)
+		}
+		return strings.Replace(maskEmailText(strings.Replace(email, "%40", "@", 1)), "@", "%40", 1)
+	})
+}
+
+// emailMaskHook masks the emails in the message and the fields of each log entry
+type emailMaskHook struct{}
+
+func (emailMaskHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (emailMaskHook) Fire(entry *logrus.Entry) error {
+	entry.Message = maskEmails(entry.Message)
+	for k, v := range entry.Data {
+		switch v := v.(type) {
+		case string:
+			entry.Data[k] = maskEmails(v)
+		case error:
+			entry.Data[k] = errors.New(maskEmails(v.Error()))
+		}
+	}
+	return nil
+}
+
+// enablePrivacyMode masks the emails in the logs of the bot and in the audit records
+func enablePrivacyMode(bot *robot) {
+	bot.log.Logger.AddHook(emailMaskHook{})
+	logrus.AddHook(emailMaskHook{})
+	if bot.audit != nil {
+		bot.audit.maskEmails = true
+	}
+}
+
+// maskCommentEmails masks the emails in the comment in the privacy_mode, since the comments are public
+func (bot *robot) maskCommentEmails(pr *prSnapshot, comment string) string {
+	if !bot.prConfig(pr).PrivacyMode {
+		return comment
+	}
+	return maskEmails(comment)
+}
@@ -0,0 +1,72 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaskEmails(t *testing.T) {
+	testCases := []struct {
+		in  string
+		out string
+	}{
+		{"signed by jane.doe+cla@example.com.", "signed by j***@example.com."},
+		{"CLA request: https://cla/check?email=jane%40example.co.uk&version=2", "CLA request: https://cla/check?email=j***%40example.co.uk&version=2"},
+		{"j***@example.com is masked", "j***@example.com is masked"},
+		{"no email @here or user@localhost", "no email @here or user@localhost"},
+	}
+	for i := range testCases {
+		assert.Equal(t, testCases[i].out, maskEmails(testCases[i].in))
+	}
+}
+
+func TestPrivacyMode(t *testing.T) {
+	buf := &bytes.Buffer{}
+	mc := &commentRecordingClient{mockClient: &mockClient{}}
+	bot := &robot{
+		cli:   mc,
+		cnf:   &configuration{PrivacyMode: true},
+		log:   framework.NewLogger(),
+		audit: &auditLog{sink: &writerSink{w: buf}, now: time.Now, log: framework.NewLogger()},
+	}
+	logs := &bytes.Buffer{}
+	bot.log.Logger.Out = logs
+	enablePrivacyMode(bot)
+
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.postPRComment(pr, "jane@example.com needs to sign the CLA")
+	assert.Equal(t, []string{"org1/repo1/1: j***@example.com needs to sign the CLA"}, mc.posted)
+
+	bot.audit.record(auditRecord{Org: org, Repo: repo, Number: number, Action: auditActionOverride,
+		Detail: "signed offline by jane@example.com"})
+	records := decodeAuditRecords(t, buf.String())
+	assert.Equal(t, "signed offline by j***@example.com", records[len(records)-1].Detail)
+
+	bot.log.WithField("email", "jane@example.com").WithError(errors.New("jane@example.com failed")).
+		Info("check jane@example.com")
+	assert.Equal(t, false, strings.Contains(logs.String(), "jane@example.com"))
+	assert.Equal(t, 3, strings.Count(logs.String(), "j***@example.com"))
+
+	// the comments are kept as they are without the privacy_mode
+	bot.cnf.PrivacyMode = false
+	bot.postPRComment(pr, "jane@example.com")
+	assert.Equal(t, "org1/repo1/1: jane@example.com", mc.posted[1])
+}
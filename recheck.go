@@ -0,0 +1,196 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRecheckRatePerMinute is used when the recheck_rate_per_minute is not configured
+	defaultRecheckRatePerMinute = 30
+	// defaultCommentOrgRecheckDone is used when the comment_org_recheck_done is not configured
+	defaultCommentOrgRecheckDone = "### CLA Recheck Summary  \n\n" +
+		"The recheck of the organization **%s** has finished. %d pull requests were rechecked: " +
+		"%d signed, %d unsigned, %d unknown."
+)
+
+// a compiled regular expression for the comment that uses to recheck the CLA of the whole organization
+var regexpRecheckOrgComment = regexp.MustCompile(`^/cla[\t ]+recheck-org$`)
+
+// orgRecheckSummary is the result of the recheck of an organization
+type orgRecheckSummary struct {
+	Org      string `json:"org"`
+	Total    int    `json:"total"`
+	Signed   int    `json:"signed"`
+	Unsigned int    `json:"unsigned"`
+	Unknown  int    `json:"unknown"`
+}
+
+// orgRechecker rechecks the blocked pull requests of an organization in the background
+type orgRechecker struct {
+	mu      sync.Mutex
+	running map[string]bool
+	limiter *rate.Limiter
+}
+
+func newOrgRechecker(ratePerMinute int) *orgRechecker {
+	if ratePerMinute <= 0 {
+		ratePerMinute = defaultRecheckRatePerMinute
+	}
+
+	return &orgRechecker{
+		running: map[string]bool{},
+		limiter: rate.NewLimiter(rate.Every(time.Minute/time.Duration(ratePerMinute)), 1),
+	}
+}
+
+// start marks the recheck of the org as running, it returns false if the org is being rechecked
+func (r *orgRechecker) start(org string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running[org] {
+		return false
+	}
+	r.running[org] = true
+	return true
+}
+
+func (r *orgRechecker) finish(org string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.running, org)
+}
+
+// listBlockedPRs lists the open pull requests of the org whose latest evaluation is unsigned or unknown.
+// Since the state store is empty after a restart, the open pull requests labeled with the cla_label_no
+// of the repositories of the org are listed from the platform as well
+func (bot *robot) listBlockedPRs(org string) []prState {
+	var blocked []prState
+	listed := map[string]bool{}
+	for _, s := range bot.store.exportSnapshot().PRs {
+		if s.Org == org && !s.Closed && (s.Status == prStatusUnsigned || s.Status == prStatusUnknown) {
+			blocked = append(blocked, s)
+			listed[s.key()] = true
+		}
+	}
+
+	for _, r := range bot.rescanRepos() {
+		o, repo, _ := strings.Cut(r, "/")
+		if o != org {
+			continue
+		}
+		repoCnf := bot.getRepoConfig(org, repo)
+		if repoCnf == nil || !repoCnf.enforces(enforcementLabel) {
+			continue
+		}
+
+		numbers, _ := bot.cli.ListOpenPullRequestsWithLabel(org, repo, bot.labelName(nil, repoCnf.CLALabelNo))
+		for _, number := range numbers {
+			if k := prKey(org, repo, number); !listed[k] {
+				blocked = append(blocked, prState{Org: org, Repo: repo, Number: number, Status: prStatusUnsigned})
+				listed[k] = true
+			}
+		}
+	}
+
+	return blocked
+}
+
+// recheckOrg queues the rechecks of the blocked pull requests of the org, throttled by the rate limiter.
+// The pull requests are rechecked by the recheck_priority, and those beyond its budget are left to the next run,
+// as well as those left when the error budget pauses the recheck.
+// It returns the number of queued pull requests, or -1 if the org is being rechecked.
+// The done is called with the summary after all the rechecks are finished
+func (bot *robot) recheckOrg(org string, logger *logrus.Entry, done func(orgRecheckSummary)) int {
+	if !bot.rechecker.start(org) {
+		return -1
+	}
+
+	prs := prioritizePRs(bot.listBlockedPRs(org), &bot.config().RecheckPriority, time.Now())
+	go func() {
+		defer bot.rechecker.finish(org)
+
+		summary := orgRecheckSummary{Org: org}
+		for i := range prs {
+			if err := bot.rechecker.limiter.Wait(context.Background()); err != nil {
+				logger.WithError(err).Error("the recheck of the org is interrupted")
+				break
+			}
+			if bot.budget.degraded(platformCodeHosting) {
+				logger.Warningf("the recheck of the org is paused by the error budget, %d pull requests are left", len(prs)-i)
+				break
+			}
+
+			s := prs[i]
+			repoCnf := bot.getRepoConfig(s.Org, s.Repo)
+			if repoCnf == nil {
+				continue
+			}
+			bot.recheckSerialized(newPRSnapshot(bot.cli, s.Org, s.Repo, s.Number).withActor(auditActorRecheck), repoCnf,
+				logger.WithField("recheck-pr", s.key()))
+
+			summary.Total++
+			latest, _ := bot.store.get(s.Org, s.Repo, s.Number)
+			switch latest.Status {
+			case prStatusSigned:
+				summary.Signed++
+			case prStatusUnsigned:
+				summary.Unsigned++
+			default:
+				summary.Unknown++
+			}
+		}
+
+		logger.WithField("summary", summary).Info("the recheck of the org has finished")
+		if done != nil {
+			done(summary)
+		}
+	}()
+
+	return len(prs)
+}
+
+// handleRecheckOrgCommand handles the `/cla recheck-org` commented in the admin repository.
+// It returns true if the comment is the command
+func (bot *robot) handleRecheckOrgCommand(org, repo, number, commenter, comment string, logger *logrus.Entry) bool {
+	if !regexpRecheckOrgComment.MatchString(comment) || bot.config().AdminRepo != org+"/"+repo {
+		return false
+	}
+
+	if pass, _ := bot.cli.CheckPermission(org, repo, commenter); !pass {
+		logger.Warningf("%s has no permission to recheck the org", commenter)
+		return true
+	}
+
+	n := bot.recheckOrg(org, logger, func(s orgRecheckSummary) {
+		pr := newPRSnapshot(bot.cli, org, repo, number).withActor(commenter)
+		bot.postPRComment(pr, bot.renderer(pr).orgRecheckDone(s))
+	})
+	if n < 0 {
+		logger.Infof("skip the recheck of the org %s by %s, since it is being rechecked", org, commenter)
+	} else {
+		logger.Infof("%s queued the recheck of %d pull requests of the org %s", commenter, n, org)
+	}
+
+	return true
+}
@@ -0,0 +1,77 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRecheckOrg(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestCommits: true,
+		successfulCheckCLASignature:     true,
+		successfulAddPRLabels:           true,
+		successfulCheckPermission:       true,
+		permission:                      true,
+		commits:                         []client.PRCommit{{AuthorName: "u1", AuthorEmail: "e1"}},
+		CLAState:                        client.CLASignStateYes,
+	}
+	repoCnf := repoConfig{RepoFilter: config.RepoFilter{Repos: []string{org}}, CLALabelYes: labelYes, CLALabelNo: labelNo}
+	bot := &robot{
+		cli:       mc,
+		cnf:       &configuration{ConfigItems: []repoConfig{repoCnf}, AdminRepo: org + "/admin"},
+		store:     newMemoryStateStore(),
+		rechecker: newOrgRechecker(60000),
+	}
+	bot.store.put(prState{Org: org, Repo: repo, Number: "1", Status: prStatusUnsigned, UnsignedUsers: []string{"u1"}})
+	bot.store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusSigned})
+	bot.store.put(prState{Org: "org2", Repo: repo, Number: "3", Status: prStatusUnknown})
+
+	logger := framework.NewLogger()
+	// not the admin repo
+	assert.Equal(t, false, bot.handleRecheckOrgCommand(org, repo, number, commenter, "/cla recheck-org", logger))
+	assert.Equal(t, false, bot.handleRecheckOrgCommand(org, "admin", number, commenter, "/check-cla", logger))
+
+	done := make(chan orgRecheckSummary)
+	assert.Equal(t, 1, bot.recheckOrg(org, logger, func(s orgRecheckSummary) { done <- s }))
+	// the org is being rechecked
+	assert.Equal(t, -1, bot.recheckOrg(org, logger, nil))
+	assert.Equal(t, orgRecheckSummary{Org: org, Total: 1, Signed: 1}, <-done)
+
+	s, _ := bot.store.get(org, repo, "1")
+	assert.Equal(t, prStatusSigned, s.Status)
+}
+
+func TestListBlockedPRs(t *testing.T) {
+	mc := &mockClient{
+		successfulListOpenPullRequests: true,
+		labeledPRs:                     map[string][]string{org + "/" + repo: {"1", "4"}, "org2/" + repo: {"5"}},
+	}
+	repoCnf := repoConfig{RepoFilter: config.RepoFilter{Repos: []string{org}}, CLALabelYes: labelYes, CLALabelNo: labelNo}
+	bot := &robot{cli: mc, cnf: &configuration{ConfigItems: []repoConfig{repoCnf}}, store: newMemoryStateStore()}
+	bot.store.put(prState{Org: org, Repo: repo, Number: "1", Status: prStatusUnsigned})
+	bot.store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusUnknown})
+	bot.store.put(prState{Org: org, Repo: repo, Number: "3", Status: prStatusUnsigned, Closed: true})
+
+	var keys []string
+	for _, s := range bot.listBlockedPRs(org) {
+		keys = append(keys, s.key())
+	}
+	// the closed one is skipped, and the labeled one missing in the store is listed from the platform
+	assert.ElementsMatch(t, []string{"org1/repo1/1", "org1/repo1/2", "org1/repo1/4"}, keys)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	repoVisibilityPublic  = "public"
+	repoVisibilityPrivate = "private"
+
+	// repoMetadataCacheTTL is how long the metadata of a repository is reused before looking it up again
+	repoMetadataCacheTTL = 10 * time.Minute
+)
+
+// repoMetadata is the metadata of a repository used to match the repoConfig
+type repoMetadata struct {
+	Private       bool   `json:"private"`
+	Archived      bool   `json:"archived"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+func (m *repoMetadata) visibility() string {
+	if m.Private {
+		return repoVisibilityPrivate
+	}
+	return repoVisibilityPublic
+}
+
+type repoMetadataEntry struct {
+	meta      repoMetadata
+	expiredAt time.Time
+}
+
+// repoMetadataCache caches the metadata of the repositories looked up from the code hosting platform
+type repoMetadataCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]repoMetadataEntry
+	lookup  func(org, repo string) (repoMetadata, bool)
+}
+
+func newRepoMetadataCache(ttl time.Duration, lookup func(org, repo string) (repoMetadata, bool)) *repoMetadataCache {
+	return &repoMetadataCache{ttl: ttl, entries: map[string]repoMetadataEntry{}, lookup: lookup}
+}
+
+// get returns the metadata of the repository, or nil if it can not be looked up
+func (c *repoMetadataCache) get(org, repo string) *repoMetadata {
+	k := org + "/" + repo
+	now := time.Now()
+
+	c.mu.Lock()
+	e, ok := c.entries[k]
+	c.mu.Unlock()
+	if ok && now.Before(e.expiredAt) {
+		return &e.meta
+	}
+
+	meta, success := c.lookup(org, repo)
+	if !success {
+		return nil
+	}
+
+	c.mu.Lock()
+	for key, v := range c.entries {
+		if now.After(v.expiredAt) {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[k] = repoMetadataEntry{meta: meta, expiredAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return &meta
+}
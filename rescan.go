@@ -0,0 +1,110 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+	"time"
+)
+
+// startRescanScheduler rescans the pull requests labeled with the cla_label_no every rescan_interval_minutes
+// in the background, so that the labels are flipped once the contributors sign without any command
+func startRescanScheduler(bot *robot, logger *logrus.Entry) {
+	if bot.config().RescanIntervalMinutes == 0 {
+		logger.Info("the rescan of the unsigned pull requests is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(bot.config().RescanIntervalMinutes) * time.Minute)
+	go func() {
+		for range ticker.C {
+			n := bot.rescanUnsignedPRs(logger.WithField("rescan", true))
+			logger.Infof("rescanned %d unsigned pull requests", n)
+		}
+	}()
+}
+
+// rescanRepos lists the repositories to rescan, which are those listed in the config items as org/repo, those of
+// the orgs listed in the config items, and those of the pull requests in the state store. The repositories of
+// an org failing to be listed are left to the state store
+func (bot *robot) rescanRepos() []string {
+	var repos []string
+	add := func(r string) {
+		if !slices.Contains(repos, r) {
+			repos = append(repos, r)
+		}
+	}
+	for i := range bot.config().ConfigItems {
+		for _, r := range bot.config().ConfigItems[i].Repos {
+			if strings.Contains(r, "/") {
+				add(r)
+				continue
+			}
+
+			names, _ := bot.cli.ListOrgRepos(r)
+			for _, name := range names {
+				add(r + "/" + name)
+			}
+		}
+	}
+
+	if bot.store != nil {
+		for _, s := range bot.store.exportSnapshot().PRs {
+			add(s.Org + "/" + s.Repo)
+		}
+	}
+
+	slices.Sort(repos)
+	return repos
+}
+
+// rescanUnsignedPRs checks again the open pull requests labeled with the cla_label_no of the repositories,
+// throttled by the rate limiter of the recheck. It stops when the error budget pauses the rechecks.
+// It returns the number of the rescanned pull requests
+func (bot *robot) rescanUnsignedPRs(logger *logrus.Entry) int {
+	n := 0
+	for _, r := range bot.rescanRepos() {
+		org, repo, _ := strings.Cut(r, "/")
+		repoCnf := bot.getRepoConfig(org, repo)
+		if repoCnf == nil || !repoCnf.enforces(enforcementLabel) {
+			continue
+		}
+
+		numbers, success := bot.cli.ListOpenPullRequestsWithLabel(org, repo, bot.labelName(nil, repoCnf.CLALabelNo))
+		if !success {
+			logger.Warningf("failed to list the unsigned pull requests of %s", r)
+			continue
+		}
+
+		for _, number := range numbers {
+			if err := bot.rechecker.limiter.Wait(context.Background()); err != nil {
+				logger.WithError(err).Error("the rescan is interrupted")
+				return n
+			}
+			if bot.budget.degraded(platformCodeHosting) {
+				logger.Warning("the rescan is paused by the error budget")
+				return n
+			}
+
+			bot.recheckSerialized(newPRSnapshot(bot.cli, org, repo, number).withActor(auditActorRescan), repoCnf,
+				logger.WithField("rescan-pr", prKey(org, repo, number)))
+			n++
+		}
+	}
+
+	return n
+}
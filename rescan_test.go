@@ -0,0 +1,63 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRescanUnsignedPRs(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestCommits: true,
+		successfulCheckCLASignature:     true,
+		successfulAddPRLabels:           true,
+		successfulRemovePRLabels:        true,
+		successfulGetPullRequestLabels:  true,
+		successfulListOpenPullRequests:  true,
+		commits:                         []client.PRCommit{{AuthorName: "u1", AuthorEmail: "e1"}},
+		labels:                          []string{labelNo},
+		CLAState:                        client.CLASignStateYes,
+		labeledPRs:                      map[string][]string{org + "/" + repo: {"1", "2"}, "org2/repo2": {"5"}},
+	}
+	items := []repoConfig{
+		{RepoFilter: config.RepoFilter{Repos: []string{org}}, CLALabelYes: labelYes, CLALabelNo: labelNo},
+		{RepoFilter: config.RepoFilter{Repos: []string{"org2/repo2"}}, CLALabelYes: labelYes, CLALabelNo: labelNo},
+	}
+	bot := &robot{
+		cli:       mc,
+		cnf:       &configuration{ConfigItems: items},
+		store:     newMemoryStateStore(),
+		rechecker: newOrgRechecker(60000),
+	}
+	bot.store.put(prState{Org: org, Repo: repo, Number: "1", Status: prStatusUnsigned, UnsignedUsers: []string{"u1"}})
+	bot.store.put(prState{Org: "org3", Repo: "repo3", Number: "1", Status: prStatusUnsigned})
+
+	assert.Equal(t, []string{"org1/repo1", "org2/repo2", "org3/repo3"}, bot.rescanRepos())
+	// the repositories of the org in the config items are listed
+	mc.successfulListOrgRepos, mc.orgRepos = true, map[string][]string{org: {"repo4", repo}}
+	assert.Equal(t, []string{"org1/repo1", "org1/repo4", "org2/repo2", "org3/repo3"}, bot.rescanRepos())
+	assert.Equal(t, 3, bot.rescanUnsignedPRs(framework.NewLogger()))
+	for _, key := range [][3]string{{org, repo, "1"}, {org, repo, "2"}, {"org2", "repo2", "5"}} {
+		s, _ := bot.store.get(key[0], key[1], key[2])
+		assert.Equal(t, prStatusSigned, s.Status, key)
+	}
+
+	// the repositories failing to list the pull requests are skipped
+	mc.successfulListOpenPullRequests = false
+	assert.Equal(t, 0, bot.rescanUnsignedPRs(framework.NewLogger()))
+}
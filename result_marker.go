@@ -0,0 +1,48 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// the kinds of the result comments which are marked, so that an identical one is not posted again
+const (
+	resultKindAllSigned = "all-signed"
+	resultKindNeedSign  = "need-sign"
+)
+
+// regexpResultMarker matches the hidden marker of the kind of a result comment
+var regexpResultMarker = regexp.MustCompile(`<!-- cla-result: ([a-z-]+) -->`)
+
+// withResultMarker appends the hidden marker of the kind to the result comment
+func withResultMarker(kind, comment string) string {
+	return comment + "\n\n<!-- cla-result: " + kind + " -->"
+}
+
+// resultCommentKind returns the kind of the result comment, or empty if it is not marked
+func resultCommentKind(body string) string {
+	if m := regexpResultMarker.FindStringSubmatch(body); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// sameResultComment checks whether the posted comment is the same kind of result with the identical body as
+// the one to post. The platform may trim the trailing spaces of the body
+func sameResultComment(posted, comment string) bool {
+	kind := resultCommentKind(comment)
+	return kind != "" && resultCommentKind(posted) == kind && strings.TrimSpace(posted) == strings.TrimSpace(comment)
+}
@@ -14,45 +14,126 @@
 package main
 
 import (
+	"context"
 	"github.com/opensourceways/robot-framework-lib/client"
 	"github.com/opensourceways/robot-framework-lib/config"
 	"github.com/opensourceways/robot-framework-lib/framework"
 	"github.com/opensourceways/robot-framework-lib/utils"
 	"github.com/sirupsen/logrus"
-	"net/url"
 	"regexp"
-	"slices"
-	"strings"
+	"time"
 )
 
 // iClient is an interface that defines methods for client-side interactions
 type iClient interface {
-	CreatePRComment(org, repo, number, comment string) (success bool)
+	CreatePRComment(org, repo, number, comment string) (commentID string, success bool)
 	GetPullRequestLabels(org, repo, number string) (result []string, success bool)
 	AddPRLabels(org, repo, number string, labels []string) (success bool)
 	RemovePRLabels(org, repo, number string, labels []string) (success bool)
 	GetPullRequestCommits(org, repo, number string) (result []client.PRCommit, success bool)
-	ListPullRequestComments(org, repo, number string) (result []client.PRComment, success bool)
+	ListPullRequestComments(org, repo, number string) (result []prComment, success bool)
 	DeletePRComment(org, repo, commentID string) (success bool)
+	UpdatePRComment(org, repo, commentID, comment string) (success bool)
 	CheckCLASignature(urlStr string) (signState string, success bool)
+	CheckCLASignatures(urlStr string, emails []string) (signStates map[string]string, success bool)
+	CheckCLASignatureDetail(urlStr string) (signState, claType string, success bool)
+	GetPullRequestCommitMessages(org, repo, number string) (result []prCommitMessage, success bool)
+	GetPullRequestChangedFileCount(org, repo, number string) (count int, success bool)
+	ListOpenPullRequestsWithLabel(org, repo, label string) (numbers []string, success bool)
+	ListOrgRepos(org string) (repos []string, success bool)
+	VerifyCLASignatureID(urlStr, email string) (signState string, success bool)
+	GetRepoMetadata(org, repo string) (result repoMetadata, success bool)
+	GetRepoPushPermission(org, repo string) (pass, success bool)
+	GetRepoFileContent(org, repo, path, ref string) (content []byte, success bool)
+	CreateCommitStatus(org, repo, sha string, status commitStatus) (success bool)
+	CreateIssue(org, repo, title, body string) (success bool)
+	CreateRepoLabel(org, repo string, label repoLabel) (success bool)
+	GetCommitVerificationStatus(org, repo, sha string) (verified, success bool)
+	GetUserEmail(login string) (email string, success bool)
+	GetBotLogin() (login string, success bool)
+	AddCommentReaction(org, repo, commentID, reaction string) (success bool)
 	CheckIfPRCreateEvent(evt *client.GenericEvent) (yes bool)
 	CheckIfPRSourceCodeUpdateEvent(evt *client.GenericEvent) (yes bool)
 	CheckPermission(org, repo, username string) (pass, success bool)
+	ListTeamMembers(org, team string) (logins []string, success bool)
+	CommentsRateLimitedUntil() (until time.Time)
+	GetPullRequestGraph(urlStr, org, repo, number string) (result prGraph, success bool)
 }
 
 type robot struct {
 	cli iClient
 	cnf *configuration
 	log *logrus.Entry
+	// watcher reloads the configuration, bot.config() must be used instead of cnf if it is not nil
+	watcher *configWatcher
+	store   stateStore
+	repos   *repoMetadataCache
+
+	mailmaps  *mailmapCache
+	rechecker *orgRechecker
+	templates *templateStore
+	claCache  *claResultCache
+	budget    *apiErrorBudget
+	plugins   []plugin
+
+	misconfigs *misconfigReporter
+	audit      *auditLog
+	teams      *teamMembersCache
+	comments   *commentQueue
+	labels     *labelBreaker
+	serial     *prSerializer
+	events     *eventDeduper
+	commands   *commandLimiter
+	notifier   *emailNotifier
+	// corporateCLA maps the emails to the companies which signed the corporate CLA by the config items
+	corporateCLA *corporateCLAStores
+	// verifications caches the verification of the signatures of the commits by the sha
+	verifications *claResultCache
+	// account remembers the login of the bot, by which its own comments are told
+	account botAccount
 }
 
-func newRobot(c *configuration, token []byte) *robot {
+func newRobot(c *configuration, token, readToken []byte) (*robot, error) {
 	logger := framework.NewLogger().WithField("component", component)
-	return &robot{cli: client.NewClient(token, logger), cnf: c, log: logger}
+	rc, err := newPlatformClient(c.platform(), token, readToken, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := newAPIErrorBudget(&c.ErrorBudget)
+	cli := wrapErrorBudgetClient(wrapMetricsClient(wrapChaosClient(rc, logger)), budget)
+	bot := &robot{
+		cli:   cli,
+		cnf:   c,
+		log:   logger,
+		store: newMemoryStateStore(),
+		repos: newRepoMetadataCache(repoMetadataCacheTTL, cli.GetRepoMetadata),
+
+		mailmaps:  newMailmapCache(),
+		claCache:  newCLAResultCache(c.CLACacheSize),
+		budget:    budget,
+		rechecker: newOrgRechecker(c.RecheckRatePerMinute),
+		plugins:   registeredPlugins,
+
+		misconfigs: newMisconfigReporter(),
+		audit:      newAuditLog(&c.Audit, logger),
+		teams:      newTeamMembersCache(teamMembersCacheTTL, cli.ListTeamMembers),
+		serial:     newPRSerializer(),
+		events:     newEventDeduper(),
+		commands:   newCommandLimiter(),
+
+		verifications: newCLAResultCache(c.CLACacheSize),
+	}
+	bot.comments = newCommentQueue(bot.flushQueuedComments)
+	bot.labels = newLabelBreaker(&c.LabelBreaker, bot.retryHeldLabels)
+	if c.PrivacyMode {
+		enablePrivacyMode(bot)
+	}
+	return bot, nil
 }
 
 func (bot *robot) GetConfigmap() config.Configmap {
-	return bot.cnf
+	return bot.config()
 }
 
 func (bot *robot) RegisterEventHandler(p framework.HandlerRegister) {
@@ -64,56 +145,185 @@ func (bot *robot) GetLogger() *logrus.Entry {
 	return bot.log
 }
 
+// getRepoConfig retrieves the repoConfig matching the repository and its metadata.
+// Archived repositories are skipped unless the repoConfig includes them
+func (bot *robot) getRepoConfig(org, repo string) *repoConfig {
+	repoCnf, ok := bot.matchRepoConfig(bot.config(), org, repo)
+	if !ok {
+		bot.log.Warningf("skip the repo %s/%s whose metadata can not be looked up", org, repo)
+	}
+	return repoCnf
+}
+
+// matchRepoConfig retrieves the repoConfig of the configuration matching the repository and its metadata.
+// It fails closed and returns false if the metadata can not be looked up, as the archived repositories and
+// those of the other visibility must not be handled by mistake
+func (bot *robot) matchRepoConfig(cnf *configuration, org, repo string) (*repoConfig, bool) {
+	var meta *repoMetadata
+	if bot.repos != nil {
+		if meta = bot.repos.get(org, repo); meta == nil {
+			return nil, false
+		}
+	}
+
+	return cnf.getMatchedRepoConfig(org, repo, meta), true
+}
+
 var (
 	// a compiled regular expression for the comment that uses to check CLA sign state
-	regexpCheckCLAComment = regexp.MustCompile(`^/check-cla$`)
+	// with an optional scope `committers` or `authors` to override the check_by_committer for one run
+	regexpCheckCLAComment = regexp.MustCompile(`^/check-cla(?:[\t ]+(committers|authors))?$`)
+	// a compiled regular expression for the comment of the maintainers that uses to check CLA sign state
+	// of only the commits after the sha, with the same optional scope
+	regexpCheckCLASinceComment = regexp.MustCompile(
+		`^/check-cla(?:[\t ]+(committers|authors))?[\t ]+--since[\t ]+([0-9a-fA-F]{7,40})$`)
+	// a compiled regular expression for the comment that uses to check the CLA of only the contributor
+	// of the login as @username or of the email, and to show the result of each email of the contributor
+	regexpCheckCLAContributorComment = regexp.MustCompile(`^/check-cla[\t ]+(@[^\s@]+|[^\s@]+@[^\s@]+)$`)
 	// a compiled regular expression for the comment that uses to remove CLA label
 	regexpCancelCLAComment = regexp.MustCompile(`^/cla[\t ]+cancel$`)
+	// a compiled regular expression for the comment that uses to check CLA sign state bypassing the caches,
+	// and to show the result of each email
+	regexpRecheckCLAComment = regexp.MustCompile(`^/cla[\t ]+recheck$`)
+	// a compiled regular expression for the comment that uses to show the commands of the bot
+	regexpHelpCLAComment = regexp.MustCompile(`^/cla[\t ]+help$`)
+	// a compiled regular expression for the comment of the maintainers that uses to force the CLA label
+	// with the reason, and optionally with a lifetime in days or hours such as 30d
+	regexpOverrideCLAComment = regexp.MustCompile(`^/cla[\t ]+override(?:[\t ]+([1-9][0-9]{0,3})([dh]))?[\t ]+(\S.*)$`)
 )
 
+// handlePullRequestEvent handles the event after the earlier events of the same pull request
 func (bot *robot) handlePullRequestEvent(evt *client.GenericEvent, cnf config.Configmap, logger *logrus.Entry) {
 	org, repo, number := utils.GetString(evt.Org), utils.GetString(evt.Repo), utils.GetString(evt.Number)
-	repoCnf := bot.cnf.getRepoConfig(org, repo)
-	// If the specified repository not match any repository  in the repoConfig list, it logs the warning and returns
-	if repoCnf == nil {
-		logger.Warningf("no config for the repo: " + org + "/" + repo)
+	ctx, span := tracer.Start(context.Background(), "handlePullRequestEvent", prSpanAttributes(org, repo, number))
+	defer span.End()
+
+	logger = deliveryLogger(evt, logger)
+	if bot.duplicateEvent(evt) {
+		logger.Info("skip the replayed delivery of the event")
 		return
 	}
 
+	bot.serial.do(prKey(org, repo, number), func() { bot.handlePullRequest(ctx, evt, logger) })
+}
+
+// handlePullRequestCommentEvent handles the event after the earlier events of the same pull request
+func (bot *robot) handlePullRequestCommentEvent(evt *client.GenericEvent, cnf config.Configmap, logger *logrus.Entry) {
+	logger = deliveryLogger(evt, logger)
+	if bot.duplicateEvent(evt) {
+		logger.Info("skip the replayed delivery of the event")
+		return
+	}
+
+	key := prKey(utils.GetString(evt.Org), utils.GetString(evt.Repo), utils.GetString(evt.Number))
+	bot.serial.do(key, func() { bot.handlePullRequestComment(evt, logger) })
+}
+
+func (bot *robot) handlePullRequest(ctx context.Context, evt *client.GenericEvent, logger *logrus.Entry) {
+	org, repo, number := utils.GetString(evt.Org), utils.GetString(evt.Repo), utils.GetString(evt.Number)
+	bot.recordPRClosed(org, repo, number, isPRClosedEvent(evt))
 	// Checks if PR is firstly created or PR source code is updated
 	if !(bot.cli.CheckIfPRCreateEvent(evt) || bot.cli.CheckIfPRSourceCodeUpdateEvent(evt)) {
 		return
 	}
 
-	bot.checkIfAllSignedCLA(org, repo, number, repoCnf, logger)
+	pr := newPRSnapshot(bot.cli, org, repo, number).withEvent(evt).withLogger(logger).withContext(ctx)
+	repoCnf := bot.usableRepoConfig(pr, logger)
+	if repoCnf == nil {
+		return
+	}
+	// Drops the event delivered out of order, which would overwrite the result of a newer evaluation
+	if bot.isStaleEvent(pr) {
+		logger.Warningf("drop the stale event of %s updated at %s", pr.key(), utils.GetString(evt.UpdateTime))
+		return
+	}
+
+	bot.checkIfAllSignedCLA(pr, repoCnf, logger)
 }
 
-func (bot *robot) handlePullRequestCommentEvent(evt *client.GenericEvent, cnf config.Configmap, logger *logrus.Entry) {
+func (bot *robot) handlePullRequestComment(evt *client.GenericEvent, logger *logrus.Entry) {
 	org, repo, number := utils.GetString(evt.Org), utils.GetString(evt.Repo), utils.GetString(evt.Number)
-	repoCnf := bot.cnf.getRepoConfig(org, repo)
-	// If the specified repository not match any repository  in the repoConfig list, it logs the warning and returns
+	pr := newPRSnapshot(bot.cli, org, repo, number).withEvent(evt).withLogger(logger)
+	cnf := bot.prConfig(pr)
+	comment := normalizeCommand(utils.GetString(evt.Comment), cnf.RelaxedCommandMatching)
+	if cnf.legacyProfile() {
+		var ok bool
+		if comment, ok = legacyCommand(utils.GetString(evt.Comment)); !ok {
+			return
+		}
+	}
+	// The administration commands are handled in the admin repo, which may have no repoConfig
+	if bot.handleRecheckOrgCommand(org, repo, number, utils.GetString(evt.Commenter), comment, logger) {
+		return
+	}
+
+	repoCnf := bot.usableRepoConfig(pr, logger)
 	if repoCnf == nil {
-		logger.Warningf("no config for the repo: " + org + "/" + repo)
 		return
 	}
 
-	comment := strings.TrimSpace(utils.GetString(evt.Comment))
 	// Checks if the comment is only "/cla cancel" that can be handled
 	if regexpCancelCLAComment.MatchString(comment) {
-		permissionPass, _ := bot.cli.CheckPermission(org, repo, utils.GetString(evt.Commenter))
-		if permissionPass {
-			prLabels, _ := bot.cli.GetPullRequestLabels(org, repo, number)
-			if slices.Contains(prLabels, repoCnf.CLALabelYes) {
-				bot.cli.RemovePRLabels(org, repo, number, []string{url.QueryEscape(repoCnf.CLALabelYes)})
-			}
+		if bot.isCLAAdmin(org, repo, utils.GetString(evt.Commenter), repoCnf) {
+			prLabels, _ := pr.getLabels()
+			bot.removeCLALabel(pr, prLabels, repoCnf, repoCnf.CLALabelYes)
+			bot.reactToCommand(pr, repoCnf)
 		}
 		return
 	}
 
-	// Checks if the comment is only "/check-cla" that can be handled
-	if !regexpCheckCLAComment.MatchString(comment) {
+	// Checks if the comment is only "/cla override <reason>" that can be handled
+	if m := regexpOverrideCLAComment.FindStringSubmatch(comment); m != nil {
+		bot.overrideCLA(pr, repoCnf, utils.GetString(evt.Commenter), m[3], parseOverrideTTL(m[1], m[2]), logger)
+		return
+	}
+
+	// Checks if the comment is only "/cla help" that can be handled
+	if regexpHelpCLAComment.MatchString(comment) {
+		bot.postHelp(pr, repoCnf)
+		return
+	}
+
+	// The commands below check the CLA, which are throttled to protect the CLA server
+	if isCheckCommand(comment) && bot.commandThrottled(pr, repoCnf, utils.GetString(evt.Commenter), logger) {
+		return
+	}
+
+	// Checks if the comment is only "/cla recheck" that can be handled
+	if regexpRecheckCLAComment.MatchString(comment) {
+		pr.recheck = true
+		bot.checkIfAllSignedCLA(pr, repoCnf, logger)
+		bot.postRecheckBreakdown(pr, repoCnf)
+		bot.replyToCommand(pr, repoCnf)
 		return
 	}
 
-	bot.checkIfAllSignedCLA(org, repo, number, repoCnf, logger)
+	// Checks if the comment is only "/check-cla @username" or "/check-cla <email>" that can be handled
+	if m := regexpCheckCLAContributorComment.FindStringSubmatch(comment); m != nil {
+		bot.checkContributor(pr, repoCnf, m[1], logger)
+		return
+	}
+
+	// Checks if the comment is only "/check-cla" that can be handled
+	m := regexpCheckCLAComment.FindStringSubmatch(comment)
+	if m == nil {
+		if m = regexpCheckCLASinceComment.FindStringSubmatch(comment); m == nil {
+			return
+		}
+	}
+
+	if m[1] != "" {
+		if !bot.checkScopeCommand(pr, repoCnf, utils.GetString(evt.Commenter), m[1], logger) {
+			return
+		}
+		repoCnf = repoCnf.withCheckScope(m[1])
+	}
+	if len(m) > 2 && m[2] != "" {
+		if !bot.checkSinceCommand(pr, repoCnf, utils.GetString(evt.Commenter), m[2], logger) {
+			return
+		}
+		repoCnf = repoCnf.withSince(m[2])
+	}
+	bot.checkIfAllSignedCLA(pr, repoCnf, logger)
+	bot.replyToCommand(pr, repoCnf)
 }
@@ -16,70 +16,529 @@ package main
 import (
 	"fmt"
 	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/utils"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	"net/url"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
-func (bot *robot) checkIfAllSignedCLA(org, repo, number string, repoCnf *repoConfig, logger *logrus.Entry) {
+func (bot *robot) checkIfAllSignedCLA(pr *prSnapshot, repoCnf *repoConfig, logger *logrus.Entry) {
+	if pr.watchdog == nil {
+		pr.watchdog = newEvaluationWatchdog(&bot.prConfig(pr).Watchdog)
+	}
+	if pr.log == nil {
+		pr.withLogger(logger)
+	}
+	logger = pr.log
+	pr.stats.start = time.Now()
+	pr.since, pr.scope = repoCnf.since, repoCnf.resultScope()
+	span := pr.withSpan("checkIfAllSignedCLA")
+	defer func() {
+		span.SetAttributes(attribute.String(spanAttrDecision, pr.stats.decision))
+		span.End()
+	}()
+	defer bot.logEvaluationSummary(pr, logger)
+	defer observeEvaluation(pr)
+	defer bot.runAfterDecision(pr)
+	defer bot.auditVerdict(pr)
+	defer bot.reportPendingStatus(pr, repoCnf)
+
+	// the signed commits are required apart from the CLA, which the override does not cover
+	if repoCnf.RequireSignedCommits {
+		bot.checkSignedCommits(pr, repoCnf, logger)
+	}
+	if bot.overridden(pr) {
+		pr.stats.decision = decisionOverridden
+		bot.recordSignedCommits(pr)
+		return
+	}
+	if graphQLURL := bot.prConfig(pr).GraphQLURL; graphQLURL != "" {
+		pr.loadGraph(graphQLURL)
+	}
 
-	commits, success := bot.cli.GetPullRequestCommits(org, repo, number)
+	if repoCnf.policy() == policyDCO {
+		bot.checkDCO(pr, repoCnf, logger)
+		return
+	}
+
+	// the commits are taken from the commit messages, which carry the head sha to record the result by
+	pr.getCommitMessages()
+
+	commits, success := pr.getCommits()
+	// no commit after the since is not caused by the commits lagging behind
+	if success && len(commits) == 0 && pr.since == "" {
+		success = bot.waitForLaggingCommits(pr, func() (int, bool) {
+			commits, success = pr.getCommits()
+			return len(commits), success
+		})
+	}
 	if !success {
-		bot.cli.CreatePRComment(org, repo, number, bot.cnf.CommentCommandTrigger)
+		pr.stats.decision = decisionCommitsUnavailable
+		bot.createPRComment(pr, repoCnf, bot.commandTriggerComment(pr, repoCnf))
+		return
+	}
+
+	if len(commits) == 0 {
+		pr.stats.decision = decisionNoCommits
+		bot.createPRComment(pr, repoCnf, bot.renderer(pr).noCommits())
+		return
+	}
+
+	commits = bot.prepareCommits(pr, commits, repoCnf)
+
+	prLabels, _ := pr.getLabels()
+	if repoCnf.combinesCLAAndDCO() {
+		bot.checkPolicy(pr, commits, prLabels, repoCnf, logger)
 		return
 	}
 
+	// all the commits are exempt
 	if len(commits) == 0 {
-		bot.cli.CreatePRComment(org, repo, number, bot.cnf.CommentPRNoCommits)
+		pr.stats.decision = prStatusSigned
+		bot.passCLASignature(pr, nil, prLabels, repoCnf)
+		bot.recordPRState(pr, true, [3][]string{})
 		return
 	}
 
-	prLabels, _ := bot.cli.GetPullRequestLabels(org, repo, number)
-	allSigned, signResult := bot.checkCLASignResult(org, repo, number, commits, repoCnf)
+	allSigned, signResult, reused := bot.reuseSameHeadResult(pr, repoCnf)
+	if !reused {
+		allSigned, signResult = bot.checkCLASignResult(pr, commits, repoCnf)
+	}
+	if pr.watchdog.tripped() {
+		pr.stats.decision = decisionStopped
+		bot.finalizeTrippedEvaluation(pr, repoCnf, logger)
+		return
+	}
+
+	pr.stats.decision, pr.stats.signResult = evaluationStatus(allSigned, signResult), signResult
 	if allSigned {
-		bot.passCLASignature(org, repo, number, signResult[0], prLabels, repoCnf)
+		bot.passCLASignature(pr, signResult[0], prLabels, repoCnf)
 	} else {
-		bot.waitCLASignature(org, repo, number, signResult[1], prLabels, repoCnf)
+		bot.waitCLASignature(pr, signResult, prLabels, repoCnf)
+	}
+	// the state is recorded after commenting, which relies on the result of the previous check
+	bot.recordPRState(pr, allSigned, signResult)
+	if !reused {
+		bot.syncSameHeadPRs(pr, repoCnf, logger)
 	}
 }
 
-func (bot *robot) checkCLASignResult(org, repo, number string,
+// prepareCommits attributes, exempts and canonicalizes the commits by the config of the repository and the plugins,
+// before the CLA of their contributors is checked
+func (bot *robot) prepareCommits(pr *prSnapshot, commits []client.PRCommit, repoCnf *repoConfig) []client.PRCommit {
+	if repoCnf.AttributeBackportsToCommitter && !repoCnf.CheckByCommitter {
+		commits = bot.attributeBackports(pr, commits)
+	}
+
+	if repoCnf.ExemptionFile != "" {
+		commits = bot.filterExemptCommits(pr, commits, repoCnf)
+	}
+
+	if len(repoCnf.ReviewerTrailers) != 0 {
+		commits = bot.appendReviewers(pr, commits, repoCnf)
+	}
+
+	if repoCnf.MailmapFile != "" {
+		commits = bot.canonicalizeIdentities(pr, commits, repoCnf)
+	}
+
+	if len(repoCnf.ExemptUsers) != 0 || len(repoCnf.ExemptEmails) != 0 {
+		commits = filterExemptContributors(commits, repoCnf)
+	}
+	return bot.runBeforeCheck(pr, commits, repoCnf)
+}
+
+// the retries of fetching the commits of the pull request with changed files but no commits
+const laggingCommitsRetries = 3
+
+// laggingCommitsRetryInterval is the wait before fetching the commits again
+var laggingCommitsRetryInterval = 2 * time.Second
+
+// waitForLaggingCommits tells the pull request with no commit from the commits lagging behind the push, by the
+// files it changes. The commits are fetched again by the fetch until they show up. It returns false if the
+// pull request changes files but has no commit after the retries, or the changed files are unknown,
+// which is a transient failure rather than an empty pull request
+func (bot *robot) waitForLaggingCommits(pr *prSnapshot, fetch func() (int, bool)) bool {
+	for i := 0; ; i++ {
+		pr.watchdog.countAPICall()
+		if count, success := bot.cli.GetPullRequestChangedFileCount(pr.org, pr.repo, pr.number); success && count == 0 {
+			return true
+		}
+		if i == laggingCommitsRetries {
+			return false
+		}
+
+		time.Sleep(laggingCommitsRetryInterval)
+		pr.reloadCommits()
+		if n, success := fetch(); !success || n != 0 {
+			return success
+		}
+	}
+}
+
+func (bot *robot) checkCLASignResult(pr *prSnapshot,
 	commits []client.PRCommit, repoCnf *repoConfig) (allSigned bool, signResult [3][]string) {
+	states, reasons, stopped := bot.classifySignStates(pr, commits, repoCnf)
+	// the partial result is discarded, the caller finalizes the evaluation of the tripped watchdog
+	if stopped {
+		return
+	}
+
+	// all the contributors are evaluated, the unknown ones are listed in the comment of the unsigned ones
+	pr.unknownReasons, signResult = reasons, states
+	if len(states[1]) == 0 && len(states[2]) != 0 {
+		bot.postUnknownStateComments(pr, repoCnf, states[2], reasons)
+	}
+
+	allSigned = len(states[0]) != 0 && len(states[1]) == 0 && len(states[2]) == 0
+	return
+}
+
+// the reasons why the sign states of the contributors are unknown
+const (
+	// unknownReasonCLAServer is the failure of the CLA server, which checking again may help
+	unknownReasonCLAServer       = ""
+	unknownReasonMissingEmail    = "missing_email"
+	unknownReasonLitePRCommitter = "lite_pr_committer"
+	unknownReasonDeniedDomain    = "denied_email_domain"
+)
+
+const (
+	// defaultCommentMissingEmail is used when the comment_missing_email is not configured
+	defaultCommentMissingEmail = "The CLA can not be checked for %s, whose commits have no email. Please set the " +
+		"email used to sign the CLA by `git config user.email`, amend the commits and push them again."
+	// defaultCommentLitePRCommitter is used when the comment_lite_pr_committer is not configured
+	defaultCommentLitePRCommitter = "The CLA can not be checked for %s, whose commits were made on the web page " +
+		"and have the email of the platform. Please commit with the email used to sign the CLA by git, " +
+		"and push the commits again."
+	// defaultCommentSignedNote is used when the comment_signed_note is not configured
+	defaultCommentSignedNote = "  \n\n%s have signed the CLA."
+	// defaultCommentUnknownNote is used when the comment_unknown_note is not configured
+	defaultCommentUnknownNote = "  \n\nThe CLA of %s can not be checked: %s."
+
+	// the instructions in the comment_unknown_note by the reason, which are used when the comment_unknown_reason_*
+	// are not configured
+	defaultCommentUnknownReasonCLAServer    = "the CLA server is unavailable, please comment `/check-cla` later"
+	defaultCommentUnknownReasonMissingEmail = "their commits have no email, please amend them with the email " +
+		"used to sign the CLA and push them again"
+	defaultCommentUnknownReasonLitePRCommitter = "their commits were made on the web page, please commit by git " +
+		"with the email used to sign the CLA and push them again"
+	defaultCommentUnknownReasonDeniedDomain = "their commits use an email which can not identify the contributor, " +
+		"please amend them with the email used to sign the CLA and push them again"
+)
+
+// postUnknownStateComments posts the comments for the contributors whose sign states are unknown, one for each
+// of the reasons, so that the contributors know whether to fix their commits or to check again later
+func (bot *robot) postUnknownStateComments(pr *prSnapshot, repoCnf *repoConfig, unknownUsers []string,
+	reasons map[string]string) {
+	groups := map[string][]string{}
+	for _, user := range unknownUsers {
+		groups[reasons[user]] = append(groups[reasons[user]], user)
+	}
+
+	r := bot.renderer(pr)
+	if users := groups[unknownReasonDeniedDomain]; len(users) != 0 {
+		bot.createPRComment(pr, repoCnf, r.deniedEmailDomain(users))
+	}
+	if users := groups[unknownReasonMissingEmail]; len(users) != 0 {
+		bot.createPRComment(pr, repoCnf, r.missingEmail(users))
+	}
+	if users := groups[unknownReasonLitePRCommitter]; len(users) != 0 {
+		bot.createPRComment(pr, repoCnf, r.litePRCommitter(users))
+	}
+	// checking again helps only the users whose state is unknown because of the failure of the CLA server
+	if len(groups[unknownReasonCLAServer]) != 0 {
+		bot.createPRComment(pr, repoCnf, bot.commandTriggerComment(pr, repoCnf))
+	}
+}
+
+// classifySignStates checks the CLA of every contributor, and groups them into the signed, unsigned and unknown
+// ones. The reasons of the unknown ones are returned too, keyed by the user. It stops if the watchdog trips
+func (bot *robot) classifySignStates(pr *prSnapshot, commits []client.PRCommit,
+	repoCnf *repoConfig) (states [3][]string, reasons map[string]string, stopped bool) {
 	users, emails := bot.ListContributorNameAndEmail(commits, repoCnf)
 	var signedUsers, unsignedUsers, unknownUsers []string
+	reasons = map[string]string{}
+	unknown := func(user, reason string) {
+		unknownUsers = append(unknownUsers, user)
+		if _, ok := reasons[user]; !ok {
+			reasons[user] = reason
+		}
+	}
+	var signatureIDs map[string][]string
+	var batchStates map[string]string
+	if repoCnf.BatchCheckURL != "" && !pr.recheck {
+		batchStates = bot.checkCLASignatures(pr, emails, repoCnf)
+	}
+	bot.prefetchEmailSignStates(pr, emails, repoCnf, batchStates)
 	for i, email := range emails {
-		if repoCnf.LitePRCommitter.Email == email || email == "" {
-			unknownUsers = append(unknownUsers, users[i])
+		// the contributor without a usable email, such as the one of a squashed commit, is checked by the login.
+		// The commit not linked to an account has no login to check
+		if repoCnf.LoginCheckURL != "" && users[i] != "" && (email == "" || repoCnf.emailDomainDenied(email)) &&
+			!pr.watchdog.tripped() {
+			if signState, claType := bot.checkLoginSignState(pr, users[i], repoCnf); signState != client.CLASignStateUnknown {
+				pr.recordSignDetail(users[i], email, signState, claType)
+				if signState == client.CLASignStateYes {
+					signedUsers = append(signedUsers, users[i])
+				} else {
+					unsignedUsers = append(unsignedUsers, users[i])
+				}
+				continue
+			}
+		}
+
+		if email == "" {
+			unknown(users[i], unknownReasonMissingEmail)
+			pr.recordSignDetail(users[i], email, client.CLASignStateUnknown, "")
+			continue
+		}
+
+		if repoCnf.LitePRCommitter.Email == email {
+			unknown(users[i], unknownReasonLitePRCommitter)
+			pr.recordSignDetail(users[i], email, client.CLASignStateUnknown, "")
 			continue
 		}
 
-		urlStr := fmt.Sprintf("%s?email=%s", repoCnf.CheckURL, email)
-		signState, _ := bot.cli.CheckCLASignature(urlStr)
+		if repoCnf.emailDomainDenied(email) {
+			unknown(users[i], unknownReasonDeniedDomain)
+			pr.recordSignDetail(users[i], email, client.CLASignStateUnknown, "")
+			continue
+		}
+
+		if repoCnf.emailDomainAllowed(email) {
+			signedUsers = append(signedUsers, users[i])
+			pr.recordSignDetail(users[i], email, client.CLASignStateYes, "")
+			continue
+		}
+
+		// the employees of the companies signing the corporate CLA need not the CLA server
+		if company, ok := bot.corporateCLA.lookup(repoCnf.CorporateCLAFile, email); ok {
+			signedUsers = append(signedUsers, users[i])
+			pr.addCorporateUser(users[i], company)
+			pr.recordSignDetail(users[i], email, client.CLASignStateYes, claTypeCorporate)
+			continue
+		}
+
+		if pr.watchdog.tripped() {
+			return states, reasons, true
+		}
+
+		signState, claType := bot.lookupEmailSignState(pr, email, repoCnf, batchStates)
+		if signState != client.CLASignStateYes && repoCnf.SignatureTrailer != "" {
+			if signatureIDs == nil {
+				signatureIDs = bot.listSignatureIDs(pr, repoCnf)
+			}
+			if bot.verifySignatureIDs(pr, email, signatureIDs[email], repoCnf) {
+				signState = client.CLASignStateYes
+			}
+		}
+		pr.recordSignDetail(users[i], email, signState, claType)
+
 		switch signState {
 		case client.CLASignStateYes:
 			signedUsers = append(signedUsers, users[i])
 		case client.CLASignStateNo:
 			unsignedUsers = append(unsignedUsers, users[i])
+			pr.addUnsignedUser(users[i], email)
 		default:
-			unknownUsers = append(unknownUsers, users[i])
+			unknown(users[i], unknownReasonCLAServer)
+		}
+	}
+
+	return [3][]string{signedUsers, unsignedUsers, unknownUsers}, reasons, false
+}
+
+// attributeBackports attributes the cherry-picked and reverted commits to their committers,
+// who are the actors performing the backports, instead of the original authors
+func (bot *robot) attributeBackports(pr *prSnapshot, commits []client.PRCommit) []client.PRCommit {
+	messages, success := pr.getCommitMessages()
+	if !success || len(messages) != len(commits) {
+		return commits
+	}
+
+	result := make([]client.PRCommit, len(messages))
+	for i := range messages {
+		result[i] = messages[i].PRCommit
+		if isBackportCommit(messages[i].Message) {
+			result[i].AuthorName = result[i].CommitterName
+			result[i].AuthorEmail = result[i].CommitterEmail
+		}
+	}
+
+	return result
+}
+
+// appendReviewers appends the reviewers recorded in the commit trailers to the contributors.
+// A reviewer is both the author and the committer of the appended commit, so that it is checked in either scope
+func (bot *robot) appendReviewers(pr *prSnapshot, commits []client.PRCommit, repoCnf *repoConfig) []client.PRCommit {
+	messages, success := pr.getCommitMessages()
+	if !success {
+		return commits
+	}
+
+	for i := range messages {
+		for _, key := range repoCnf.ReviewerTrailers {
+			for _, value := range commitTrailerValues(messages[i].Message, key) {
+				name, email, ok := parseTrailerIdentity(value)
+				if !ok {
+					continue
+				}
+				commits = append(commits, client.PRCommit{
+					AuthorName: name, AuthorEmail: email, CommitterName: name, CommitterEmail: email,
+				})
+			}
+		}
+	}
+
+	return commits
+}
+
+// isStaleEvent checks whether the event is older than the one of the latest evaluation of the pull request,
+// which happens when the webhooks are retried or delayed and delivered out of order
+func (bot *robot) isStaleEvent(pr *prSnapshot) bool {
+	if bot.store == nil || pr.eventTime.IsZero() {
+		return false
+	}
+
+	s, ok := bot.store.get(pr.org, pr.repo, pr.number)
+	return ok && pr.eventTime.Before(s.LastEventTime)
+}
+
+// listSignatureIDs collects the signature ids declared in the commit trailers, grouped by the contributor email
+func (bot *robot) listSignatureIDs(pr *prSnapshot, repoCnf *repoConfig) map[string][]string {
+	ids := map[string][]string{}
+	commits, success := pr.getCommitMessages()
+	if !success {
+		return ids
+	}
+
+	for i := range commits {
+		email := commits[i].AuthorEmail
+		if repoCnf.CheckByCommitter {
+			email = commits[i].CommitterEmail
+		}
+		for _, id := range commitTrailerValues(commits[i].Message, repoCnf.SignatureTrailer) {
+			if !slices.Contains(ids[email], id) {
+				ids[email] = append(ids[email], id)
+			}
+		}
+	}
+
+	return ids
+}
+
+// verifySignatureIDs returns true if the CLA server confirms that any of the signature ids belongs to the email.
+// The ids are public in the commit trailers, so an id of another contributor is never accepted
+func (bot *robot) verifySignatureIDs(pr *prSnapshot, email string, ids []string, repoCnf *repoConfig) bool {
+	for _, id := range ids {
+		urlStr, err := signatureVerifyURL(repoCnf.SignatureVerifyURL, pr.org, pr.repo, email, id)
+		if err != nil {
+			pr.logger().WithError(err).Error("invalid signature_verify_url")
+			return false
 		}
+
+		pr.watchdog.countCLALookup()
+		if signState, _ := bot.cli.VerifyCLASignatureID(urlStr, email); signState == client.CLASignStateYes {
+			return true
+		}
+	}
+
+	return false
+}
+
+// signatureVerifyURL adds the signature id, together with the email and the repository it is declared for,
+// to the query of the signature_verify_url
+func signatureVerifyURL(verifyURL, org, repo, email, id string) (string, error) {
+	u, err := url.Parse(verifyURL)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("signature_id", id)
+	q.Set("email", email)
+	q.Set("org", org)
+	q.Set("repo", repo)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// evaluationStatus returns the prStatus of the result of the CLA evaluation
+func evaluationStatus(allSigned bool, signResult [3][]string) string {
+	if allSigned {
+		return prStatusSigned
 	}
+	if len(signResult[1]) != 0 {
+		return prStatusUnsigned
+	}
+	return prStatusUnknown
+}
 
-	if len(unknownUsers) != 0 {
-		bot.cli.CreatePRComment(org, repo, number, bot.cnf.CommentCommandTrigger)
-		signResult[2] = unknownUsers
+// recordPRState saves the result of the CLA evaluation into the state store
+func (bot *robot) recordPRState(pr *prSnapshot, allSigned bool, signResult [3][]string) {
+	if bot.store == nil {
 		return
 	}
 
-	if len(unsignedUsers) != 0 {
-		signResult[1] = unsignedUsers
+	s := prState{
+		Org:            pr.org,
+		Repo:           pr.repo,
+		Number:         pr.number,
+		Status:         evaluationStatus(allSigned, signResult),
+		SignedUsers:    signResult[0],
+		UnsignedUsers:  signResult[1],
+		UnknownUsers:   signResult[2],
+		LastEvaluation: time.Now().UTC(),
+		LastEventTime:  pr.eventTime,
+		Head:           pr.head,
+		HeadSHA:        pr.fetchedHeadSHA(),
+		Base:           pr.base,
+		Scope:          pr.scope,
+		Since:          pr.since,
+		SignedCommits:  pr.signedCommits,
+	}
+	if len(signResult[1]) != 0 {
+		s.UnsignedEmails, s.UnsignedUserEmails = pr.unsignedEmails, pr.unsignedUserEmails
+	}
+	old, ok := bot.store.get(pr.org, pr.repo, pr.number)
+	if ok {
+		s.CommentCount, s.EmailsSent, s.Closed = old.CommentCount, old.EmailsSent, old.Closed
+		s.CommentIDs = old.CommentIDs
+		// a recheck without event keeps the event of the latest evaluation
+		if !old.LastEventTime.Before(s.LastEventTime) {
+			s.LastEventTime, s.Head, s.Base = old.LastEventTime, old.Head, old.Base
+		}
+	}
+	s.appendHistory(old.History)
+	bot.store.put(s)
+	for _, users := range signResult {
+		bot.store.seeContributors(pr.org, users, s.LastEvaluation)
+	}
+}
+
+// isPRClosedEvent checks whether the pull request of the event is closed or merged
+func isPRClosedEvent(evt *client.GenericEvent) bool {
+	state := utils.GetString(evt.State)
+	return state == prEventStateClosed || state == prEventStateMerged
+}
+
+// recordPRClosed records whether the pull request evaluated before is closed, so that a closed pull request is
+// no longer rechecked or reported as blocked, and a reopened one is again
+func (bot *robot) recordPRClosed(org, repo, number string, closed bool) {
+	if bot.store == nil {
 		return
 	}
 
-	signResult[0] = signedUsers
-	allSigned = len(emails) != 0 && len(signedUsers) == len(emails)
-	return
+	s, ok := bot.store.get(org, repo, number)
+	if !ok || s.Closed == closed {
+		return
+	}
+	s.Closed = closed
+	bot.store.put(s)
 }
 
 func (bot *robot) ListContributorNameAndEmail(commits []client.PRCommit, repoCnf *repoConfig) ([]string, []string) {
@@ -105,63 +564,304 @@ func (bot *robot) ListContributorNameAndEmail(commits []client.PRCommit, repoCnf
 	return authors[:authorSize], authorEmails[:authorSize]
 }
 
-func (bot *robot) passCLASignature(org, repo, number string, signedUsers, prLabels []string, repoCnf *repoConfig) {
-
-	if slices.Contains(prLabels, repoCnf.CLALabelNo) {
-		if !bot.cli.RemovePRLabels(org, repo, number, []string{url.QueryEscape(repoCnf.CLALabelNo)}) {
-			bot.cli.CreatePRComment(org, repo, number, bot.cnf.CommentUpdateLabelFailed)
+func (bot *robot) passCLASignature(pr *prSnapshot, signedUsers, prLabels []string, repoCnf *repoConfig) {
+	r := bot.renderer(pr)
+	comment, post := r.updateLabelFailed(), bot.labelUpdateFailed
+	if bot.applyCLALabel(pr, prLabels, repoCnf, repoCnf.CLALabelYes, repoCnf.CLALabelNo) {
+		comment = r.allSigned(signedUsers, bot.markFirstSigned(pr, signedUsers), repoCnf)
+		comment, post = withResultMarker(resultKindAllSigned, comment), bot.replaceCLAResultComment
+		if repoCnf.PreserveGuideComment {
+			post = bot.preserveCLASignGuideComments
 		}
 	}
+	post(pr, repoCnf, comment)
+	bot.reportCommitStatus(pr, repoCnf, commitStatusSuccess, commitStatusDescriptionSigned)
+}
+
+// waitCLASignature labels the pull request having unsigned contributors, and comments the result of all the
+// contributors, including the signed ones and the unknown ones with the instructions by the reasons
+func (bot *robot) waitCLASignature(pr *prSnapshot, signResult [3][]string, prLabels []string, repoCnf *repoConfig) {
+	unsignedUsers := signResult[1]
+	if len(unsignedUsers) == 0 {
+		return
+	}
 
-	comment := bot.cnf.CommentUpdateLabelFailed
-	if bot.cli.AddPRLabels(org, repo, number, []string{repoCnf.CLALabelYes}) {
-		signedUserMark := make([]string, len(signedUsers))
-		for i, user := range signedUsers {
-			signedUserMark[i] = strings.ReplaceAll(bot.cnf.UserMarkFormat, bot.cnf.PlaceholderCommitter, user)
+	r := bot.renderer(pr)
+	comment, post := r.updateLabelFailed(), bot.labelUpdateFailed
+	if bot.applyCLALabel(pr, prLabels, repoCnf, repoCnf.CLALabelNo, repoCnf.CLALabelYes) {
+		comment = r.someNeedSign(bot.commentSomeNeedSign(pr), unsignedUsers, repoCnf) +
+			r.claReconfirm(bot.reconfirmingUsers(pr, unsignedUsers, repoCnf), repoCnf)
+		// the earlier releases list only the unsigned users
+		if !bot.prConfig(pr).legacyProfile() {
+			comment += r.signedNote(signResult[0]) + r.unknownNotes(signResult[2], pr.unknownReasons) +
+				r.accountEmailMismatch(bot.authorEmailMismatch(pr, repoCnf))
 		}
-		comment = strings.ReplaceAll(bot.cnf.CommentAllSigned, bot.cnf.PlaceholderCommitter,
-			strings.Join(signedUserMark, ", "))
-		bot.removeCLASignGuideComment(org, repo, number)
+		comment, post = withResultMarker(resultKindNeedSign, comment), bot.replaceCLAResultComment
 	}
-	bot.cli.CreatePRComment(org, repo, number, comment)
+	post(pr, repoCnf, comment)
+	bot.reportCommitStatus(pr, repoCnf, commitStatusFailure, commitStatusDescriptionUnsigned)
+	bot.notifyUnsignedContributors(pr, repoCnf)
+}
 
+// defaultCommentMaxCommentsReached is used when the comment_max_comments_reached is not configured
+const defaultCommentMaxCommentsReached = "The CLA bot has posted too many comments on this pull request, " +
+	"and it will only update the labels from now on. Please see the earlier comments for the CLA status."
+
+// createPRComment posts the comment unless the bot has posted max_comments comments on the pull request.
+// When the cap is reached, a final note is posted instead and the bot only updates the labels afterward
+func (bot *robot) createPRComment(pr *prSnapshot, repoCnf *repoConfig, comment string) {
+	if repoCnf.MaxComments == 0 || bot.store == nil {
+		bot.postPRComment(pr, comment)
+		return
+	}
+
+	count := bot.commentCount(pr)
+	if count > repoCnf.MaxComments {
+		return
+	}
+	if count == repoCnf.MaxComments {
+		comment = bot.renderer(pr).maxCommentsReached()
+	}
+
+	if bot.postPRComment(pr, comment) {
+		s, _ := bot.store.get(pr.org, pr.repo, pr.number)
+		s.Org, s.Repo, s.Number = pr.org, pr.repo, pr.number
+		s.CommentCount++
+		bot.store.put(s)
+	}
 }
 
-func (bot *robot) waitCLASignature(org, repo, number string, unsignedUsers, prLabels []string, repoCnf *repoConfig) {
-	if len(unsignedUsers) == 0 {
+// commentsExhausted checks whether the bot can not post the comment of the result any more,
+// in which case the earlier comments are kept since they are the only status for the contributors
+func (bot *robot) commentsExhausted(pr *prSnapshot, repoCnf *repoConfig) bool {
+	return repoCnf.MaxComments != 0 && bot.commentCount(pr) >= repoCnf.MaxComments
+}
+
+// commentCount returns how many comments the bot has posted on the pull request
+func (bot *robot) commentCount(pr *prSnapshot) int {
+	if bot.store == nil {
+		return 0
+	}
+
+	s, _ := bot.store.get(pr.org, pr.repo, pr.number)
+	return s.CommentCount
+}
+
+// commentSomeNeedSign chooses the full guidance for the first failed check of the pull request,
+// and the brief one for the subsequent failures
+func (bot *robot) commentSomeNeedSign(pr *prSnapshot) string {
+	cnf := bot.prConfig(pr)
+	if cnf.CommentSomeNeedSignAgain == "" || bot.store == nil {
+		return bot.template(cnf.CommentSomeNeedSign)
+	}
+
+	if s, ok := bot.store.get(pr.org, pr.repo, pr.number); ok && s.Status == prStatusUnsigned {
+		return bot.template(cnf.CommentSomeNeedSignAgain)
+	}
+	return bot.template(cnf.CommentSomeNeedSign)
+}
+
+const (
+	placeholderMailingList = "{{mailing_list}}"
+	placeholderMaintainer  = "{{maintainer}}"
+)
+
+// commandTriggerComment renders the comment asking to trigger the check again, preferring the one of the repoConfig
+func (bot *robot) commandTriggerComment(pr *prSnapshot, repoCnf *repoConfig) string {
+	return bot.renderer(pr).commandTrigger(repoCnf)
+}
+
+// defaultCommentCheckScope is used when the comment_check_scope is not configured
+const defaultCommentCheckScope = "  \n\nThis check was run against the emails of the **%s** of the commits."
+
+// defaultCommentCheckSince is appended to the result comment of the `/check-cla --since`
+const defaultCommentCheckSince = "  \n\nThis check was run against the commits after %s only."
+
+// defaultCommentSinceNotFound is posted when the commit of the `/check-cla --since` is not in the pull request
+const defaultCommentSinceNotFound = "### CLA Signature Manual  \n\nThe commit %s of `/check-cla --since` is not found " +
+	"in the pull request, please check it. "
+
+// checkSinceCommand checks whether the commenter can scope the check by the `/check-cla --since`, which is
+// allowed to the maintainers only, and whether the commit of the sha is in the pull request
+func (bot *robot) checkSinceCommand(pr *prSnapshot, repoCnf *repoConfig, commenter, sha string, logger *logrus.Entry) bool {
+	if !bot.isCLAAdmin(pr.org, pr.repo, commenter, repoCnf) {
+		logger.Warningf("ignore the /check-cla --since of %s who is not a maintainer", commenter)
+		return false
+	}
+
+	if _, success := pr.getCommitMessages(); !success {
+		bot.createPRComment(pr, repoCnf, bot.commandTriggerComment(pr, repoCnf))
+		return false
+	}
+	if pr.commitIndex(sha) < 0 {
+		bot.createPRComment(pr, repoCnf, fmt.Sprintf(defaultCommentSinceNotFound, sha))
+		return false
+	}
+	return true
+}
+
+// checkScopeCommand checks whether the commenter can override the check_by_committer by the `/check-cla authors`
+// or `/check-cla committers`. Only the maintainers can, since the run of the scope updates the CLA label and the
+// commit status, which would bypass the policy of the repository otherwise
+func (bot *robot) checkScopeCommand(pr *prSnapshot, repoCnf *repoConfig, commenter, scope string,
+	logger *logrus.Entry) bool {
+	if !bot.isCLAAdmin(pr.org, pr.repo, commenter, repoCnf) {
+		logger.Warningf("ignore the /check-cla %s of %s who is not a maintainer", scope, commenter)
+		return false
+	}
+	return true
+}
+
+// replaceCLAResultComment edits the latest comment of the CLA result in place, so that the history of the comment
+// is kept and the subscribers are not notified of a new comment on every push. The older ones are deleted.
+// A new comment is posted if there is none to edit, or the edit fails. The latest one is left untouched if it is
+// the same kind of result with the identical body, such as the one of a `/check-cla` which changes nothing
+func (bot *robot) replaceCLAResultComment(pr *prSnapshot, repoCnf *repoConfig, comment string) {
+	if !bot.botLoginKnown(pr) {
 		return
 	}
+	bot.recordResultComments(pr, bot.replaceResultComments(pr, repoCnf, bot.claResultCommentIDs(pr), comment))
+}
+
+// replaceResultComments replaces the comments of the ids, from the oldest, with the comment. They are all
+// deleted before posting the comment under the legacy compatibility_profile, as the earlier releases do.
+// It returns the ids of the result comments left on the pull request, as far as they are known
+func (bot *robot) replaceResultComments(pr *prSnapshot, repoCnf *repoConfig, ids []string, comment string) []string {
+	if bot.prConfig(pr).legacyProfile() {
+		bot.deleteCLAResultComments(pr, ids)
+		return bot.createResultComment(pr, repoCnf, nil, comment)
+	}
 
-	if slices.Contains(prLabels, repoCnf.CLALabelYes) {
-		if !bot.cli.RemovePRLabels(org, repo, number, []string{url.QueryEscape(repoCnf.CLALabelYes)}) {
-			bot.cli.CreatePRComment(org, repo, number, bot.cnf.CommentUpdateLabelFailed)
-		}
+	if n := len(ids); n != 0 && bot.latestCommentIs(pr, ids[n-1], comment) {
+		bot.deleteCLAResultComments(pr, ids[:n-1])
+		return ids[n-1:]
+	}
+	if n := len(ids); n != 0 && bot.updatePRComment(pr, ids[n-1], comment) {
+		bot.deleteCLAResultComments(pr, ids[:n-1])
+		return ids[n-1:]
 	}
 
-	comment := bot.cnf.CommentUpdateLabelFailed
-	if bot.cli.AddPRLabels(org, repo, number, []string{repoCnf.CLALabelNo}) {
-		unsignedUserMark := make([]string, len(unsignedUsers))
-		for i, user := range unsignedUsers {
-			unsignedUserMark[i] = strings.ReplaceAll(bot.cnf.UserMarkFormat, bot.cnf.PlaceholderCommitter, user)
-		}
-		comment = fmt.Sprintf(bot.cnf.CommentSomeNeedSign, strings.Join(unsignedUserMark, ", "),
-			repoCnf.SignURL, repoCnf.FAQURL)
-		bot.removeCLASignGuideComment(org, repo, number)
+	// the earlier comments are kept once the comments are exhausted
+	kept := ids
+	if !bot.commentsExhausted(pr, repoCnf) {
+		bot.deleteCLAResultComments(pr, ids)
+		kept = nil
 	}
-	bot.cli.CreatePRComment(org, repo, number, comment)
+	return bot.createResultComment(pr, repoCnf, kept, comment)
+}
 
+// createResultComment posts the result comment, and returns the ids of the kept comments with the posted one
+func (bot *robot) createResultComment(pr *prSnapshot, repoCnf *repoConfig, kept []string, comment string) []string {
+	pr.postedCommentID = ""
+	bot.createPRComment(pr, repoCnf, comment)
+	if pr.postedCommentID == "" {
+		return kept
+	}
+	return append(slices.Clip(kept), pr.postedCommentID)
 }
 
-func (bot *robot) removeCLASignGuideComment(org, repo, number string) {
-	comments, success := bot.cli.ListPullRequestComments(org, repo, number)
-	if !success {
+// recordResultComments saves the ids of the comments of the CLA result on the pull request into the state,
+// so that they are exported with the state
+func (bot *robot) recordResultComments(pr *prSnapshot, ids []string) {
+	if bot.store == nil {
 		return
 	}
 
+	s, ok := bot.store.get(pr.org, pr.repo, pr.number)
+	if !ok && len(ids) == 0 {
+		return
+	}
+	s.Org, s.Repo, s.Number = pr.org, pr.repo, pr.number
+	s.CommentIDs = ids
+	bot.store.put(s)
+}
+
+// claResultCommentIDs lists the comments of the CLA result on the pull request, from the oldest
+func (bot *robot) claResultCommentIDs(pr *prSnapshot) []string {
+	comments, success := pr.getComments()
+	if !success {
+		return nil
+	}
+	return bot.resultCommentIDs(pr, comments)
+}
+
+// botAccount remembers the login of the account of the bot once it is got
+type botAccount struct {
+	mu    sync.Mutex
+	login string
+}
+
+// botLogin returns the login of the account of the bot, which is empty if it can not be got
+func (bot *robot) botLogin() string {
+	bot.account.mu.Lock()
+	defer bot.account.mu.Unlock()
+
+	if bot.account.login == "" {
+		bot.account.login, _ = bot.cli.GetBotLogin()
+	}
+	return bot.account.login
+}
+
+// botLoginKnown checks whether the login of the account of the bot is got. The comments of the bot can not be
+// found without it, so the comments replacing them are not posted, otherwise one is duplicated on every event
+func (bot *robot) botLoginKnown(pr *prSnapshot) bool {
+	if bot.botLogin() != "" {
+		return true
+	}
+
+	pr.logger().Warning("skip replacing the comments, the login of the bot is unknown")
+	return false
+}
+
+// resultCommentIDs picks the comments of the CLA result from the comments of the bot. The comments of the others
+// are never matched, so that a copy of the result posted by a user can neither be edited nor suppress the result
+func (bot *robot) resultCommentIDs(pr *prSnapshot, comments []prComment) []string {
+	cnf := bot.prConfig(pr)
+	login := bot.botLogin()
+	var ids []string
 	for i := range comments {
-		if strings.Contains(comments[i].Body, bot.cnf.PlaceholderCLASignGuideTitle) ||
-			strings.Contains(comments[i].Body, bot.cnf.PlaceholderCLASignPassTitle) {
-			bot.cli.DeletePRComment(org, repo, comments[i].ID)
+		if login == "" || comments[i].Author != login {
+			continue
+		}
+		// the sign guides kept after the check passes are not replaced any more
+		if resultCommentKind(comments[i].Body) == resultKindResolvedGuide {
+			continue
+		}
+		if strings.Contains(comments[i].Body, cnf.PlaceholderCLASignGuideTitle) ||
+			strings.Contains(comments[i].Body, cnf.PlaceholderCLASignPassTitle) ||
+			resultCommentKind(comments[i].Body) != "" ||
+			slices.ContainsFunc(resultTitles, func(t string) bool { return strings.HasPrefix(comments[i].Body, t) }) {
+			ids = append(ids, comments[i].ID)
 		}
 	}
+	return ids
+}
+
+// latestCommentIs checks whether the comment of the id is the same result as the comment to post
+func (bot *robot) latestCommentIs(pr *prSnapshot, id, comment string) bool {
+	comments, _ := pr.getComments()
+	for i := range comments {
+		if comments[i].ID == id {
+			return sameResultComment(comments[i].Body, bot.maskCommentEmails(pr, comment))
+		}
+	}
+	return false
+}
+
+func (bot *robot) deleteCLAResultComments(pr *prSnapshot, ids []string) {
+	// Deleting the outdated comments is not essential, it is paused while the platform fails too often
+	if bot.budget.degraded(platformCodeHosting) {
+		return
+	}
+
+	for _, id := range ids {
+		bot.deletePRComment(pr, id)
+	}
+}
+
+func (bot *robot) removeCLASignGuideComment(pr *prSnapshot) {
+	if !bot.budget.degraded(platformCodeHosting) {
+		bot.deleteCLAResultComments(pr, bot.claResultCommentIDs(pr))
+		bot.recordResultComments(pr, nil)
+	}
 }
@@ -14,9 +14,15 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -34,24 +40,90 @@ type mockClient struct {
 	successfulGetPullRequestLabels           bool
 	successfulListPullRequestComments        bool
 	successfulCheckPermission                bool
+	successfulGetPullRequestCommitMessages   bool
+	successfulVerifyCLASignatureID           bool
+	successfulGetRepoMetadata                bool
+	successfulGetRepoFileContent             bool
+	successfulGetRepoPushPermission          bool
+	pushPermission                           bool
+	successfulCheckCLASignatures             bool
+	successfulCreateCommitStatus             bool
+	successfulUpdatePRComment                bool
+	successfulGetChangedFileCount            bool
+	changedFileCount                         int
+	successfulListOpenPullRequests           bool
+	labeledPRs                               map[string][]string
+	updatedComments                          map[string]string
+	deletedComments                          []string
+	claType                                  string
 	permission                               bool
 	method                                   string
 	commits                                  []client.PRCommit
 	prComments                               []client.PRComment
+	userComments                             []client.PRComment
 	labels                                   []string
 	CLAState                                 string
+	commitMessages                           []prCommitMessage
+	signatureIDState                         string
+	verifyURL                                string
+	repoMeta                                 repoMetadata
+	fileContent                              []byte
+	signStates                               map[string]string
+	comment                                  string
+	createdCommentID                         string
+	botLoginUnknown                          bool
+	status                                   commitStatus
+	successfulCreateIssue                    bool
+	issues                                   []string
+	successfulCreateRepoLabel                bool
+	repoLabels                               []repoLabel
+	successfulGetCommitVerificationStatus    bool
+	unverifiedCommits                        []string
+	successfulGetUserEmail                   bool
+	userEmails                               map[string]string
+	successfulAddCommentReaction             bool
+	reactions                                []string
+	successfulListTeamMembers                bool
+	successfulListOrgRepos                   bool
+	orgRepos                                 map[string][]string
+	teamMembers                              map[string][]string
+	commentsLimitedUntil                     time.Time
+	graph                                    prGraph
+	successfulGetPullRequestGraph            bool
 }
 
-func (m *mockClient) CreatePRComment(org, repo, number, comment string) bool {
+func (m *mockClient) GetPullRequestGraph(urlStr, org, repo, number string) (prGraph, bool) {
+	m.method = "GetPullRequestGraph"
+	return m.graph, m.successfulGetPullRequestGraph
+}
+
+func (m *mockClient) CommentsRateLimitedUntil() time.Time {
+	return m.commentsLimitedUntil
+}
+
+func (m *mockClient) CreatePRComment(org, repo, number, comment string) (string, bool) {
 	m.method = "CreatePRComment"
-	return m.successfulCreatePRComment
+	m.comment = comment
+	return m.createdCommentID, m.successfulCreatePRComment
 }
 
 func (m *mockClient) DeletePRComment(org, repo, commentID string) bool {
 	m.method = "DeletePRComment"
+	m.deletedComments = append(m.deletedComments, commentID)
 	return m.successfulDeletePRComment
 }
 
+func (m *mockClient) UpdatePRComment(org, repo, commentID, comment string) bool {
+	m.method = "UpdatePRComment"
+	if m.successfulUpdatePRComment {
+		if m.updatedComments == nil {
+			m.updatedComments = map[string]string{}
+		}
+		m.updatedComments[commentID] = comment
+	}
+	return m.successfulUpdatePRComment
+}
+
 func (m *mockClient) CheckCLASignature(urlStr string) (string, bool) {
 	m.method = "CheckCLASignature"
 	return m.CLAState, m.successfulCheckCLASignature
@@ -87,9 +159,26 @@ func (m *mockClient) GetPullRequestLabels(org, repo, number string) ([]string, b
 	return m.labels, m.successfulGetPullRequestLabels
 }
 
-func (m *mockClient) ListPullRequestComments(org, repo, number string) ([]client.PRComment, bool) {
+// mockBotLogin is the account of the bot, which posts the prComments of the mockClient
+const mockBotLogin = "cla-bot"
+
+func (m *mockClient) ListPullRequestComments(org, repo, number string) ([]prComment, bool) {
 	m.method = "ListPullRequestComments"
-	return m.prComments, m.successfulListPullRequestComments
+	comments := make([]prComment, 0, len(m.prComments)+len(m.userComments))
+	for i := range m.prComments {
+		comments = append(comments, prComment{PRComment: m.prComments[i], Author: mockBotLogin})
+	}
+	for i := range m.userComments {
+		comments = append(comments, prComment{PRComment: m.userComments[i], Author: "user1"})
+	}
+	return comments, m.successfulListPullRequestComments
+}
+
+func (m *mockClient) GetBotLogin() (string, bool) {
+	if m.botLoginUnknown {
+		return "", false
+	}
+	return mockBotLogin, true
 }
 
 func (m *mockClient) CheckPermission(org, repo, username string) (bool, bool) {
@@ -97,6 +186,96 @@ func (m *mockClient) CheckPermission(org, repo, username string) (bool, bool) {
 	return m.permission, m.successfulCheckPermission
 }
 
+func (m *mockClient) GetPullRequestChangedFileCount(org, repo, number string) (int, bool) {
+	m.method = "GetPullRequestChangedFileCount"
+	return m.changedFileCount, m.successfulGetChangedFileCount
+}
+
+func (m *mockClient) ListTeamMembers(org, team string) ([]string, bool) {
+	m.method = "ListTeamMembers"
+	return m.teamMembers[org+"/"+team], m.successfulListTeamMembers
+}
+
+func (m *mockClient) ListOrgRepos(org string) ([]string, bool) {
+	m.method = "ListOrgRepos"
+	return m.orgRepos[org], m.successfulListOrgRepos
+}
+
+func (m *mockClient) ListOpenPullRequestsWithLabel(org, repo, label string) ([]string, bool) {
+	m.method = "ListOpenPullRequestsWithLabel"
+	return m.labeledPRs[org+"/"+repo], m.successfulListOpenPullRequests
+}
+
+func (m *mockClient) GetPullRequestCommitMessages(org, repo, number string) ([]prCommitMessage, bool) {
+	m.method = "GetPullRequestCommitMessages"
+	return m.commitMessages, m.successfulGetPullRequestCommitMessages
+}
+
+func (m *mockClient) VerifyCLASignatureID(urlStr, email string) (string, bool) {
+	m.method = "VerifyCLASignatureID"
+	m.verifyURL = urlStr
+	return m.signatureIDState, m.successfulVerifyCLASignatureID
+}
+
+func (m *mockClient) GetRepoMetadata(org, repo string) (repoMetadata, bool) {
+	m.method = "GetRepoMetadata"
+	return m.repoMeta, m.successfulGetRepoMetadata
+}
+
+func (m *mockClient) GetRepoPushPermission(org, repo string) (bool, bool) {
+	m.method = "GetRepoPushPermission"
+	return m.pushPermission, m.successfulGetRepoPushPermission
+}
+
+func (m *mockClient) CheckCLASignatures(urlStr string, emails []string) (map[string]string, bool) {
+	m.method = "CheckCLASignatures"
+	return m.signStates, m.successfulCheckCLASignatures
+}
+
+func (m *mockClient) CheckCLASignatureDetail(urlStr string) (string, string, bool) {
+	m.method = "CheckCLASignatureDetail"
+	return m.CLAState, m.claType, m.successfulCheckCLASignature
+}
+
+func (m *mockClient) GetRepoFileContent(org, repo, path, ref string) ([]byte, bool) {
+	m.method = "GetRepoFileContent"
+	return m.fileContent, m.successfulGetRepoFileContent
+}
+
+func (m *mockClient) CreateCommitStatus(org, repo, sha string, status commitStatus) bool {
+	m.method = "CreateCommitStatus"
+	m.status = status
+	return m.successfulCreateCommitStatus
+}
+
+func (m *mockClient) CreateRepoLabel(org, repo string, label repoLabel) bool {
+	m.method = "CreateRepoLabel"
+	m.repoLabels = append(m.repoLabels, label)
+	return m.successfulCreateRepoLabel
+}
+
+func (m *mockClient) GetCommitVerificationStatus(org, repo, sha string) (bool, bool) {
+	m.method = "GetCommitVerificationStatus"
+	return !slices.Contains(m.unverifiedCommits, sha), m.successfulGetCommitVerificationStatus
+}
+
+func (m *mockClient) GetUserEmail(login string) (string, bool) {
+	m.method = "GetUserEmail"
+	return m.userEmails[login], m.successfulGetUserEmail
+}
+
+func (m *mockClient) AddCommentReaction(org, repo, commentID, reaction string) bool {
+	m.method = "AddCommentReaction"
+	m.reactions = append(m.reactions, commentID+": "+reaction)
+	return m.successfulAddCommentReaction
+}
+
+func (m *mockClient) CreateIssue(org, repo, title, body string) bool {
+	m.method = "CreateIssue"
+	m.issues = append(m.issues, org+"/"+repo+": "+title)
+	return m.successfulCreateIssue
+}
+
 const (
 	org       = "org1"
 	repo      = "repo1"
@@ -119,27 +298,27 @@ func TestRemoveCLASignGuideComment(t *testing.T) {
 	case1 := "ListPullRequestComments"
 	cli.method = ""
 	// get comments failed
-	bot.removeCLASignGuideComment(org, repo, number)
+	bot.removeCLASignGuideComment(newPRSnapshot(mc, org, repo, number))
 	execMethod1 := cli.method
 	assert.Equal(t, case1, execMethod1)
 
 	cli.method = ""
 	cli.successfulListPullRequestComments = true
 	// getting comments to remove
-	bot.removeCLASignGuideComment(org, repo, number)
+	bot.removeCLASignGuideComment(newPRSnapshot(mc, org, repo, number))
 	execMethod2 := cli.method
 	assert.Equal(t, case1, execMethod2)
 
 	cli.method = ""
 	cli.prComments = []client.PRComment{
 		{
-			"123132",
-			"111123",
+			ID:   "123132",
+			Body: "111123",
 		},
 	}
 	bot.cnf.PlaceholderCLASignGuideTitle = "222"
 	// not found CLA sign guide comment
-	bot.removeCLASignGuideComment(org, repo, number)
+	bot.removeCLASignGuideComment(newPRSnapshot(mc, org, repo, number))
 	execMethod3 := cli.method
 	assert.Equal(t, case1, execMethod3)
 
@@ -147,11 +326,90 @@ func TestRemoveCLASignGuideComment(t *testing.T) {
 	cli.method = ""
 	bot.cnf.PlaceholderCLASignGuideTitle = "111"
 	// delete the CLA sign guide comment
-	bot.removeCLASignGuideComment(org, repo, number)
+	bot.removeCLASignGuideComment(newPRSnapshot(mc, org, repo, number))
 	execMethod4 := cli.method
 	assert.Equal(t, case4, execMethod4)
 }
 
+func TestReplaceCLAResultComment(t *testing.T) {
+	newBot := func(successfulUpdate bool) (*robot, *mockClient) {
+		mc := &mockClient{
+			successfulListPullRequestComments: true,
+			successfulCreatePRComment:         true,
+			successfulDeletePRComment:         true,
+			successfulUpdatePRComment:         successfulUpdate,
+			prComments: []client.PRComment{
+				{ID: "1", Body: "#guide the first check"},
+				{ID: "2", Body: "thanks for the review"},
+				{ID: "3", Body: "#pass the second check"},
+			},
+		}
+		return &robot{cli: mc, cnf: &configuration{
+			PlaceholderCLASignGuideTitle: "#guide",
+			PlaceholderCLASignPassTitle:  "#pass",
+		}}, mc
+	}
+
+	// the latest comment is edited in place and the older ones are deleted
+	bot, mc := newBot(true)
+	bot.replaceCLAResultComment(newPRSnapshot(mc, org, repo, number), &repoConfig{}, "#guide the third check")
+	assert.Equal(t, map[string]string{"3": "#guide the third check"}, mc.updatedComments)
+	assert.Equal(t, []string{"1"}, mc.deletedComments)
+	assert.Equal(t, "", mc.comment)
+
+	// a new comment is posted when the edit fails
+	bot, mc = newBot(false)
+	bot.replaceCLAResultComment(newPRSnapshot(mc, org, repo, number), &repoConfig{}, "#guide the third check")
+	assert.Equal(t, []string{"1", "3"}, mc.deletedComments)
+	assert.Equal(t, "#guide the third check", mc.comment)
+
+	// a new comment is posted when there is none to edit
+	bot, mc = newBot(true)
+	mc.prComments = mc.prComments[1:2]
+	bot.replaceCLAResultComment(newPRSnapshot(mc, org, repo, number), &repoConfig{}, "#pass the first check")
+	assert.Empty(t, mc.updatedComments)
+	assert.Empty(t, mc.deletedComments)
+	assert.Equal(t, "#pass the first check", mc.comment)
+
+	// the latest comment is kept when it is the same result, and the older ones are deleted
+	passed := withResultMarker(resultKindAllSigned, "#pass the third check")
+	bot, mc = newBot(true)
+	mc.prComments = append(mc.prComments, client.PRComment{ID: "4", Body: passed + "  \n"})
+	bot.replaceCLAResultComment(newPRSnapshot(mc, org, repo, number), &repoConfig{}, passed)
+	assert.Empty(t, mc.updatedComments)
+	assert.Equal(t, []string{"1", "3"}, mc.deletedComments)
+	assert.Equal(t, "", mc.comment)
+
+	// the latest comment of another kind of result is edited
+	bot, mc = newBot(true)
+	mc.prComments = append(mc.prComments,
+		client.PRComment{ID: "4", Body: withResultMarker(resultKindNeedSign, "#pass the third check")})
+	bot.replaceCLAResultComment(newPRSnapshot(mc, org, repo, number), &repoConfig{}, passed)
+	assert.Equal(t, map[string]string{"4": passed}, mc.updatedComments)
+	// the copies of the result posted by the users are neither edited nor deleted, and do not suppress the result
+	bot, mc = newBot(true)
+	mc.prComments = mc.prComments[1:2]
+	mc.userComments = []client.PRComment{{ID: "5", Body: passed}, {ID: "6", Body: "#guide quoted by a user"}}
+	bot.replaceCLAResultComment(newPRSnapshot(mc, org, repo, number), &repoConfig{}, passed)
+	assert.Empty(t, mc.updatedComments)
+	assert.Empty(t, mc.deletedComments)
+	assert.Equal(t, passed, mc.comment)
+
+	// nothing is posted while the login of the bot is unknown, otherwise the result is duplicated
+	bot, mc = newBot(true)
+	mc.botLoginUnknown = true
+	bot.replaceCLAResultComment(newPRSnapshot(mc, org, repo, number), &repoConfig{}, passed)
+	assert.Empty(t, mc.updatedComments)
+	assert.Empty(t, mc.deletedComments)
+	assert.Equal(t, "", mc.comment)
+	// the login is remembered once it is got
+	mc.botLoginUnknown = false
+	assert.Equal(t, mockBotLogin, bot.botLogin())
+	mc.botLoginUnknown = true
+	bot.replaceCLAResultComment(newPRSnapshot(mc, org, repo, number), &repoConfig{}, passed)
+	assert.Equal(t, map[string]string{"3": passed}, mc.updatedComments)
+}
+
 func TestWaitCLASignature(t *testing.T) {
 	mc := new(mockClient)
 	bot := &robot{cli: mc, cnf: &configuration{
@@ -169,14 +427,14 @@ func TestWaitCLASignature(t *testing.T) {
 
 	case1 := "unsigned users is empty"
 	cli.method = case1
-	bot.waitCLASignature(org, repo, number, []string{}, []string{labelYes}, repoCnf)
+	bot.waitCLASignature(newPRSnapshot(mc, org, repo, number), [3][]string{}, []string{labelYes}, repoCnf)
 	execMethod1 := cli.method
 	assert.Equal(t, case1, execMethod1)
 
 	case2 := "CreatePRComment"
 	cli.method = ""
 	// PR labels contains CLA failed label
-	bot.waitCLASignature(org, repo, number, []string{"user1"}, []string{labelNo}, repoCnf)
+	bot.waitCLASignature(newPRSnapshot(mc, org, repo, number), [3][]string{1: {"user1"}}, []string{labelNo}, repoCnf)
 	execMethod2 := cli.method
 	assert.Equal(t, case2, execMethod2)
 
@@ -184,7 +442,7 @@ func TestWaitCLASignature(t *testing.T) {
 	cli.method = ""
 	cli.successfulAddPRLabels = true
 	// remove CLA success label, and add CLA failed label
-	bot.waitCLASignature(org, repo, number, []string{"user1"}, []string{labelYes}, repoCnf)
+	bot.waitCLASignature(newPRSnapshot(mc, org, repo, number), [3][]string{1: {"user1"}}, []string{labelYes}, repoCnf)
 	execMethod3 := cli.method
 	assert.Equal(t, case3, execMethod3)
 }
@@ -207,7 +465,7 @@ func TestPassCLASignature(t *testing.T) {
 	case1 := "CreatePRComment"
 	cli.method = ""
 	// PR labels contains CLA failed label and CLA success label
-	bot.passCLASignature(org, repo, number, []string{"user2"}, []string{labelYes, labelNo}, repoCnf)
+	bot.passCLASignature(newPRSnapshot(mc, org, repo, number), []string{"user2"}, []string{labelYes, labelNo}, repoCnf)
 	execMethod1 := cli.method
 	assert.Equal(t, case1, execMethod1)
 
@@ -215,7 +473,7 @@ func TestPassCLASignature(t *testing.T) {
 	cli.method = ""
 	cli.successfulAddPRLabels = true
 	// PR labels is empty
-	bot.passCLASignature(org, repo, number, []string{"user3"}, []string{}, repoCnf)
+	bot.passCLASignature(newPRSnapshot(mc, org, repo, number), []string{"user3"}, []string{}, repoCnf)
 	execMethod2 := cli.method
 	assert.Equal(t, case2, execMethod2)
 
@@ -234,16 +492,16 @@ func TestListContributorNameAndEmail(t *testing.T) {
 
 	commits1 := []client.PRCommit{
 		{
-			"u1",
-			"e1",
-			"u2",
-			"e2",
+			AuthorName:     "u1",
+			AuthorEmail:    "e1",
+			CommitterName:  "u2",
+			CommitterEmail: "e2",
 		},
 		{
-			"u1",
-			"e1",
-			"u2",
-			"e2",
+			AuthorName:     "u1",
+			AuthorEmail:    "e1",
+			CommitterName:  "u2",
+			CommitterEmail: "e2",
 		},
 	}
 	// use author info
@@ -267,14 +525,14 @@ func TestCheckCLASignResult(t *testing.T) {
 	assert.Equal(t, true, ok)
 	repoCnf := &repoConfig{
 		LitePRCommitter: litePRCommiter{
-			"e0",
-			"u0",
+			Email: "e0",
+			Name:  "u0",
 		},
 	}
 
 	var commits []client.PRCommit
 	// PR commits is empty
-	allSigned, signResult := bot.checkCLASignResult(org, repo, number, commits, repoCnf)
+	allSigned, signResult := bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
 	assert.Equal(t, false, allSigned)
 	assert.Equal(t, ([]string)(nil), signResult[0])
 	assert.Equal(t, ([]string)(nil), signResult[1])
@@ -282,15 +540,15 @@ func TestCheckCLASignResult(t *testing.T) {
 
 	commits1 := []client.PRCommit{
 		{
-			"u3",
-			"e3",
-			"u3",
-			"e3",
+			AuthorName:     "u3",
+			AuthorEmail:    "e3",
+			CommitterName:  "u3",
+			CommitterEmail: "e3",
 		},
 	}
 	cli.CLAState = client.CLASignStateUnknown
 	// CLA sever is unavailable
-	allSigned1, signResult1 := bot.checkCLASignResult(org, repo, number, commits1, repoCnf)
+	allSigned1, signResult1 := bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits1, repoCnf)
 	assert.Equal(t, false, allSigned1)
 	assert.Equal(t, ([]string)(nil), signResult1[0])
 	assert.Equal(t, ([]string)(nil), signResult1[1])
@@ -298,7 +556,7 @@ func TestCheckCLASignResult(t *testing.T) {
 
 	cli.CLAState = client.CLASignStateNo
 	// CLA sever is available, but not signed
-	allSigned2, signResult2 := bot.checkCLASignResult(org, repo, number, commits1, repoCnf)
+	allSigned2, signResult2 := bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits1, repoCnf)
 	assert.Equal(t, false, allSigned2)
 	assert.Equal(t, ([]string)(nil), signResult2[0])
 	assert.Equal(t, []string{"u3"}, signResult2[1])
@@ -306,7 +564,7 @@ func TestCheckCLASignResult(t *testing.T) {
 
 	cli.CLAState = client.CLASignStateYes
 	// CLA sever is available, and signed
-	allSigned3, signResult3 := bot.checkCLASignResult(org, repo, number, commits1, repoCnf)
+	allSigned3, signResult3 := bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits1, repoCnf)
 	assert.Equal(t, true, allSigned3)
 	assert.Equal(t, []string{"u3"}, signResult3[0])
 	assert.Equal(t, ([]string)(nil), signResult3[1])
@@ -314,17 +572,403 @@ func TestCheckCLASignResult(t *testing.T) {
 
 	commits2 := []client.PRCommit{
 		{
-			"u0",
-			"e0",
-			"u0",
-			"e0",
+			AuthorName:     "u0",
+			AuthorEmail:    "e0",
+			CommitterName:  "u0",
+			CommitterEmail: "e0",
 		},
 	}
 	cli.CLAState = client.CLASignStateYes
 	// CLA sever is available, and signed, but email is invalid
-	allSigned4, signResult4 := bot.checkCLASignResult(org, repo, number, commits2, repoCnf)
+	allSigned4, signResult4 := bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits2, repoCnf)
 	assert.Equal(t, false, allSigned4)
 	assert.Equal(t, ([]string)(nil), signResult4[0])
 	assert.Equal(t, ([]string)(nil), signResult4[1])
 	assert.Equal(t, []string{"u0"}, signResult4[2])
 }
+
+func TestCheckCLASignResultInBatch(t *testing.T) {
+	mc := &mockClient{
+		successfulCheckCLASignatures: true,
+		signStates:                   map[string]string{"e1": client.CLASignStateYes, "e2": client.CLASignStateNo},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{}, claCache: newCLAResultCache(0)}
+	repoCnf := &repoConfig{BatchCheckURL: "http://localhost/cla/batch", CLACacheTTLSeconds: 60}
+	commits := []client.PRCommit{{AuthorName: "u1", AuthorEmail: "e1"}, {AuthorName: "u2", AuthorEmail: "e2"}}
+
+	allSigned, result := bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	assert.Equal(t, false, allSigned)
+	assert.Equal(t, []string{"u2"}, result[1])
+	assert.Equal(t, "CheckCLASignatures", mc.method)
+
+	// the signed e1 is cached, the email missing in the response is unknown
+	mc.signStates = map[string]string{}
+	states := bot.checkCLASignatures(newPRSnapshot(mc, org, repo, number), []string{"e1", "e2"}, repoCnf)
+	assert.Equal(t, map[string]string{"e1": client.CLASignStateYes, "e2": client.CLASignStateUnknown}, states)
+}
+
+func TestCheckCLASignResultBySignatureTrailer(t *testing.T) {
+	mc := new(mockClient)
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	repoCnf := &repoConfig{SignatureTrailer: "CLA", SignatureVerifyURL: "http://localhost/verify?tenant=t1"}
+
+	commits := []client.PRCommit{{AuthorName: "u1", AuthorEmail: "e1"}}
+	mc.CLAState = client.CLASignStateNo
+	mc.successfulGetPullRequestCommitMessages = true
+	mc.commitMessages = []prCommitMessage{
+		{PRCommit: commits[0], SHA: "s1", Message: "subject\n\nbody\n\nCLA: 1234"},
+	}
+
+	// the signature id is invalid
+	mc.signatureIDState = client.CLASignStateNo
+	allSigned, signResult := bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	assert.Equal(t, false, allSigned)
+	assert.Equal(t, []string{"u1"}, signResult[1])
+
+	// the signature id is valid
+	mc.signatureIDState = client.CLASignStateYes
+	allSigned, signResult = bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	assert.Equal(t, true, allSigned)
+	assert.Equal(t, []string{"u1"}, signResult[0])
+	// the email and the repository are verified together with the signature id, after the existing query
+	assert.Equal(t, "http://localhost/verify?email=e1&org="+org+"&repo="+repo+"&signature_id=1234&tenant=t1",
+		mc.verifyURL)
+
+	// the signature id is not declared in the trailers
+	mc.commitMessages[0].Message = "subject\n\nCLA: 1234\n\nSigned-off-by: u1 <e1>\n"
+	allSigned, _ = bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	assert.Equal(t, false, allSigned)
+	mc.commitMessages[0].Message = "CLA: 1234"
+	allSigned, _ = bot.checkCLASignResult(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	assert.Equal(t, false, allSigned)
+}
+
+func TestCheckCLASignResultOfMixedStates(t *testing.T) {
+	mc := &multiServerClient{mockClient: &mockClient{successfulAddPRLabels: true, successfulCreatePRComment: true},
+		states: map[string]string{
+			"icla?email=e1": client.CLASignStateYes,
+			"icla?email=e2": client.CLASignStateNo,
+		}}
+	bot := &robot{cli: mc, cnf: &configuration{
+		UserMarkFormat: "@ddd", PlaceholderCommitter: "ddd", CommentSomeNeedSign: "%s need to sign %s %s",
+	}}
+	repoCnf := &repoConfig{CheckURL: "icla", CLALabelYes: labelYes, CLALabelNo: labelNo}
+	commits := []client.PRCommit{
+		{AuthorName: "u1", AuthorEmail: "e1"},
+		{AuthorName: "u2", AuthorEmail: "e2"},
+		{AuthorName: "u3", AuthorEmail: "e3"},
+		{AuthorName: "u4", AuthorEmail: ""},
+	}
+
+	// the unknown contributors do not hide the unsigned ones, and all of them are listed in one comment
+	pr := newPRSnapshot(mc, org, repo, number)
+	allSigned, signResult := bot.checkCLASignResult(pr, commits, repoCnf)
+	assert.Equal(t, false, allSigned)
+	assert.Equal(t, [3][]string{{"u1"}, {"u2"}, {"u3", "u4"}}, signResult)
+	assert.Equal(t, prStatusUnsigned, evaluationStatus(allSigned, signResult))
+	assert.Equal(t, "", mc.comment)
+
+	bot.waitCLASignature(pr, signResult, nil, repoCnf)
+	assert.Contains(t, mc.comment, "@u2 need to sign")
+	assert.Contains(t, mc.comment, "@u1 have signed the CLA.")
+	assert.Contains(t, mc.comment, "The CLA of @u4 can not be checked: their commits have no email")
+	assert.Contains(t, mc.comment, "The CLA of @u3 can not be checked: the CLA server is unavailable")
+}
+
+func TestCheckScope(t *testing.T) {
+	m := regexpCheckCLAComment.FindStringSubmatch("/check-cla committers")
+	assert.Equal(t, []string{"/check-cla committers", checkScopeCommitters}, m)
+	m = regexpCheckCLAComment.FindStringSubmatch("/check-cla")
+	assert.Equal(t, []string{"/check-cla", ""}, m)
+	assert.Equal(t, true, regexpCheckCLAComment.FindStringSubmatch("/check-cla everyone") == nil)
+
+	mc := &mockClient{successfulAddPRLabels: true}
+	bot := &robot{cli: mc, cnf: &configuration{CommentAllSigned: "all signed", PlaceholderCommitter: "ccc"}}
+	repoCnf := &repoConfig{CLALabelYes: labelYes, CLALabelNo: labelNo, CheckByCommitter: true}
+
+	bot.passCLASignature(newPRSnapshot(mc, org, repo, number), []string{"u1"}, nil, repoCnf)
+	assert.Equal(t, withResultMarker(resultKindAllSigned, "all signed"), mc.comment)
+
+	scoped := repoCnf.withCheckScope(checkScopeAuthors)
+	assert.Equal(t, false, scoped.CheckByCommitter)
+	assert.Equal(t, true, repoCnf.CheckByCommitter)
+	bot.passCLASignature(newPRSnapshot(mc, org, repo, number), []string{"u1"}, nil, scoped)
+	comment := "all signed" + fmt.Sprintf(defaultCommentCheckScope, checkScopeAuthors)
+	assert.Equal(t, withResultMarker(resultKindAllSigned, comment), mc.comment)
+}
+
+func TestCheckScopeByMaintainers(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestCommits:   true,
+		successfulCheckCLASignature:       true,
+		successfulGetPullRequestLabels:    true,
+		successfulAddPRLabels:             true,
+		successfulListPullRequestComments: true,
+		successfulCreatePRComment:         true,
+		successfulCheckPermission:         true,
+		CLAState:                          client.CLASignStateYes,
+		commits: []client.PRCommit{
+			{AuthorName: "u1", AuthorEmail: "u1@example.com", CommitterName: "u2", CommitterEmail: "u2@example.com"},
+		},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{ConfigItems: []repoConfig{{
+		RepoFilter:  config.RepoFilter{Repos: []string{org}},
+		CLALabelYes: labelYes, CLALabelNo: labelNo, CheckURL: "https://cla.example.com/check",
+		SignURL: "https://cla.example.com/sign", FAQURL: "https://cla.example.com/faq",
+	}}}}
+	str := func(v string) *string { return &v }
+	evt := &client.GenericEvent{Org: str(org), Repo: str(repo), Number: str(number), Commenter: str(commenter),
+		Comment: str("/check-cla committers")}
+
+	// the scope of the others is ignored, which leaves the labels and the commit status unchanged
+	bot.handlePullRequestComment(evt, logrus.NewEntry(logrus.New()))
+	assert.Equal(t, "CheckPermission", mc.method)
+	assert.Equal(t, "", mc.comment)
+
+	// the maintainers can scope the check
+	mc.permission = true
+	bot.handlePullRequestComment(evt, logrus.NewEntry(logrus.New()))
+	assert.Contains(t, mc.comment, fmt.Sprintf(defaultCommentCheckScope, checkScopeCommitters))
+}
+
+func TestCheckSince(t *testing.T) {
+	m := regexpCheckCLASinceComment.FindStringSubmatch("/check-cla authors --since ABCDEF1")
+	assert.Equal(t, []string{"/check-cla authors --since ABCDEF1", checkScopeAuthors, "ABCDEF1"}, m)
+	assert.Equal(t, true, regexpCheckCLASinceComment.FindStringSubmatch("/check-cla --since xyz") == nil)
+
+	commits := []client.PRCommit{
+		{AuthorName: "u1", AuthorEmail: "e1", CommitterName: "u1", CommitterEmail: "e1"},
+		{AuthorName: "u2", AuthorEmail: "e2", CommitterName: "u2", CommitterEmail: "e2"},
+		{AuthorName: "u3", AuthorEmail: "e3", CommitterName: "u3", CommitterEmail: "e3"},
+	}
+	mc := &mockClient{
+		successfulGetPullRequestCommitMessages: true,
+		successfulCheckCLASignature:            true,
+		successfulGetPullRequestLabels:         true,
+		successfulAddPRLabels:                  true,
+		successfulListPullRequestComments:      true,
+		successfulCreatePRComment:              true,
+		CLAState:                               client.CLASignStateYes,
+		commitMessages: []prCommitMessage{
+			{PRCommit: commits[0], SHA: "aaaaaaa111"}, {PRCommit: commits[1], SHA: "bbbbbbb222"},
+			{PRCommit: commits[2], SHA: "ccccccc333"},
+		},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{CommentAllSigned: "all signed", PlaceholderCommitter: "ccc"},
+		store: newMemoryStateStore()}
+	repoCnf := &repoConfig{CLALabelYes: labelYes, CLALabelNo: labelNo}
+
+	// only the maintainers can scope the check
+	assert.Equal(t, false, bot.checkSinceCommand(newPRSnapshot(mc, org, repo, number), repoCnf, commenter, "aaaaaaa",
+		logrus.NewEntry(logrus.New())))
+	assert.Equal(t, "", mc.comment)
+
+	mc.permission, mc.successfulCheckPermission = true, true
+	assert.Equal(t, false, bot.checkSinceCommand(newPRSnapshot(mc, org, repo, number), repoCnf, commenter, "ddddddd",
+		logrus.NewEntry(logrus.New())))
+	assert.Equal(t, fmt.Sprintf(defaultCommentSinceNotFound, "ddddddd"), mc.comment)
+	assert.Equal(t, true, bot.checkSinceCommand(newPRSnapshot(mc, org, repo, number), repoCnf, commenter, "AAAAAAA",
+		logrus.NewEntry(logrus.New())))
+
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf.withSince("aaaaaaa"), logrus.NewEntry(logrus.New()))
+	got, _ := pr.getCommits()
+	assert.Equal(t, commits[1:], got)
+	sha, _ := pr.headSHA()
+	assert.Equal(t, "ccccccc333", sha)
+	assert.Equal(t, withResultMarker(resultKindAllSigned, "all signed"+fmt.Sprintf(defaultCommentCheckSince, "aaaaaaa")),
+		mc.comment)
+	// the partial result is marked
+	s, _ := bot.store.get(org, repo, number)
+	assert.Equal(t, "aaaaaaa", s.Since)
+
+	// no commit after the head
+	pr = newPRSnapshot(mc, org, repo, number)
+	pr.since = "ccccccc"
+	got, ok := pr.getCommits()
+	assert.Equal(t, true, ok)
+	assert.Empty(t, got)
+}
+
+func TestAttributeBackports(t *testing.T) {
+	mc := new(mockClient)
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	commits := []client.PRCommit{
+		{AuthorName: "u1", AuthorEmail: "e1", CommitterName: "u2", CommitterEmail: "e2"},
+		{AuthorName: "u3", AuthorEmail: "e3", CommitterName: "u2", CommitterEmail: "e2"},
+	}
+
+	// failed to get the commit messages
+	assert.Equal(t, commits, bot.attributeBackports(newPRSnapshot(mc, org, repo, number), commits))
+
+	mc.successfulGetPullRequestCommitMessages = true
+	mc.commitMessages = []prCommitMessage{
+		{PRCommit: commits[0], Message: "fix\n\n(cherry picked from commit 1234567)"},
+		{PRCommit: commits[1], Message: "feature"},
+	}
+	got := bot.attributeBackports(newPRSnapshot(mc, org, repo, number), commits)
+	users, emails := bot.ListContributorNameAndEmail(got, &repoConfig{})
+	assert.Equal(t, []string{"u2", "u3"}, users)
+	assert.Equal(t, []string{"e2", "e3"}, emails)
+}
+
+func TestAppendReviewers(t *testing.T) {
+	mc := &mockClient{successfulGetPullRequestCommitMessages: true}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	commits := []client.PRCommit{{AuthorName: "u1", AuthorEmail: "e1", CommitterName: "u1", CommitterEmail: "e1"}}
+	mc.commitMessages = []prCommitMessage{
+		{PRCommit: commits[0], Message: "fix\n\nReviewed-by: u2 <e2@example.com>\nsuggested-by: <e3@example.com>\n" +
+			"Suggested-by: u4\nSigned-off-by: u5 <e5@example.com>"},
+	}
+	repoCnf := &repoConfig{ReviewerTrailers: []string{"Reviewed-by", "Suggested-by"}}
+
+	got := bot.appendReviewers(newPRSnapshot(mc, org, repo, number), commits, repoCnf)
+	users, emails := bot.ListContributorNameAndEmail(got, repoCnf)
+	assert.Equal(t, []string{"u1", "u2", "e3@example.com"}, users)
+	assert.Equal(t, []string{"e1", "e2@example.com", "e3@example.com"}, emails)
+}
+
+func TestCommentSomeNeedSign(t *testing.T) {
+	bot := &robot{cli: new(mockClient), cnf: &configuration{CommentSomeNeedSign: "first"}, store: newMemoryStateStore()}
+	pr := newPRSnapshot(bot.cli, org, repo, number)
+	bot.store.put(prState{Org: org, Repo: repo, Number: number, Status: prStatusUnsigned})
+	assert.Equal(t, "first", bot.commentSomeNeedSign(pr))
+
+	bot.cnf.CommentSomeNeedSignAgain = "again"
+	assert.Equal(t, "again", bot.commentSomeNeedSign(pr))
+
+	// the previous check passed
+	bot.store.put(prState{Org: org, Repo: repo, Number: number, Status: prStatusSigned})
+	assert.Equal(t, "first", bot.commentSomeNeedSign(pr))
+
+	bot.store.remove(org, repo, number)
+	assert.Equal(t, "first", bot.commentSomeNeedSign(pr))
+}
+
+func TestCreatePRCommentWithCap(t *testing.T) {
+	mc := &mockClient{successfulCreatePRComment: true}
+	bot := &robot{cli: mc, cnf: &configuration{}, store: newMemoryStateStore()}
+	pr := newPRSnapshot(mc, org, repo, number)
+	repoCnf := &repoConfig{MaxComments: 2}
+
+	for i := 0; i < 2; i++ {
+		bot.createPRComment(pr, repoCnf, "result")
+		assert.Equal(t, "result", mc.comment)
+	}
+	assert.Equal(t, true, bot.commentsExhausted(pr, repoCnf))
+
+	// the final note
+	bot.createPRComment(pr, repoCnf, "result")
+	assert.Equal(t, defaultCommentMaxCommentsReached, mc.comment)
+
+	mc.method, mc.comment = "", ""
+	bot.createPRComment(pr, repoCnf, "result")
+	assert.Equal(t, "", mc.method)
+
+	// the count survives the evaluations
+	bot.recordPRState(pr, false, [3][]string{nil, {"u1"}})
+	assert.Equal(t, 3, bot.commentCount(pr))
+}
+
+func TestCommandTriggerComment(t *testing.T) {
+	bot := &robot{cli: new(mockClient), cnf: &configuration{CommentCommandTrigger: "comment /check-cla again"}}
+	repoCnf := &repoConfig{Contact: repoContact{MailingList: "dev@example.com", Maintainer: "@m1"}}
+	assert.Equal(t, "comment /check-cla again", bot.commandTriggerComment(nil, repoCnf))
+
+	repoCnf.CommentCommandTrigger = "ask {{maintainer}} or mail to {{mailing_list}}"
+	assert.Equal(t, "ask @m1 or mail to dev@example.com", bot.commandTriggerComment(nil, repoCnf))
+}
+
+func TestIsStaleEvent(t *testing.T) {
+	bot := &robot{cli: new(mockClient), cnf: &configuration{}, store: newMemoryStateStore()}
+	newer := "2024-10-26T10:32:41+08:00"
+	older := "2024-10-26T10:30:00+08:00"
+	head := "org1/repo1/sha2"
+
+	pr := newPRSnapshot(bot.cli, org, repo, number).withEvent(&client.GenericEvent{UpdateTime: &newer, Head: &head})
+	assert.Equal(t, false, bot.isStaleEvent(pr))
+	bot.recordPRState(pr, true, [3][]string{{"u1"}})
+
+	stale := newPRSnapshot(bot.cli, org, repo, number).withEvent(&client.GenericEvent{UpdateTime: &older})
+	assert.Equal(t, true, bot.isStaleEvent(stale))
+
+	// the events without time and the rechecks are never dropped, and keep the latest event
+	recheck := newPRSnapshot(bot.cli, org, repo, number)
+	assert.Equal(t, false, bot.isStaleEvent(recheck))
+	bot.recordPRState(recheck, true, [3][]string{{"u1"}})
+	s, _ := bot.store.get(org, repo, number)
+	assert.Equal(t, head, s.Head)
+	assert.Equal(t, true, bot.isStaleEvent(stale))
+}
+
+// laggingCommitsClient returns no commit until the commits are fetched the lag times
+type laggingCommitsClient struct {
+	*mockClient
+	lag     int
+	fetches int
+}
+
+func (c *laggingCommitsClient) GetPullRequestCommits(org, repo, number string) ([]client.PRCommit, bool) {
+	if c.fetches++; c.fetches <= c.lag {
+		return nil, true
+	}
+	return c.mockClient.GetPullRequestCommits(org, repo, number)
+}
+
+func TestWaitForLaggingCommits(t *testing.T) {
+	interval := laggingCommitsRetryInterval
+	laggingCommitsRetryInterval = 0
+	defer func() { laggingCommitsRetryInterval = interval }()
+
+	mc := &laggingCommitsClient{mockClient: &mockClient{
+		successfulGetPullRequestCommits: true,
+		successfulGetChangedFileCount:   true,
+		successfulGetPullRequestLabels:  true,
+		successfulCreatePRComment:       true,
+		commits:                         []client.PRCommit{{AuthorName: "u1", AuthorEmail: "u1@example.com"}},
+		changedFileCount:                2,
+	}, lag: 2}
+	bot := &robot{cli: mc, cnf: &configuration{CommentPRNoCommits: "no commits", CommentCommandTrigger: "try again"}}
+	repoCnf := &repoConfig{CheckURL: "icla"}
+
+	// the commits show up after the retries
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf, logrus.NewEntry(logrus.New()))
+	assert.Equal(t, 3, mc.fetches)
+	assert.NotEqual(t, decisionNoCommits, pr.stats.decision)
+	assert.NotEqual(t, decisionCommitsUnavailable, pr.stats.decision)
+
+	// the commits are still missing while the files are changed
+	mc.fetches, mc.lag = 0, laggingCommitsRetries+1
+	pr = newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf, logrus.NewEntry(logrus.New()))
+	assert.Equal(t, laggingCommitsRetries+1, mc.fetches)
+	assert.Equal(t, decisionCommitsUnavailable, pr.stats.decision)
+	assert.Equal(t, "try again", mc.comment)
+
+	// the pull request is empty indeed
+	mc.fetches, mc.changedFileCount = 0, 0
+	pr = newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, repoCnf, logrus.NewEntry(logrus.New()))
+	assert.Equal(t, 1, mc.fetches)
+	assert.Equal(t, decisionNoCommits, pr.stats.decision)
+	assert.Equal(t, "no commits", mc.comment)
+}
+
+func TestRecordPRClosed(t *testing.T) {
+	s := func(v string) *string { return &v }
+	bot := &robot{cli: &mockClient{}, cnf: &configuration{}, store: newMemoryStateStore()}
+	bot.store.put(prState{Org: org, Repo: repo, Number: number, Status: prStatusUnsigned,
+		UnsignedEmails: []string{"u1@example.com"}})
+
+	evt := &client.GenericEvent{Org: s(org), Repo: s(repo), Number: s(number), State: s(prEventStateMerged)}
+	bot.handlePullRequest(context.Background(), evt, logrus.NewEntry(logrus.New()))
+	state, _ := bot.store.get(org, repo, number)
+	assert.Equal(t, true, state.Closed)
+
+	// reopened
+	evt.State = s("opened")
+	bot.handlePullRequest(context.Background(), evt, logrus.NewEntry(logrus.New()))
+	state, _ = bot.store.get(org, repo, number)
+	assert.Equal(t, false, state.Closed)
+}
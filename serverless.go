@@ -0,0 +1,415 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/config"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/opensourceways/robot-framework-lib/utils"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// envLambdaRuntimeAPI is the host of the runtime api of the serverless function, which is set by the platform
+	envLambdaRuntimeAPI = "AWS_LAMBDA_RUNTIME_API"
+
+	lambdaRuntimeVersion      = "2018-06-01"
+	lambdaRequestIDHeader     = "Lambda-Runtime-Aws-Request-Id"
+	lambdaRuntimeContentType  = "application/json"
+	stateFilePerm             = 0o600
+	serverlessInvocationField = "invocation"
+)
+
+// apiGatewayRequest is the invocation of the serverless function by the api gateway.
+// Both the version 1.0 and 2.0 of the payload are accepted
+type apiGatewayRequest struct {
+	HTTPMethod            string            `json:"httpMethod"`
+	Path                  string            `json:"path"`
+	RawPath               string            `json:"rawPath"`
+	RawQueryString        string            `json:"rawQueryString"`
+	QueryStringParameters map[string]string `json:"queryStringParameters"`
+	Headers               map[string]string `json:"headers"`
+	Body                  string            `json:"body"`
+	IsBase64Encoded       bool              `json:"isBase64Encoded"`
+	RequestContext        struct {
+		HTTP struct {
+			Method string `json:"method"`
+		} `json:"http"`
+	} `json:"requestContext"`
+}
+
+// apiGatewayResponse is the result of the invocation returned to the api gateway
+type apiGatewayResponse struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// httpRequest converts the invocation to the http request served by the handlers of the bot
+func (req *apiGatewayRequest) httpRequest() (*http.Request, error) {
+	method := req.HTTPMethod
+	if method == "" {
+		method = req.RequestContext.HTTP.Method
+	}
+	path := req.RawPath
+	if path == "" {
+		path = req.Path
+	}
+	if method == "" || path == "" {
+		return nil, errors.New("the method and path of the invocation can not be empty")
+	}
+
+	query := req.RawQueryString
+	if query == "" && len(req.QueryStringParameters) != 0 {
+		values := url.Values{}
+		for k, v := range req.QueryStringParameters {
+			values.Set(k, v)
+		}
+		query = values.Encode()
+	}
+
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(req.Body); err != nil {
+			return nil, fmt.Errorf("invalid base64 body: %w", err)
+		}
+	}
+
+	target := path
+	if query != "" {
+		target += "?" + query
+	}
+	r, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range req.Headers {
+		r.Header.Set(k, v)
+	}
+	return r, nil
+}
+
+// responseBuffer is the http.ResponseWriter collecting the response of a handler for the api gateway
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: http.Header{}}
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *responseBuffer) response() apiGatewayResponse {
+	resp := apiGatewayResponse{StatusCode: b.status, Body: b.body.String()}
+	if resp.StatusCode == 0 {
+		resp.StatusCode = http.StatusOK
+	}
+	if len(b.header) != 0 {
+		resp.Headers = make(map[string]string, len(b.header))
+		for k := range b.header {
+			resp.Headers[k] = b.header.Get(k)
+		}
+	}
+	return resp
+}
+
+// invokeHandler serves the invocation of the api gateway by the handler
+func invokeHandler(handler http.Handler, payload []byte) (apiGatewayResponse, error) {
+	var req apiGatewayRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return apiGatewayResponse{}, fmt.Errorf("invalid invocation: %w", err)
+	}
+	r, err := req.httpRequest()
+	if err != nil {
+		return apiGatewayResponse{}, err
+	}
+
+	rec := newResponseBuffer()
+	handler.ServeHTTP(rec, r)
+	return rec.response(), nil
+}
+
+// eventHandlers collects the handlers registered by the bot, so that the events are handled
+// without the server of the framework
+type eventHandlers struct {
+	issue              framework.GenericHandlerFunc
+	issueComment       framework.GenericHandlerFunc
+	pullRequest        framework.GenericHandlerFunc
+	pullRequestComment framework.GenericHandlerFunc
+	push               framework.GenericHandlerFunc
+}
+
+func (h *eventHandlers) RegisterIssueHandler(fn framework.GenericHandlerFunc) {
+	h.issue = fn
+}
+
+func (h *eventHandlers) RegisterIssueCommentHandler(fn framework.GenericHandlerFunc) {
+	h.issueComment = fn
+}
+
+func (h *eventHandlers) RegisterPullRequestHandler(fn framework.GenericHandlerFunc) {
+	h.pullRequest = fn
+}
+
+func (h *eventHandlers) RegisterPullRequestCommentHandler(fn framework.GenericHandlerFunc) {
+	h.pullRequestComment = fn
+}
+
+func (h *eventHandlers) RegisterPushEventHandler(fn framework.GenericHandlerFunc) {
+	h.push = fn
+}
+
+// syncDispatcher handles the webhook events like the dispatcher of the framework, except that the event is
+// handled before the response, since the serverless function may be frozen once it has responded
+type syncDispatcher struct {
+	cnf config.Configmap
+	h   eventHandlers
+	log *logrus.Entry
+}
+
+func newSyncDispatcher(bot framework.Robot, logger *logrus.Entry) *syncDispatcher {
+	d := &syncDispatcher{cnf: bot.GetConfigmap(), log: logger}
+	bot.RegisterEventHandler(&d.h)
+	return d
+}
+
+func (d *syncDispatcher) handler(evt *client.GenericEvent) framework.GenericHandlerFunc {
+	switch *evt.EventType {
+	case framework.IssueEvent:
+		return d.h.issue
+	case framework.PullRequestEvent:
+		return d.h.pullRequest
+	case framework.NoteEvent:
+		switch utils.GetString(evt.CommentKind) {
+		case client.CommentOnIssue:
+			return d.h.issueComment
+		case client.CommentOnPR:
+			return d.h.pullRequestComment
+		}
+	case framework.PushEvent:
+		return d.h.push
+	}
+	return nil
+}
+
+func (d *syncDispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	evt := client.NewGenericEvent(w, r, d.log.WithField("module", "parse-request"))
+	if evt.EventType == nil {
+		d.log.Warning("the request not within the scope of processing")
+		return
+	}
+
+	fn := d.handler(evt)
+	if fn == nil {
+		d.log.WithField("request", "discard").Warning("there is no function to handle this request")
+		return
+	}
+	fn(evt, d.cnf, d.log.WithFields(*evt.CollectLoggingFields()))
+}
+
+// serverlessHandler serves the webhook events at the handle path by the dispatcher, and the other apis of the bot
+// by the mux
+func serverlessHandler(handlePath string, dispatcher http.Handler, mux http.Handler) http.Handler {
+	webhookPath := "/" + strings.TrimPrefix(handlePath, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == webhookPath {
+			dispatcher.ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// loadStateFile restores the state store from the snapshot in the file. Nothing is restored if the file does not exist
+func loadStateFile(path string, store stateStore) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snapshot stateSnapshot
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("invalid state file: %w", err)
+	}
+	return store.importSnapshot(snapshot)
+}
+
+// saveStateFile writes the snapshot of the state store to the file. It is written to a temporary file first,
+// so that an interrupted write does not corrupt the state of the previous invocations
+func saveStateFile(path string, store stateStore) error {
+	data, err := json.Marshal(store.exportSnapshot())
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Chmod(stateFilePerm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// lambdaRuntime polls the invocations from the runtime api of the serverless platform and posts their results
+type lambdaRuntime struct {
+	api     string
+	cli     *http.Client
+	handler http.Handler
+	// afterInvoke runs after each invocation is served and before its result is posted
+	afterInvoke func()
+	log         *logrus.Entry
+}
+
+func (rt *lambdaRuntime) url(path string) string {
+	return fmt.Sprintf("http://%s/%s/runtime/%s", rt.api, lambdaRuntimeVersion, path)
+}
+
+// next waits for the next invocation
+func (rt *lambdaRuntime) next() (requestID string, payload []byte, err error) {
+	resp, err := rt.cli.Get(rt.url("invocation/next"))
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("unexpected status %d of the next invocation", resp.StatusCode)
+	}
+	if payload, err = io.ReadAll(resp.Body); err != nil {
+		return "", nil, err
+	}
+	return resp.Header.Get(lambdaRequestIDHeader), payload, nil
+}
+
+func (rt *lambdaRuntime) post(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	resp, err := rt.cli.Post(rt.url(path), lambdaRuntimeContentType, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d of posting the result", resp.StatusCode)
+	}
+	return nil
+}
+
+// invoke serves one invocation and posts its result
+func (rt *lambdaRuntime) invoke() error {
+	requestID, payload, err := rt.next()
+	if err != nil {
+		return err
+	}
+	logger := rt.log.WithField(serverlessInvocationField, requestID)
+
+	resp, err := invokeHandler(rt.handler, payload)
+	if rt.afterInvoke != nil {
+		rt.afterInvoke()
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to serve the invocation")
+		return rt.post("invocation/"+requestID+"/error", map[string]string{
+			"errorMessage": err.Error(),
+			"errorType":    "InvalidInvocation",
+		})
+	}
+	return rt.post("invocation/"+requestID+"/response", resp)
+}
+
+// run serves the invocations until the runtime api fails
+func (rt *lambdaRuntime) run() error {
+	for {
+		if err := rt.invoke(); err != nil {
+			return err
+		}
+	}
+}
+
+// runServerless serves the invocations of the api gateway by the bot on the serverless platform.
+// The state is restored from the state file at the cold start and written back after each invocation,
+// so the function must not run concurrently, e.g. its reserved concurrency must be 1
+func runServerless(bot *robot, handlePath, stateFile string, mux http.Handler) error {
+	api := os.Getenv(envLambdaRuntimeAPI)
+	if api == "" {
+		return fmt.Errorf("the %s is not set, the serverless mode must run on the serverless platform", envLambdaRuntimeAPI)
+	}
+	if err := loadStateFile(stateFile, bot.store); err != nil {
+		return fmt.Errorf("failed to load the state file: %w", err)
+	}
+
+	logger := bot.log.WithField("mode", "serverless")
+	rt := &lambdaRuntime{
+		api:     api,
+		cli:     &http.Client{},
+		handler: serverlessHandler(handlePath, newSyncDispatcher(bot, logger), mux),
+		afterInvoke: func() {
+			if err := saveStateFile(stateFile, bot.store); err != nil {
+				logger.WithError(err).Error("failed to save the state file")
+			}
+		},
+		log: logger,
+	}
+	return rt.run()
+}
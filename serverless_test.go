@@ -0,0 +1,153 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/config"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type handlerRecorder struct {
+	cnf    config.Configmap
+	events []string
+}
+
+func (h *handlerRecorder) GetConfigmap() config.Configmap {
+	return h.cnf
+}
+
+func (h *handlerRecorder) GetLogger() *logrus.Entry {
+	return framework.NewLogger()
+}
+
+func (h *handlerRecorder) RegisterEventHandler(p framework.HandlerRegister) {
+	p.RegisterPullRequestHandler(func(evt *client.GenericEvent, _ config.Configmap, _ *logrus.Entry) {
+		h.events = append(h.events, *evt.EventType)
+	})
+}
+
+func TestAPIGatewayRequest(t *testing.T) {
+	v1 := apiGatewayRequest{
+		HTTPMethod:            http.MethodPost,
+		Path:                  "/webhook",
+		QueryStringParameters: map[string]string{"a": "b"},
+		Headers:               map[string]string{"x-gitcode-event": framework.PullRequestEvent},
+		Body:                  base64.StdEncoding.EncodeToString([]byte("{}")),
+		IsBase64Encoded:       true,
+	}
+	r, err := v1.httpRequest()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "/webhook", r.URL.Path)
+	assert.Equal(t, "b", r.URL.Query().Get("a"))
+	assert.Equal(t, framework.PullRequestEvent, r.Header.Get("X-GitCode-Event"))
+	body, _ := io.ReadAll(r.Body)
+	assert.Equal(t, "{}", string(body))
+
+	var v2 apiGatewayRequest
+	assert.Equal(t, nil, json.Unmarshal([]byte(
+		`{"rawPath":"/ui/org1/repo1/1","rawQueryString":"token=t","requestContext":{"http":{"method":"GET"}}}`), &v2))
+	r, err = v2.httpRequest()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, http.MethodGet, r.Method)
+	assert.Equal(t, "/ui/org1/repo1/1", r.URL.Path)
+	assert.Equal(t, "t", r.URL.Query().Get("token"))
+
+	_, err = (&apiGatewayRequest{Path: "/webhook"}).httpRequest()
+	assert.NotEqual(t, nil, err)
+	_, err = (&apiGatewayRequest{HTTPMethod: http.MethodPost, Path: "/webhook", Body: "!", IsBase64Encoded: true}).httpRequest()
+	assert.NotEqual(t, nil, err)
+}
+
+func TestStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store := newMemoryStateStore()
+
+	// nothing is restored at the first cold start
+	assert.Equal(t, nil, loadStateFile(path, store))
+	assert.Equal(t, 0, len(store.exportSnapshot().PRs))
+
+	store.put(prState{Org: org, Repo: repo, Number: number, Status: prStatusUnsigned, UnsignedUsers: []string{"u1"}})
+	assert.Equal(t, nil, saveStateFile(path, store))
+
+	another := newMemoryStateStore()
+	assert.Equal(t, nil, loadStateFile(path, another))
+	s, ok := another.get(org, repo, number)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, prStatusUnsigned, s.Status)
+	matches, _ := filepath.Glob(path + ".*.tmp")
+	assert.Equal(t, 0, len(matches))
+}
+
+func TestLambdaRuntime(t *testing.T) {
+	invocations := []string{
+		`{"httpMethod":"POST","path":"/webhook","headers":{"X-GitCode-Event":"Merge Request Hook"},"body":"{}"}`,
+		`{"rawPath":"/healthz","requestContext":{"http":{"method":"GET"}}}`,
+		`not json`,
+	}
+	results := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/invocation/next") {
+			if len(invocations) == 0 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set(lambdaRequestIDHeader, "id"+string(rune('0'+len(invocations))))
+			_, _ = io.WriteString(w, invocations[0])
+			invocations = invocations[1:]
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		results[strings.TrimPrefix(r.URL.Path, "/"+lambdaRuntimeVersion+"/runtime/invocation/")] = string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	recorder := &handlerRecorder{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	saved := 0
+	rt := &lambdaRuntime{
+		api:         strings.TrimPrefix(srv.URL, "http://"),
+		cli:         srv.Client(),
+		handler:     serverlessHandler("webhook", newSyncDispatcher(recorder, framework.NewLogger()), mux),
+		afterInvoke: func() { saved++ },
+		log:         framework.NewLogger(),
+	}
+	assert.NotEqual(t, nil, rt.run())
+
+	// the event is handled before the response
+	assert.Equal(t, []string{framework.PullRequestEvent}, recorder.events)
+	assert.Equal(t, 3, saved)
+
+	var resp apiGatewayResponse
+	assert.Equal(t, nil, json.Unmarshal([]byte(results["id3/response"]), &resp))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, nil, json.Unmarshal([]byte(results["id2/response"]), &resp))
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Contains(t, results["id1/error"], "invalid invocation")
+}
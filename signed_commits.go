@@ -0,0 +1,214 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSignedCommitsLabelYes = "signed-commits-yes"
+	defaultSignedCommitsLabelNo  = "signed-commits-no"
+
+	// signedCommitsMarker is the hidden marker of the comments of the signed commits, by which the comment of
+	// the previous check is replaced. It differs from the result markers, so that the CLA result does not replace it
+	signedCommitsMarker = "<!-- signed-commits -->"
+
+	// defaultCommentUnverifiedCommits is used when the comment_unverified_commits is not configured
+	defaultCommentUnverifiedCommits = "### Signed Commits Guide  \n\nThis repository requires the commits to be " +
+		"signed by a GPG or S/MIME signature verified by the platform, but the following commits are not:  \n\n%s\n" +
+		"Please add your signing key to your account, sign the commits by `git rebase --exec 'git commit --amend " +
+		"--no-edit -S' HEAD~<the number of the commits>`, and push them again."
+	// defaultCommentVerifiedCommits is used when the comment_verified_commits is not configured
+	defaultCommentVerifiedCommits = "### Signed Commits Pass  \n\nAll the commits are signed by verified signatures. :wave:"
+
+	// the results of the require_signed_commits, which is unknown if any commit can not be checked
+	signedCommitsVerified   = "verified"
+	signedCommitsUnverified = "unverified"
+	signedCommitsUnknown    = "unknown"
+
+	// commitVerificationCacheTTL is how long the verification of a commit is cached. The verification of a sha
+	// never changes, the TTL only frees the commits of the pull requests merged long ago
+	commitVerificationCacheTTL = 24 * time.Hour
+)
+
+func (c *repoConfig) signedCommitsLabelYes() string {
+	if c.SignedCommitsLabelYes == "" {
+		return defaultSignedCommitsLabelYes
+	}
+	return c.SignedCommitsLabelYes
+}
+
+func (c *repoConfig) signedCommitsLabelNo() string {
+	if c.SignedCommitsLabelNo == "" {
+		return defaultSignedCommitsLabelNo
+	}
+	return c.SignedCommitsLabelNo
+}
+
+// unverifiedCommit is a commit which is not signed by a verified signature
+type unverifiedCommit struct {
+	sha    string
+	author string
+}
+
+// checkSignedCommits checks that every commit of the pull request is signed by a verified signature, except
+// those of the exempt accounts, and labels and comments the result. Nothing is changed if any commit can not
+// be checked, since the check of the next event will tell
+func (bot *robot) checkSignedCommits(pr *prSnapshot, repoCnf *repoConfig, logger *logrus.Entry) {
+	pr.signedCommits = signedCommitsUnknown
+	messages, success := pr.getCommitMessages()
+	if !success || len(messages) == 0 {
+		return
+	}
+
+	var unverified []unverifiedCommit
+	for i := range messages {
+		if repoCnf.isExemptContributor(messages[i].AuthorName, messages[i].AuthorEmail) {
+			continue
+		}
+		if pr.watchdog.tripped() {
+			return
+		}
+
+		verified, success := bot.commitVerified(pr, messages[i].SHA)
+		if !success {
+			logger.Warningf("failed to get the verification of the commit %s of %s", messages[i].SHA, pr.key())
+			return
+		}
+		if !verified {
+			unverified = append(unverified, unverifiedCommit{sha: messages[i].SHA, author: messages[i].AuthorName})
+		}
+	}
+	logger.Infof("%d of %d commits of %s are not signed by verified signatures", len(unverified), len(messages),
+		pr.key())
+
+	label, other := repoCnf.signedCommitsLabelYes(), repoCnf.signedCommitsLabelNo()
+	pr.signedCommits = signedCommitsVerified
+	if len(unverified) != 0 {
+		label, other = other, label
+		pr.signedCommits = signedCommitsUnverified
+	}
+	bot.applySignedCommitsLabel(pr, repoCnf, label, other)
+
+	if !bot.botLoginKnown(pr) {
+		return
+	}
+	ids := bot.signedCommitsCommentIDs(pr)
+	r := bot.renderer(pr)
+	switch {
+	case len(unverified) != 0:
+		bot.replaceResultComments(pr, repoCnf, ids, r.unverifiedCommits(unverified)+"\n\n"+signedCommitsMarker)
+	case len(ids) != 0:
+		// the pass is commented only to resolve the guide posted before
+		bot.replaceResultComments(pr, repoCnf, ids, r.verifiedCommits()+"\n\n"+signedCommitsMarker)
+	}
+}
+
+// commitVerified checks whether the commit is signed by a verified signature, reusing the verification cached
+// by the sha
+func (bot *robot) commitVerified(pr *prSnapshot, sha string) (bool, bool) {
+	key := pr.org + "/" + pr.repo + "@" + sha
+	if bot.verifications != nil {
+		if v, ok := bot.verifications.get(key); ok {
+			return v == signedCommitsVerified, true
+		}
+	}
+
+	pr.watchdog.countAPICall()
+	verified, success := bot.cli.GetCommitVerificationStatus(pr.org, pr.repo, sha)
+	if success && bot.verifications != nil {
+		v := signedCommitsUnverified
+		if verified {
+			v = signedCommitsVerified
+		}
+		bot.verifications.put(key, v, commitVerificationCacheTTL)
+	}
+	return verified, success
+}
+
+// recordSignedCommits saves the result of the require_signed_commits of the evaluation skipped by an override,
+// together with the head it is checked at
+func (bot *robot) recordSignedCommits(pr *prSnapshot) {
+	if bot.store == nil || pr.signedCommits == "" {
+		return
+	}
+
+	s, _ := bot.store.get(pr.org, pr.repo, pr.number)
+	s.Org, s.Repo, s.Number = pr.org, pr.repo, pr.number
+	s.SignedCommits = pr.signedCommits
+	if sha := pr.fetchedHeadSHA(); sha != "" {
+		s.HeadSHA = sha
+	}
+	bot.store.put(s)
+}
+
+// applySignedCommitsLabel replaces the label of the other result by the one of the result. The labels are
+// left untouched under the report enforcement level
+func (bot *robot) applySignedCommitsLabel(pr *prSnapshot, repoCnf *repoConfig, label, other string) {
+	if !repoCnf.enforces(enforcementLabel) {
+		return
+	}
+
+	prLabels, _ := pr.getLabels()
+	if other = bot.labelName(pr, other); slices.Contains(prLabels, other) {
+		bot.removePRLabels(pr, []string{other})
+	}
+	if label = bot.labelName(pr, label); !slices.Contains(prLabels, label) {
+		bot.addPRLabels(pr, []string{label})
+	}
+}
+
+// signedCommitsCommentIDs lists the comments of the signed commits on the pull request, from the oldest
+func (bot *robot) signedCommitsCommentIDs(pr *prSnapshot) []string {
+	comments, success := pr.getComments()
+	if !success {
+		return nil
+	}
+
+	login := bot.botLogin()
+	var ids []string
+	for i := range comments {
+		if login != "" && comments[i].Author == login && strings.Contains(comments[i].Body, signedCommitsMarker) {
+			ids = append(ids, comments[i].ID)
+		}
+	}
+	return ids
+}
+
+// unverifiedCommits renders the comment listing the commits which are not signed by verified signatures
+func (r *commentRenderer) unverifiedCommits(commits []unverifiedCommit) string {
+	var b strings.Builder
+	for _, c := range commits {
+		sha := c.sha
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		fmt.Fprintf(&b, "- `%s` by %s\n", sha, escapeMarkdownName(c.author))
+	}
+
+	data := r.data(nil)
+	data.Commits = b.String()
+	return r.render(r.format(r.cnf.CommentUnverifiedCommits, defaultCommentUnverifiedCommits), data,
+		func(s string) string { return fmt.Sprintf(s, data.Commits) })
+}
+
+func (r *commentRenderer) verifiedCommits() string {
+	return r.render(r.format(r.cnf.CommentVerifiedCommits, defaultCommentVerifiedCommits), r.data(nil),
+		func(s string) string { return s })
+}
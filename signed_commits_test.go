@@ -0,0 +1,105 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckSignedCommits(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestCommitMessages: true,
+		successfulGetCommitVerificationStatus:  true,
+		successfulAddPRLabels:                  true,
+		successfulRemovePRLabels:               true,
+		successfulCreatePRComment:              true,
+		successfulUpdatePRComment:              true,
+		successfulGetPullRequestLabels:         true,
+		successfulListPullRequestComments:      true,
+		labels:                                 []string{defaultSignedCommitsLabelYes},
+		unverifiedCommits:                      []string{"0123456789", "fedcba987"},
+		commitMessages: []prCommitMessage{
+			{PRCommit: client.PRCommit{AuthorName: "u1", AuthorEmail: "u1@example.com"}, SHA: "abcdef123"},
+			{PRCommit: client.PRCommit{AuthorName: "u2", AuthorEmail: "u2@example.com"}, SHA: "0123456789"},
+			{PRCommit: client.PRCommit{AuthorName: "bot", AuthorEmail: "bot@example.com"}, SHA: "fedcba987"},
+		},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	repoCnf := &repoConfig{RequireSignedCommits: true, ExemptUsers: []string{"bot"}}
+	logger := logrus.NewEntry(logrus.New())
+
+	// the unverified commits are listed, except those of the exempt accounts
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.checkSignedCommits(pr, repoCnf, logger)
+	assert.Equal(t, []string{defaultSignedCommitsLabelNo}, pr.stats.labelsAdded)
+	assert.Equal(t, []string{defaultSignedCommitsLabelYes}, pr.stats.labelsRemoved)
+	assert.Equal(t, "CreatePRComment", mc.method)
+	assert.Contains(t, mc.comment, "- `0123456` by u2\n")
+	assert.NotContains(t, mc.comment, "abcdef1")
+	assert.NotContains(t, mc.comment, "fedcba9")
+	assert.Contains(t, mc.comment, signedCommitsMarker)
+	assert.Equal(t, signedCommitsUnverified, pr.signedCommits)
+
+	// the guide is resolved once all the commits are verified
+	mc.unverifiedCommits, mc.labels, mc.comment = nil, []string{defaultSignedCommitsLabelNo}, ""
+	mc.prComments = []client.PRComment{{ID: "c1", Body: "guide\n\n" + signedCommitsMarker}, {ID: "c2", Body: "cla"}}
+	pr = newPRSnapshot(mc, org, repo, number)
+	bot.checkSignedCommits(pr, repoCnf, logger)
+	assert.Equal(t, []string{defaultSignedCommitsLabelYes}, pr.stats.labelsAdded)
+	assert.Contains(t, mc.updatedComments["c1"], "All the commits are signed by verified signatures")
+	assert.Equal(t, "", mc.comment)
+	assert.Equal(t, signedCommitsVerified, pr.signedCommits)
+
+	// nothing is changed if a commit can not be checked
+	mc.successfulGetCommitVerificationStatus, mc.updatedComments = false, nil
+	pr = newPRSnapshot(mc, org, repo, number)
+	bot.checkSignedCommits(pr, repoCnf, logger)
+	assert.Equal(t, 0, len(pr.stats.labelsAdded))
+	assert.Equal(t, 0, len(mc.updatedComments))
+	assert.Equal(t, signedCommitsUnknown, pr.signedCommits)
+}
+
+func TestCheckSignedCommitsCached(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestCommitMessages: true,
+		successfulGetCommitVerificationStatus:  true,
+		successfulAddPRLabels:                  true,
+		successfulRemovePRLabels:               true,
+		successfulCreatePRComment:              true,
+		successfulGetPullRequestLabels:         true,
+		successfulListPullRequestComments:      true,
+		unverifiedCommits:                      []string{"0123456789"},
+		commitMessages: []prCommitMessage{
+			{PRCommit: client.PRCommit{AuthorName: "u1", AuthorEmail: "u1@example.com"}, SHA: "abcdef123"},
+			{PRCommit: client.PRCommit{AuthorName: "u2", AuthorEmail: "u2@example.com"}, SHA: "0123456789"},
+		},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{}, verifications: newCLAResultCache(0)}
+	repoCnf := &repoConfig{RequireSignedCommits: true}
+	logger := logrus.NewEntry(logrus.New())
+
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.checkSignedCommits(pr, repoCnf, logger)
+	assert.Equal(t, signedCommitsUnverified, pr.signedCommits)
+	assert.Equal(t, 2, bot.verifications.len())
+
+	// the verifications of the shas are reused without the platform
+	mc.successfulGetCommitVerificationStatus, mc.unverifiedCommits = false, nil
+	pr = newPRSnapshot(mc, org, repo, number)
+	bot.checkSignedCommits(pr, repoCnf, logger)
+	assert.Equal(t, signedCommitsUnverified, pr.signedCommits)
+}
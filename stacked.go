@@ -0,0 +1,137 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+	"time"
+)
+
+// sameHeadReuseWindow bounds the age of the unsigned result of the same head which is reused.
+// The signed results are reused whatever their age, since a signature is not expected to be revoked
+const sameHeadReuseWindow = time.Minute
+
+// resultScope identifies the options of the repository which change the result of the same commits, so that
+// a result is only shared with the evaluations of the same scope
+func (c *repoConfig) resultScope() string {
+	users, emails := slices.Clone(c.ExemptUsers), make([]string, len(c.ExemptEmails))
+	for i := range c.ExemptEmails {
+		emails[i] = strings.ToLower(c.ExemptEmails[i])
+	}
+	slices.Sort(users)
+	slices.Sort(emails)
+
+	digest := sha256.Sum256([]byte(fmt.Sprintf("%t\x00%q\x00%q\x00%s\x00%s", c.CheckByCommitter, users, emails,
+		c.ExemptionFile, c.ExemptionRef)))
+	return hex.EncodeToString(digest[:8])
+}
+
+// evaluatedBase returns the base branch of the pull request, which is the one recorded by the latest evaluation
+// if the snapshot has no event, such as for the rechecks
+func (bot *robot) evaluatedBase(pr *prSnapshot) string {
+	if pr.base != "" || bot.store == nil {
+		return pr.base
+	}
+
+	s, _ := bot.store.get(pr.org, pr.repo, pr.number)
+	return s.Base
+}
+
+// sameHeadState returns the latest full evaluation of the other open pull requests of the repository at the same
+// head sha, which are into the same base and evaluated in the same scope
+func (bot *robot) sameHeadState(pr *prSnapshot, sha, base, scope string) (latest prState, found bool) {
+	for _, s := range bot.store.listByHeadSHA(pr.org, pr.repo, sha) {
+		if s.Number == pr.number || s.Base != base || s.Scope != scope || s.Since != "" {
+			continue
+		}
+		if !found || s.LastEvaluation.After(latest.LastEvaluation) {
+			latest, found = s, true
+		}
+	}
+	return
+}
+
+// reuseSameHeadResult returns the result of the other pull request at the same head, such as the stacked or
+// duplicated ones, instead of querying the CLA server again. The result is shared only between the pull requests
+// into the same base and evaluated in the same resultScope. The unknown results are never reused,
+// and neither are the results for the `/cla recheck`
+func (bot *robot) reuseSameHeadResult(pr *prSnapshot, repoCnf *repoConfig) (allSigned bool, signResult [3][]string, ok bool) {
+	if !repoCnf.ShareSameHeadResult || bot.store == nil || pr.recheck || repoCnf.since != "" {
+		return
+	}
+
+	sha, found := pr.headSHA()
+	if !found {
+		return
+	}
+
+	s, found := bot.sameHeadState(pr, sha, bot.evaluatedBase(pr), repoCnf.resultScope())
+	if !found {
+		return
+	}
+
+	switch s.Status {
+	case prStatusSigned:
+		return true, [3][]string{s.SignedUsers}, true
+	case prStatusUnsigned:
+		if time.Since(s.LastEvaluation) <= sameHeadReuseWindow {
+			pr.unsignedEmails, pr.unsignedUserEmails = s.UnsignedEmails, s.UnsignedUserEmails
+			return false, [3][]string{nil, s.UnsignedUsers}, true
+		}
+	}
+	return
+}
+
+// syncSameHeadPRs rechecks the other open pull requests at the same head, into the same base and in the same
+// scope, whose result differs from the one just recorded, so that their labels are kept in sync. They reuse the
+// recorded result. The rechecks run after the handlings of the events of those pull requests
+func (bot *robot) syncSameHeadPRs(pr *prSnapshot, repoCnf *repoConfig, logger *logrus.Entry) {
+	if !repoCnf.ShareSameHeadResult || bot.store == nil || repoCnf.since != "" {
+		return
+	}
+
+	current, ok := bot.store.get(pr.org, pr.repo, pr.number)
+	if !ok || current.HeadSHA == "" || current.Status == prStatusUnknown || current.Since != "" {
+		return
+	}
+
+	for _, s := range bot.sameHeadPRs(&current) {
+		cnf := bot.getRepoConfig(s.Org, s.Repo)
+		if cnf == nil {
+			continue
+		}
+
+		logger.Infof("sync the result of %s to %s at the same head", pr.key(), s.key())
+		// the recheck waits for the handlings of the other pull request, which may be syncing this one
+		go bot.recheckSerialized(newPRSnapshot(bot.cli, s.Org, s.Repo, s.Number).withActor(auditActorSameHeadPRs), cnf,
+			logger.WithField("same-head-pr", s.key()))
+	}
+}
+
+// sameHeadPRs lists the other open pull requests sharing the result of the current one, whose status differs
+func (bot *robot) sameHeadPRs(current *prState) []prState {
+	var prs []prState
+	for _, s := range bot.store.listByHeadSHA(current.Org, current.Repo, current.HeadSHA) {
+		if s.Number != current.Number && s.Status != current.Status && s.Base == current.Base &&
+			s.Scope == current.Scope {
+			prs = append(prs, s)
+		}
+	}
+	return prs
+}
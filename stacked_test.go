@@ -0,0 +1,139 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/server-common-lib/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// countingCLAClient counts the checks sent to the CLA server
+type countingCLAClient struct {
+	*mockClient
+	checks int
+}
+
+func (c *countingCLAClient) CheckCLASignature(urlStr string) (string, bool) {
+	c.checks++
+	return c.mockClient.CheckCLASignature(urlStr)
+}
+
+func TestShareSameHeadResult(t *testing.T) {
+	mc := &countingCLAClient{mockClient: &mockClient{
+		successfulGetPullRequestCommitMessages: true,
+		successfulGetPullRequestLabels:         true,
+		successfulAddPRLabels:                  true,
+		successfulRemovePRLabels:               true,
+		successfulCheckCLASignature:            true,
+		CLAState:                               client.CLASignStateYes,
+		commitMessages: []prCommitMessage{{
+			PRCommit: client.PRCommit{AuthorName: "jane", AuthorEmail: "jane@example.com"},
+			SHA:      "sha1",
+		}},
+	}}
+	repoCnf := &repoConfig{RepoFilter: config.RepoFilter{Repos: []string{org}}, CLALabelYes: labelYes,
+		CLALabelNo: labelNo, CheckURL: "url", ShareSameHeadResult: true}
+	bot := &robot{cli: mc, cnf: &configuration{ConfigItems: []repoConfig{*repoCnf}}, store: newMemoryStateStore()}
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	scope := repoCnf.resultScope()
+
+	// the pull request 2 was found unsigned long ago at the same head
+	bot.store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusUnsigned, HeadSHA: "sha1",
+		Scope: scope, UnsignedUsers: []string{"jane"}, LastEvaluation: time.Now().Add(-time.Hour)})
+	// neither the closed one, nor the one into another base, nor the one checked in another scope is synced
+	bot.store.put(prState{Org: org, Repo: repo, Number: "4", Status: prStatusUnsigned, HeadSHA: "sha1",
+		Scope: scope, Closed: true})
+	bot.store.put(prState{Org: org, Repo: repo, Number: "5", Status: prStatusUnsigned, HeadSHA: "sha1",
+		Scope: scope, Base: "release"})
+	bot.store.put(prState{Org: org, Repo: repo, Number: "6", Status: prStatusUnsigned, HeadSHA: "sha1",
+		Scope: repoCnf.withCheckScope(checkScopeCommitters).resultScope()})
+
+	bot.checkIfAllSignedCLA(newPRSnapshot(mc, org, repo, number), repoCnf, logger)
+	assert.Equal(t, 1, mc.checks)
+	s, _ := bot.store.get(org, repo, number)
+	assert.Equal(t, "sha1", s.HeadSHA)
+	assert.Equal(t, prStatusSigned, s.Status)
+
+	// the pull request 2 is synced by the signed result without querying the CLA server again
+	assert.Eventually(t, func() bool {
+		s, _ = bot.store.get(org, repo, "2")
+		return s.Status == prStatusSigned
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"jane"}, s.SignedUsers)
+	for _, n := range []string{"4", "5", "6"} {
+		s, _ = bot.store.get(org, repo, n)
+		assert.Equal(t, prStatusUnsigned, s.Status, n)
+	}
+
+	// the pull request 3 at the same head reuses the result
+	bot.checkIfAllSignedCLA(newPRSnapshot(mc, org, repo, "3"), repoCnf, logger)
+	assert.Equal(t, 1, mc.checks)
+
+	// the recheck bypasses the shared result
+	pr := newPRSnapshot(mc, org, repo, "3")
+	pr.recheck = true
+	_, _, reused := bot.reuseSameHeadResult(pr, repoCnf)
+	assert.Equal(t, false, reused)
+}
+
+func TestReuseSameHeadUnsignedResult(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestCommitMessages: true,
+		commitMessages:                         []prCommitMessage{{SHA: "sha1"}},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{}, store: newMemoryStateStore()}
+	repoCnf := &repoConfig{ShareSameHeadResult: true}
+
+	bot.store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusUnsigned, HeadSHA: "sha1",
+		Scope: repoCnf.resultScope(), UnsignedUsers: []string{"jane"}, LastEvaluation: time.Now()})
+	allSigned, signResult, reused := bot.reuseSameHeadResult(newPRSnapshot(mc, org, repo, number), repoCnf)
+	assert.Equal(t, true, reused)
+	assert.Equal(t, false, allSigned)
+	assert.Equal(t, []string{"jane"}, signResult[1])
+
+	// the outdated unsigned result and the unknown result are not reused
+	bot.store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusUnsigned, HeadSHA: "sha1",
+		Scope: repoCnf.resultScope(), LastEvaluation: time.Now().Add(-2 * sameHeadReuseWindow)})
+	_, _, reused = bot.reuseSameHeadResult(newPRSnapshot(mc, org, repo, number), repoCnf)
+	assert.Equal(t, false, reused)
+
+	bot.store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusUnknown, HeadSHA: "sha1",
+		Scope: repoCnf.resultScope(), LastEvaluation: time.Now()})
+	_, _, reused = bot.reuseSameHeadResult(newPRSnapshot(mc, org, repo, number), repoCnf)
+	assert.Equal(t, false, reused)
+
+	// the result into another base, or in another scope, is not reused
+	bot.store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusSigned, HeadSHA: "sha1",
+		Scope: repoCnf.resultScope(), Base: "release", LastEvaluation: time.Now()})
+	_, _, reused = bot.reuseSameHeadResult(newPRSnapshot(mc, org, repo, number), repoCnf)
+	assert.Equal(t, false, reused)
+
+	bot.store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusSigned, HeadSHA: "sha1",
+		Scope: repoCnf.resultScope(), LastEvaluation: time.Now()})
+	_, _, reused = bot.reuseSameHeadResult(newPRSnapshot(mc, org, repo, number), repoCnf)
+	assert.Equal(t, true, reused)
+	_, _, reused = bot.reuseSameHeadResult(newPRSnapshot(mc, org, repo, number),
+		&repoConfig{ShareSameHeadResult: true, ExemptUsers: []string{"bot"}})
+	assert.Equal(t, false, reused)
+
+	// the partial result of the `/check-cla --since` is not reused
+	bot.store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusSigned, HeadSHA: "sha1",
+		Scope: repoCnf.resultScope(), Since: "sha0", LastEvaluation: time.Now()})
+	_, _, reused = bot.reuseSameHeadResult(newPRSnapshot(mc, org, repo, number), repoCnf)
+	assert.Equal(t, false, reused)
+}
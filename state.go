@@ -0,0 +1,413 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// stateSnapshotVersion is the version of the snapshot format produced by exportSnapshot.
+	// It must be increased whenever the layout of prState or stateSnapshot changes incompatibly
+	stateSnapshotVersion = 1
+
+	prStatusSigned   = "signed"
+	prStatusUnsigned = "unsigned"
+	prStatusUnknown  = "unknown"
+
+	// prEventStateClosed and prEventStateMerged are the states of the pull request in the webhook event
+	// which is no longer open
+	prEventStateClosed = "closed"
+	prEventStateMerged = "merged"
+)
+
+// prState is the context the bot keeps for a pull request after evaluating its CLA
+type prState struct {
+	Org           string   `json:"org"`
+	Repo          string   `json:"repo"`
+	Number        string   `json:"number"`
+	Status        string   `json:"status"`
+	SignedUsers   []string `json:"signed_users,omitempty"`
+	UnsignedUsers []string `json:"unsigned_users,omitempty"`
+	UnknownUsers  []string `json:"unknown_users,omitempty"`
+	// CommentIDs are the ids of the comments of the CLA result on the pull request, as far as they are known
+	CommentIDs     []string  `json:"comment_ids,omitempty"`
+	LastEvaluation time.Time `json:"last_evaluation"`
+	// LastEventTime is the time of the latest webhook event which triggered an evaluation
+	LastEventTime time.Time `json:"last_event_time,omitempty"`
+	// Head is the head of the pull request in the latest webhook event
+	Head string `json:"head,omitempty"`
+	// HeadSHA is the sha of the head commit in the latest evaluation, if it was fetched
+	HeadSHA string `json:"head_sha,omitempty"`
+	// SignedCommits is the result of the require_signed_commits in the latest evaluation, if it is required
+	SignedCommits string `json:"signed_commits,omitempty"`
+	// Base is the base branch of the pull request in the latest webhook event
+	Base string `json:"base,omitempty"`
+	// Scope is the resultScope of the repository in the latest evaluation. The results of the same head are
+	// only shared between the pull requests of the same Base and Scope
+	Scope string `json:"scope,omitempty"`
+	// Since is the sha the latest evaluation checked the commits after, by the `/check-cla --since`. Such a
+	// result is partial and is never shared with the other pull requests
+	Since string `json:"since,omitempty"`
+	// UnsignedEmails are the distinct emails of the UnsignedUsers, in lower case
+	UnsignedEmails []string `json:"unsigned_emails,omitempty"`
+	// UnsignedUserEmails maps each of the UnsignedUsers to the email in lower case
+	UnsignedUserEmails map[string]string `json:"unsigned_user_emails,omitempty"`
+	// CommentCount is the number of the comments posted by the bot on the pull request
+	CommentCount int `json:"comment_count,omitempty"`
+	// EmailsSent is the number of the emails sent to the unsigned contributors of the pull request
+	EmailsSent int `json:"emails_sent,omitempty"`
+	// History is the latest evaluations of the pull request, the oldest first
+	History []prEvaluation `json:"history,omitempty"`
+	// Override is the override of the CLA check which expires, during which the pull request is not checked
+	Override *claOverride `json:"override,omitempty"`
+	// Closed is set once the pull request is closed or merged. The state is kept for the history, but the pull
+	// request is no longer blocked by the CLA
+	Closed bool `json:"closed,omitempty"`
+}
+
+// maxPRHistory is the max number of the evaluations kept in the history of a pull request
+const maxPRHistory = 20
+
+// prEvaluation is an entry of the history of a pull request
+type prEvaluation struct {
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+	Head   string    `json:"head,omitempty"`
+}
+
+// appendHistory appends the current evaluation to the history, dropping the oldest ones beyond maxPRHistory
+func (s *prState) appendHistory(history []prEvaluation) {
+	// the history is shared with the one in the store, so it is clipped to be copied when appending
+	history = append(slices.Clip(history), prEvaluation{Time: s.LastEvaluation, Status: s.Status, Head: s.Head})
+	if n := len(history); n > maxPRHistory {
+		history = history[n-maxPRHistory:]
+	}
+	s.History = history
+}
+
+func (s *prState) key() string {
+	return prKey(s.Org, s.Repo, s.Number)
+}
+
+func prKey(org, repo, number string) string {
+	return org + "/" + repo + "/" + number
+}
+
+// contributorState is the milestones of a contributor of an organization
+type contributorState struct {
+	Org  string `json:"org"`
+	User string `json:"user"`
+	// FirstSeen is when the contributor showed up in an evaluation of the organization the first time
+	FirstSeen time.Time `json:"first_seen"`
+	// FirstSigned is when the contributor passed the CLA check the first time, it is zero until then
+	FirstSigned time.Time `json:"first_signed,omitempty"`
+	// FirstSignedPR is the key of the pull request of the first pass
+	FirstSignedPR string `json:"first_signed_pr,omitempty"`
+}
+
+func (c *contributorState) key() string {
+	return contributorKey(c.Org, c.User)
+}
+
+func contributorKey(org, user string) string {
+	return org + "/" + user
+}
+
+// stateSnapshot is a versioned copy of the whole state store, used to move state between instances
+type stateSnapshot struct {
+	Version      int                 `json:"version"`
+	ExportedAt   time.Time           `json:"exported_at"`
+	PRs          []prState           `json:"prs"`
+	Indexes      map[string][]string `json:"indexes,omitempty"`
+	Contributors []contributorState  `json:"contributors,omitempty"`
+	// Emailed maps each email notified of the CLA to when it was notified
+	Emailed map[string]time.Time `json:"emailed,omitempty"`
+}
+
+// stateStore keeps the per-PR context. Implementations must be safe for concurrent use
+type stateStore interface {
+	get(org, repo, number string) (prState, bool)
+	put(s prState)
+	remove(org, repo, number string)
+	// listByHeadSHA lists the states of the open pull requests of the repository whose head is the sha
+	listByHeadSHA(org, repo, sha string) []prState
+	// listUnsignedEmails maps the unsigned emails of the repository to the numbers of the open pull requests
+	// blocked by them
+	listUnsignedEmails(org, repo string) map[string][]string
+	// seeContributors records the first time each user shows up in the organization
+	seeContributors(org string, users []string, at time.Time)
+	// markFirstSigned records the first pass of each user in the organization, and returns the users
+	// passing the first time
+	markFirstSigned(org string, users []string, pr string, at time.Time) []string
+	// markEmailed records the email notified at the time, unless it was notified within the interval before.
+	// It returns whether the email is recorded
+	markEmailed(email string, at time.Time, interval time.Duration) bool
+	exportSnapshot() stateSnapshot
+	importSnapshot(snapshot stateSnapshot) error
+}
+
+// memoryStateStore is a stateStore held in the memory of the process
+type memoryStateStore struct {
+	mu  sync.RWMutex
+	prs map[string]prState
+	// unsignedIndex maps an unsigned user to the keys of the PRs blocked by the user
+	unsignedIndex map[string][]string
+	// unsignedEmailIndex maps an unsigned email to the keys of the PRs blocked by the email
+	unsignedEmailIndex map[string][]string
+	contributors       map[string]contributorState
+	// emailed maps an email to when it was notified
+	emailed map[string]time.Time
+}
+
+func newMemoryStateStore() *memoryStateStore {
+	return &memoryStateStore{
+		prs:                map[string]prState{},
+		unsignedIndex:      map[string][]string{},
+		unsignedEmailIndex: map[string][]string{},
+		contributors:       map[string]contributorState{},
+		emailed:            map[string]time.Time{},
+	}
+}
+
+func (m *memoryStateStore) get(org, repo, number string) (prState, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.prs[prKey(org, repo, number)]
+	return s, ok
+}
+
+func (m *memoryStateStore) put(s prState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.unindex(s.key())
+	m.prs[s.key()] = s
+	m.index(s)
+}
+
+func (m *memoryStateStore) remove(org, repo, number string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := prKey(org, repo, number)
+	m.unindex(k)
+	delete(m.prs, k)
+}
+
+func (m *memoryStateStore) listByHeadSHA(org, repo, sha string) []prState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []prState
+	for _, s := range m.prs {
+		if s.HeadSHA == sha && s.Org == org && s.Repo == repo && !s.Closed {
+			result = append(result, s)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].key() < result[j].key() })
+	return result
+}
+
+func (m *memoryStateStore) listUnsignedEmails(org, repo string) map[string][]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	prefix := prKey(org, repo, "")
+	result := map[string][]string{}
+	for email, keys := range m.unsignedEmailIndex {
+		for _, k := range keys {
+			if number, ok := strings.CutPrefix(k, prefix); ok {
+				result[email] = append(result[email], number)
+			}
+		}
+		slices.Sort(result[email])
+	}
+	return result
+}
+
+func (m *memoryStateStore) seeContributors(org string, users []string, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range users {
+		m.seeContributor(org, user, at)
+	}
+}
+
+func (m *memoryStateStore) seeContributor(org, user string, at time.Time) contributorState {
+	k := contributorKey(org, user)
+	c, ok := m.contributors[k]
+	if !ok {
+		c = contributorState{Org: org, User: user, FirstSeen: at}
+		m.contributors[k] = c
+	}
+	return c
+}
+
+func (m *memoryStateStore) markFirstSigned(org string, users []string, pr string, at time.Time) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var first []string
+	for _, user := range users {
+		c := m.seeContributor(org, user, at)
+		if !c.FirstSigned.IsZero() {
+			continue
+		}
+		c.FirstSigned, c.FirstSignedPR = at, pr
+		m.contributors[c.key()] = c
+		first = append(first, user)
+	}
+	return first
+}
+
+func (m *memoryStateStore) markEmailed(email string, at time.Time, interval time.Duration) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the emails notified before the interval are forgotten
+	for k, t := range m.emailed {
+		if at.Sub(t) >= interval {
+			delete(m.emailed, k)
+		}
+	}
+	if _, ok := m.emailed[email]; ok {
+		return false
+	}
+	m.emailed[email] = at
+	return true
+}
+
+// index indexes the unsigned users and emails of the pull request, unless it is closed and blocked no more
+func (m *memoryStateStore) index(s prState) {
+	if s.Closed {
+		return
+	}
+
+	addToIndex(m.unsignedIndex, s.UnsignedUsers, s.key())
+	addToIndex(m.unsignedEmailIndex, s.UnsignedEmails, s.key())
+}
+
+func (m *memoryStateStore) unindex(k string) {
+	old, ok := m.prs[k]
+	if !ok {
+		return
+	}
+
+	removeFromIndex(m.unsignedIndex, old.UnsignedUsers, k)
+	removeFromIndex(m.unsignedEmailIndex, old.UnsignedEmails, k)
+}
+
+func addToIndex(index map[string][]string, values []string, k string) {
+	for _, v := range values {
+		if !slices.Contains(index[v], k) {
+			index[v] = append(index[v], k)
+		}
+	}
+}
+
+func removeFromIndex(index map[string][]string, values []string, k string) {
+	for _, v := range values {
+		keys := slices.DeleteFunc(index[v], func(key string) bool { return key == k })
+		if len(keys) == 0 {
+			delete(index, v)
+		} else {
+			index[v] = keys
+		}
+	}
+}
+
+func (m *memoryStateStore) exportSnapshot() stateSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := stateSnapshot{
+		Version:    stateSnapshotVersion,
+		ExportedAt: time.Now().UTC(),
+		PRs:        make([]prState, 0, len(m.prs)),
+		Indexes:    make(map[string][]string, len(m.unsignedIndex)),
+	}
+	for _, s := range m.prs {
+		snapshot.PRs = append(snapshot.PRs, s)
+	}
+	sort.Slice(snapshot.PRs, func(i, j int) bool { return snapshot.PRs[i].key() < snapshot.PRs[j].key() })
+
+	for _, c := range m.contributors {
+		snapshot.Contributors = append(snapshot.Contributors, c)
+	}
+	sort.Slice(snapshot.Contributors, func(i, j int) bool {
+		return snapshot.Contributors[i].key() < snapshot.Contributors[j].key()
+	})
+
+	for user, keys := range m.unsignedIndex {
+		snapshot.Indexes[user] = slices.Clone(keys)
+		slices.Sort(snapshot.Indexes[user])
+	}
+
+	if len(m.emailed) != 0 {
+		snapshot.Emailed = maps.Clone(m.emailed)
+	}
+
+	return snapshot
+}
+
+// importSnapshot replaces the content of the store with the snapshot.
+// The indexes in the snapshot are informative only, they are rebuilt from the PR states
+func (m *memoryStateStore) importSnapshot(snapshot stateSnapshot) error {
+	if snapshot.Version != stateSnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d, expect %d", snapshot.Version, stateSnapshotVersion)
+	}
+
+	prs := make(map[string]prState, len(snapshot.PRs))
+	for i := range snapshot.PRs {
+		s := snapshot.PRs[i]
+		if s.Org == "" || s.Repo == "" || s.Number == "" {
+			return errors.New("the org, repo and number of a pull request state can not be empty")
+		}
+		prs[s.key()] = s
+	}
+
+	contributors := make(map[string]contributorState, len(snapshot.Contributors))
+	for _, c := range snapshot.Contributors {
+		if c.Org == "" || c.User == "" {
+			return errors.New("the org and user of a contributor state can not be empty")
+		}
+		contributors[c.key()] = c
+	}
+
+	emailed := maps.Clone(snapshot.Emailed)
+	if emailed == nil {
+		emailed = map[string]time.Time{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prs, m.contributors, m.emailed = prs, contributors, emailed
+	m.unsignedIndex, m.unsignedEmailIndex = map[string][]string{}, map[string][]string{}
+	for _, s := range m.prs {
+		m.index(s)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemoryStateStore(t *testing.T) {
+	store := newMemoryStateStore()
+	store.put(prState{Org: org, Repo: repo, Number: number, Status: prStatusUnsigned, UnsignedUsers: []string{"u1"}})
+	store.put(prState{Org: org, Repo: repo, Number: "2", Status: prStatusUnsigned, UnsignedUsers: []string{"u1", "u2"}})
+
+	got, ok := store.get(org, repo, number)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, prStatusUnsigned, got.Status)
+
+	snapshot := store.exportSnapshot()
+	assert.Equal(t, stateSnapshotVersion, snapshot.Version)
+	assert.Equal(t, 2, len(snapshot.PRs))
+	assert.Equal(t, []string{"org1/repo1/1", "org1/repo1/2"}, snapshot.Indexes["u1"])
+
+	// the user signed, the PR is not blocked by the user any more
+	store.put(prState{Org: org, Repo: repo, Number: number, Status: prStatusSigned, SignedUsers: []string{"u1"}})
+	assert.Equal(t, []string{"org1/repo1/2"}, store.exportSnapshot().Indexes["u1"])
+
+	// the closed PR is not blocked by its unsigned users and emails
+	store.put(prState{Org: org, Repo: repo, Number: "3", Status: prStatusUnsigned, UnsignedUsers: []string{"u3"},
+		UnsignedEmails: []string{"u3@example.com"}})
+	assert.Equal(t, map[string][]string{"u3@example.com": {"3"}}, store.listUnsignedEmails(org, repo))
+	closed, _ := store.get(org, repo, "3")
+	closed.Closed = true
+	store.put(closed)
+	assert.Empty(t, store.listUnsignedEmails(org, repo))
+	assert.Empty(t, store.exportSnapshot().Indexes["u3"])
+	store.remove(org, repo, "3")
+
+	store.remove(org, repo, "2")
+	assert.Equal(t, 0, len(store.exportSnapshot().Indexes))
+
+	now := time.Now()
+	assert.Equal(t, true, store.markEmailed("u1@example.com", now, time.Hour))
+	assert.Equal(t, false, store.markEmailed("u1@example.com", now.Add(time.Minute), time.Hour))
+	snapshot = store.exportSnapshot()
+
+	another := newMemoryStateStore()
+	assert.Equal(t, nil, another.importSnapshot(snapshot))
+	assert.Equal(t, snapshot.Indexes, another.exportSnapshot().Indexes)
+	// the notified emails are moved together
+	assert.Equal(t, false, another.markEmailed("u1@example.com", now.Add(time.Minute), time.Hour))
+	assert.Equal(t, true, another.markEmailed("u1@example.com", now.Add(time.Hour), time.Hour))
+
+	snapshot.Version = 0
+	assert.NotEqual(t, nil, another.importSnapshot(snapshot))
+	snapshot.Version = stateSnapshotVersion
+	snapshot.PRs = append(snapshot.PRs, prState{Org: org})
+	assert.NotEqual(t, nil, another.importSnapshot(snapshot))
+}
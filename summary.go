@@ -0,0 +1,165 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// the decisions of an evaluation in the summary, besides the prStatus
+const (
+	decisionNoCommits          = "no-commits"
+	decisionCommitsUnavailable = "commits-unavailable"
+	decisionStopped            = "stopped-by-watchdog"
+)
+
+// evaluationStats records what an evaluation did to the pull request, which is logged as its summary
+type evaluationStats struct {
+	start           time.Time
+	decision        string
+	signResult      [3][]string
+	labelsAdded     []string
+	labelsRemoved   []string
+	commentsPosted  int
+	commentsDeleted int
+	commentsUpdated int
+	commitStatus    string
+	writes          int
+}
+
+// labelsChanged checks whether the evaluation added or removed any label
+func (s *evaluationStats) labelsChanged() bool {
+	return len(s.labelsAdded) != 0 || len(s.labelsRemoved) != 0
+}
+
+func (bot *robot) addPRLabels(pr *prSnapshot, labels []string) bool {
+	// The label apis are not called while they keep failing
+	if !bot.labels.allow() {
+		return false
+	}
+
+	pr.stats.writes++
+	ok := bot.cli.AddPRLabels(pr.org, pr.repo, pr.number, labels)
+	bot.labels.record(ok)
+	bot.auditMutation(pr, auditActionLabelAdd, auditLabels(labels), ok)
+	if !ok {
+		return false
+	}
+	pr.stats.labelsAdded = append(pr.stats.labelsAdded, labels...)
+	return true
+}
+
+func (bot *robot) removePRLabels(pr *prSnapshot, labels []string) bool {
+	if !bot.labels.allow() {
+		return false
+	}
+
+	pr.stats.writes++
+	ok := bot.cli.RemovePRLabels(pr.org, pr.repo, pr.number, labels)
+	bot.labels.record(ok)
+	bot.auditMutation(pr, auditActionLabelRemove, auditLabels(labels), ok)
+	if !ok {
+		return false
+	}
+	pr.stats.labelsRemoved = append(pr.stats.labelsRemoved, labels...)
+	return true
+}
+
+func (bot *robot) postPRComment(pr *prSnapshot, comment string) bool {
+	comment, ok := bot.runBeforeComment(pr, comment)
+	if !ok {
+		return true
+	}
+	comment = bot.maskCommentEmails(pr, comment)
+
+	// The comment is held until the rate limit of the comments lifts, instead of being dropped
+	if until, limited := bot.commentRateLimited(); limited {
+		return bot.queueComment(pr, comment, until)
+	}
+
+	pr.stats.writes++
+	var commentID string
+	commentID, ok = bot.cli.CreatePRComment(pr.org, pr.repo, pr.number, comment)
+	if !ok {
+		if until, limited := bot.commentRateLimited(); limited {
+			return bot.queueComment(pr, comment, until)
+		}
+	}
+	bot.auditMutation(pr, auditActionCommentCreate, "", ok)
+	if !ok {
+		return false
+	}
+	pr.stats.commentsPosted++
+	pr.postedCommentID = commentID
+	return true
+}
+
+func (bot *robot) queueComment(pr *prSnapshot, comment string, until time.Time) bool {
+	ok := bot.comments.add(pr, comment, until)
+	bot.auditMutation(pr, auditActionCommentQueue, "", ok)
+	return ok
+}
+
+func (bot *robot) deletePRComment(pr *prSnapshot, commentID string) {
+	pr.stats.writes++
+	ok := bot.cli.DeletePRComment(pr.org, pr.repo, commentID)
+	bot.auditMutation(pr, auditActionCommentDelete, commentID, ok)
+	if ok {
+		pr.stats.commentsDeleted++
+	}
+}
+
+func (bot *robot) updatePRComment(pr *prSnapshot, commentID, comment string) bool {
+	comment, ok := bot.runBeforeComment(pr, comment)
+	if !ok {
+		return true
+	}
+	comment = bot.maskCommentEmails(pr, comment)
+
+	pr.stats.writes++
+	ok = bot.cli.UpdatePRComment(pr.org, pr.repo, commentID, comment)
+	bot.auditMutation(pr, auditActionCommentUpdate, commentID, ok)
+	if !ok {
+		return false
+	}
+	pr.stats.commentsUpdated++
+	return true
+}
+
+// logEvaluationSummary logs one entry with the complete decision of the evaluation
+func (bot *robot) logEvaluationSummary(pr *prSnapshot, logger *logrus.Entry) {
+	apiCalls, claLookups := pr.stats.writes, 0
+	if pr.watchdog != nil {
+		apiCalls += pr.watchdog.apiCalls
+		claLookups = pr.watchdog.claLookups
+	}
+
+	logger.WithFields(logrus.Fields{
+		"pr":               pr.key(),
+		logFieldVerdict:    pr.stats.decision,
+		"signed":           len(pr.stats.signResult[0]),
+		"unsigned":         len(pr.stats.signResult[1]),
+		"unknown":          len(pr.stats.signResult[2]),
+		"labels-added":     pr.stats.labelsAdded,
+		"labels-removed":   pr.stats.labelsRemoved,
+		"comments-posted":  pr.stats.commentsPosted,
+		"comments-deleted": pr.stats.commentsDeleted,
+		"comments-updated": pr.stats.commentsUpdated,
+		"commit-status":    pr.stats.commitStatus,
+		"duration-ms":      time.Since(pr.stats.start).Milliseconds(),
+		"api-calls":        apiCalls,
+		"cla-lookups":      claLookups,
+	}).Info("evaluation summary")
+}
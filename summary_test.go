@@ -0,0 +1,54 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLogEvaluationSummary(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestCommitMessages: true,
+		successfulGetPullRequestLabels:         true,
+		successfulAddPRLabels:                  true,
+		successfulRemovePRLabels:               true,
+		successfulCreatePRComment:              true,
+		successfulCheckCLASignature:            true,
+		successfulListPullRequestComments:      true,
+		CLAState:                               client.CLASignStateNo,
+		labels:                                 []string{labelYes},
+		commitMessages:                         []prCommitMessage{{PRCommit: client.PRCommit{AuthorName: "u1", AuthorEmail: "e1"}, SHA: "sha1"}},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{CommentSomeNeedSign: "%s %s %s"}}
+	logger, hook := test.NewNullLogger()
+
+	bot.checkIfAllSignedCLA(newPRSnapshot(mc, org, repo, number),
+		&repoConfig{CLALabelYes: labelYes, CLALabelNo: labelNo}, logrus.NewEntry(logger))
+	entry := hook.LastEntry()
+	assert.Equal(t, "evaluation summary", entry.Message)
+	assert.Equal(t, prStatusUnsigned, entry.Data[logFieldVerdict])
+	assert.Equal(t, number, entry.Data[logFieldNumber])
+	assert.NotEmpty(t, entry.Data[logFieldDeliveryID])
+	assert.Equal(t, 1, entry.Data["unsigned"])
+	assert.Equal(t, []string{labelNo}, entry.Data["labels-added"])
+	assert.Equal(t, []string{labelYes}, entry.Data["labels-removed"])
+	assert.Equal(t, 1, entry.Data["comments-posted"])
+	// the reads of the commits, labels and comments, and the writes of the labels and comment
+	assert.Equal(t, 6, entry.Data["api-calls"])
+	assert.Equal(t, 1, entry.Data["cla-lookups"])
+}
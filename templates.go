@@ -0,0 +1,267 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/opensourceways/server-common-lib/utils"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// templateRefPrefix marks a comment in the config which refers to a template in the templates file,
+	// such as `comment_all_signed: template:all_signed`
+	templateRefPrefix = "template:"
+	// templateReloadInterval is the min interval to check whether the templates file is changed
+	templateReloadInterval = 10 * time.Second
+)
+
+// commentFields returns the comments of the config, keyed by the field
+func (c *configuration) commentFields() map[string]string {
+	fields := map[string]string{
+		"comment_command_trigger":                    c.CommentCommandTrigger,
+		"comment_pr_no_commits":                      c.CommentPRNoCommits,
+		"comment_all_signed":                         c.CommentAllSigned,
+		"comment_some_need_sign":                     c.CommentSomeNeedSign,
+		"comment_update_label_failed":                c.CommentUpdateLabelFailed,
+		"comment_check_scope":                        c.CommentCheckScope,
+		"comment_org_recheck_done":                   c.CommentOrgRecheckDone,
+		"comment_some_need_sign_again":               c.CommentSomeNeedSignAgain,
+		"comment_max_comments_reached":               c.CommentMaxCommentsReached,
+		"comment_watchdog_exceeded":                  c.CommentWatchdogExceeded,
+		"comment_denied_email_domain":                c.CommentDeniedEmailDomain,
+		"comment_missing_email":                      c.CommentMissingEmail,
+		"comment_lite_pr_committer":                  c.CommentLitePRCommitter,
+		"comment_signed_note":                        c.CommentSignedNote,
+		"comment_unknown_note":                       c.CommentUnknownNote,
+		"comment_unknown_reason_cla_server":          c.CommentUnknownReasonCLAServer,
+		"comment_unknown_reason_missing_email":       c.CommentUnknownReasonMissingEmail,
+		"comment_unknown_reason_lite_pr_committer":   c.CommentUnknownReasonLitePRCommitter,
+		"comment_unknown_reason_denied_email_domain": c.CommentUnknownReasonDeniedDomain,
+		"comment_contributor_not_found":              c.CommentContributorNotFound,
+		"comment_account_email_mismatch":             c.CommentAccountEmailMismatch,
+		"comment_account_email_signed":               c.CommentAccountEmailSigned,
+		"comment_unverified_commits":                 c.CommentUnverifiedCommits,
+		"comment_verified_commits":                   c.CommentVerifiedCommits,
+		"comment_dco_unsigned":                       c.CommentDCOUnsigned,
+		"comment_dco_signed":                         c.CommentDCOSigned,
+		"comment_policy_unsigned":                    c.CommentPolicyUnsigned,
+		"comment_policy_signed":                      c.CommentPolicySigned,
+		"comment_first_signed":                       c.CommentFirstSigned,
+		"comment_corporate_signed":                   c.CommentCorporateSigned,
+		"comment_help":                               c.CommentHelp,
+		"comment_command_done":                       c.CommentCommandDone,
+		"comment_override":                           c.CommentOverride,
+		"comment_cla_reconfirm":                      c.CommentCLAReconfirm,
+		"comment_label_retry":                        c.CommentLabelRetry,
+		"comment_guide_resolved":                     c.CommentGuideResolved,
+		"misconfig_report.comment":                   c.MisconfigReport.Comment,
+		"command_rate_limit.comment":                 c.CommandRateLimit.Comment,
+	}
+	for i := range c.ConfigItems {
+		fields[fmt.Sprintf("config_items[%d].comment_command_trigger", i)] = c.ConfigItems[i].CommentCommandTrigger
+	}
+	return fields
+}
+
+// templateRefs returns the names of the templates referred to by the comments of the config, keyed by the field
+func (c *configuration) templateRefs() map[string]string {
+	refs := map[string]string{}
+	for field, v := range c.commentFields() {
+		if name, ok := strings.CutPrefix(v, templateRefPrefix); ok {
+			refs[field] = name
+		}
+	}
+	return refs
+}
+
+// validateCommentTemplates checks the comments of the config written as the Go text/template
+func (c *configuration) validateCommentTemplates() error {
+	fields := c.commentFields()
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	for _, field := range names {
+		if err := validateCommentTemplate(fields[field]); err != nil {
+			return fmt.Errorf("invalid template of the %s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// missingTemplates lists the fields referring to the templates which do not exist
+func missingTemplates(refs, templates map[string]string) []string {
+	var missing []string
+	for field, name := range refs {
+		if _, ok := templates[name]; !ok {
+			missing = append(missing, field)
+		}
+	}
+
+	sort.Strings(missing)
+	return missing
+}
+
+// readTemplates reads the templates from a yaml file mapping the names to the texts, or from all the
+// yaml files in a directory. It also returns the fingerprint of the files to detect the changes
+func readTemplates(path string) (map[string]string, string, error) {
+	files := []string{path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, "", err
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			if ext := filepath.Ext(entry.Name()); !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	templates := map[string]string{}
+	var fingerprint strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, "", err
+		}
+		fmt.Fprintf(&fingerprint, "%s@%d;", file, info.ModTime().UnixNano())
+
+		items := map[string]string{}
+		if err = utils.LoadFromYaml(file, &items); err != nil {
+			return nil, "", fmt.Errorf("load the templates file %s failed: %w", file, err)
+		}
+		for name, text := range items {
+			if _, ok := templates[name]; ok {
+				return nil, "", errors.New("the template " + name + " is duplicated in " + file)
+			}
+			if err = validateCommentTemplate(text); err != nil {
+				return nil, "", fmt.Errorf("invalid template %s in %s: %w", name, file, err)
+			}
+			templates[name] = text
+		}
+	}
+
+	return templates, fingerprint.String(), nil
+}
+
+// templateStore holds the templates read from the templates file, and reloads them when the file is changed.
+// A reload missing any template referred to by the config is rejected, and the previous templates are kept
+type templateStore struct {
+	mu          sync.Mutex
+	path        string
+	refs        map[string]string
+	templates   map[string]string
+	fingerprint string
+	checkedAt   time.Time
+	now         func() time.Time
+	log         *logrus.Entry
+}
+
+// newTemplateStore loads the templates and returns the fields referring to the missing templates
+func newTemplateStore(path string, refs map[string]string, logger *logrus.Entry) (*templateStore, []string, error) {
+	templates, fingerprint, err := readTemplates(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if missing := missingTemplates(refs, templates); len(missing) != 0 {
+		return nil, missing, errors.New("the templates referred to by the config are missing")
+	}
+
+	return &templateStore{
+		path:        path,
+		refs:        refs,
+		templates:   templates,
+		fingerprint: fingerprint,
+		checkedAt:   time.Now(),
+		now:         time.Now,
+		log:         logger,
+	}, nil, nil
+}
+
+func (s *templateStore) get(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.now().Sub(s.checkedAt) >= templateReloadInterval {
+		s.checkedAt = s.now()
+		s.reload()
+	}
+
+	text, ok := s.templates[name]
+	return text, ok
+}
+
+func (s *templateStore) reload() {
+	templates, fingerprint, err := readTemplates(s.path)
+	if err != nil {
+		s.log.WithError(err).Error("reload the templates failed, keep the previous ones")
+		return
+	}
+	if fingerprint == s.fingerprint {
+		return
+	}
+
+	if missing := missingTemplates(s.refs, templates); len(missing) != 0 {
+		s.log.Errorf("reject the reload of the templates, which misses the templates referred to by %v", missing)
+		return
+	}
+
+	s.templates, s.fingerprint = templates, fingerprint
+	s.log.Infof("reload %d templates from %s", len(templates), s.path)
+}
+
+// missing lists the fields of the refs referring to the templates which do not exist
+func (s *templateStore) missing(refs map[string]string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return missingTemplates(refs, s.templates)
+}
+
+// setRefs replaces the templates referred to by the config, which the reloads of the templates must have
+func (s *templateStore) setRefs(refs map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refs = refs
+}
+
+// template resolves the text of the config which may refer to a template in the templates file
+func (bot *robot) template(text string) string {
+	name, ok := strings.CutPrefix(text, templateRefPrefix)
+	if !ok || bot.templates == nil {
+		return text
+	}
+
+	if t, ok := bot.templates.get(name); ok {
+		return t
+	}
+	return text
+}
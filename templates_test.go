@@ -0,0 +1,61 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTemplateStore(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		assert.Equal(t, nil, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	write("pass.yaml", "all_signed: \"all signed: ddd\"\n")
+	write("fail.yml", "need_sign: \"%s need to sign\"\n")
+	write("README", "not a template")
+
+	cnf := &configuration{
+		CommentAllSigned:    "template:all_signed",
+		CommentSomeNeedSign: "template:need_sign",
+		ConfigItems:         []repoConfig{{CommentCommandTrigger: "template:trigger"}},
+	}
+	_, missing, err := newTemplateStore(dir, cnf.templateRefs(), framework.NewLogger())
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, []string{"config_items[0].comment_command_trigger"}, missing)
+
+	cnf.ConfigItems[0].CommentCommandTrigger = ""
+	store, _, err := newTemplateStore(dir, cnf.templateRefs(), framework.NewLogger())
+	assert.Equal(t, nil, err)
+	bot := &robot{cnf: cnf, templates: store}
+	assert.Equal(t, "all signed: ddd", bot.template(cnf.CommentAllSigned))
+	assert.Equal(t, "plain", bot.template("plain"))
+
+	// the change is reloaded after the interval
+	now := time.Now()
+	store.now = func() time.Time { return now.Add(time.Minute) }
+	write("pass.yaml", "all_signed: \"everyone signed\"\n")
+	assert.Equal(t, nil, os.Chtimes(filepath.Join(dir, "pass.yaml"), now.Add(time.Second), now.Add(time.Second)))
+	assert.Equal(t, "everyone signed", bot.template(cnf.CommentAllSigned))
+
+	// the reload missing a referred template is rejected
+	store.now = func() time.Time { return now.Add(time.Hour) }
+	assert.Equal(t, nil, os.Remove(filepath.Join(dir, "fail.yml")))
+	assert.Equal(t, "%s need to sign", bot.template(cnf.CommentSomeNeedSign))
+}
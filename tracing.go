@@ -0,0 +1,104 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+)
+
+// the envs of the OTLP endpoint, either of which enables the export of the spans. The exporter is configured by
+// the other OTEL_EXPORTER_OTLP_* envs, and the sampler by the OTEL_TRACES_SAMPLER
+const (
+	envOTLPEndpoint       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envOTLPTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+)
+
+// the attributes of the spans
+const (
+	spanAttrOrg       = "cla.org"
+	spanAttrRepo      = "cla.repo"
+	spanAttrNumber    = "cla.number"
+	spanAttrDecision  = "cla.decision"
+	spanAttrSignState = "cla.sign_state"
+)
+
+// tracer creates the spans of the handling, which are dropped unless startTracing installs the exporter
+var tracer = otel.Tracer(component)
+
+// startTracing exports the spans to the OTLP endpoint over http if it is set in the environment.
+// It returns the function flushing the spans left at the exit
+func startTracing(logger *logrus.Entry) (func(), error) {
+	if os.Getenv(envOTLPEndpoint) == "" && os.Getenv(envOTLPTracesEndpoint) == "" {
+		return func() {}, nil
+	}
+
+	ctx := context.Background()
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the service name is overridden by the OTEL_SERVICE_NAME and the OTEL_RESOURCE_ATTRIBUTES
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(component)),
+		resource.WithFromEnv(), resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return func() {
+		if err := provider.Shutdown(ctx); err != nil {
+			logger.WithError(err).Warning("failed to flush the spans")
+		}
+	}, nil
+}
+
+// prSpanAttributes identifies the pull request of the span
+func prSpanAttributes(org, repo, number string) trace.SpanStartOption {
+	return trace.WithAttributes(
+		attribute.String(spanAttrOrg, org), attribute.String(spanAttrRepo, repo), attribute.String(spanAttrNumber, number))
+}
+
+// withSpan starts the span of a stage of the evaluation, which becomes the parent of the spans started later
+// by the evaluation. It must not be called by the concurrent lookups of the evaluation
+func (pr *prSnapshot) withSpan(name string) trace.Span {
+	var span trace.Span
+	pr.ctx, span = tracer.Start(pr.traceContext(), name, prSpanAttributes(pr.org, pr.repo, pr.number))
+	return span
+}
+
+// startClientSpan starts the span of a request of the evaluation to a server, which is safe for the concurrent
+// lookups of the evaluation
+func (pr *prSnapshot) startClientSpan(name string) trace.Span {
+	_, span := tracer.Start(pr.traceContext(), name, trace.WithSpanKind(trace.SpanKindClient))
+	return span
+}
+
+// endClientSpan marks the span failed if the request failed, and ends it
+func endClientSpan(span trace.Span, success bool) {
+	if !success {
+		span.SetStatus(codes.Error, "the request failed")
+	}
+	span.End()
+}
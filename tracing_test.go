@@ -0,0 +1,63 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestStartTracingWithoutEndpoint(t *testing.T) {
+	t.Setenv(envOTLPEndpoint, "")
+	t.Setenv(envOTLPTracesEndpoint, "")
+	stop, err := startTracing(framework.NewLogger())
+	assert.Equal(t, nil, err)
+	stop()
+}
+
+func TestEvaluationSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	mc := &mockClient{
+		successfulGetPullRequestCommits: true,
+		successfulGetPullRequestLabels:  true,
+		successfulAddPRLabels:           true,
+		successfulCreatePRComment:       true,
+		successfulCheckCLASignature:     true,
+		CLAState:                        client.CLASignStateYes,
+		commits:                         []client.PRCommit{{AuthorName: "u1", AuthorEmail: "e1"}},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{}}
+	ctx, span := tracer.Start(context.Background(), "handlePullRequestEvent")
+	bot.checkIfAllSignedCLA(newPRSnapshot(mc, org, repo, number).withContext(ctx), &repoConfig{}, framework.NewLogger())
+	span.End()
+
+	// the request to the CLA server is a child of the evaluation, which is a child of the handling
+	spans := recorder.Ended()
+	assert.Equal(t, 3, len(spans))
+	assert.Equal(t, "CheckCLASignature", spans[0].Name())
+	assert.Equal(t, "checkIfAllSignedCLA", spans[1].Name())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, spans[2].SpanContext().SpanID(), spans[1].Parent().SpanID())
+	assert.Contains(t, spans[0].Attributes(), attribute.String(spanAttrSignState, client.CLASignStateYes))
+	assert.Contains(t, spans[1].Attributes(), attribute.String(spanAttrDecision, prStatusSigned))
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// a compiled regular expression for a line of the commit trailers, such as `Signed-off-by: name <email>`
+	regexpTrailerLine = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*)[\t ]*:[\t ]*(.+)$`)
+	// a compiled regular expression for the line appended by `git cherry-pick -x`
+	regexpCherryPickLine = regexp.MustCompile(`(?m)^\(cherry picked from commit [0-9a-f]{7,40}\)[\t ]*$`)
+	// a compiled regular expression for the line in the message of the commit created by `git revert`
+	regexpRevertLine = regexp.MustCompile(`(?m)^This reverts commit [0-9a-f]{7,40}`)
+	// a compiled regular expression for the identity in the value of a trailer, such as `name <email>`
+	regexpTrailerIdentity = regexp.MustCompile(`^(.*?)[\t ]*<([^<>\s@]+@[^<>\s]+)>$`)
+)
+
+// parseCommitTrailers parses the trailers in the last paragraph of the commit message.
+// The keys of the result are in lower case, since git compares the trailer keys case-insensitively
+func parseCommitTrailers(message string) map[string][]string {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n")), "\n")
+
+	start := len(lines)
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+	// the message only has a subject, there are no trailers
+	if start == 0 {
+		return nil
+	}
+
+	trailers := map[string][]string{}
+	for _, line := range lines[start:] {
+		m := regexpTrailerLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		key := strings.ToLower(m[1])
+		trailers[key] = append(trailers[key], strings.TrimSpace(m[2]))
+	}
+
+	return trailers
+}
+
+// commitTrailerValues returns the values of the trailer key in the commit message
+func commitTrailerValues(message, key string) []string {
+	return parseCommitTrailers(message)[strings.ToLower(key)]
+}
+
+// isBackportCommit checks whether the commit is a cherry-pick or a revert of another commit
+func isBackportCommit(message string) bool {
+	message = strings.ReplaceAll(message, "\r\n", "\n")
+	return regexpCherryPickLine.MatchString(message) || regexpRevertLine.MatchString(message)
+}
+
+// parseTrailerIdentity parses the name and email in the value of a trailer, such as `Reviewed-by: name <email>`
+func parseTrailerIdentity(value string) (name, email string, ok bool) {
+	m := regexpTrailerIdentity.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return "", "", false
+	}
+
+	name = m[1]
+	if name == "" {
+		name = m[2]
+	}
+	return name, m[2], true
+}
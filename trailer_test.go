@@ -0,0 +1,46 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseCommitTrailers(t *testing.T) {
+	testCases := []struct {
+		desc string
+		in   string
+		out  map[string][]string
+	}{
+		{"only subject", "CLA: 1", nil},
+		{"no trailers", "subject\n\nbody", map[string][]string{}},
+		{
+			"trailers in the last paragraph",
+			"subject\r\n\r\nCLA: 1\r\n\r\nSigned-off-by: u1 <e1>\r\nsigned-off-by: u2 <e2>\r\n",
+			map[string][]string{"signed-off-by": {"u1 <e1>", "u2 <e2>"}},
+		},
+	}
+	for i := range testCases {
+		t.Run(testCases[i].desc, func(t *testing.T) {
+			assert.Equal(t, testCases[i].out, parseCommitTrailers(testCases[i].in))
+		})
+	}
+}
+
+func TestIsBackportCommit(t *testing.T) {
+	assert.Equal(t, true, isBackportCommit("fix bug\n\n(cherry picked from commit 0123456789abcdef)"))
+	assert.Equal(t, true, isBackportCommit("Revert \"fix bug\"\r\n\r\nThis reverts commit 0123456789abcdef.\r\n"))
+	assert.Equal(t, false, isBackportCommit("fix bug\n\nThis is cherry picked from commit 0123456"))
+}
@@ -0,0 +1,112 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"crypto/subtle"
+	"github.com/sirupsen/logrus"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// uiPathPrefix is the prefix of the status page of a pull request, /ui/{org}/{repo}/{number}
+const uiPathPrefix = "/ui/"
+
+// uiPageTemplate renders the CLA status of a pull request
+var uiPageTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>CLA status of {{.Org}}/{{.Repo}}#{{.Number}}</title></head>
+<body>
+<h1>CLA status of {{.Org}}/{{.Repo}}#{{.Number}}</h1>
+<p>Status: <strong>{{.Status}}</strong>, last checked at {{.LastEvaluation.Format "2006-01-02 15:04:05 MST"}}</p>
+<table border="1">
+<tr><th>User</th><th>State</th></tr>
+{{range .SignedUsers}}<tr><td>{{.}}</td><td>signed</td></tr>
+{{end}}{{range .UnsignedUsers}}<tr><td>{{.}}</td><td>unsigned</td></tr>
+{{end}}{{range .UnknownUsers}}<tr><td>{{.}}</td><td>unknown</td></tr>
+{{end}}</table>
+<h2>History</h2>
+<ul>
+{{range .History}}<li>{{.Time.Format "2006-01-02 15:04:05 MST"}} {{.Status}}{{if .Head}} at {{.Head}}{{end}}</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// uiServer serves the status pages of the pull requests from the state store
+type uiServer struct {
+	store stateStore
+	// token is required to read the pages unless it is empty, which makes the pages public
+	token []byte
+	log   *logrus.Entry
+}
+
+// registerUIHandlers mounts the status pages on the mux. The pages are disabled unless they are
+// public or protected by the token
+func registerUIHandlers(mux *http.ServeMux, bot *robot, token []byte, public bool, logger *logrus.Entry) {
+	if !public && len(token) == 0 {
+		logger.Info("the status pages are disabled because they are neither public nor protected by a token")
+		return
+	}
+
+	s := &uiServer{store: bot.store, log: logger}
+	if !public {
+		s.token = token
+	}
+	mux.HandleFunc(uiPathPrefix, s.handleStatusPage)
+}
+
+// authorized accepts the token in the Authorization header or the query parameter `token`,
+// so that the page can be opened from a link
+func (s *uiServer) authorized(r *http.Request) bool {
+	if len(s.token) == 0 {
+		return true
+	}
+
+	key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if key == "" {
+		key = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(key), s.token) == 1
+}
+
+func (s *uiServer) handleStatusPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.authorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, uiPathPrefix), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, ok := s.store.get(parts[0], parts[1], parts[2])
+	if !ok {
+		http.Error(w, "no CLA status of the pull request", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := uiPageTemplate.Execute(w, &state); err != nil {
+		s.log.WithError(err).Errorf("render the status page of %s failed", state.key())
+	}
+}
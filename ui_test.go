@@ -0,0 +1,50 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUIStatusPage(t *testing.T) {
+	bot := &robot{cli: new(mockClient), cnf: &configuration{}, store: newMemoryStateStore()}
+	bot.recordPRState(newPRSnapshot(bot.cli, org, repo, number), false, [3][]string{{"u1"}, {"<u2>"}})
+
+	mux := http.NewServeMux()
+	registerUIHandlers(mux, bot, []byte("secret"), false, framework.NewLogger())
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("/ui/org1/repo1/1").Code)
+
+	w := get("/ui/org1/repo1/1?token=secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, true, strings.Contains(w.Body.String(), "<td>u1</td><td>signed</td>"))
+	assert.Equal(t, true, strings.Contains(w.Body.String(), "<td>&lt;u2&gt;</td><td>unsigned</td>"))
+
+	assert.Equal(t, http.StatusNotFound, get("/ui/org1/repo1/2?token=secret").Code)
+	assert.Equal(t, http.StatusNotFound, get("/ui/org1/repo1?token=secret").Code)
+
+	mux = http.NewServeMux()
+	registerUIHandlers(mux, bot, nil, true, framework.NewLogger())
+	assert.Equal(t, http.StatusOK, get("/ui/org1/repo1/1").Code)
+}
@@ -0,0 +1,114 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// urlValidationConfig tightens the validation of the urls of the config items
+type urlValidationConfig struct {
+	// RequireHTTPS rejects the check urls, sign urls and faq urls which are not https
+	RequireHTTPS bool `json:"require_https"`
+	// CheckConnectivity probes the hosts of the check urls at the startup, which fails if any of them
+	// can not be reached
+	CheckConnectivity bool `json:"check_connectivity"`
+}
+
+// validateURL checks that the url of the field is an absolute http or https url. The check urls are checked
+// with their variables replaced, and can not have a fragment since the email may be added to the query
+func validateURL(field, rawURL string, isCheckURL bool) error {
+	urlStr := rawURL
+	if isCheckURL {
+		urlStr = claCheckURL(rawURL, "org", "repo", "user@example.com")
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return fmt.Errorf("the %s %q is not a valid url: %w", field, rawURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("the %s %q must be an absolute http or https url", field, rawURL)
+	}
+	if isCheckURL && strings.Contains(rawURL, "#") {
+		return fmt.Errorf("the %s %q can not have a fragment, the email may be added to the query", field, rawURL)
+	}
+	return nil
+}
+
+// validateURLs checks the check urls, the sign url, the faq url and the signature verify url
+func (c *repoConfig) validateURLs() error {
+	for _, checkURL := range c.checkURLs() {
+		if checkURL == "" {
+			continue
+		}
+		if err := validateURL("check_url", checkURL, true); err != nil {
+			return err
+		}
+	}
+
+	if c.SignURL != "" {
+		if err := validateURL("sign_url", c.SignURL, false); err != nil {
+			return err
+		}
+	}
+	if c.FAQURL != "" {
+		if err := validateURL("faq_url", c.FAQURL, false); err != nil {
+			return err
+		}
+	}
+	if c.SignatureVerifyURL != "" {
+		if err := validateURL("signature_verify_url", c.SignatureVerifyURL, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireHTTPS checks that the check urls, the sign url and the faq url are https
+func (c *repoConfig) requireHTTPS() error {
+	for _, rawURL := range append(c.checkURLs(), c.SignURL, c.FAQURL) {
+		if rawURL != "" && !strings.HasPrefix(rawURL, "https://") {
+			return fmt.Errorf("the url %q must be https since the require_https of the url_validation is set", rawURL)
+		}
+	}
+	return nil
+}
+
+// validateURLSchemes checks the urls of all the config items against the url_validation
+func (c *configuration) validateURLSchemes() error {
+	if !c.URLValidation.RequireHTTPS {
+		return nil
+	}
+
+	for i := range c.ConfigItems {
+		for _, item := range c.inheritedItems(i) {
+			if err := item.requireHTTPS(); err != nil {
+				return fmt.Errorf("config_items[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkConnectivity probes the hosts of the check urls, and returns the error of the first unreachable one
+func (c *configuration) checkConnectivity(probe *readinessProbe) error {
+	for _, host := range checkURLHosts(c) {
+		if ok, reason := probe.probe(host); !ok {
+			return fmt.Errorf("the CLA server %s can not be reached: %s", host, reason)
+		}
+	}
+	return nil
+}
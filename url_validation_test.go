@@ -0,0 +1,67 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/server-common-lib/utils"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateURLs(t *testing.T) {
+	testCases := []struct {
+		desc string
+		in   repoConfig
+		err  string
+	}{
+		{"valid", repoConfig{CheckURL: "https://cla.example.com/icla", SignURL: "https://cla.example.com/sign?c=1"}, ""},
+		{"relative check url", repoConfig{CheckURL: "cla.example.com/icla"}, "must be an absolute http or https url"},
+		{"check url with query", repoConfig{CheckURL: "https://cla.example.com/icla?org=org1"}, ""},
+		{"templated check url", repoConfig{CheckURL: "https://cla.example.com/{org}/{repo}?email={email}"}, ""},
+		{"check url with fragment", repoConfig{CheckURL: "https://cla.example.com/icla#sign"}, "can not have a fragment"},
+		{"one of check urls", repoConfig{CheckURLs: []string{"ftp://cla.example.com"}}, "must be an absolute"},
+		{"faq url", repoConfig{FAQURL: "://faq"}, "the faq_url \"://faq\" is not a valid url"},
+		{"signature verify url", repoConfig{SignatureVerifyURL: "cla.example.com/verify"}, "the signature_verify_url"},
+	}
+	for i := range testCases {
+		t.Run(testCases[i].desc, func(t *testing.T) {
+			err := testCases[i].in.validateURLs()
+			if testCases[i].err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, testCases[i].err)
+			}
+		})
+	}
+}
+
+func TestValidateURLSchemes(t *testing.T) {
+	cnf := &configuration{}
+	assert.NoError(t, utils.LoadFromYaml(findTestdata(t, configYaml), cnf))
+	assert.NoError(t, cnf.Validate())
+
+	cnf.URLValidation.RequireHTTPS = true
+	assert.ErrorContains(t, cnf.Validate(), `config_items[0]: the url "http://localhost:7003/cla" must be https`)
+}
+
+func TestCheckConnectivity(t *testing.T) {
+	cla := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	cnf := &configuration{ConfigItems: []repoConfig{{CheckURL: cla.URL + "/cla"}}}
+	assert.NoError(t, cnf.checkConnectivity(newReadinessProbe()))
+
+	cla.Close()
+	assert.ErrorContains(t, cnf.checkConnectivity(newReadinessProbe()), "the CLA server "+cla.URL+" can not be reached")
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// userMentionsMore summarizes the users beyond the limit of the mentions
+	userMentionsMore = " and %d more"
+	// userMentionsDetails lists all the users in a collapsible block, without mentioning them
+	userMentionsDetails = "\n\n<details><summary>All the %d contributors</summary>\n\n%s\n\n</details>"
+)
+
+// userMentionsConfig bounds the users mentioned in a comment, so that the comment of a pull request with many
+// contributors does not exceed the max length of the comments of the code hosting platform
+type userMentionsConfig struct {
+	// Limit is the max number of the users mentioned in a comment, the rest of them are summarized as
+	// "and N more". It is unlimited if it is 0
+	Limit int `json:"limit"`
+	// Details appends all the users in a collapsible block to the comment whose users are truncated
+	Details bool `json:"details"`
+}
+
+func (c *userMentionsConfig) validate() error {
+	if c.Limit < 0 {
+		return errors.New("the limit of the user_mentions can not be negative")
+	}
+	return nil
+}
+
+func (c *userMentionsConfig) truncated(users []string) bool {
+	return c.Limit != 0 && len(users) > c.Limit
+}
+
+// userMarks marks the users by the user_mark_format, and summarizes those beyond the limit of the user_mentions
+func (r *commentRenderer) userMarks(users []string) string {
+	shown := users
+	if r.cnf.UserMentions.truncated(users) {
+		shown = users[:r.cnf.UserMentions.Limit]
+	}
+
+	marks := make([]string, len(shown))
+	for i, user := range shown {
+		marks[i] = strings.ReplaceAll(r.cnf.UserMarkFormat, r.cnf.PlaceholderCommitter, escapeMarkdownName(user))
+	}
+
+	s := strings.Join(marks, ", ")
+	if n := len(users) - len(shown); n > 0 {
+		s += fmt.Sprintf(userMentionsMore, n)
+	}
+	return s
+}
+
+// userDetails lists all the users in a collapsible block if they are truncated and the details is enabled
+func (r *commentRenderer) userDetails(users []string) string {
+	if !r.cnf.UserMentions.Details || !r.cnf.UserMentions.truncated(users) {
+		return ""
+	}
+
+	names := make([]string, len(users))
+	for i, user := range users {
+		names[i] = escapeMarkdownName(user)
+	}
+	return fmt.Sprintf(userMentionsDetails, len(users), strings.Join(names, ", "))
+}
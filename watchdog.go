@@ -0,0 +1,140 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultManualReviewLabel is used when the manual_review_label of the watchdog is not configured
+	defaultManualReviewLabel = "cla-manual-review"
+	// defaultCommentWatchdogExceeded is used when the comment_watchdog_exceeded is not configured
+	defaultCommentWatchdogExceeded = "The CLA check of this pull request was stopped because %s. " +
+		"A maintainer needs to review the CLA status manually."
+
+	commitStatusDescriptionManualReview = "The CLA check was stopped and needs a manual review"
+)
+
+// watchdogConfig bounds the work of one evaluation, so that a pathological pull request can not
+// block the handling of the other events. Each limit is disabled if it is 0
+type watchdogConfig struct {
+	// MaxAPICalls is the max number of the calls to the code hosting platform to read the pull request
+	MaxAPICalls int `json:"max_api_calls"`
+	// MaxCLALookups is the max number of the requests to the CLA server
+	MaxCLALookups int `json:"max_cla_lookups"`
+	// TimeoutSeconds is the max wall time of an evaluation
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// ManualReviewLabel is added to the pull request when the evaluation is stopped. Default is cla-manual-review
+	ManualReviewLabel string `json:"manual_review_label"`
+}
+
+func (c *watchdogConfig) validate() error {
+	if c.MaxAPICalls < 0 || c.MaxCLALookups < 0 || c.TimeoutSeconds < 0 {
+		return errors.New("the limits of the watchdog can not be negative")
+	}
+	return nil
+}
+
+func (c *watchdogConfig) manualReviewLabel() string {
+	if c.ManualReviewLabel == "" {
+		return defaultManualReviewLabel
+	}
+	return c.ManualReviewLabel
+}
+
+// evaluationWatchdog tracks the work of one evaluation. All the methods are safe on a nil watchdog,
+// which never trips, and for the concurrent lookups of the evaluation
+type evaluationWatchdog struct {
+	mu         sync.Mutex
+	cfg        *watchdogConfig
+	deadline   time.Time
+	apiCalls   int
+	claLookups int
+	reason     string
+	now        func() time.Time
+}
+
+func newEvaluationWatchdog(cfg *watchdogConfig) *evaluationWatchdog {
+	w := &evaluationWatchdog{cfg: cfg, now: time.Now}
+	if cfg.TimeoutSeconds != 0 {
+		w.deadline = w.now().Add(time.Duration(cfg.TimeoutSeconds) * time.Second)
+	}
+	return w
+}
+
+func (w *evaluationWatchdog) countAPICall() {
+	if w != nil {
+		w.mu.Lock()
+		w.apiCalls++
+		w.mu.Unlock()
+	}
+}
+
+func (w *evaluationWatchdog) countCLALookup() {
+	if w != nil {
+		w.mu.Lock()
+		w.claLookups++
+		w.mu.Unlock()
+	}
+}
+
+// tripped checks the limits and returns true if any of them is exceeded. The reason is kept once tripped
+func (w *evaluationWatchdog) tripped() bool {
+	if w == nil {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.reason != "" {
+		return true
+	}
+
+	switch {
+	case w.cfg.MaxAPICalls != 0 && w.apiCalls > w.cfg.MaxAPICalls:
+		w.reason = fmt.Sprintf("it made more than %d api calls", w.cfg.MaxAPICalls)
+	case w.cfg.MaxCLALookups != 0 && w.claLookups > w.cfg.MaxCLALookups:
+		w.reason = fmt.Sprintf("it made more than %d CLA lookups", w.cfg.MaxCLALookups)
+	case !w.deadline.IsZero() && w.now().After(w.deadline):
+		w.reason = fmt.Sprintf("it took more than %d seconds", w.cfg.TimeoutSeconds)
+	}
+	return w.reason != ""
+}
+
+// finalizeTrippedEvaluation stops the evaluation with a partial decision: the pull request is labeled
+// to be reviewed manually and the reason is explained in a comment. The result of an earlier evaluation
+// no longer holds, so the yes label is removed and the status is pending until the manual review
+func (bot *robot) finalizeTrippedEvaluation(pr *prSnapshot, repoCnf *repoConfig, logger *logrus.Entry) {
+	watchdogExceededTotal.Inc()
+	logger.WithFields(logrus.Fields{
+		"api-calls":   pr.watchdog.apiCalls,
+		"cla-lookups": pr.watchdog.claLookups,
+	}).Warningf("the evaluation of %s is stopped by the watchdog: %s", pr.key(), pr.watchdog.reason)
+
+	if repoCnf.enforces(enforcementLabel) &&
+		!bot.addPRLabels(pr, []string{bot.labelName(pr, bot.prConfig(pr).Watchdog.manualReviewLabel())}) {
+		bot.labelUpdateFailed(pr, repoCnf, bot.renderer(pr).updateLabelFailed())
+	}
+	if labels, ok := pr.getLabels(); ok && !bot.removeCLALabel(pr, labels, repoCnf, repoCnf.CLALabelYes) {
+		bot.labelUpdateFailed(pr, repoCnf, bot.renderer(pr).updateLabelFailed())
+	}
+	bot.reportCommitStatus(pr, repoCnf, commitStatusPending, commitStatusDescriptionManualReview)
+	bot.createPRComment(pr, repoCnf, bot.renderer(pr).watchdogExceeded(pr.watchdog.reason))
+	bot.recordPRState(pr, false, [3][]string{})
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Huawei Technologies Co., Ltd. 2024. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"github.com/opensourceways/robot-framework-lib/client"
+	"github.com/opensourceways/robot-framework-lib/framework"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEvaluationWatchdog(t *testing.T) {
+	var nilWatchdog *evaluationWatchdog
+	nilWatchdog.countAPICall()
+	assert.Equal(t, false, nilWatchdog.tripped())
+
+	w := newEvaluationWatchdog(&watchdogConfig{MaxCLALookups: 1})
+	w.countCLALookup()
+	assert.Equal(t, false, w.tripped())
+	w.countCLALookup()
+	assert.Equal(t, true, w.tripped())
+	assert.Equal(t, "it made more than 1 CLA lookups", w.reason)
+
+	now := time.Now()
+	w = newEvaluationWatchdog(&watchdogConfig{TimeoutSeconds: 10})
+	w.now = func() time.Time { return now.Add(time.Minute) }
+	assert.Equal(t, true, w.tripped())
+}
+
+func TestCheckIfAllSignedCLAWithWatchdog(t *testing.T) {
+	mc := &mockClient{
+		successfulGetPullRequestCommits: true,
+		successfulGetPullRequestLabels:  true,
+		successfulAddPRLabels:           true,
+		successfulCreatePRComment:       true,
+		successfulCheckCLASignature:     true,
+		CLAState:                        client.CLASignStateYes,
+		commits: []client.PRCommit{
+			{AuthorName: "u1", AuthorEmail: "e1"},
+			{AuthorName: "u2", AuthorEmail: "e2"},
+		},
+	}
+	bot := &robot{cli: mc, cnf: &configuration{Watchdog: watchdogConfig{MaxCLALookups: 1}}, store: newMemoryStateStore()}
+	before := testutil.ToFloat64(watchdogExceededTotal)
+
+	bot.checkIfAllSignedCLA(newPRSnapshot(mc, org, repo, number), &repoConfig{}, framework.NewLogger())
+	assert.Equal(t, before+1, testutil.ToFloat64(watchdogExceededTotal))
+	assert.Equal(t, "The CLA check of this pull request was stopped because it made more than 1 CLA lookups. "+
+		"A maintainer needs to review the CLA status manually.", mc.comment)
+	s, _ := bot.store.get(org, repo, number)
+	assert.Equal(t, prStatusUnknown, s.Status)
+
+	// the result of the earlier evaluation is withdrawn
+	mc.labels = []string{"cla-pass"}
+	mc.successfulRemovePRLabels, mc.successfulGetPullRequestCommitMessages, mc.successfulCreateCommitStatus = true, true, true
+	mc.commitMessages = []prCommitMessage{
+		{PRCommit: client.PRCommit{AuthorName: "u1", AuthorEmail: "e1"}, SHA: "sha0"},
+		{PRCommit: client.PRCommit{AuthorName: "u2", AuthorEmail: "e2"}, SHA: "sha1"},
+	}
+	pr := newPRSnapshot(mc, org, repo, number)
+	bot.checkIfAllSignedCLA(pr, &repoConfig{CLALabelYes: "cla-pass", CommitStatus: true}, framework.NewLogger())
+	assert.Equal(t, []string{"cla-pass"}, pr.stats.labelsRemoved)
+	assert.Equal(t, commitStatusPending, mc.status.State)
+	assert.Equal(t, commitStatusPending, pr.stats.commitStatus)
+}